@@ -0,0 +1,378 @@
+// Package admin serves an authenticated HTTP API for managing the Codex
+// webhooks that feed this service.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/settings"
+	"github.com/Acrylic125/webhook-ingest-ws/webhooks"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// maxBodySize bounds request bodies.
+const maxBodySize = 64 << 10
+
+type Handler struct {
+	token         string
+	client        graphql.Client
+	stats         *ingest.Stats
+	callbackURL   string
+	securityToken string
+	bucketID      string
+	validate      *validator.Validate
+	mux           *http.ServeMux
+}
+
+// NewHandler returns the admin API, mounted under /admin/. Requests must send
+// "Authorization: Bearer <token>". Webhooks are created with callbackURL and
+// securityToken; since the token authenticates deliveries to us, no other
+// callback URL is accepted. Only the webhooks in bucketID are listed and
+// deleted, and new ones are created in it.
+func NewHandler(token string, client graphql.Client, stats *ingest.Stats, callbackURL string, securityToken string, bucketID string) *Handler {
+	h := &Handler{
+		token:         token,
+		client:        client,
+		stats:         stats,
+		callbackURL:   callbackURL,
+		securityToken: securityToken,
+		bucketID:      bucketID,
+		validate:      validator.New(),
+		mux:           http.NewServeMux(),
+	}
+	h.mux.HandleFunc("GET /admin/webhooks", h.listWebhooks)
+	h.mux.HandleFunc("POST /admin/webhooks", h.createWebhook)
+	h.mux.HandleFunc("DELETE /admin/webhooks/{id}", h.deleteWebhook)
+	h.mux.HandleFunc("GET /admin/stats", h.listStats)
+	h.mux.HandleFunc("GET /admin/api-tokens", h.listApiTokens)
+	h.mux.HandleFunc("DELETE /admin/api-tokens/{id}", h.deleteApiToken)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// Webhook is a Codex webhook with what we've received from it.
+type Webhook struct {
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Type        codex.WebhookType    `json:"type"`
+	CallbackUrl string               `json:"callbackUrl"`
+	Status      string               `json:"status"`
+	CreatedAt   time.Time            `json:"createdAt"`
+	Stats       *ingest.WebhookStats `json:"stats,omitempty"`
+}
+
+func (h *Handler) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	existing, err := webhooks.ListWebhooks(r.Context(), h.client, h.bucketID)
+	if err != nil {
+		writeCodexError(w, err)
+		return
+	}
+	list := make([]Webhook, len(existing))
+	for i, webhook := range existing {
+		list[i] = Webhook{
+			ID:          webhook.Id,
+			Name:        webhook.Name,
+			Type:        webhook.WebhookType,
+			CallbackUrl: webhook.CallbackUrl,
+			Status:      webhook.Status,
+			CreatedAt:   time.Unix(int64(webhook.Created), 0).UTC(),
+		}
+		if stats, ok := h.stats.Get(webhook.Id); ok {
+			list[i].Stats = &stats
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handler) listStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.stats.All())
+}
+
+// Comparison is a numeric condition; set fields are combined.
+type Comparison struct {
+	Gt  string `json:"gt,omitempty" validate:"omitempty,number"`
+	Gte string `json:"gte,omitempty" validate:"omitempty,number"`
+	Lt  string `json:"lt,omitempty" validate:"omitempty,number"`
+	Lte string `json:"lte,omitempty" validate:"omitempty,number"`
+	Eq  string `json:"eq,omitempty" validate:"omitempty,number"`
+}
+
+func (c *Comparison) input() *codex.ComparisonOperatorInput {
+	if c == nil {
+		return nil
+	}
+	input := codex.ComparisonOperatorInput{}
+	for _, field := range []struct {
+		value string
+		dst   **string
+	}{{c.Gt, &input.Gt}, {c.Gte, &input.Gte}, {c.Lt, &input.Lt}, {c.Lte, &input.Lte}, {c.Eq, &input.Eq}} {
+		if field.value != "" {
+			*field.dst = codex.Ptr(field.value)
+		}
+	}
+	if input == (codex.ComparisonOperatorInput{}) {
+		return nil
+	}
+	return &input
+}
+
+// CreateWebhookRequest creates one webhook. The name is prefixed with
+// webhooks.AdminPrefix so the reconciler leaves it alone.
+type CreateWebhookRequest struct {
+	Type            string `json:"type" validate:"required,oneof=TOKEN_PAIR_EVENT PRICE_EVENT MARKET_CAP_EVENT"`
+	Name            string `json:"name" validate:"required,max=100"`
+	AlertRecurrence string `json:"alertRecurrence,omitempty" validate:"omitempty,oneof=ONCE INDEFINITE"`
+	NetworkIds      []int  `json:"networkIds,omitempty" validate:"dive,min=1"`
+	PairAddress     string `json:"pairAddress,omitempty"`
+	TokenAddress    string `json:"tokenAddress,omitempty"`
+
+	// TOKEN_PAIR_EVENT only.
+	ExchangeAddress string   `json:"exchangeAddress,omitempty"`
+	Maker           string   `json:"maker,omitempty"`
+	EventTypes      []string `json:"eventTypes,omitempty" validate:"dive,oneof=SWAP MINT BURN SYNC BUY SELL COLLECT COLLECT_PROTOCOL"`
+	MinSwapValueUsd string   `json:"minSwapValueUsd,omitempty" validate:"omitempty,number"`
+
+	// PRICE_EVENT only.
+	PriceUsd *Comparison `json:"priceUsd,omitempty"`
+
+	// MARKET_CAP_EVENT only, at least one of.
+	FdvMarketCapUsd         *Comparison `json:"fdvMarketCapUsd,omitempty"`
+	CirculatingMarketCapUsd *Comparison `json:"circulatingMarketCapUsd,omitempty"`
+}
+
+func (h *Handler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	req := CreateWebhookRequest{}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		writeError(w, http.StatusBadRequest, "validation failed: "+err.Error())
+		return
+	}
+	input, err := h.createInput(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := codex.CreateWebhooks(r.Context(), h.client, input)
+	if err != nil {
+		writeCodexError(w, err)
+		return
+	}
+	var created []*codex.CreatedWebhook
+	created = append(created, resp.CreateWebhooks.TokenPairEventWebhooks...)
+	created = append(created, resp.CreateWebhooks.PriceWebhooks...)
+	created = append(created, resp.CreateWebhooks.MarketCapWebhooks...)
+	for _, webhook := range created {
+		if webhook != nil {
+			log.Info().Str("webhook_id", webhook.Id).Str("name", webhook.Name).Msg("webhook created through admin API")
+			writeJSON(w, http.StatusCreated, webhook)
+			return
+		}
+	}
+	writeError(w, http.StatusBadGateway, "codex created no webhook")
+}
+
+func (h *Handler) createInput(req CreateWebhookRequest) (codex.CreateWebhooksInput, error) {
+	name := webhooks.AdminPrefix + req.Name
+	bucketID, sortkey := codex.Ptr(h.bucketID), codex.Ptr(webhooks.SortkeyAdmin)
+	callbackURL := h.callbackURL
+	recurrence := codex.AlertRecurrenceIndefinite
+	if req.AlertRecurrence != "" {
+		recurrence = codex.AlertRecurrence(req.AlertRecurrence)
+	}
+
+	if req.Type == ingest.WebhookTypeTokenPairEvent {
+		args := webhooks.TokenPairWebhookArgs(settings.WebhookConfig{
+			Name:            name,
+			NetworkIds:      req.NetworkIds,
+			PairAddress:     req.PairAddress,
+			TokenAddress:    req.TokenAddress,
+			ExchangeAddress: req.ExchangeAddress,
+			Maker:           req.Maker,
+			EventTypes:      req.EventTypes,
+			MinSwapValueUsd: req.MinSwapValueUsd,
+		}, callbackURL, h.securityToken)
+		args.AlertRecurrence = recurrence
+		args.BucketId = bucketID
+		args.BucketSortkey = sortkey
+		return codex.CreateWebhooksInput{
+			TokenPairEventWebhooksInput: &codex.CreateTokenPairEventWebhooksInput{
+				Webhooks: []codex.CreateTokenPairEventWebhookArgs{args},
+			},
+		}, nil
+	}
+
+	if req.TokenAddress == "" || len(req.NetworkIds) != 1 {
+		return codex.CreateWebhooksInput{}, fmt.Errorf("%s webhooks need a tokenAddress and exactly one networkId", req.Type)
+	}
+	var pairAddress *codex.StringEqualsConditionInput
+	if req.PairAddress != "" {
+		pairAddress = &codex.StringEqualsConditionInput{Eq: req.PairAddress}
+	}
+	tokenAddress := codex.StringEqualsConditionInput{Eq: req.TokenAddress}
+	networkID := codex.IntEqualsConditionInput{Eq: req.NetworkIds[0]}
+
+	if req.Type == ingest.WebhookTypePriceEvent {
+		priceUsd := req.PriceUsd.input()
+		if priceUsd == nil {
+			return codex.CreateWebhooksInput{}, errors.New("PRICE_EVENT webhooks need a priceUsd condition")
+		}
+		return codex.CreateWebhooksInput{
+			PriceWebhooksInput: &codex.CreatePriceWebhooksInput{
+				Webhooks: []codex.CreatePriceWebhookArgs{{
+					Name:            name,
+					CallbackUrl:     callbackURL,
+					SecurityToken:   h.securityToken,
+					AlertRecurrence: recurrence,
+					Conditions: codex.PriceEventWebhookConditionInput{
+						TokenAddress: tokenAddress,
+						NetworkId:    networkID,
+						PriceUsd:     *priceUsd,
+						PairAddress:  pairAddress,
+					},
+					Deduplicate:   codex.Ptr(true),
+					BucketId:      bucketID,
+					BucketSortkey: sortkey,
+				}},
+			},
+		}, nil
+	}
+
+	fdv, circulating := req.FdvMarketCapUsd.input(), req.CirculatingMarketCapUsd.input()
+	if fdv == nil && circulating == nil {
+		return codex.CreateWebhooksInput{}, errors.New("MARKET_CAP_EVENT webhooks need a fdvMarketCapUsd or circulatingMarketCapUsd condition")
+	}
+	return codex.CreateWebhooksInput{
+		MarketCapWebhooksInput: &codex.CreateMarketCapWebhooksInput{
+			Webhooks: []codex.CreateMarketCapWebhookArgs{{
+				Name:            name,
+				CallbackUrl:     callbackURL,
+				SecurityToken:   h.securityToken,
+				AlertRecurrence: recurrence,
+				Conditions: codex.MarketCapEventWebhookConditionInput{
+					TokenAddress:            tokenAddress,
+					NetworkId:               networkID,
+					FdvMarketCapUsd:         fdv,
+					CirculatingMarketCapUsd: circulating,
+					PairAddress:             pairAddress,
+				},
+				Deduplicate:   codex.Ptr(true),
+				BucketId:      bucketID,
+				BucketSortkey: sortkey,
+			}},
+		},
+	}, nil
+}
+
+type DeleteWebhookResponse struct {
+	DeletedIds []string `json:"deletedIds"`
+}
+
+func (h *Handler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	result, err := webhooks.DeleteWebhooks(r.Context(), h.client, h.bucketID, []string{id})
+	if err != nil {
+		writeCodexError(w, err)
+		return
+	}
+	if len(result.Foreign) > 0 {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("webhook %s is not in bucket %s", id, h.bucketID))
+		return
+	}
+	deleted := DeleteWebhookResponse{DeletedIds: []string{}}
+	deleted.DeletedIds = append(deleted.DeletedIds, result.Deleted...)
+	if len(deleted.DeletedIds) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("webhook %s not found", id))
+		return
+	}
+	log.Info().Str("webhook_id", id).Msg("webhook deleted through admin API")
+	writeJSON(w, http.StatusOK, deleted)
+}
+
+// ApiToken is a short-lived Codex API token, without the token itself.
+type ApiToken struct {
+	ID           string  `json:"id"`
+	ExpiresAt    string  `json:"expiresAt"`
+	RequestLimit string  `json:"requestLimit"`
+	Remaining    *string `json:"remaining"`
+}
+
+func (h *Handler) listApiTokens(w http.ResponseWriter, r *http.Request) {
+	resp, err := codex.ApiTokens(r.Context(), h.client)
+	if err != nil {
+		writeCodexError(w, err)
+		return
+	}
+	list := make([]ApiToken, len(resp.ApiTokens))
+	for i, token := range resp.ApiTokens {
+		list[i] = ApiToken{
+			ID:           token.Id,
+			ExpiresAt:    token.ExpiresTimeString,
+			RequestLimit: token.RequestLimit,
+			Remaining:    token.Remaining,
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handler) deleteApiToken(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := codex.DeleteApiToken(r.Context(), h.client, id); err != nil {
+		writeCodexError(w, err)
+		return
+	}
+	log.Info().Str("token_id", id).Msg("api token deleted through admin API")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("Failed to write admin response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func writeCodexError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, codex.ErrBadInput):
+		status = http.StatusBadRequest
+	case errors.Is(err, codex.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, codex.ErrRateLimited):
+		status = http.StatusTooManyRequests
+	}
+	writeError(w, status, err.Error())
+}
@@ -0,0 +1,209 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/codex/codexstub"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/webhooks"
+)
+
+const (
+	adminToken  = "admin-token"
+	callbackURL = "http://localhost/send-data"
+)
+
+func newTestHandler(t *testing.T, stub *codexstub.Server, stats *ingest.Stats, bucketID string) *Handler {
+	t.Helper()
+	client := codex.NewClient(codex.Options{
+		Endpoint:   stub.URL(),
+		Token:      "codex-key",
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+	})
+	return NewHandler(adminToken, client, stats, callbackURL, "secret", bucketID)
+}
+
+// do serves a request authenticated with adminToken.
+func do(h *Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// create creates a webhook through h and returns its ID.
+func create(t *testing.T, h *Handler, body string) string {
+	t.Helper()
+	w := do(h, "POST", "/admin/webhooks", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create got %d: %s", w.Code, w.Body)
+	}
+	var created codex.CreatedWebhook
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	return created.Id
+}
+
+func TestAuthorization(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	h := newTestHandler(t, stub, ingest.NewStats(), "bucket")
+
+	for _, tt := range []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"valid", "Bearer " + adminToken, http.StatusOK},
+		{"no header", "", http.StatusUnauthorized},
+		{"not bearer", "Basic " + adminToken, http.StatusUnauthorized},
+		{"wrong token", "Bearer other-token", http.StatusUnauthorized},
+		{"token prefix", "Bearer " + adminToken[:5], http.StatusUnauthorized},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/admin/stats", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	h := newTestHandler(t, stub, ingest.NewStats(), "bucket")
+
+	for _, tt := range []struct {
+		name string
+		body string
+	}{
+		{"invalid JSON", `{"type":`},
+		{"unknown field", `{"type":"TOKEN_PAIR_EVENT","name":"weth","callbackUrl":"https://elsewhere.example/hook"}`},
+		{"no type", `{"name":"weth"}`},
+		{"unknown type", `{"type":"NFT_EVENT","name":"weth"}`},
+		{"no name", `{"type":"TOKEN_PAIR_EVENT"}`},
+		{"bad network", `{"type":"TOKEN_PAIR_EVENT","name":"weth","networkIds":[0]}`},
+		{"bad event type", `{"type":"TOKEN_PAIR_EVENT","name":"weth","eventTypes":["TRANSFER"]}`},
+		{"bad number", `{"type":"TOKEN_PAIR_EVENT","name":"weth","minSwapValueUsd":"lots"}`},
+		{"price without condition", `{"type":"PRICE_EVENT","name":"weth","tokenAddress":"0xweth","networkIds":[1]}`},
+		{"price on two networks", `{"type":"PRICE_EVENT","name":"weth","tokenAddress":"0xweth","networkIds":[1,56],"priceUsd":{"gt":"1"}}`},
+		{"market cap without condition", `{"type":"MARKET_CAP_EVENT","name":"weth","tokenAddress":"0xweth","networkIds":[1]}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(h, "POST", "/admin/webhooks", tt.body); w.Code != http.StatusBadRequest {
+				t.Fatalf("got %d, want 400: %s", w.Code, w.Body)
+			}
+		})
+	}
+	if n := len(stub.Webhooks()); n != 0 {
+		t.Fatalf("codex has %d webhooks after invalid requests, want 0", n)
+	}
+
+	id := create(t, h, `{"type":"PRICE_EVENT","name":"weth","tokenAddress":"0xweth","networkIds":[1],"priceUsd":{"gt":"3000"}}`)
+	webhook := stub.Webhooks()[0]
+	if webhook.ID != id || webhook.Name != webhooks.AdminPrefix+"weth" || webhook.Type != codex.WebhookTypePriceEvent ||
+		webhook.CallbackURL != callbackURL || webhook.SecurityToken != "secret" || webhook.BucketID != "bucket" || webhook.BucketSortkey != webhooks.SortkeyAdmin {
+		t.Fatalf("created %+v", webhook)
+	}
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	h := newTestHandler(t, stub, ingest.NewStats(), "bucket")
+	ours := create(t, h, `{"type":"TOKEN_PAIR_EVENT","name":"ours","tokenAddress":"0xweth"}`)
+	foreign := create(t, newTestHandler(t, stub, ingest.NewStats(), "other"), `{"type":"TOKEN_PAIR_EVENT","name":"foreign","tokenAddress":"0xweth"}`)
+
+	if w := do(h, "DELETE", "/admin/webhooks/"+foreign, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("deleting a webhook of another bucket got %d, want 403: %s", w.Code, w.Body)
+	}
+	if n := len(stub.Webhooks()); n != 2 {
+		t.Fatalf("codex has %d webhooks, want the foreign one kept", n)
+	}
+
+	w := do(h, "DELETE", "/admin/webhooks/"+ours, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	var deleted DeleteWebhookResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &deleted); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted.DeletedIds) != 1 || deleted.DeletedIds[0] != ours {
+		t.Fatalf("deleted %v, want %s", deleted.DeletedIds, ours)
+	}
+	if left := stub.Webhooks(); len(left) != 1 || left[0].ID != foreign {
+		t.Fatalf("codex has %+v, want only the foreign webhook", left)
+	}
+
+	if w := do(h, "DELETE", "/admin/webhooks/"+ours, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("deleting again got %d, want 404: %s", w.Code, w.Body)
+	}
+}
+
+func TestListWebhooksMergesStats(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	stats := ingest.NewStats()
+	h := newTestHandler(t, stub, stats, "bucket")
+	received := create(t, h, `{"type":"TOKEN_PAIR_EVENT","name":"received","tokenAddress":"0xweth"}`)
+	quiet := create(t, h, `{"type":"TOKEN_PAIR_EVENT","name":"quiet","tokenAddress":"0xusdc"}`)
+	foreign := create(t, newTestHandler(t, stub, ingest.NewStats(), "other"), `{"type":"TOKEN_PAIR_EVENT","name":"foreign","tokenAddress":"0xweth"}`)
+	stats.Record(received, ingest.WebhookTypeTokenPairEvent, 3)
+	stats.Record(received, ingest.WebhookTypeTokenPairEvent, 2)
+	stats.Record(foreign, ingest.WebhookTypeTokenPairEvent, 1)
+
+	w := do(h, "GET", "/admin/webhooks", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	var list []Webhook
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("listed %+v, want the 2 webhooks of the bucket", list)
+	}
+	for _, webhook := range list {
+		switch webhook.ID {
+		case received:
+			if webhook.Stats == nil || webhook.Stats.Deliveries != 2 || webhook.Stats.Events != 5 {
+				t.Fatalf("webhook %s has stats %+v, want 2 deliveries of 5 events", webhook.ID, webhook.Stats)
+			}
+		case quiet:
+			if webhook.Stats != nil {
+				t.Fatalf("webhook %s has stats %+v, want none", webhook.ID, webhook.Stats)
+			}
+		default:
+			t.Fatalf("listed webhook %s outside the bucket", webhook.ID)
+		}
+		if webhook.CallbackUrl != callbackURL || webhook.Type != codex.WebhookTypeTokenPairEvent {
+			t.Fatalf("listed %+v", webhook)
+		}
+	}
+
+	// The stats endpoint covers every webhook received from, in any bucket.
+	w = do(h, "GET", "/admin/stats", "")
+	var all []ingest.WebhookStats
+	if err := json.Unmarshal(w.Body.Bytes(), &all); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("stats %+v, want 2 webhooks", all)
+	}
+}
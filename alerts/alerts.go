@@ -0,0 +1,465 @@
+// Package alerts lets signed-in clients create price and market cap alerts
+// over the socket. Each alert is a Codex webhook owned by one user, and its
+// deliveries are sent only to that user's connections.
+//
+// Alerts are recorded in a file local to the instance that created them, so
+// alerts need a single instance: deliveries reaching a replica that doesn't
+// hold the alert are dropped rather than sent to its owner.
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/webhooks"
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+// Alert kinds.
+const (
+	KindPrice     = "price"
+	KindMarketCap = "marketCap"
+)
+
+// Directions an alert's value crosses its threshold in.
+const (
+	DirectionAbove = "above"
+	DirectionBelow = "below"
+)
+
+// Market caps a market cap alert can watch.
+const (
+	MarketCapFdv         = "fdv"
+	MarketCapCirculating = "circulating"
+)
+
+// Topic is the envelope topic of triggered alerts.
+const Topic = "alerts"
+
+// SourceAlert is the envelope source of triggered alerts.
+const SourceAlert = "codex-alert"
+
+var (
+	ErrNotFound     = errors.New("alert not found")
+	ErrLimitReached = errors.New("alert limit reached")
+)
+
+// Alert is a user's price or market cap alert.
+type Alert struct {
+	ID           string `json:"id"`
+	UserID       string `json:"userId"`
+	Kind         string `json:"kind"`
+	TokenAddress string `json:"tokenAddress"`
+	NetworkID    int    `json:"networkId"`
+	PairAddress  string `json:"pairAddress,omitempty"`
+	Direction    string `json:"direction"`
+	// Threshold is in USD.
+	Threshold  string                `json:"threshold"`
+	MarketCap  string                `json:"marketCap,omitempty"`
+	Recurrence codex.AlertRecurrence `json:"recurrence"`
+	WebhookID  string                `json:"webhookId"`
+	CreatedAt  time.Time             `json:"createdAt"`
+	// TriggeredAt is when the alert last triggered.
+	TriggeredAt *time.Time `json:"triggeredAt,omitempty"`
+}
+
+// CreateRequest describes an alert, e.g. a price alert for a token with
+// direction "below" and threshold "0.50".
+type CreateRequest struct {
+	Kind         string `json:"kind" validate:"required,oneof=price marketCap"`
+	TokenAddress string `json:"tokenAddress" validate:"required"`
+	NetworkID    int    `json:"networkId" validate:"required,min=1"`
+	PairAddress  string `json:"pairAddress,omitempty"`
+	Direction    string `json:"direction" validate:"required,oneof=above below"`
+	Threshold    string `json:"threshold" validate:"required,numeric"`
+	// MarketCap is the market cap watched by marketCap alerts, fdv if empty.
+	MarketCap string `json:"marketCap,omitempty" validate:"omitempty,oneof=fdv circulating"`
+	// Recurrence defaults to ONCE: the alert is removed once it triggers.
+	Recurrence string `json:"recurrence,omitempty" validate:"omitempty,oneof=ONCE INDEFINITE"`
+}
+
+// Triggered is sent to the owner of an alert when it triggers, with the
+// delivery Codex sent for it.
+type Triggered struct {
+	Alert Alert           `json:"alert"`
+	Event json.RawMessage `json:"event"`
+}
+
+type Options struct {
+	CallbackURL   string
+	SecurityToken string
+	// BucketID is the Codex bucket alert webhooks are created and deleted
+	// in.
+	BucketID string
+	// MaxPerUser caps the active alerts of each user. Defaults to 20.
+	MaxPerUser int
+	// Timeout of Codex requests. Defaults to 15s.
+	Timeout time.Duration
+}
+
+// Manager creates and deletes the webhooks of alerts and sends their
+// deliveries to the connections of their owner on hub. It implements
+// ingest.Owners.
+type Manager struct {
+	client graphql.Client
+	hub    *ws.Hub[any]
+	store  *Store
+	opts   Options
+
+	mu     sync.Mutex
+	alerts map[string]*Alert
+	// webhooks maps a webhook ID to the ID of its alert.
+	webhooks map[string]string
+	// removed holds the webhooks of alerts removed on triggering until Codex
+	// has deleted them, so retried deliveries are dropped.
+	removed map[string]struct{}
+	// public holds webhooks looked up in Codex and found not to be alerts.
+	public map[string]struct{}
+	// creating counts the alerts being created per user, so concurrent
+	// requests can't go over MaxPerUser.
+	creating map[string]int
+}
+
+// New creates a Manager with the alerts recorded in store. Deliveries from
+// webhooks of recorded alerts must not reach other users, so failing to load
+// them is an error.
+func New(client graphql.Client, hub *ws.Hub[any], store *Store, opts Options) (*Manager, error) {
+	if opts.MaxPerUser == 0 {
+		opts.MaxPerUser = 20
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 15 * time.Second
+	}
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		client:   client,
+		hub:      hub,
+		store:    store,
+		opts:     opts,
+		alerts:   make(map[string]*Alert),
+		webhooks: make(map[string]string),
+		removed:  make(map[string]struct{}),
+		public:   make(map[string]struct{}),
+		creating: make(map[string]int),
+	}
+	for _, alert := range state.Alerts {
+		m.alerts[alert.ID] = &alert
+		m.webhooks[alert.WebhookID] = alert.ID
+	}
+	return m, nil
+}
+
+// Create creates the webhook of an alert owned by userID.
+func (m *Manager) Create(ctx context.Context, userID string, req CreateRequest) (Alert, error) {
+	m.mu.Lock()
+	count := m.creating[userID]
+	for _, alert := range m.alerts {
+		if alert.UserID == userID {
+			count++
+		}
+	}
+	if count >= m.opts.MaxPerUser {
+		m.mu.Unlock()
+		return Alert{}, ErrLimitReached
+	}
+	m.creating[userID]++
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.creating[userID]--; m.creating[userID] <= 0 {
+			delete(m.creating, userID)
+		}
+	}()
+
+	alert := Alert{
+		ID:           newAlertID(),
+		UserID:       userID,
+		Kind:         req.Kind,
+		TokenAddress: req.TokenAddress,
+		NetworkID:    req.NetworkID,
+		PairAddress:  req.PairAddress,
+		Direction:    req.Direction,
+		Threshold:    req.Threshold,
+		Recurrence:   codex.AlertRecurrenceOnce,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if req.Recurrence != "" {
+		alert.Recurrence = codex.AlertRecurrence(req.Recurrence)
+	}
+	if alert.Kind == KindMarketCap {
+		alert.MarketCap = MarketCapFdv
+		if req.MarketCap != "" {
+			alert.MarketCap = req.MarketCap
+		}
+	}
+
+	resp, err := codex.CreateWebhooks(ctx, m.client, m.createInput(alert))
+	if err != nil {
+		return Alert{}, err
+	}
+	for _, webhook := range append(resp.CreateWebhooks.PriceWebhooks, resp.CreateWebhooks.MarketCapWebhooks...) {
+		if webhook != nil {
+			alert.WebhookID = webhook.Id
+		}
+	}
+	if alert.WebhookID == "" {
+		return Alert{}, errors.New("codex created no webhook")
+	}
+
+	m.mu.Lock()
+	m.alerts[alert.ID] = &alert
+	m.webhooks[alert.WebhookID] = alert.ID
+	err = m.save()
+	if err != nil {
+		delete(m.alerts, alert.ID)
+		delete(m.webhooks, alert.WebhookID)
+	}
+	m.mu.Unlock()
+	if err != nil {
+		// An alert we can't record would reach every client after a restart.
+		m.deleteWebhook(alert.WebhookID)
+		return Alert{}, err
+	}
+	log.Info().Str("alert_id", alert.ID).Str("user_id", userID).Str("webhook_id", alert.WebhookID).Msg("alert created")
+	return alert, nil
+}
+
+func (m *Manager) createInput(alert Alert) codex.CreateWebhooksInput {
+	comparison := &codex.ComparisonOperatorInput{}
+	if alert.Direction == DirectionAbove {
+		comparison.Gt = codex.Ptr(alert.Threshold)
+	} else {
+		comparison.Lt = codex.Ptr(alert.Threshold)
+	}
+	var pairAddress *codex.StringEqualsConditionInput
+	if alert.PairAddress != "" {
+		pairAddress = &codex.StringEqualsConditionInput{Eq: alert.PairAddress}
+	}
+	name := webhooks.AlertPrefix + alert.ID
+	tokenAddress := codex.StringEqualsConditionInput{Eq: alert.TokenAddress}
+	networkID := codex.IntEqualsConditionInput{Eq: alert.NetworkID}
+
+	if alert.Kind == KindPrice {
+		return codex.CreateWebhooksInput{
+			PriceWebhooksInput: &codex.CreatePriceWebhooksInput{
+				Webhooks: []codex.CreatePriceWebhookArgs{{
+					Name:            name,
+					CallbackUrl:     m.opts.CallbackURL,
+					SecurityToken:   m.opts.SecurityToken,
+					AlertRecurrence: alert.Recurrence,
+					Conditions: codex.PriceEventWebhookConditionInput{
+						TokenAddress: tokenAddress,
+						NetworkId:    networkID,
+						PriceUsd:     *comparison,
+						PairAddress:  pairAddress,
+					},
+					BucketId:      codex.Ptr(m.opts.BucketID),
+					BucketSortkey: codex.Ptr(webhooks.SortkeyAlert),
+				}},
+			},
+		}
+	}
+
+	conditions := codex.MarketCapEventWebhookConditionInput{
+		TokenAddress: tokenAddress,
+		NetworkId:    networkID,
+		PairAddress:  pairAddress,
+	}
+	if alert.MarketCap == MarketCapCirculating {
+		conditions.CirculatingMarketCapUsd = comparison
+	} else {
+		conditions.FdvMarketCapUsd = comparison
+	}
+	return codex.CreateWebhooksInput{
+		MarketCapWebhooksInput: &codex.CreateMarketCapWebhooksInput{
+			Webhooks: []codex.CreateMarketCapWebhookArgs{{
+				Name:            name,
+				CallbackUrl:     m.opts.CallbackURL,
+				SecurityToken:   m.opts.SecurityToken,
+				AlertRecurrence: alert.Recurrence,
+				Conditions:      conditions,
+				BucketId:        codex.Ptr(m.opts.BucketID),
+				BucketSortkey:   codex.Ptr(webhooks.SortkeyAlert),
+			}},
+		},
+	}
+}
+
+// List returns the alerts of userID, oldest first.
+func (m *Manager) List(userID string) []Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	alerts := []Alert{}
+	for _, alert := range m.alerts {
+		if alert.UserID == userID {
+			alerts = append(alerts, *alert)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].CreatedAt.Before(alerts[j].CreatedAt)
+	})
+	return alerts
+}
+
+// Cancel deletes the alert with the given ID if userID owns it.
+func (m *Manager) Cancel(ctx context.Context, userID string, id string) error {
+	m.mu.Lock()
+	alert, ok := m.alerts[id]
+	m.mu.Unlock()
+	if !ok || alert.UserID != userID {
+		return ErrNotFound
+	}
+
+	_, err := webhooks.DeleteWebhooks(ctx, m.client, m.opts.BucketID, []string{alert.WebhookID})
+	if err != nil && !errors.Is(err, codex.ErrNotFound) {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(alert)
+	if err := m.save(); err != nil {
+		log.Error().Err(err).Msg("failed to save alerts")
+	}
+	log.Info().Str("alert_id", id).Str("user_id", userID).Msg("alert cancelled")
+	return nil
+}
+
+// Deliver sends a delivery from the webhook of an alert to the connections of
+// its owner. ONCE alerts are removed once they trigger. Deliveries from alert
+// webhooks this instance doesn't hold, e.g. retries after an alert was
+// removed, are claimed and dropped so they are never broadcast. Price and
+// market cap webhooks it doesn't know are looked up in Codex, and an error is
+// returned if that fails.
+func (m *Manager) Deliver(ctx context.Context, webhookID string, webhookType string, data json.RawMessage) (bool, error) {
+	if webhookType != ingest.WebhookTypePriceEvent && webhookType != ingest.WebhookTypeMarketCapEvent {
+		return false, nil
+	}
+	m.mu.Lock()
+	id, ok := m.webhooks[webhookID]
+	if !ok {
+		_, removed := m.removed[webhookID]
+		_, public := m.public[webhookID]
+		m.mu.Unlock()
+		switch {
+		case removed:
+			log.Debug().Str("webhook_id", webhookID).Msg("dropping delivery of a removed alert")
+			return true, nil
+		case public:
+			return false, nil
+		}
+		return m.claimUnknown(ctx, webhookID)
+	}
+	alert := m.alerts[id]
+	now := time.Now().UTC()
+	alert.TriggeredAt = &now
+	triggered := Triggered{Alert: *alert, Event: data}
+	once := alert.Recurrence == codex.AlertRecurrenceOnce
+	if once {
+		m.remove(alert)
+		m.removed[webhookID] = struct{}{}
+		if err := m.save(); err != nil {
+			log.Error().Err(err).Msg("failed to save alerts")
+		}
+	}
+	m.mu.Unlock()
+
+	envelope := ws.NewEnvelope(ws.EnvelopeEvent, Topic, triggered)
+	envelope.Source = SourceAlert
+	reached, err := m.hub.PublishWhere(envelope, func(client *ws.UserClient[any]) bool {
+		return userID(client) == triggered.Alert.UserID
+	})
+	if err != nil {
+		log.Error().Err(err).Str("alert_id", id).Msg("failed to encode alert")
+	}
+	log.Info().Str("alert_id", id).Str("user_id", triggered.Alert.UserID).Str("type", webhookType).Int("reached", reached).Msg("alert triggered")
+
+	// Codex stops delivering ONCE webhooks after the first time but keeps
+	// them around.
+	if once {
+		go m.deleteWebhook(webhookID)
+	}
+	return true, nil
+}
+
+// claimUnknown looks up a webhook that isn't the webhook of a recorded alert
+// and claims it unless Codex has it as a webhook other than an alert.
+// Webhooks that no longer exist are claimed, as they may have been alerts.
+func (m *Manager) claimUnknown(ctx context.Context, webhookID string) (bool, error) {
+	webhook, err := webhooks.GetWebhook(ctx, m.client, webhookID)
+	if err != nil {
+		return false, err
+	}
+	if webhook != nil && !isAlertWebhook(*webhook) {
+		m.mu.Lock()
+		m.public[webhookID] = struct{}{}
+		m.mu.Unlock()
+		return false, nil
+	}
+	log.Warn().Str("webhook_id", webhookID).Msg("dropping delivery of an unknown alert webhook")
+	return true, nil
+}
+
+func isAlertWebhook(webhook codex.Webhook) bool {
+	return strings.HasPrefix(webhook.Name, webhooks.AlertPrefix) ||
+		(webhook.BucketSortkey != nil && *webhook.BucketSortkey == webhooks.SortkeyAlert)
+}
+
+// deleteWebhook deletes the webhook of a triggered alert, forgetting it once
+// Codex no longer has it.
+func (m *Manager) deleteWebhook(webhookID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.Timeout)
+	defer cancel()
+	if _, err := webhooks.DeleteWebhooks(ctx, m.client, m.opts.BucketID, []string{webhookID}); err != nil {
+		log.Warn().Err(err).Str("webhook_id", webhookID).Msg("failed to delete alert webhook")
+		return
+	}
+	m.mu.Lock()
+	delete(m.removed, webhookID)
+	m.mu.Unlock()
+}
+
+// remove must be called with m.mu held.
+func (m *Manager) remove(alert *Alert) {
+	delete(m.alerts, alert.ID)
+	delete(m.webhooks, alert.WebhookID)
+}
+
+// save must be called with m.mu held.
+func (m *Manager) save() error {
+	state := &State{Alerts: make([]Alert, 0, len(m.alerts))}
+	for _, alert := range m.alerts {
+		state.Alerts = append(state.Alerts, *alert)
+	}
+	sort.Slice(state.Alerts, func(i, j int) bool {
+		return state.Alerts[i].CreatedAt.Before(state.Alerts[j].CreatedAt)
+	})
+	if err := m.store.Save(state); err != nil {
+		return fmt.Errorf("failed to save alerts: %w", err)
+	}
+	return nil
+}
+
+func newAlertID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,356 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/apitokens"
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/codex/codexstub"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+	"github.com/gorilla/websocket"
+)
+
+type testHubManager struct {
+	hub *ws.Hub[any]
+}
+
+func (m *testHubManager) GetHub() *ws.Hub[any]                                          { return m.hub }
+func (m *testHubManager) OnRegister(client *ws.UserClient[any]) error                   { return nil }
+func (m *testHubManager) OnUnregister(client *ws.UserClient[any]) error                 { return nil }
+func (m *testHubManager) OnReceiveMessage(client *ws.UserClient[any], msg []byte) error { return nil }
+
+// userHeader names the user of a test connection, which is anonymous without
+// it.
+const userHeader = "X-Test-User"
+
+func newTestManager(t *testing.T, stub *codexstub.Server, opts Options) *Manager {
+	t.Helper()
+	opts.CallbackURL = "http://localhost/send-data"
+	opts.SecurityToken = "secret"
+	opts.BucketID = "bucket"
+	client := codex.NewClient(codex.Options{Endpoint: stub.URL(), Token: "codex-key"})
+	manager, err := New(client, ws.NewHub[any](), NewStore(filepath.Join(t.TempDir(), "alerts.json")), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+// connect serves the hub of manager and connects a client for each user,
+// with "" for an anonymous one. It returns the alerts each client receives.
+func connect(t *testing.T, manager *Manager, users ...string) []<-chan Triggered {
+	t.Helper()
+	registry := ws.NewRegistry()
+	err := ws.Register(registry, "alerts", &testHubManager{hub: manager.hub}, ws.HubConfig[any]{
+		Path: "/ws",
+		Authenticate: Authenticate(func(r *http.Request) (apitokens.Session, error) {
+			if user := r.Header.Get(userHeader); user != "" {
+				return apitokens.Session{UserID: user}, nil
+			}
+			return apitokens.Session{}, apitokens.ErrNoSession
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub, _ := registry.Get("alerts")
+	server := httptest.NewServer(hub)
+	t.Cleanup(server.Close)
+
+	received := make([]<-chan Triggered, 0, len(users))
+	for _, user := range users {
+		header := http.Header{}
+		if user != "" {
+			header.Set(userHeader, user)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		alerts := make(chan Triggered, 16)
+		go func() {
+			defer close(alerts)
+			for {
+				_, message, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				envelope := struct {
+					Topic string    `json:"topic"`
+					Data  Triggered `json:"data"`
+				}{}
+				if json.Unmarshal(message, &envelope) == nil && envelope.Topic == Topic {
+					alerts <- envelope.Data
+				}
+			}
+		}()
+		received = append(received, alerts)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for manager.hub.ClientCount() < len(users) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for clients to register")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return received
+}
+
+// received returns the alerts that arrive on alerts within wait.
+func received(alerts <-chan Triggered, wait time.Duration) []Triggered {
+	var triggered []Triggered
+	timeout := time.After(wait)
+	for {
+		select {
+		case alert, ok := <-alerts:
+			if !ok {
+				return triggered
+			}
+			triggered = append(triggered, alert)
+		case <-timeout:
+			return triggered
+		}
+	}
+}
+
+func priceAlert() CreateRequest {
+	return CreateRequest{
+		Kind:         KindPrice,
+		TokenAddress: "0xtoken",
+		NetworkID:    1,
+		Direction:    DirectionBelow,
+		Threshold:    "0.5",
+	}
+}
+
+func TestCreateLimit(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	manager := newTestManager(t, stub, Options{MaxPerUser: 2})
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := manager.Create(ctx, "alice", priceAlert()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := manager.Create(ctx, "alice", priceAlert()); !errors.Is(err, ErrLimitReached) {
+		t.Fatalf("third alert: got %v, want %v", err, ErrLimitReached)
+	}
+	if _, err := manager.Create(ctx, "bob", priceAlert()); err != nil {
+		t.Fatalf("another user's alert: %v", err)
+	}
+	if n := len(stub.Webhooks()); n != 3 {
+		t.Fatalf("%d webhooks created, want 3", n)
+	}
+	if alerts := manager.List("alice"); len(alerts) != 2 {
+		t.Fatalf("alice has %d alerts, want 2", len(alerts))
+	}
+}
+
+func TestCreateRecordsWebhook(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	manager := newTestManager(t, stub, Options{})
+
+	alert, err := manager.Create(context.Background(), "alice", CreateRequest{
+		Kind:         KindMarketCap,
+		TokenAddress: "0xtoken",
+		NetworkID:    1,
+		Direction:    DirectionAbove,
+		Threshold:    "1000000",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhooks := stub.Webhooks()
+	if len(webhooks) != 1 || webhooks[0].ID != alert.WebhookID {
+		t.Fatalf("got webhooks %+v, want the alert's webhook %s", webhooks, alert.WebhookID)
+	}
+	webhook := webhooks[0]
+	if webhook.Type != codex.WebhookTypeMarketCapEvent || webhook.Name != "alert:"+alert.ID || webhook.BucketID != "bucket" || webhook.BucketSortkey != "alert" {
+		t.Fatalf("webhook created as %+v", webhook)
+	}
+	if alert.MarketCap != MarketCapFdv || alert.Recurrence != codex.AlertRecurrenceOnce {
+		t.Fatalf("alert created with market cap %q and recurrence %q", alert.MarketCap, alert.Recurrence)
+	}
+
+	reloaded, err := New(manager.client, manager.hub, manager.store, manager.opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alerts := reloaded.List("alice"); len(alerts) != 1 || alerts[0].ID != alert.ID {
+		t.Fatalf("reloaded alerts %+v", alerts)
+	}
+}
+
+func TestCancelOwnAlertOnly(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	manager := newTestManager(t, stub, Options{})
+	ctx := context.Background()
+
+	alert, err := manager.Create(ctx, "alice", priceAlert())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Cancel(ctx, "bob", alert.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("cancelling another user's alert: got %v, want %v", err, ErrNotFound)
+	}
+	if len(stub.Webhooks()) != 1 || len(manager.List("alice")) != 1 {
+		t.Fatal("another user cancelled the alert")
+	}
+
+	if err := manager.Cancel(ctx, "alice", alert.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(stub.Webhooks()); n != 0 {
+		t.Fatalf("%d webhooks left after cancelling", n)
+	}
+	if alerts := manager.List("alice"); len(alerts) != 0 {
+		t.Fatalf("alerts left after cancelling: %+v", alerts)
+	}
+	if err := manager.Cancel(ctx, "alice", alert.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("cancelling twice: got %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestDeliverToOwner(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	manager := newTestManager(t, stub, Options{})
+	ctx := context.Background()
+
+	once, err := manager.Create(ctx, "alice", priceAlert())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recurring := priceAlert()
+	recurring.Recurrence = string(codex.AlertRecurrenceIndefinite)
+	indefinite, err := manager.Create(ctx, "bob", recurring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := connect(t, manager, "alice", "bob", "")
+	alice, bob, anonymous := conns[0], conns[1], conns[2]
+
+	event := json.RawMessage(`{"priceUsd":"0.4"}`)
+	claimed, err := manager.Deliver(ctx, once.WebhookID, ingest.WebhookTypePriceEvent, event)
+	if err != nil || !claimed {
+		t.Fatalf("delivery of alice's alert: claimed %t, %v", claimed, err)
+	}
+	if got := received(alice, time.Second); len(got) != 1 || got[0].Alert.ID != once.ID || string(got[0].Event) != string(event) {
+		t.Fatalf("alice received %+v", got)
+	}
+	if got := received(bob, 100*time.Millisecond); len(got) != 0 {
+		t.Fatalf("bob received alice's alert: %+v", got)
+	}
+
+	// A retry of the triggered ONCE alert is still claimed, and dropped.
+	claimed, err = manager.Deliver(ctx, once.WebhookID, ingest.WebhookTypePriceEvent, event)
+	if err != nil || !claimed {
+		t.Fatalf("retried delivery: claimed %t, %v", claimed, err)
+	}
+	if len(manager.List("alice")) != 0 {
+		t.Fatal("ONCE alert kept after triggering")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(stub.Webhooks()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("webhook of the triggered alert was not deleted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	claimed, err = manager.Deliver(ctx, once.WebhookID, ingest.WebhookTypePriceEvent, event)
+	if err != nil || !claimed {
+		t.Fatalf("delivery after the webhook was deleted: claimed %t, %v", claimed, err)
+	}
+
+	claimed, err = manager.Deliver(ctx, indefinite.WebhookID, ingest.WebhookTypePriceEvent, event)
+	if err != nil || !claimed {
+		t.Fatalf("delivery of bob's alert: claimed %t, %v", claimed, err)
+	}
+	if got := received(bob, time.Second); len(got) != 1 || got[0].Alert.ID != indefinite.ID {
+		t.Fatalf("bob received %+v", got)
+	}
+	if len(manager.List("bob")) != 1 {
+		t.Fatal("INDEFINITE alert removed after triggering")
+	}
+	for _, alerts := range []<-chan Triggered{alice, anonymous} {
+		if got := received(alerts, 100*time.Millisecond); len(got) != 0 {
+			t.Fatalf("bob's alert reached another connection: %+v", got)
+		}
+	}
+}
+
+func TestDeliverUnownedWebhooks(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	manager := newTestManager(t, stub, Options{})
+	ctx := context.Background()
+
+	// An alert created by another instance, and a price webhook that isn't
+	// an alert.
+	other := newTestManager(t, stub, Options{})
+	foreign, err := other.Create(ctx, "alice", priceAlert())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := codex.CreateWebhooks(ctx, manager.client, codex.CreateWebhooksInput{
+		PriceWebhooksInput: &codex.CreatePriceWebhooksInput{
+			Webhooks: []codex.CreatePriceWebhookArgs{{
+				Name:            "prices",
+				CallbackUrl:     "http://localhost/send-data",
+				SecurityToken:   "secret",
+				AlertRecurrence: codex.AlertRecurrenceIndefinite,
+				Conditions: codex.PriceEventWebhookConditionInput{
+					TokenAddress: codex.StringEqualsConditionInput{Eq: "0xtoken"},
+					NetworkId:    codex.IntEqualsConditionInput{Eq: 1},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := resp.CreateWebhooks.PriceWebhooks[0].Id
+
+	tests := []struct {
+		name        string
+		webhookID   string
+		webhookType string
+		claimed     bool
+	}{
+		{"alert of another instance", foreign.WebhookID, ingest.WebhookTypePriceEvent, true},
+		{"deleted webhook", "webhook-404", ingest.WebhookTypeMarketCapEvent, true},
+		{"price webhook", public, ingest.WebhookTypePriceEvent, false},
+		{"token pair webhook", "webhook-404", ingest.WebhookTypeTokenPairEvent, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claimed, err := manager.Deliver(ctx, test.webhookID, test.webhookType, json.RawMessage(`{}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claimed != test.claimed {
+				t.Fatalf("claimed %t, want %t", claimed, test.claimed)
+			}
+		})
+	}
+
+	stub.FailNext("GetWebhooks", http.StatusInternalServerError)
+	manager.client = codex.NewClient(codex.Options{Endpoint: stub.URL(), Token: "codex-key", MaxRetries: -1})
+	if _, err := manager.Deliver(ctx, "webhook-unknown", ingest.WebhookTypePriceEvent, json.RawMessage(`{}`)); err == nil {
+		t.Fatal("delivery from an unknown webhook was decided without Codex")
+	}
+}
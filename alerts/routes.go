@@ -0,0 +1,113 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Acrylic125/webhook-ingest-ws/apitokens"
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+	"github.com/rs/zerolog/log"
+)
+
+type ListRequest struct{}
+
+type ListResponse struct {
+	Alerts []Alert `json:"alerts"`
+}
+
+type CancelRequest struct {
+	ID string `json:"id" validate:"required"`
+}
+
+type CancelResponse struct {
+	ID string `json:"id"`
+}
+
+// Handle registers the createAlert, listAlerts and cancelAlert message types
+// on router. They need a connection to the hub of manager signed in with a
+// session; see Authenticate.
+func Handle(router *ws.Router[any], manager *Manager) {
+	ws.Handle(router, "createAlert", func(client *ws.UserClient[any], req CreateRequest) (Alert, error) {
+		user, err := signedIn(client)
+		if err != nil {
+			return Alert{}, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), manager.opts.Timeout)
+		defer cancel()
+		alert, err := manager.Create(ctx, user, req)
+		if err != nil {
+			return Alert{}, replyError("failed to create alert", err)
+		}
+		return alert, nil
+	})
+	ws.Handle(router, "listAlerts", func(client *ws.UserClient[any], req ListRequest) (ListResponse, error) {
+		user, err := signedIn(client)
+		if err != nil {
+			return ListResponse{}, err
+		}
+		return ListResponse{Alerts: manager.List(user)}, nil
+	})
+	ws.Handle(router, "cancelAlert", func(client *ws.UserClient[any], req CancelRequest) (CancelResponse, error) {
+		user, err := signedIn(client)
+		if err != nil {
+			return CancelResponse{}, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), manager.opts.Timeout)
+		defer cancel()
+		if err := manager.Cancel(ctx, user, req.ID); err != nil {
+			return CancelResponse{}, replyError("failed to cancel alert", err)
+		}
+		return CancelResponse{ID: req.ID}, nil
+	})
+}
+
+func signedIn(client *ws.UserClient[any]) (string, error) {
+	user := userID(client)
+	if user == "" {
+		return "", ws.NewError(ws.ErrCodeInvalidRequest, "alerts need a signed-in connection to the alerts hub")
+	}
+	return user, nil
+}
+
+// replyError maps err to the error reported to the client. Codex errors
+// other than bad input are logged rather than passed on.
+func replyError(message string, err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return ws.NewError(ws.ErrCodeInvalidParams, err.Error())
+	case errors.Is(err, ErrLimitReached):
+		return ws.NewError(ws.ErrCodeInvalidRequest, err.Error())
+	case errors.Is(err, codex.ErrBadInput):
+		return ws.NewError(ws.ErrCodeInvalidParams, err.Error())
+	}
+	log.Error().Err(err).Msg(message)
+	return ws.NewError(ws.ErrCodeInternal, message)
+}
+
+func userID(client *ws.UserClient[any]) string {
+	session, _ := client.Data().(apitokens.Session)
+	return session.UserID
+}
+
+// Authenticate adapts a session authenticator for ws.HubConfig.Authenticate.
+// Connections without a session are accepted anonymously and can't use
+// alerts. Browsers can't set headers on WebSockets, so the session token may
+// also be passed as the access_token query parameter.
+func Authenticate(authenticate func(r *http.Request) (apitokens.Session, error)) func(r *http.Request) (any, error) {
+	return func(r *http.Request) (any, error) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		session, err := authenticate(r)
+		if errors.Is(err, apitokens.ErrNoSession) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return session, nil
+	}
+}
@@ -0,0 +1,98 @@
+package apitokens
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/rs/zerolog/log"
+)
+
+// Handler serves a session's token: POST returns it, issuing or refreshing
+// it as needed, and DELETE revokes it.
+type Handler struct {
+	vendor       *Vendor
+	authenticate func(r *http.Request) (Session, error)
+}
+
+func NewHandler(vendor *Vendor, authenticate func(r *http.Request) (Session, error)) *Handler {
+	return &Handler{vendor: vendor, authenticate: authenticate}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Browsers call this cross-origin with an Authorization header.
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "POST, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+		w.Header().Set("Access-Control-Max-Age", "600")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "POST, DELETE, OPTIONS")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	session, err := h.authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := h.vendor.Revoke(r.Context(), session); err != nil {
+			log.Error().Err(err).Str("user_id", session.UserID).Msg("Failed to revoke api token")
+			writeVendorError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	token, err := h.vendor.Token(r.Context(), session)
+	if err != nil {
+		if !errors.Is(err, ErrQuotaExceeded) {
+			log.Error().Err(err).Str("user_id", session.UserID).Msg("Failed to issue api token")
+		}
+		writeVendorError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, token)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("Failed to write api token response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+// writeVendorError doesn't pass Codex's messages on, as they may describe our
+// account.
+func writeVendorError(w http.ResponseWriter, err error) {
+	quotaErr := &QuotaError{}
+	switch {
+	case errors.As(err, &quotaErr):
+		w.Header().Set("Retry-After", strconv.Itoa(int(quotaErr.RetryAfter.Round(time.Second).Seconds())))
+		writeError(w, http.StatusTooManyRequests, quotaErr.Error())
+	case errors.Is(err, codex.ErrRateLimited), errors.Is(err, codex.ErrUnavailable):
+		writeError(w, http.StatusServiceUnavailable, "codex is unavailable")
+	default:
+		writeError(w, http.StatusBadGateway, "codex request failed")
+	}
+}
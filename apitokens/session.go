@@ -0,0 +1,74 @@
+package apitokens
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoSession      = errors.New("no session token")
+	ErrInvalidSession = errors.New("invalid session token")
+	ErrSessionExpired = errors.New("session expired")
+)
+
+type sessionClaims struct {
+	Subject   string `json:"sub"`
+	SessionID string `json:"sid"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// SessionAuthenticator returns a func reading the session from an
+// "Authorization: Bearer <jwt>" header. The JWT must be signed with HS256
+// using key and carry the user ID as sub, an exp and optionally a session ID
+// as sid.
+func SessionAuthenticator(key []byte) func(r *http.Request) (Session, error) {
+	return func(r *http.Request) (Session, error) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			return Session{}, ErrNoSession
+		}
+
+		parts := strings.Split(token, ".")
+		if len(parts) != 3 {
+			return Session{}, ErrInvalidSession
+		}
+		header := struct {
+			Alg string `json:"alg"`
+		}{}
+		if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+			return Session{}, ErrInvalidSession
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return Session{}, ErrInvalidSession
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(parts[0] + "." + parts[1]))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return Session{}, ErrInvalidSession
+		}
+
+		claims := sessionClaims{}
+		if err := decodeSegment(parts[1], &claims); err != nil || claims.Subject == "" || claims.ExpiresAt == 0 {
+			return Session{}, ErrInvalidSession
+		}
+		if !time.Now().Before(time.Unix(claims.ExpiresAt, 0)) {
+			return Session{}, ErrSessionExpired
+		}
+		return Session{UserID: claims.Subject, SessionID: claims.SessionID}, nil
+	}
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
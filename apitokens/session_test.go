@@ -0,0 +1,55 @@
+package apitokens
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var sessionKey = []byte("session-key")
+
+func signJWT(key []byte, header string, claims string) string {
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestSessionAuthenticator(t *testing.T) {
+	hs256 := `{"alg":"HS256","typ":"JWT"}`
+	valid := fmt.Sprintf(`{"sub":"alice","sid":"1","exp":%d}`, time.Now().Add(time.Hour).Unix())
+	for _, tt := range []struct {
+		name          string
+		authorization string
+		want          error
+	}{
+		{"valid", "Bearer " + signJWT(sessionKey, hs256, valid), nil},
+		{"no header", "", ErrNoSession},
+		{"not bearer", "Basic " + signJWT(sessionKey, hs256, valid), ErrNoSession},
+		{"malformed", "Bearer abc.def", ErrInvalidSession},
+		{"wrong alg", "Bearer " + signJWT(sessionKey, `{"alg":"none"}`, valid), ErrInvalidSession},
+		{"bad signature", "Bearer " + signJWT([]byte("other-key"), hs256, valid), ErrInvalidSession},
+		{"no subject", "Bearer " + signJWT(sessionKey, hs256, fmt.Sprintf(`{"exp":%d}`, time.Now().Add(time.Hour).Unix())), ErrInvalidSession},
+		{"no expiry", "Bearer " + signJWT(sessionKey, hs256, `{"sub":"alice"}`), ErrInvalidSession},
+		{"expired", "Bearer " + signJWT(sessionKey, hs256, fmt.Sprintf(`{"sub":"alice","exp":%d}`, time.Now().Add(-time.Minute).Unix())), ErrSessionExpired},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/codex/token", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			session, err := SessionAuthenticator(sessionKey)(r)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && (session != Session{UserID: "alice", SessionID: "1"}) {
+				t.Fatalf("got %+v", session)
+			}
+		})
+	}
+}
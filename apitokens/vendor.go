@@ -0,0 +1,282 @@
+// Package apitokens vends short-lived Codex API tokens to browser clients so
+// they can query Codex directly without ever seeing CodexToken.
+package apitokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrQuotaExceeded = errors.New("api token quota exceeded")
+
+// QuotaError is returned when a user has been issued Options.Quota tokens
+// within Options.QuotaWindow. It matches ErrQuotaExceeded.
+type QuotaError struct {
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", ErrQuotaExceeded, e.RetryAfter.Round(time.Second))
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+type Options struct {
+	// TTL is how long issued tokens are valid for. Defaults to 1h.
+	TTL time.Duration
+	// RequestLimit is how many root fields a token may resolve. Defaults to
+	// 5000.
+	RequestLimit int
+	// RefreshBefore is how long before expiry a cached token is replaced by a
+	// new one. Defaults to 5m.
+	RefreshBefore time.Duration
+	// Quota is how many tokens a user may be issued per QuotaWindow, across
+	// their sessions. Defaults to 10 per 24h.
+	Quota       int
+	QuotaWindow time.Duration
+}
+
+// Session identifies a signed-in user's session. Tokens are cached per
+// session and counted against the quota per user.
+type Session struct {
+	UserID    string
+	SessionID string
+}
+
+// Token is a short-lived Codex API token.
+type Token struct {
+	ID    string `json:"-"`
+	Token string `json:"token"`
+	// ExpiresAt is when Codex stops accepting the token.
+	ExpiresAt time.Time `json:"expiresAt"`
+	// RefreshAt is when asking again returns a new token.
+	RefreshAt    time.Time `json:"refreshAt"`
+	RequestLimit int       `json:"requestLimit"`
+}
+
+// Vendor issues, caches and revokes Codex API tokens per session. The cache
+// and quotas are kept in memory, so each replica counts its own.
+type Vendor struct {
+	client graphql.Client
+	opts   Options
+
+	mu       sync.Mutex
+	sessions map[Session]*session
+	// issued holds when each user was issued a token within the quota window.
+	issued map[string][]time.Time
+}
+
+type session struct {
+	// mu is held while issuing so concurrent requests share one token.
+	mu    sync.Mutex
+	token *Token
+}
+
+func NewVendor(client graphql.Client, opts Options) *Vendor {
+	if opts.TTL == 0 {
+		opts.TTL = time.Hour
+	}
+	if opts.RequestLimit == 0 {
+		opts.RequestLimit = 5000
+	}
+	if opts.RefreshBefore == 0 {
+		opts.RefreshBefore = 5 * time.Minute
+	}
+	if opts.Quota == 0 {
+		opts.Quota = 10
+	}
+	if opts.QuotaWindow == 0 {
+		opts.QuotaWindow = 24 * time.Hour
+	}
+	return &Vendor{
+		client:   client,
+		opts:     opts,
+		sessions: make(map[Session]*session),
+		issued:   make(map[string][]time.Time),
+	}
+}
+
+// Token returns the session's cached token, issuing a new one if there is
+// none or it is due for refresh. A token due for refresh is still returned
+// while it is valid if the user is over quota.
+func (v *Vendor) Token(ctx context.Context, s Session) (Token, error) {
+	v.mu.Lock()
+	entry, ok := v.sessions[s]
+	if !ok {
+		entry = &session{}
+		v.sessions[s] = entry
+	}
+	v.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	now := time.Now()
+	if entry.token != nil && now.Before(entry.token.RefreshAt) {
+		return *entry.token, nil
+	}
+
+	if err := v.reserve(s.UserID, now); err != nil {
+		if entry.token != nil && now.Before(entry.token.ExpiresAt) {
+			return *entry.token, nil
+		}
+		return Token{}, err
+	}
+	token, err := v.issue(ctx)
+	if err != nil {
+		v.unreserve(s.UserID, now)
+		return Token{}, err
+	}
+	// The replaced token is left to expire rather than revoked, as the client
+	// may still have requests in flight with it.
+	entry.token = &token
+	log.Debug().Str("user_id", s.UserID).Str("token_id", token.ID).Time("expires_at", token.ExpiresAt).Msg("issued Codex API token")
+	return token, nil
+}
+
+// Revoke deletes the session's token from Codex and forgets it. Revoking
+// doesn't give back quota.
+func (v *Vendor) Revoke(ctx context.Context, s Session) error {
+	v.mu.Lock()
+	entry, ok := v.sessions[s]
+	v.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.token == nil {
+		return nil
+	}
+	if time.Now().Before(entry.token.ExpiresAt) {
+		if _, err := codex.DeleteApiToken(ctx, v.client, entry.token.ID); err != nil && !errors.Is(err, codex.ErrNotFound) {
+			return fmt.Errorf("failed to revoke api token %s: %w", entry.token.ID, err)
+		}
+	}
+	entry.token = nil
+	return nil
+}
+
+// Run drops expired tokens and quota records until ctx is done.
+func (v *Vendor) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			v.prune(time.Now())
+		}
+	}
+}
+
+func (v *Vendor) prune(now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for s, entry := range v.sessions {
+		if !entry.mu.TryLock() {
+			// Issuing or revoking.
+			continue
+		}
+		if entry.token == nil || !now.Before(entry.token.ExpiresAt) {
+			delete(v.sessions, s)
+		}
+		entry.mu.Unlock()
+	}
+	for user := range v.issued {
+		v.expireIssued(user, now)
+	}
+}
+
+// reserve counts a token against the user's quota, or returns a QuotaError.
+func (v *Vendor) reserve(user string, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	issued := v.expireIssued(user, now)
+	if len(issued) >= v.opts.Quota {
+		return &QuotaError{RetryAfter: issued[0].Add(v.opts.QuotaWindow).Sub(now)}
+	}
+	v.issued[user] = append(issued, now)
+	return nil
+}
+
+func (v *Vendor) unreserve(user string, at time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	issued := v.issued[user]
+	for i := len(issued) - 1; i >= 0; i-- {
+		if issued[i].Equal(at) {
+			v.issued[user] = append(issued[:i], issued[i+1:]...)
+			break
+		}
+	}
+	if len(v.issued[user]) == 0 {
+		delete(v.issued, user)
+	}
+}
+
+// expireIssued drops the user's issue times that left the quota window and
+// returns the rest, oldest first. v.mu must be held.
+func (v *Vendor) expireIssued(user string, now time.Time) []time.Time {
+	issued := v.issued[user]
+	cutoff := now.Add(-v.opts.QuotaWindow)
+	i := 0
+	for i < len(issued) && !issued[i].After(cutoff) {
+		i++
+	}
+	issued = issued[i:]
+	if len(issued) == 0 {
+		delete(v.issued, user)
+		return nil
+	}
+	v.issued[user] = issued
+	return issued
+}
+
+func (v *Vendor) issue(ctx context.Context) (Token, error) {
+	resp, err := codex.CreateApiTokens(ctx, v.client, codex.CreateApiTokensInput{
+		Count:        codex.Ptr(1),
+		RequestLimit: codex.Ptr(strconv.Itoa(v.opts.RequestLimit)),
+		ExpiresIn:    codex.Ptr(int(v.opts.TTL.Seconds())),
+	})
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to create api token: %w", err)
+	}
+	if len(resp.CreateApiTokens) == 0 {
+		return Token{}, errors.New("codex created no api token")
+	}
+	created := resp.CreateApiTokens[0]
+
+	expiresAt, err := time.Parse(time.RFC3339, created.ExpiresTimeString)
+	if err != nil {
+		return Token{}, fmt.Errorf("invalid expiry [%s] of api token %s: %w", created.ExpiresTimeString, created.Id, err)
+	}
+	requestLimit, err := strconv.Atoi(created.RequestLimit)
+	if err != nil {
+		requestLimit = v.opts.RequestLimit
+	}
+	refreshAt := expiresAt.Add(-v.opts.RefreshBefore)
+	if halfway := time.Now().Add(time.Until(expiresAt) / 2); refreshAt.Before(halfway) {
+		// Don't reissue on every request when the TTL is shorter than
+		// RefreshBefore.
+		refreshAt = halfway
+	}
+	return Token{
+		ID:           created.Id,
+		Token:        created.Token,
+		ExpiresAt:    expiresAt,
+		RefreshAt:    refreshAt,
+		RequestLimit: requestLimit,
+	}, nil
+}
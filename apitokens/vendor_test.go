@@ -0,0 +1,178 @@
+package apitokens
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/codex/codexstub"
+	"github.com/Khan/genqlient/graphql"
+)
+
+func newStubClient(stub *codexstub.Server) graphql.Client {
+	return codex.NewClient(codex.Options{
+		Endpoint:   stub.URL(),
+		Token:      "codex-key",
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+	})
+}
+
+// dueForRefresh makes the session's cached token due for refresh.
+func dueForRefresh(v *Vendor, s Session) {
+	v.mu.Lock()
+	entry := v.sessions[s]
+	v.mu.Unlock()
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	entry.token.RefreshAt = time.Now().Add(-time.Second)
+}
+
+func TestTokenCachedUntilRefresh(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	v := NewVendor(newStubClient(stub), Options{TTL: time.Hour, RequestLimit: 100, RefreshBefore: 10 * time.Minute})
+	s := Session{UserID: "alice", SessionID: "1"}
+	ctx := context.Background()
+
+	first, err := v.Token(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.RequestLimit != 100 || first.Token == "" {
+		t.Fatalf("got %+v", first)
+	}
+	if refreshIn := time.Until(first.RefreshAt); refreshIn < 45*time.Minute || refreshIn > 50*time.Minute {
+		t.Fatalf("token refreshes in %s, want RefreshBefore its expiry", refreshIn)
+	}
+	if again, err := v.Token(ctx, s); err != nil || again.ID != first.ID {
+		t.Fatalf("got %+v (%v), want the cached token", again, err)
+	}
+
+	dueForRefresh(v, s)
+	refreshed, err := v.Token(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.ID == first.ID {
+		t.Fatal("token due for refresh was not replaced")
+	}
+	// The replaced token is left to expire.
+	if n := len(stub.ApiTokens()); n != 2 {
+		t.Fatalf("codex has %d tokens, want 2", n)
+	}
+}
+
+func TestTokenQuota(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	v := NewVendor(newStubClient(stub), Options{Quota: 2, QuotaWindow: time.Hour})
+	ctx := context.Background()
+	first, second := Session{UserID: "alice", SessionID: "1"}, Session{UserID: "alice", SessionID: "2"}
+
+	// Failed issues don't count against the quota.
+	stub.FailNext("CreateApiTokens", http.StatusInternalServerError)
+	if _, err := v.Token(ctx, first); err == nil {
+		t.Fatal("want an error when Codex fails")
+	}
+	if _, err := v.Token(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	dueForRefresh(v, first)
+	valid, err := v.Token(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Over quota, a token due for refresh is served while it is valid.
+	dueForRefresh(v, first)
+	if got, err := v.Token(ctx, first); err != nil || got.ID != valid.ID {
+		t.Fatalf("got %+v (%v), want the still valid token", got, err)
+	}
+
+	_, err = v.Token(ctx, second)
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("got %v, want a QuotaError", err)
+	}
+	if quotaErr.RetryAfter < 59*time.Minute || quotaErr.RetryAfter > time.Hour {
+		t.Fatalf("retry after %s, want when the oldest token leaves the window", quotaErr.RetryAfter)
+	}
+	if _, err := v.Token(ctx, Session{UserID: "bob"}); err != nil {
+		t.Fatalf("another user was limited: %v", err)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	client := newStubClient(stub)
+	v := NewVendor(client, Options{})
+	s := Session{UserID: "alice"}
+	ctx := context.Background()
+
+	if err := v.Revoke(ctx, s); err != nil {
+		t.Fatalf("revoking without a token: %v", err)
+	}
+	token, err := v.Token(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Revoke(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(stub.ApiTokens()); n != 0 {
+		t.Fatalf("codex has %d tokens after revoking, want 0", n)
+	}
+	again, err := v.Token(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID == token.ID {
+		t.Fatal("revoked token was served again")
+	}
+
+	// Tokens Codex no longer has are forgotten.
+	if _, err := codex.DeleteApiToken(ctx, client, again.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Revoke(ctx, s); err != nil {
+		t.Fatalf("revoking a token deleted on Codex: %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	v := NewVendor(newStubClient(stub), Options{TTL: time.Hour, QuotaWindow: 2 * time.Hour})
+	ctx := context.Background()
+	if _, err := v.Token(ctx, Session{UserID: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Revoke(ctx, Session{UserID: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Token(ctx, Session{UserID: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	v.prune(time.Now())
+	if len(v.sessions) != 1 || len(v.issued) != 2 {
+		t.Fatalf("kept %d sessions and quotas of %d users, want bob's session and both quotas", len(v.sessions), len(v.issued))
+	}
+	if _, ok := v.sessions[Session{UserID: "bob"}]; !ok {
+		t.Fatal("valid token was pruned")
+	}
+
+	v.prune(time.Now().Add(90 * time.Minute))
+	if len(v.sessions) != 0 || len(v.issued) != 2 {
+		t.Fatalf("kept %d sessions and quotas of %d users after expiry, want none and both", len(v.sessions), len(v.issued))
+	}
+	v.prune(time.Now().Add(3 * time.Hour))
+	if len(v.issued) != 0 {
+		t.Fatalf("kept quotas of %d users after the window, want none", len(v.issued))
+	}
+}
@@ -0,0 +1,399 @@
+// Package backfill recovers token pair events missed while the service was
+// down or a webhook wasn't delivering, from Codex's event history.
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+// pageSize is how many events are requested per page of history.
+const pageSize = 200
+
+type Options struct {
+	// Gap is how long a pair may go without events before its next event
+	// triggers a backfill. Defaults to 5m.
+	Gap time.Duration
+	// GapCooldown is the least time between two backfills of a pair
+	// triggered by gaps, so pairs that are naturally quiet for longer than
+	// Gap don't query Codex's history on nearly every event. Defaults to 1h.
+	GapCooldown time.Duration
+	// MaxWindow bounds how far back a backfill reaches. Pairs without events
+	// for longer aren't backfilled on startup. Defaults to 6h.
+	MaxWindow time.Duration
+	// MaxEvents bounds how many events one backfill publishes. Defaults to
+	// 1000.
+	MaxEvents int
+	// MaxPairs bounds how many pairs are checkpointed. The least recently
+	// active are dropped. Defaults to 10000.
+	MaxPairs int
+	// DedupWindow is how long published events are remembered so they are
+	// published once whether they arrive live or from history. Defaults to
+	// 15m.
+	DedupWindow time.Duration
+	// FlushInterval is how often checkpoints are saved. Defaults to 10s.
+	FlushInterval time.Duration
+}
+
+// Backfiller implements ingest.Tracker. It checkpoints the latest event of
+// each pair, and publishes the events between a checkpoint and now through
+// the handler after a restart, or when a pair's events resume after a gap.
+type Backfiller struct {
+	client  graphql.Client
+	handler *ingest.Handler
+	store   *Store
+	opts    Options
+
+	mu    sync.Mutex
+	pairs map[string]*Checkpoint
+	dirty bool
+	// seen maps the key of each recently published event to when it was
+	// published. seenOrder holds the keys oldest first.
+	seen      map[string]time.Time
+	seenOrder []string
+	// pending holds the backfills to run by pair topic.
+	pending map[string]job
+	// gapBackfills maps a pair topic to when a gap last triggered its
+	// backfill.
+	gapBackfills map[string]time.Time
+	wake         chan struct{}
+}
+
+// job backfills the events of pair from from to to, in Unix seconds.
+type job struct {
+	pair Checkpoint
+	from int
+	to   int
+}
+
+func New(client graphql.Client, handler *ingest.Handler, store *Store, opts Options) *Backfiller {
+	if opts.Gap == 0 {
+		opts.Gap = 5 * time.Minute
+	}
+	if opts.GapCooldown == 0 {
+		opts.GapCooldown = time.Hour
+	}
+	if opts.MaxWindow == 0 {
+		opts.MaxWindow = 6 * time.Hour
+	}
+	if opts.MaxEvents == 0 {
+		opts.MaxEvents = 1000
+	}
+	if opts.MaxPairs == 0 {
+		opts.MaxPairs = 10000
+	}
+	if opts.DedupWindow == 0 {
+		opts.DedupWindow = 15 * time.Minute
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = 10 * time.Second
+	}
+	return &Backfiller{
+		client:       client,
+		handler:      handler,
+		store:        store,
+		opts:         opts,
+		pairs:        make(map[string]*Checkpoint),
+		seen:         make(map[string]time.Time),
+		pending:      make(map[string]job),
+		wake:         make(chan struct{}, 1),
+		gapBackfills: make(map[string]time.Time),
+	}
+}
+
+func eventKey(data ingest.TokenPairEventData) string {
+	if data.Event.TransactionHash == "" {
+		return ""
+	}
+	return fmt.Sprintf("%d:%s:%d", data.Pair.NetworkID, data.Event.TransactionHash, data.Event.LogIndex)
+}
+
+func (b *Backfiller) Track(data []ingest.TokenPairEventData) []ingest.TokenPairEventData {
+	now := time.Now()
+	gap := int(b.opts.Gap.Seconds())
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var fresh []ingest.TokenPairEventData
+	for _, d := range data {
+		topic := ingest.PairTopic(d.Pair.Address, d.Pair.NetworkID)
+		checkpoint, ok := b.pairs[topic]
+		if ok && checkpoint.Timestamp > 0 && d.Event.Timestamp-checkpoint.Timestamp > gap && b.gapCooledDown(topic, now) {
+			b.schedule(topic, *checkpoint, checkpoint.Timestamp, d.Event.Timestamp)
+		}
+		if !b.admit(d, now) {
+			continue
+		}
+		b.advance(topic, d, now)
+		fresh = append(fresh, d)
+	}
+	return fresh
+}
+
+// gapCooledDown reports whether a gap may trigger a backfill of the pair,
+// and if so records that one did. b.mu must be held.
+func (b *Backfiller) gapCooledDown(topic string, now time.Time) bool {
+	if last, ok := b.gapBackfills[topic]; ok && now.Sub(last) < b.opts.GapCooldown {
+		return false
+	}
+	b.gapBackfills[topic] = now
+	return true
+}
+
+// admit reports whether d wasn't published before and records it as
+// published. b.mu must be held.
+func (b *Backfiller) admit(d ingest.TokenPairEventData, now time.Time) bool {
+	b.expireSeen(now)
+	key := eventKey(d)
+	if key == "" {
+		// Can't tell duplicates apart.
+		return true
+	}
+	if _, ok := b.seen[key]; ok {
+		return false
+	}
+	topic := ingest.PairTopic(d.Pair.Address, d.Pair.NetworkID)
+	if checkpoint, ok := b.pairs[topic]; ok && d.Event.Timestamp == checkpoint.Timestamp && slices.Contains(checkpoint.Events, key) {
+		return false
+	}
+	b.seen[key] = now
+	b.seenOrder = append(b.seenOrder, key)
+	return true
+}
+
+// expireSeen must be called with b.mu held.
+func (b *Backfiller) expireSeen(now time.Time) {
+	cutoff := now.Add(-b.opts.DedupWindow)
+	i := 0
+	for i < len(b.seenOrder) && b.seen[b.seenOrder[i]].Before(cutoff) {
+		delete(b.seen, b.seenOrder[i])
+		i++
+	}
+	if i > 0 {
+		b.seenOrder = slices.Delete(b.seenOrder, 0, i)
+	}
+}
+
+// advance moves the pair's checkpoint to d if it is newer. b.mu must be
+// held.
+func (b *Backfiller) advance(topic string, d ingest.TokenPairEventData, now time.Time) {
+	checkpoint, ok := b.pairs[topic]
+	if !ok {
+		if len(b.pairs) >= b.opts.MaxPairs {
+			b.evict()
+		}
+		checkpoint = &Checkpoint{Address: d.Pair.Address, NetworkID: d.Pair.NetworkID}
+		b.pairs[topic] = checkpoint
+	}
+	if d.Pair.ExchangeHash != "" {
+		checkpoint.ExchangeHash = d.Pair.ExchangeHash
+	}
+	if d.Pair.Token0 != "" {
+		checkpoint.Token0 = d.Pair.Token0
+	}
+	if d.Pair.Token1 != "" {
+		checkpoint.Token1 = d.Pair.Token1
+	}
+	key := eventKey(d)
+	switch {
+	case d.Event.Timestamp > checkpoint.Timestamp:
+		checkpoint.Timestamp = d.Event.Timestamp
+		checkpoint.Events = nil
+		if key != "" {
+			checkpoint.Events = []string{key}
+		}
+	case d.Event.Timestamp == checkpoint.Timestamp && key != "" && !slices.Contains(checkpoint.Events, key):
+		checkpoint.Events = append(checkpoint.Events, key)
+	}
+	checkpoint.UpdatedAt = now
+	b.dirty = true
+}
+
+// evict drops the least recently active pair. b.mu must be held.
+func (b *Backfiller) evict() {
+	oldest := ""
+	for topic, checkpoint := range b.pairs {
+		if oldest == "" || checkpoint.UpdatedAt.Before(b.pairs[oldest].UpdatedAt) {
+			oldest = topic
+		}
+	}
+	delete(b.pairs, oldest)
+	delete(b.gapBackfills, oldest)
+}
+
+// schedule queues a backfill of the pair, merging it with one already
+// queued. b.mu must be held.
+func (b *Backfiller) schedule(topic string, pair Checkpoint, from int, to int) {
+	if queued, ok := b.pending[topic]; ok {
+		from = min(from, queued.from)
+		to = max(to, queued.to)
+	}
+	b.pending[topic] = job{pair: pair, from: from, to: to}
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run loads the checkpoints, backfills the pairs active within MaxWindow up
+// to now, and then runs backfills as gaps are detected until ctx is done.
+func (b *Backfiller) Run(ctx context.Context) {
+	state, err := b.store.Load()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load backfill checkpoints")
+	} else {
+		now := time.Now()
+		b.mu.Lock()
+		for _, checkpoint := range state.Pairs {
+			topic := ingest.PairTopic(checkpoint.Address, checkpoint.NetworkID)
+			if current, ok := b.pairs[topic]; ok {
+				// Live events arrived before the checkpoints were loaded.
+				if current.Timestamp > checkpoint.Timestamp {
+					b.schedule(topic, *current, checkpoint.Timestamp, current.Timestamp)
+				}
+				continue
+			}
+			checkpoint := checkpoint
+			b.pairs[topic] = &checkpoint
+			if now.Sub(checkpoint.UpdatedAt) < b.opts.MaxWindow {
+				b.schedule(topic, checkpoint, checkpoint.Timestamp, int(now.Unix()))
+			}
+		}
+		log.Info().Int("pairs", len(state.Pairs)).Int("backfills", len(b.pending)).Msg("loaded backfill checkpoints")
+		b.mu.Unlock()
+	}
+
+	ticker := time.NewTicker(b.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.Flush(); err != nil {
+				log.Error().Err(err).Msg("failed to save backfill checkpoints")
+			}
+		case <-b.wake:
+			b.runPending(ctx)
+		}
+	}
+}
+
+func (b *Backfiller) runPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		b.mu.Lock()
+		var topic string
+		var next job
+		for t, j := range b.pending {
+			topic, next = t, j
+			break
+		}
+		delete(b.pending, topic)
+		b.mu.Unlock()
+		if topic == "" {
+			return
+		}
+
+		published, err := b.backfill(ctx, next)
+		event := log.Info()
+		if err != nil {
+			event = log.Error().Err(err)
+		}
+		event.Str("topic", topic).Int("from", next.from).Int("to", next.to).Int("published", published).Msg("backfilled pair")
+	}
+}
+
+// backfill publishes the events of j missing from live traffic and returns
+// how many it published.
+func (b *Backfiller) backfill(ctx context.Context, j job) (int, error) {
+	from := max(j.from, int(time.Now().Add(-b.opts.MaxWindow).Unix()))
+	if from > j.to {
+		return 0, nil
+	}
+	pair := ingest.Pair{
+		Address:      j.pair.Address,
+		ExchangeHash: j.pair.ExchangeHash,
+		ID:           fmt.Sprintf("%s:%d", j.pair.Address, j.pair.NetworkID),
+		NetworkID:    j.pair.NetworkID,
+		Token0:       j.pair.Token0,
+		Token1:       j.pair.Token1,
+	}
+	topic := ingest.PairTopic(pair.Address, pair.NetworkID)
+
+	published := 0
+	var cursor *string
+	for published < b.opts.MaxEvents {
+		resp, err := codex.GetTokenEvents(ctx, b.client, codex.EventsQueryInput{
+			Address:   pair.Address,
+			NetworkId: pair.NetworkID,
+			Timestamp: &codex.EventQueryTimestampInput{From: from, To: j.to},
+		}, cursor, codex.Ptr(pageSize))
+		if err != nil {
+			return published, err
+		}
+		if resp.GetTokenEvents == nil {
+			break
+		}
+
+		now := time.Now()
+		var fresh []ingest.TokenPairEventData
+		b.mu.Lock()
+		for _, event := range resp.GetTokenEvents.Items {
+			if event == nil || published+len(fresh) >= b.opts.MaxEvents {
+				continue
+			}
+			d := ingest.NormalizeEvent(*event, pair)
+			if b.admit(d, now) {
+				b.advance(topic, d, now)
+				fresh = append(fresh, d)
+			}
+		}
+		b.mu.Unlock()
+		if err := b.handler.PublishBackfill(fresh); err != nil {
+			return published, err
+		}
+		published += len(fresh)
+
+		cursor = resp.GetTokenEvents.Cursor
+		if cursor == nil || *cursor == "" || len(resp.GetTokenEvents.Items) == 0 {
+			return published, nil
+		}
+	}
+	log.Warn().Str("topic", topic).Int("max_events", b.opts.MaxEvents).Msg("backfill truncated")
+	return published, nil
+}
+
+// Flush saves the checkpoints if they changed since the last flush.
+func (b *Backfiller) Flush() error {
+	b.mu.Lock()
+	if !b.dirty {
+		b.mu.Unlock()
+		return nil
+	}
+	state := &State{Pairs: make([]Checkpoint, 0, len(b.pairs))}
+	for _, checkpoint := range b.pairs {
+		state.Pairs = append(state.Pairs, *checkpoint)
+	}
+	b.dirty = false
+	b.mu.Unlock()
+
+	sort.Slice(state.Pairs, func(i, j int) bool {
+		return state.Pairs[i].UpdatedAt.After(state.Pairs[j].UpdatedAt)
+	})
+	if err := b.store.Save(state); err != nil {
+		b.mu.Lock()
+		b.dirty = true
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
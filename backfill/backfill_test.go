@@ -0,0 +1,340 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/gorilla/websocket"
+)
+
+func event(address string, timestamp int, txHash string) ingest.TokenPairEventData {
+	return ingest.TokenPairEventData{
+		Event: ingest.TokenPairEvent{Address: address, Timestamp: timestamp, TransactionHash: txHash},
+		Pair:  ingest.Pair{Address: address, NetworkID: 1},
+	}
+}
+
+func TestGapBackfillCooldown(t *testing.T) {
+	b := New(nil, nil, NewStore(filepath.Join(t.TempDir(), "backfill.json")), Options{
+		Gap:         time.Minute,
+		GapCooldown: time.Hour,
+	})
+	topic := ingest.PairTopic("0xa", 1)
+
+	b.Track([]ingest.TokenPairEventData{event("0xa", 1000, "0x1")})
+	b.Track([]ingest.TokenPairEventData{event("0xa", 1030, "0x2")})
+	if len(b.pending) != 0 {
+		t.Fatalf("events within Gap scheduled %v", b.pending)
+	}
+
+	b.Track([]ingest.TokenPairEventData{event("0xa", 1200, "0x3")})
+	if j, ok := b.pending[topic]; !ok || j.from != 1030 || j.to != 1200 {
+		t.Fatalf("gap not scheduled for backfill: %v", b.pending)
+	}
+	delete(b.pending, topic)
+
+	// The pair is quiet for longer than Gap again, but was just backfilled.
+	b.Track([]ingest.TokenPairEventData{event("0xa", 1400, "0x4")})
+	if len(b.pending) != 0 {
+		t.Fatalf("gap within GapCooldown scheduled %v", b.pending)
+	}
+
+	b.gapBackfills[topic] = time.Now().Add(-2 * time.Hour)
+	b.Track([]ingest.TokenPairEventData{event("0xa", 1600, "0x5")})
+	if j, ok := b.pending[topic]; !ok || j.from != 1400 || j.to != 1600 {
+		t.Fatalf("gap after GapCooldown not scheduled: %v", b.pending)
+	}
+}
+
+// historyClient answers GetTokenEvents with the events of history within the
+// requested time range, a page at a time, and counts the requests it gets.
+type historyClient struct {
+	mu       sync.Mutex
+	history  []codex.CreatedEvent
+	requests int
+}
+
+func (c *historyClient) MakeRequest(ctx context.Context, req *graphql.Request, resp *graphql.Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests++
+
+	encoded, err := json.Marshal(req.Variables)
+	if err != nil {
+		return err
+	}
+	vars := struct {
+		Query  codex.EventsQueryInput `json:"query"`
+		Cursor *string                `json:"cursor"`
+		Limit  *int                   `json:"limit"`
+	}{}
+	if err := json.Unmarshal(encoded, &vars); err != nil {
+		return err
+	}
+	var matched []*codex.CreatedEvent
+	for i := range c.history {
+		event := &c.history[i]
+		if event.Address == vars.Query.Address && event.Timestamp >= vars.Query.Timestamp.From && event.Timestamp <= vars.Query.Timestamp.To {
+			matched = append(matched, event)
+		}
+	}
+	start := 0
+	if vars.Cursor != nil {
+		start, _ = strconv.Atoi(*vars.Cursor)
+	}
+	end := min(start+*vars.Limit, len(matched))
+	page := &codex.GetTokenEventsGetTokenEventsEventConnection{Items: matched[start:end]}
+	if end < len(matched) {
+		page.Cursor = codex.Ptr(strconv.Itoa(end))
+	}
+	resp.Data.(*codex.GetTokenEventsResponse).GetTokenEvents = page
+	return nil
+}
+
+func (c *historyClient) requestCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests
+}
+
+func historyEvent(address string, timestamp int, txHash string) codex.CreatedEvent {
+	return codex.CreatedEvent{Address: address, EventType: codex.EventTypeSwap, NetworkId: 1, Timestamp: timestamp, TransactionHash: txHash}
+}
+
+type testHubManager struct {
+	hub *ws.Hub[any]
+}
+
+func (m *testHubManager) GetHub() *ws.Hub[any]                                          { return m.hub }
+func (m *testHubManager) OnRegister(client *ws.UserClient[any]) error                   { return nil }
+func (m *testHubManager) OnUnregister(client *ws.UserClient[any]) error                 { return nil }
+func (m *testHubManager) OnReceiveMessage(client *ws.UserClient[any], msg []byte) error { return nil }
+
+// published is an envelope published by the handler.
+type published struct {
+	Source   string                      `json:"source"`
+	Backfill bool                        `json:"backfill"`
+	Data     ingest.TokenPairWebhookBody `json:"data"`
+}
+
+// newTestHandler returns a handler publishing token pair events to a hub, and
+// a channel receiving every envelope a client of the hub gets.
+func newTestHandler(t *testing.T) (*ingest.Handler, <-chan published) {
+	t.Helper()
+	registry := ws.NewRegistry()
+	if err := ws.Register(registry, "pairs", &testHubManager{hub: ws.NewHub[any]()}, ws.HubConfig[any]{Path: "/ws"}); err != nil {
+		t.Fatal(err)
+	}
+	hub, _ := registry.Get("pairs")
+	server := httptest.NewServer(hub)
+	t.Cleanup(server.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	waitFor(t, "the client to connect", func() bool { return hub.ClientCount() == 1 })
+
+	envelopes := make(chan published, 64)
+	go func() {
+		for {
+			var envelope published
+			if err := conn.ReadJSON(&envelope); err != nil {
+				return
+			}
+			envelopes <- envelope
+		}
+	}()
+	return ingest.NewHandler("", registry, map[string]string{ingest.WebhookTypeTokenPairEvent: "pairs"}), envelopes
+}
+
+// waitFor polls until cond holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// receive returns the transaction hashes of the events in the next n
+// envelopes, which must all be flagged as backfill.
+func receive(t *testing.T, envelopes <-chan published, n int) []string {
+	t.Helper()
+	var hashes []string
+	for range n {
+		select {
+		case envelope := <-envelopes:
+			if !envelope.Backfill || envelope.Source != ingest.SourceCodexBackfill {
+				t.Fatalf("got envelope from %q with backfill %t, want it flagged as backfill", envelope.Source, envelope.Backfill)
+			}
+			for _, d := range envelope.Data.Data {
+				hashes = append(hashes, d.Event.TransactionHash)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an envelope")
+		}
+	}
+	return hashes
+}
+
+// assertNoMore fails if another envelope arrives shortly.
+func assertNoMore(t *testing.T, envelopes <-chan published) {
+	t.Helper()
+	select {
+	case envelope := <-envelopes:
+		t.Fatalf("got unexpected envelope %+v", envelope)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBackfillSkipsLiveEvents(t *testing.T) {
+	now := int(time.Now().Unix())
+	client := &historyClient{history: []codex.CreatedEvent{
+		historyEvent("0xa", now-300, "0x1"),
+		historyEvent("0xa", now-200, "0x2"),
+		historyEvent("0xa", now-200, "0x3"),
+		historyEvent("0xa", now-100, "0x4"),
+	}}
+	handler, envelopes := newTestHandler(t)
+	b := New(client, handler, NewStore(filepath.Join(t.TempDir(), "backfill.json")), Options{})
+	topic := ingest.PairTopic("0xa", 1)
+
+	if fresh := b.Track([]ingest.TokenPairEventData{event("0xa", now-200, "0x2")}); len(fresh) != 1 {
+		t.Fatalf("live event dropped: %v", fresh)
+	}
+	n, err := b.backfill(context.Background(), job{pair: *b.pairs[topic], from: now - 400, to: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Events missed live are published, including 0x3 sharing a timestamp
+	// with the live 0x2.
+	if got := receive(t, envelopes, 1); n != 3 || !slices.Equal(got, []string{"0x1", "0x3", "0x4"}) {
+		t.Fatalf("published %d events %v, want 0x1, 0x3 and 0x4", n, got)
+	}
+	if checkpoint := b.pairs[topic]; checkpoint.Timestamp != now-100 {
+		t.Fatalf("checkpoint at %d, want %d", checkpoint.Timestamp, now-100)
+	}
+
+	// Events published from history aren't published again live.
+	if fresh := b.Track([]ingest.TokenPairEventData{event("0xa", now-100, "0x4")}); len(fresh) != 0 {
+		t.Fatalf("backfilled event published again live: %v", fresh)
+	}
+	if n, err := b.backfill(context.Background(), job{pair: *b.pairs[topic], from: now - 400, to: now}); err != nil || n != 0 {
+		t.Fatalf("second backfill published %d events (%v), want none", n, err)
+	}
+	assertNoMore(t, envelopes)
+}
+
+func TestBackfillMaxEvents(t *testing.T) {
+	now := int(time.Now().Unix())
+	client := &historyClient{}
+	for i := range pageSize + 50 {
+		client.history = append(client.history, historyEvent("0xa", now-1000+i, "0x"+strconv.Itoa(i)))
+	}
+	handler, envelopes := newTestHandler(t)
+	b := New(client, handler, NewStore(filepath.Join(t.TempDir(), "backfill.json")), Options{MaxEvents: pageSize + 10})
+
+	n, err := b.backfill(context.Background(), job{pair: Checkpoint{Address: "0xa", NetworkID: 1}, from: now - 2000, to: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := receive(t, envelopes, 2)
+	if n != pageSize+10 || len(got) != pageSize+10 || got[len(got)-1] != "0x"+strconv.Itoa(pageSize+9) {
+		t.Fatalf("published %d events, want the first %d", len(got), pageSize+10)
+	}
+	if requests := client.requestCount(); requests != 2 {
+		t.Fatalf("made %d requests, want 2", requests)
+	}
+	assertNoMore(t, envelopes)
+}
+
+func TestFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	store := NewStore(path)
+	b := New(nil, nil, store, Options{})
+	b.Track([]ingest.TokenPairEventData{event("0xa", 1000, "0x1"), event("0xa", 1000, "0x2")})
+	b.Track([]ingest.TokenPairEventData{event("0xb", 2000, "0x3")})
+
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	state, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Pairs) != 2 {
+		t.Fatalf("saved %d pairs, want 2", len(state.Pairs))
+	}
+	for _, checkpoint := range state.Pairs {
+		if checkpoint.Address == "0xa" && (checkpoint.Timestamp != 1000 || !slices.Equal(checkpoint.Events, []string{"1:0x1:0", "1:0x2:0"})) {
+			t.Fatalf("saved %+v, want both events at 1000", checkpoint)
+		}
+	}
+
+	// Unchanged checkpoints aren't saved again.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("unchanged checkpoints were saved again: %v", err)
+	}
+}
+
+func TestRunBackfillsAfterRestart(t *testing.T) {
+	now := int(time.Now().Unix())
+	store := NewStore(filepath.Join(t.TempDir(), "backfill.json"))
+	before := New(nil, nil, store, Options{})
+	before.Track([]ingest.TokenPairEventData{event("0xa", now-200, "0x1")})
+	if err := before.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// While down, 0x2 happened in the same second as the checkpointed 0x1.
+	client := &historyClient{history: []codex.CreatedEvent{
+		historyEvent("0xa", now-300, "0x0"),
+		historyEvent("0xa", now-200, "0x1"),
+		historyEvent("0xa", now-200, "0x2"),
+		historyEvent("0xa", now-100, "0x3"),
+	}}
+	handler, envelopes := newTestHandler(t)
+	b := New(client, handler, store, Options{FlushInterval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if got := receive(t, envelopes, 1); !slices.Equal(got, []string{"0x2", "0x3"}) {
+		t.Fatalf("published %v after restart, want 0x2 and 0x3", got)
+	}
+	assertNoMore(t, envelopes)
+	waitFor(t, "the new checkpoint to be saved", func() bool {
+		state, err := store.Load()
+		return err == nil && len(state.Pairs) == 1 && state.Pairs[0].Timestamp == now-100
+	})
+}
@@ -0,0 +1,78 @@
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoint is how far the events of a pair have been processed.
+type Checkpoint struct {
+	Address      string `json:"address"`
+	NetworkID    int    `json:"networkId"`
+	ExchangeHash string `json:"exchangeHash,omitempty"`
+	Token0       string `json:"token0,omitempty"`
+	Token1       string `json:"token1,omitempty"`
+	// Timestamp of the latest event processed, in Unix seconds.
+	Timestamp int `json:"timestamp"`
+	// Events are the keys of the events processed at Timestamp, so events
+	// sharing it aren't published twice.
+	Events    []string  `json:"events,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type State struct {
+	Pairs []Checkpoint `json:"pairs"`
+}
+
+// Store persists State as a JSON file.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Load returns the stored state, or an empty state if the file does not exist.
+func (s *Store) Load() (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := &State{}
+	content, err := os.ReadFile(filepath.Clean(s.path))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while reading backfill state file [%s]: %w", s.path, err)
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("error while parsing backfill state file [%s]: %w", s.path, err)
+	}
+	return state, nil
+}
+
+// Save replaces the stored state atomically.
+func (s *Store) Save(state *State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode backfill state: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return fmt.Errorf("error while writing backfill state file [%s]: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("error while replacing backfill state file [%s]: %w", s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package backplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrClosed = errors.New("backplane closed")
+
+// Backplane fans broadcasts out to every server instance subscribed to a
+// channel, including the publisher itself.
+type Backplane interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe calls handler for every payload published on channel until
+	// ctx is done. Handlers for one subscription are called sequentially.
+	Subscribe(ctx context.Context, channel string, handler func(payload []byte)) error
+	Close() error
+}
+
+type subscription struct {
+	payloads chan []byte
+	done     chan struct{}
+}
+
+// InProcess is a Backplane for hubs living in the same process.
+type InProcess struct {
+	mu          sync.RWMutex
+	closed      bool
+	done        chan struct{}
+	nextID      int
+	subscribers map[string]map[int]*subscription
+}
+
+func NewInProcess() *InProcess {
+	return &InProcess{
+		done:        make(chan struct{}),
+		subscribers: make(map[string]map[int]*subscription),
+	}
+}
+
+func (b *InProcess) Publish(ctx context.Context, channel string, payload []byte) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	subs := make([]*subscription, 0, len(b.subscribers[channel]))
+	for _, sub := range b.subscribers[channel] {
+		subs = append(subs, sub)
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.payloads <- payload:
+		case <-sub.done:
+		case <-b.done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *InProcess) Subscribe(ctx context.Context, channel string, handler func(payload []byte)) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	id := b.nextID
+	b.nextID++
+	sub := &subscription{
+		payloads: make(chan []byte, 256),
+		done:     make(chan struct{}),
+	}
+	if b.subscribers[channel] == nil {
+		b.subscribers[channel] = make(map[int]*subscription)
+	}
+	b.subscribers[channel][id] = sub
+	b.mu.Unlock()
+
+	go func() {
+		defer func() {
+			close(sub.done)
+			b.mu.Lock()
+			delete(b.subscribers[channel], id)
+			b.mu.Unlock()
+		}()
+		for {
+			select {
+			case payload := <-sub.payloads:
+				handler(payload)
+			case <-ctx.Done():
+				return
+			case <-b.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *InProcess) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package backplane
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/backplane/resp"
+	"github.com/rs/zerolog/log"
+)
+
+type RedisOptions struct {
+	Addr     string
+	Password string
+	// DialTimeout also bounds PUBLISH round trips. Defaults to 5s.
+	DialTimeout time.Duration
+	// MaxReconnectBackoff caps the delay between subscription reconnects.
+	// Defaults to 10s.
+	MaxReconnectBackoff time.Duration
+	// PingInterval is how often subscription connections are pinged. One
+	// that hears nothing back for PingInterval plus DialTimeout is assumed
+	// dead and reconnected. Defaults to 30s.
+	PingInterval time.Duration
+}
+
+// Redis is a Backplane over Redis pub/sub, speaking RESP directly so any
+// Redis-protocol compatible server can be used.
+type Redis struct {
+	opts RedisOptions
+
+	mu      sync.Mutex
+	closed  bool
+	cancels []context.CancelFunc
+
+	// publishMu serializes publishes over conn, so a slow PUBLISH doesn't
+	// hold up Subscribe.
+	publishMu sync.Mutex
+	conn      *redisConn
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func NewRedis(opts RedisOptions) *Redis {
+	if opts.DialTimeout == 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	if opts.MaxReconnectBackoff == 0 {
+		opts.MaxReconnectBackoff = 10 * time.Second
+	}
+	if opts.PingInterval == 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	return &Redis{opts: opts}
+}
+
+func (b *Redis) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: b.opts.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", b.opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial redis [%s]: %w", b.opts.Addr, err)
+	}
+	c := &redisConn{Conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	if b.opts.Password != "" {
+		if _, err := c.do(time.Now().Add(b.opts.DialTimeout), []byte("AUTH"), []byte(b.opts.Password)); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to authenticate with redis: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) do(deadline time.Time, args ...[]byte) (resp.Value, error) {
+	c.SetDeadline(deadline)
+	defer c.SetDeadline(time.Time{})
+	if err := resp.WriteCommand(c.w, args...); err != nil {
+		return resp.Value{}, err
+	}
+	reply, err := resp.Read(c.r)
+	if err != nil {
+		return resp.Value{}, err
+	}
+	return reply, reply.Err()
+}
+
+func (b *Redis) Publish(ctx context.Context, channel string, payload []byte) error {
+	b.publishMu.Lock()
+	defer b.publishMu.Unlock()
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	// A pooled connection may have gone stale since the last publish, so a
+	// failure on it is retried once on a fresh connection.
+	reused := b.conn != nil
+	for {
+		if b.conn == nil {
+			conn, err := b.dial(ctx)
+			if err != nil {
+				return err
+			}
+			b.conn = conn
+		}
+
+		deadline := time.Now().Add(b.opts.DialTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		_, err := b.conn.do(deadline, []byte("PUBLISH"), []byte(channel), payload)
+		if err == nil {
+			return nil
+		}
+		b.conn.Close()
+		b.conn = nil
+		if !reused || ctx.Err() != nil {
+			return fmt.Errorf("failed to publish to [%s]: %w", channel, err)
+		}
+		reused = false
+	}
+}
+
+func (b *Redis) Subscribe(ctx context.Context, channel string, handler func(payload []byte)) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	b.cancels = append(b.cancels, cancel)
+	b.mu.Unlock()
+
+	conn, err := b.subscribe(ctx, channel)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	go func() {
+		backoff := 100 * time.Millisecond
+		for {
+			err := b.readMessages(ctx, conn, handler)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn().Err(err).Str("channel", channel).Msg("Backplane subscription lost, reconnecting")
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				conn, err = b.subscribe(ctx, channel)
+				if err == nil {
+					backoff = 100 * time.Millisecond
+					break
+				}
+				log.Warn().Err(err).Str("channel", channel).Dur("backoff", backoff).Msg("Backplane resubscribe failed")
+				backoff = min(backoff*2, b.opts.MaxReconnectBackoff)
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *Redis) subscribe(ctx context.Context, channel string) (*redisConn, error) {
+	conn, err := b.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(time.Now().Add(b.opts.DialTimeout), []byte("SUBSCRIBE"), []byte(channel))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe to [%s]: %w", channel, err)
+	}
+	if len(reply.Array) < 1 || reply.Array[0].Text() != "subscribe" {
+		conn.Close()
+		return nil, fmt.Errorf("%w: unexpected SUBSCRIBE reply", resp.ErrProtocol)
+	}
+	return conn, nil
+}
+
+// readMessages blocks until the connection fails or ctx is done. The
+// connection is pinged every PingInterval, and fails if nothing, not even a
+// pong, is read for PingInterval plus DialTimeout.
+func (b *Redis) readMessages(ctx context.Context, conn *redisConn, handler func(payload []byte)) error {
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go b.ping(conn, done)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(b.opts.PingInterval + b.opts.DialTimeout))
+		reply, err := resp.Read(conn.r)
+		if err != nil {
+			return err
+		}
+		if len(reply.Array) == 3 && reply.Array[0].Text() == "message" {
+			handler(reply.Array[2].Str)
+		}
+	}
+}
+
+// ping writes a PING to a subscription connection every PingInterval until
+// done is closed. Pongs are read, and ignored, by readMessages.
+func (b *Redis) ping(conn *redisConn, done <-chan struct{}) {
+	ticker := time.NewTicker(b.opts.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		conn.SetWriteDeadline(time.Now().Add(b.opts.DialTimeout))
+		if err := resp.WriteCommand(conn.w, []byte("PING")); err != nil {
+			// readMessages fails too once the connection is closed.
+			conn.Close()
+			return
+		}
+	}
+}
+
+func (b *Redis) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	for _, cancel := range b.cancels {
+		cancel()
+	}
+	b.mu.Unlock()
+
+	b.publishMu.Lock()
+	defer b.publishMu.Unlock()
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package backplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/backplane/redisstub"
+)
+
+func startRedis(t *testing.T) *redisstub.Server {
+	t.Helper()
+	server, err := redisstub.Start("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func newRedis(t *testing.T, server *redisstub.Server, opts RedisOptions) *Redis {
+	t.Helper()
+	opts.Addr = server.Addr()
+	opts.Password = "secret"
+	b := NewRedis(opts)
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func subscribe(t *testing.T, b *Redis, channel string) <-chan string {
+	t.Helper()
+	received := make(chan string, 16)
+	if err := b.Subscribe(context.Background(), channel, func(payload []byte) {
+		received <- string(payload)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return received
+}
+
+// publishUntilReceived publishes until received gets the payload, since a
+// reconnecting subscriber misses what is published in the meantime.
+func publishUntilReceived(t *testing.T, b *Redis, channel string, received <-chan string) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := b.Publish(context.Background(), channel, []byte("hello")); err != nil {
+			t.Logf("publish: %v", err)
+		}
+		select {
+		case payload := <-received:
+			if payload != "hello" {
+				t.Fatalf("received %q, want hello", payload)
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("timed out waiting for the published payload")
+		}
+	}
+}
+
+func TestRedisPublishSubscribe(t *testing.T) {
+	server := startRedis(t)
+	b := newRedis(t, server, RedisOptions{})
+	received := subscribe(t, b, "ch")
+
+	if err := b.Publish(context.Background(), "ch", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case payload := <-received:
+		if payload != "hello" {
+			t.Fatalf("received %q, want hello", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the published payload")
+	}
+}
+
+func TestRedisReconnectsAfterDrop(t *testing.T) {
+	server := startRedis(t)
+	b := newRedis(t, server, RedisOptions{})
+	received := subscribe(t, b, "ch")
+	publishUntilReceived(t, b, "ch", received)
+
+	server.DropConnections()
+	publishUntilReceived(t, b, "ch", received)
+}
+
+func TestRedisReconnectsHalfOpenSubscription(t *testing.T) {
+	server := startRedis(t)
+	subscriber := newRedis(t, server, RedisOptions{
+		DialTimeout:  100 * time.Millisecond,
+		PingInterval: 20 * time.Millisecond,
+	})
+	received := subscribe(t, subscriber, "ch")
+
+	// The subscription connection stays open but hears nothing, so only
+	// its unanswered pings tell it to reconnect.
+	server.Hang()
+	publisher := newRedis(t, server, RedisOptions{})
+	publishUntilReceived(t, publisher, "ch", received)
+}
+
+func TestRedisSubscribeDuringPublish(t *testing.T) {
+	server := startRedis(t)
+	b := newRedis(t, server, RedisOptions{DialTimeout: time.Second})
+	if err := b.Publish(context.Background(), "ch", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A publish stuck on a hung connection doesn't keep Subscribe waiting.
+	server.Hang()
+	go b.Publish(context.Background(), "ch", []byte("stuck"))
+	time.Sleep(20 * time.Millisecond)
+	subscribed := make(chan error, 1)
+	go func() {
+		subscribed <- b.Subscribe(context.Background(), "other", func(payload []byte) {})
+	}()
+	select {
+	case err := <-subscribed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Subscribe waited on a publish")
+	}
+}
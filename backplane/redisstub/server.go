@@ -0,0 +1,217 @@
+// Package redisstub is a minimal in-process Redis-protocol server supporting
+// PING, AUTH, PUBLISH, SUBSCRIBE and UNSUBSCRIBE, for exercising the Redis
+// backplane without a real Redis.
+package redisstub
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/Acrylic125/webhook-ingest-ws/backplane/resp"
+)
+
+type Server struct {
+	listener net.Listener
+	password string
+
+	mu          sync.Mutex
+	conns       map[*client]struct{}
+	subscribers map[string]map[*client]struct{}
+	wg          sync.WaitGroup
+}
+
+type client struct {
+	conn   net.Conn
+	mu     sync.Mutex
+	w      *bufio.Writer
+	authed bool
+	subs   map[string]struct{}
+	// hung clients are sent nothing.
+	hung bool
+}
+
+func (c *client) write(fn func(w *bufio.Writer)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hung {
+		return nil
+	}
+	fn(c.w)
+	return c.w.Flush()
+}
+
+// Start listens on a random local port. If password is non-empty, clients
+// must AUTH before any other command.
+func Start(password string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener:    listener,
+		password:    password,
+		conns:       make(map[*client]struct{}),
+		subscribers: make(map[string]map[*client]struct{}),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the listener and drops every connection.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.DropConnections()
+	s.wg.Wait()
+	return err
+}
+
+// DropConnections closes every client connection while keeping the listener
+// open, simulating a Redis restart.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.conn.Close()
+	}
+}
+
+// Hang stops replying and forwarding messages to every current connection
+// while keeping them open, simulating a half-open connection. Later
+// connections are served normally.
+func (s *Server) Hang() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.mu.Lock()
+		c.hung = true
+		c.mu.Unlock()
+	}
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &client{
+			conn:   conn,
+			w:      bufio.NewWriter(conn),
+			authed: s.password == "",
+			subs:   make(map[string]struct{}),
+		}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c *client) {
+	defer s.wg.Done()
+	defer s.disconnect(c)
+
+	r := bufio.NewReader(c.conn)
+	for {
+		cmd, err := resp.Read(r)
+		if err != nil {
+			return
+		}
+		if cmd.Kind != resp.KindArray || len(cmd.Array) == 0 {
+			c.write(func(w *bufio.Writer) { resp.WriteError(w, "ERR invalid command") })
+			continue
+		}
+		s.handle(c, strings.ToUpper(cmd.Array[0].Text()), cmd.Array[1:])
+	}
+}
+
+func (s *Server) handle(c *client, name string, args []resp.Value) {
+	if !c.authed && name != "AUTH" {
+		c.write(func(w *bufio.Writer) { resp.WriteError(w, "NOAUTH Authentication required.") })
+		return
+	}
+
+	switch name {
+	case "PING":
+		c.write(func(w *bufio.Writer) { resp.WriteSimple(w, "PONG") })
+	case "AUTH":
+		if len(args) != 1 || args[0].Text() != s.password {
+			c.write(func(w *bufio.Writer) { resp.WriteError(w, "WRONGPASS invalid password") })
+			return
+		}
+		c.authed = true
+		c.write(func(w *bufio.Writer) { resp.WriteSimple(w, "OK") })
+	case "PUBLISH":
+		if len(args) != 2 {
+			c.write(func(w *bufio.Writer) { resp.WriteError(w, "ERR wrong number of arguments for 'publish' command") })
+			return
+		}
+		n := s.publish(args[0].Text(), args[1].Str)
+		c.write(func(w *bufio.Writer) { resp.WriteInt(w, int64(n)) })
+	case "SUBSCRIBE", "UNSUBSCRIBE":
+		for _, arg := range args {
+			channel := arg.Text()
+			s.mu.Lock()
+			if name == "SUBSCRIBE" {
+				if s.subscribers[channel] == nil {
+					s.subscribers[channel] = make(map[*client]struct{})
+				}
+				s.subscribers[channel][c] = struct{}{}
+				c.subs[channel] = struct{}{}
+			} else {
+				delete(s.subscribers[channel], c)
+				delete(c.subs, channel)
+			}
+			count := len(c.subs)
+			s.mu.Unlock()
+
+			c.write(func(w *bufio.Writer) {
+				resp.WriteArrayHeader(w, 3)
+				resp.WriteBulk(w, []byte(strings.ToLower(name)))
+				resp.WriteBulk(w, []byte(channel))
+				resp.WriteInt(w, int64(count))
+			})
+		}
+	default:
+		c.write(func(w *bufio.Writer) { resp.WriteError(w, "ERR unknown command '"+name+"'") })
+	}
+}
+
+func (s *Server) publish(channel string, payload []byte) int {
+	s.mu.Lock()
+	targets := make([]*client, 0, len(s.subscribers[channel]))
+	for c := range s.subscribers[channel] {
+		targets = append(targets, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range targets {
+		c.write(func(w *bufio.Writer) {
+			resp.WriteArrayHeader(w, 3)
+			resp.WriteBulk(w, []byte("message"))
+			resp.WriteBulk(w, []byte(channel))
+			resp.WriteBulk(w, payload)
+		})
+	}
+	return len(targets)
+}
+
+func (s *Server) disconnect(c *client) {
+	c.conn.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, c)
+	for channel := range c.subs {
+		delete(s.subscribers[channel], c)
+	}
+}
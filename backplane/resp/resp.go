@@ -0,0 +1,140 @@
+// Package resp implements the subset of the Redis serialization protocol
+// (RESP2) needed for pub/sub.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var ErrProtocol = errors.New("resp protocol error")
+
+type Kind byte
+
+const (
+	KindSimple Kind = '+'
+	KindError  Kind = '-'
+	KindInt    Kind = ':'
+	KindBulk   Kind = '$'
+	KindArray  Kind = '*'
+)
+
+// Value is a decoded RESP value. Null bulk strings and arrays have Null set.
+type Value struct {
+	Kind  Kind
+	Str   []byte
+	Int   int64
+	Array []Value
+	Null  bool
+}
+
+// Text returns the value as a string for simple, error and bulk values.
+func (v Value) Text() string {
+	return string(v.Str)
+}
+
+// Err returns the server error carried by an error value, if any.
+func (v Value) Err() error {
+	if v.Kind != KindError {
+		return nil
+	}
+	return fmt.Errorf("redis: %s", v.Str)
+}
+
+func Read(r *bufio.Reader) (Value, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return Value{}, err
+	}
+	if len(line) == 0 {
+		return Value{}, fmt.Errorf("%w: empty line", ErrProtocol)
+	}
+
+	kind, rest := Kind(line[0]), line[1:]
+	switch kind {
+	case KindSimple, KindError:
+		return Value{Kind: kind, Str: rest}, nil
+	case KindInt:
+		n, err := strconv.ParseInt(string(rest), 10, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: bad integer %q", ErrProtocol, rest)
+		}
+		return Value{Kind: kind, Int: n}, nil
+	case KindBulk:
+		n, err := strconv.Atoi(string(rest))
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: bad bulk length %q", ErrProtocol, rest)
+		}
+		if n < 0 {
+			return Value{Kind: kind, Null: true}, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return Value{}, err
+		}
+		return Value{Kind: kind, Str: buf[:n]}, nil
+	case KindArray:
+		n, err := strconv.Atoi(string(rest))
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: bad array length %q", ErrProtocol, rest)
+		}
+		if n < 0 {
+			return Value{Kind: kind, Null: true}, nil
+		}
+		items := make([]Value, n)
+		for i := range items {
+			if items[i], err = Read(r); err != nil {
+				return Value{}, err
+			}
+		}
+		return Value{Kind: kind, Array: items}, nil
+	default:
+		return Value{}, fmt.Errorf("%w: unknown type %q", ErrProtocol, line[0])
+	}
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("%w: line not terminated by CRLF", ErrProtocol)
+	}
+	return line[:len(line)-2], nil
+}
+
+// WriteCommand writes args as an array of bulk strings, the form clients use
+// to send commands.
+func WriteCommand(w *bufio.Writer, args ...[]byte) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		WriteBulk(w, arg)
+	}
+	return w.Flush()
+}
+
+func WriteSimple(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "+%s\r\n", s)
+}
+
+func WriteError(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "-%s\r\n", s)
+}
+
+func WriteInt(w *bufio.Writer, n int64) {
+	fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+func WriteBulk(w *bufio.Writer, b []byte) {
+	fmt.Fprintf(w, "$%d\r\n", len(b))
+	w.Write(b)
+	w.WriteString("\r\n")
+}
+
+func WriteArrayHeader(w *bufio.Writer, n int) {
+	fmt.Fprintf(w, "*%d\r\n", n)
+}
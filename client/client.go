@@ -0,0 +1,432 @@
+// Package client is a Go client for the WebSocket feed served by this module.
+// It reconnects with jittered backoff, re-subscribes to its topics, resumes
+// from the last received sequence number and decodes token pair events into
+// typed channels.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/utils"
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrNotConnected = errors.New("client not connected")
+	ErrNoReply      = errors.New("no reply from feed")
+)
+
+type Options struct {
+	// URL of the hub, e.g. "ws://localhost:8080/ws/pairs".
+	URL string
+	// Token is sent as "Authorization: Bearer <token>" when set.
+	Token  string
+	Header http.Header
+	// Topics to subscribe to on every (re)connect. Subscribe adds to them.
+	Topics []string
+	// Mode is the subscribe mode for Topics: ws.SubscribeModeAll (default) or
+	// ws.SubscribeModeLatest to only receive the newest event per pair.
+	Mode string
+	// MinBackoff and MaxBackoff bound the jittered reconnect delay. They
+	// default to 250ms and 30s, and MaxBackoff is raised to MinBackoff if
+	// below it.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// BufferSize of the TokenPairEvents and Messages channels. Defaults to 256.
+	BufferSize int
+	// ReplyTimeout bounds how long Subscribe and Unsubscribe wait for the
+	// feed to reply. Defaults to 10s.
+	ReplyTimeout time.Duration
+	Dialer       *websocket.Dialer
+}
+
+// Message is an envelope as received, with its data left undecoded.
+type Message struct {
+	Version  int             `json:"v"`
+	Type     ws.EnvelopeType `json:"type"`
+	Topic    string          `json:"topic,omitempty"`
+	Seq      uint64          `json:"seq,omitempty"`
+	ServerTs int64           `json:"serverTs"`
+	Source   string          `json:"source,omitempty"`
+	Backfill bool            `json:"backfill,omitempty"`
+	ID       json.RawMessage `json:"id,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Error    *ws.Error       `json:"error,omitempty"`
+}
+
+// TokenPairEvent is one event from a TOKEN_PAIR_EVENT webhook delivery,
+// received on the TOKEN_PAIR_EVENT topic or a pair or token topic.
+type TokenPairEvent struct {
+	Seq      uint64
+	ServerTs time.Time
+	// Backfill is set on events recovered after they were missed live.
+	Backfill bool
+	Event    ingest.TokenPairEvent
+	Pair     ingest.Pair
+}
+
+type Client struct {
+	opts Options
+
+	tokenPairEvents chan TokenPairEvent
+	messages        chan Message
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	topics  map[string]struct{}
+	lastSeq map[string]uint64
+	nextID  int
+	// replies maps the ID of a request waiting for its reply to the func
+	// handling it, called from the read loop with the reply's error, or
+	// ErrNotConnected if the connection dropped first.
+	replies map[string]func(err error)
+}
+
+func New(opts Options) *Client {
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff = 250 * time.Millisecond
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	opts.MaxBackoff = max(opts.MaxBackoff, opts.MinBackoff)
+	if opts.BufferSize == 0 {
+		opts.BufferSize = 256
+	}
+	if opts.ReplyTimeout == 0 {
+		opts.ReplyTimeout = 10 * time.Second
+	}
+	if opts.Dialer == nil {
+		opts.Dialer = websocket.DefaultDialer
+	}
+
+	topics := make(map[string]struct{}, len(opts.Topics))
+	for _, topic := range opts.Topics {
+		topics[ingest.NormalizeTopic(topic)] = struct{}{}
+	}
+	return &Client{
+		opts:            opts,
+		tokenPairEvents: make(chan TokenPairEvent, opts.BufferSize),
+		messages:        make(chan Message, opts.BufferSize),
+		topics:          topics,
+		lastSeq:         make(map[string]uint64),
+		replies:         make(map[string]func(err error)),
+	}
+}
+
+// TokenPairEvents receives every token pair event, one per event in a
+// delivery. It must be drained: reading from the feed waits while it is
+// full. It is closed when Run returns.
+func (c *Client) TokenPairEvents() <-chan TokenPairEvent {
+	return c.tokenPairEvents
+}
+
+// Messages receives every envelope other than token pair events and replies,
+// including notices and errors. Reading it is optional: messages arriving
+// while it is full are dropped. It is closed when Run returns.
+func (c *Client) Messages() <-chan Message {
+	return c.messages
+}
+
+// Run connects and keeps the client connected until ctx is done. It returns
+// ctx.Err(). Run must only be called once.
+func (c *Client) Run(ctx context.Context) error {
+	defer close(c.tokenPairEvents)
+	defer close(c.messages)
+
+	attempt := 0
+	for {
+		conn, err := c.connect(ctx)
+		if err == nil {
+			attempt = 0
+			err = c.readLoop(ctx, conn)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		delay := c.backoff(attempt)
+		attempt++
+		log.Warn().Err(err).Dur("retry_in", delay).Str("url", c.opts.URL).Msg("Feed connection lost")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	return utils.Backoff(c.opts.MinBackoff, c.opts.MaxBackoff, attempt)
+}
+
+func (c *Client) connect(ctx context.Context) (*websocket.Conn, error) {
+	header := http.Header{}
+	for key, values := range c.opts.Header {
+		header[key] = append([]string(nil), values...)
+	}
+	if c.opts.Token != "" {
+		header.Set("Authorization", "Bearer "+c.opts.Token)
+	}
+
+	conn, resp, err := c.opts.Dialer.DialContext(ctx, c.opts.URL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to connect to [%s]: %s: %w", c.opts.URL, resp.Status, err)
+		}
+		return nil, fmt.Errorf("failed to connect to [%s]: %w", c.opts.URL, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn = conn
+
+	if len(c.topics) > 0 {
+		topics := make([]string, 0, len(c.topics))
+		resume := make(map[string]uint64)
+		for topic := range c.topics {
+			topics = append(topics, topic)
+			if seq, ok := c.lastSeq[topic]; ok {
+				resume[topic] = seq
+			}
+		}
+		// The read loop isn't running yet, so the reply is handled when it
+		// arrives rather than waited on.
+		err := c.request("subscribe", ws.SubscribeRequest{Topics: topics, Mode: c.opts.Mode, Resume: resume}, func(err error) {
+			var replyErr *ws.Error
+			if errors.As(err, &replyErr) {
+				log.Error().Err(err).Strs("topics", topics).Msg("Feed rejected subscription")
+			}
+		})
+		if err != nil {
+			conn.Close()
+			c.conn = nil
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// request sends a request and, if onReply is set, has it called with the
+// outcome of the reply. It must be called with c.mu held.
+func (c *Client) request(msgType string, data any, onReply func(err error)) error {
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", msgType, err)
+	}
+	c.nextID++
+	id := fmt.Sprint(c.nextID)
+	frame, err := json.Marshal(ws.Request{
+		ID:   json.RawMessage(id),
+		Type: msgType,
+		Data: encoded,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", msgType, err)
+	}
+	if err := c.conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+		return fmt.Errorf("failed to send %s request: %w", msgType, err)
+	}
+	if onReply != nil {
+		c.replies[id] = onReply
+	}
+	return nil
+}
+
+// call sends a request and waits up to Options.ReplyTimeout for its reply.
+// An error reply is returned as a *ws.Error. The reply is read by Run, so
+// call must not be made while TokenPairEvents is full and not being read.
+func (c *Client) call(msgType string, data any) error {
+	replied := make(chan error, 1)
+	c.mu.Lock()
+	err := c.request(msgType, data, func(err error) {
+		replied <- err
+	})
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(c.opts.ReplyTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-replied:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%w to %s within %s", ErrNoReply, msgType, c.opts.ReplyTimeout)
+	}
+}
+
+// Subscribe adds topics to the subscription and waits for the feed to
+// confirm it. If the client is not connected the topics are subscribed on
+// the next connect and ErrNotConnected is returned. Topics the feed rejects,
+// with a *ws.Error, are not subscribed again on reconnect.
+func (c *Client) Subscribe(topics ...string) error {
+	c.mu.Lock()
+	topics = normalizeTopics(topics)
+	var added []string
+	for _, topic := range topics {
+		if _, ok := c.topics[topic]; !ok {
+			c.topics[topic] = struct{}{}
+			added = append(added, topic)
+		}
+	}
+	c.mu.Unlock()
+
+	err := c.call("subscribe", ws.SubscribeRequest{Topics: topics, Mode: c.opts.Mode})
+	var replyErr *ws.Error
+	if errors.As(err, &replyErr) {
+		c.mu.Lock()
+		for _, topic := range added {
+			delete(c.topics, topic)
+		}
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// Unsubscribe removes topics from the subscription and waits for the feed to
+// confirm it.
+func (c *Client) Unsubscribe(topics ...string) error {
+	c.mu.Lock()
+	topics = normalizeTopics(topics)
+	for _, topic := range topics {
+		delete(c.topics, topic)
+		delete(c.lastSeq, topic)
+	}
+	c.mu.Unlock()
+	return c.call("unsubscribe", ws.UnsubscribeRequest{Topics: topics})
+}
+
+func (c *Client) readLoop(ctx context.Context, conn *websocket.Conn) error {
+	stop := context.AfterFunc(ctx, func() {
+		conn.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second),
+		)
+		conn.Close()
+	})
+	defer stop()
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		replies := c.replies
+		c.replies = make(map[string]func(err error))
+		c.mu.Unlock()
+		conn.Close()
+		for _, onReply := range replies {
+			onReply(ErrNotConnected)
+		}
+	}()
+
+	for {
+		_, frame, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		// Batched frames are arrays of envelopes.
+		var messages []Message
+		if trimmed := bytes.TrimSpace(frame); len(trimmed) > 0 && trimmed[0] == '[' {
+			err = json.Unmarshal(trimmed, &messages)
+		} else {
+			messages = make([]Message, 1)
+			err = json.Unmarshal(frame, &messages[0])
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("Invalid frame from feed")
+			continue
+		}
+
+		for _, message := range messages {
+			if err := c.dispatch(ctx, message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Client) dispatch(ctx context.Context, message Message) error {
+	// The server replays only messages after the resumed seq, so the latest
+	// seq is tracked as-is; it may go backwards if the server restarted.
+	if message.Topic != "" && message.Seq > 0 {
+		c.mu.Lock()
+		c.lastSeq[message.Topic] = message.Seq
+		c.mu.Unlock()
+	}
+
+	if len(message.ID) > 0 && (message.Type == ws.EnvelopeAck || message.Type == ws.EnvelopeError) {
+		c.mu.Lock()
+		onReply, ok := c.replies[string(message.ID)]
+		delete(c.replies, string(message.ID))
+		c.mu.Unlock()
+		if ok {
+			var err error
+			if message.Type == ws.EnvelopeError {
+				err = message.Error
+				if message.Error == nil {
+					err = &ws.Error{Code: ws.ErrCodeInternal, Message: "error reply without details"}
+				}
+			}
+			onReply(err)
+			return nil
+		}
+	}
+
+	switch {
+	case message.Type == ws.EnvelopeAck:
+		return nil
+	case message.Type == ws.EnvelopeEvent && ingest.IsTokenPairTopic(message.Topic):
+		body := ingest.TokenPairWebhookBody{}
+		if err := json.Unmarshal(message.Data, &body); err != nil {
+			log.Warn().Err(err).Uint64("seq", message.Seq).Msg("Invalid token pair event")
+			return nil
+		}
+		for _, data := range body.Data {
+			event := TokenPairEvent{
+				Seq:      message.Seq,
+				ServerTs: time.UnixMilli(message.ServerTs),
+				Backfill: message.Backfill,
+				Event:    data.Event,
+				Pair:     data.Pair,
+			}
+			select {
+			case c.tokenPairEvents <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	default:
+		select {
+		case c.messages <- message:
+		default:
+			log.Warn().Str("type", string(message.Type)).Str("topic", message.Topic).Msg("Messages channel full, dropping message")
+		}
+		return nil
+	}
+}
+
+// normalizeTopics returns topics as the server names them, so the sequence
+// numbers received on them are found on resume.
+func normalizeTopics(topics []string) []string {
+	normalized := make([]string, len(topics))
+	for i, topic := range topics {
+		normalized[i] = ingest.NormalizeTopic(topic)
+	}
+	return normalized
+}
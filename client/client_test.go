@@ -0,0 +1,254 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+	"github.com/gorilla/websocket"
+)
+
+type testHubManager struct {
+	hub    *ws.Hub[any]
+	router *ws.Router[any]
+}
+
+func (m *testHubManager) GetHub() *ws.Hub[any]                          { return m.hub }
+func (m *testHubManager) OnRegister(client *ws.UserClient[any]) error   { return nil }
+func (m *testHubManager) OnUnregister(client *ws.UserClient[any]) error { return nil }
+
+func (m *testHubManager) OnReceiveMessage(client *ws.UserClient[any], message []byte) error {
+	return m.router.Dispatch(client, message)
+}
+
+// demandEvents reports topics gaining their first or losing their last
+// subscriber, so tests know when the server applied a (un)subscribe.
+type demandEvents struct {
+	demanded chan string
+	released chan string
+}
+
+func (d *demandEvents) TopicDemanded(topic string) { d.demanded <- topic }
+func (d *demandEvents) TopicReleased(topic string) { d.released <- topic }
+
+type testServer struct {
+	url    string
+	hub    *ws.Hub[any]
+	demand *demandEvents
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	router := ws.NewRouter[any]()
+	ws.HandleSubscriptions(router)
+	manager := &testHubManager{hub: ws.NewHub[any](), router: router}
+	demand := &demandEvents{demanded: make(chan string, 16), released: make(chan string, 16)}
+	manager.hub.UseDemand(demand)
+
+	registry := ws.NewRegistry()
+	if err := ws.Register(registry, "pairs", manager, ws.HubConfig[any]{Path: "/ws"}); err != nil {
+		t.Fatal(err)
+	}
+	hub, _ := registry.Get("pairs")
+	server := httptest.NewServer(hub)
+	t.Cleanup(server.Close)
+	return &testServer{
+		url:    "ws" + strings.TrimPrefix(server.URL, "http"),
+		hub:    manager.hub,
+		demand: demand,
+	}
+}
+
+func (s *testServer) publish(t *testing.T, topic string, pairAddress string) {
+	t.Helper()
+	body := ingest.TokenPairWebhookBody{
+		Type: ingest.WebhookTypeTokenPairEvent,
+		Data: []ingest.TokenPairEventData{{
+			Event: ingest.TokenPairEvent{Address: pairAddress, EventType: "Swap"},
+			Pair:  ingest.Pair{Address: pairAddress, NetworkID: 1},
+		}},
+	}
+	if _, err := s.hub.Publish(ws.NewEnvelope(ws.EnvelopeEvent, topic, body)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func wait[V any](t *testing.T, ch <-chan V, what string) V {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		panic("unreachable")
+	}
+}
+
+func run(t *testing.T, c *Client) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	server := newTestServer(t)
+	watched, other := ingest.PairTopic("0xa", 1), ingest.PairTopic("0xb", 1)
+	c := New(Options{URL: server.url, Topics: []string{watched}})
+	run(t, c)
+	wait(t, server.demand.demanded, "subscribe")
+
+	server.publish(t, other, "0xb")
+	server.publish(t, watched, "0xa")
+	event := wait(t, c.TokenPairEvents(), "event")
+	if event.Pair.Address != "0xa" || event.Seq != 1 {
+		t.Fatalf("want the first event of 0xa, got %s seq %d", event.Pair.Address, event.Seq)
+	}
+
+	if err := c.Subscribe(other); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, server.demand.demanded, "second subscribe")
+	server.publish(t, other, "0xb")
+	event = wait(t, c.TokenPairEvents(), "event")
+	if event.Pair.Address != "0xb" || event.Seq != 2 {
+		t.Fatalf("want the second event of 0xb, got %s seq %d", event.Pair.Address, event.Seq)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	server := newTestServer(t)
+	kept, dropped := ingest.PairTopic("0xa", 1), ingest.PairTopic("0xb", 1)
+	c := New(Options{URL: server.url, Topics: []string{kept, dropped}})
+	run(t, c)
+	wait(t, server.demand.demanded, "subscribe")
+	wait(t, server.demand.demanded, "subscribe")
+
+	if err := c.Unsubscribe(dropped); err != nil {
+		t.Fatal(err)
+	}
+	if topic := wait(t, server.demand.released, "unsubscribe"); topic != dropped {
+		t.Fatalf("released %s, want %s", topic, dropped)
+	}
+
+	server.publish(t, dropped, "0xb")
+	server.publish(t, kept, "0xa")
+	event := wait(t, c.TokenPairEvents(), "event")
+	if event.Pair.Address != "0xa" {
+		t.Fatalf("received an event of unsubscribed pair %s", event.Pair.Address)
+	}
+}
+
+func TestSubscribeRejected(t *testing.T) {
+	server := newTestServer(t)
+	c := New(Options{URL: server.url, Topics: []string{ingest.PairTopic("0xa", 1)}})
+	run(t, c)
+	wait(t, server.demand.demanded, "subscribe")
+
+	invalid := strings.Repeat("x", 300)
+	err := c.Subscribe(invalid)
+	var replyErr *ws.Error
+	if !errors.As(err, &replyErr) || replyErr.Code != ws.ErrCodeInvalidParams {
+		t.Fatalf("got %v, want an invalid params error", err)
+	}
+	c.mu.Lock()
+	_, kept := c.topics[invalid]
+	c.mu.Unlock()
+	if kept {
+		t.Fatal("rejected topic is subscribed again on reconnect")
+	}
+}
+
+func TestMessagesDoNotBlockEvents(t *testing.T) {
+	server := newTestServer(t)
+	topic := ingest.PairTopic("0xa", 1)
+	c := New(Options{URL: server.url, Topics: []string{topic, "notices"}, BufferSize: 1})
+	run(t, c)
+	wait(t, server.demand.demanded, "subscribe")
+
+	// Messages is never read.
+	for i := range 4 {
+		if _, err := server.hub.Publish(ws.NewEnvelope(ws.EnvelopeNotice, "notices", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.publish(t, topic, "0xa")
+	if event := wait(t, c.TokenPairEvents(), "event"); event.Pair.Address != "0xa" {
+		t.Fatalf("got an event of %s, want 0xa", event.Pair.Address)
+	}
+}
+
+// connRecorder keeps the connections dialed so a test can break them.
+type connRecorder struct {
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func (r *connRecorder) dial(ctx context.Context, network string, addr string) (net.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+	if err == nil {
+		r.mu.Lock()
+		r.conns = append(r.conns, conn)
+		r.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (r *connRecorder) closeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, conn := range r.conns {
+		conn.Close()
+	}
+}
+
+func TestResume(t *testing.T) {
+	server := newTestServer(t)
+	topic := ingest.PairTopic("0xa", 1)
+	recorder := &connRecorder{}
+	c := New(Options{
+		URL:        server.url,
+		Topics:     []string{topic},
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+		Dialer:     &websocket.Dialer{NetDialContext: recorder.dial},
+	})
+	run(t, c)
+	wait(t, server.demand.demanded, "subscribe")
+
+	server.publish(t, topic, "0xa")
+	if event := wait(t, c.TokenPairEvents(), "event"); event.Seq != 1 {
+		t.Fatalf("got seq %d, want 1", event.Seq)
+	}
+
+	recorder.closeAll()
+	wait(t, server.demand.released, "disconnect")
+	// Published while the client is away, and replayed when it resumes.
+	server.publish(t, topic, "0xa")
+	server.publish(t, topic, "0xa")
+	wait(t, server.demand.demanded, "resubscribe")
+
+	for _, want := range []uint64{2, 3} {
+		if event := wait(t, c.TokenPairEvents(), "replayed event"); event.Seq != want {
+			t.Fatalf("got seq %d, want %d", event.Seq, want)
+		}
+	}
+	server.publish(t, topic, "0xa")
+	if event := wait(t, c.TokenPairEvents(), "live event"); event.Seq != 4 {
+		t.Fatalf("got seq %d, want 4", event.Seq)
+	}
+}
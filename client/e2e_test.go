@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/codex/codexstub"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/settings"
+	"github.com/Acrylic125/webhook-ingest-ws/webhooks"
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+)
+
+func swap(pairAddress string) ingest.TokenPairEventData {
+	return ingest.TokenPairEventData{
+		Event: ingest.TokenPairEvent{
+			Address:            pairAddress,
+			Data:               ingest.EventData{Protocol: "UniswapV2", Type: "Swap"},
+			EventDisplayType:   "Buy",
+			EventType:          "Swap",
+			EventType2:         "Swap",
+			LiquidityToken:     "0xliquidity",
+			Maker:              "0xmaker",
+			QuoteToken:         "token1",
+			Timestamp:          1700000000,
+			Token0PoolValueUsd: "1",
+			Token0SwapValueUsd: "1",
+			Token0ValueBase:    "1",
+			Token0ValueUsd:     "1",
+			Token1PoolValueUsd: "1",
+			Token1SwapValueUsd: "1",
+			Token1ValueBase:    "1",
+			Token1ValueUsd:     "1",
+			TransactionHash:    "0xtx",
+		},
+		Pair: ingest.Pair{
+			Address:      pairAddress,
+			ExchangeHash: "0xexchange",
+			ID:           pairAddress + ":1",
+			NetworkID:    1,
+			Token0:       "0xtoken0",
+			Token1:       "0xtoken1",
+		},
+	}
+}
+
+// TestEndToEnd provisions a webhook on the Codex stub, has the stub deliver
+// an event to the ingest handler and reads it from the hub with a client.
+func TestEndToEnd(t *testing.T) {
+	const secret = "webhook-secret"
+	pairAddress := "0xAbC0000000000000000000000000000000000001"
+
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+
+	router := ws.NewRouter[any]()
+	ws.HandleSubscriptions(router)
+	manager := &testHubManager{hub: ws.NewHub[any](), router: router}
+	manager.hub.UseTopicNormalizer(ingest.NormalizeTopic)
+	demand := &demandEvents{demanded: make(chan string, 16), released: make(chan string, 16)}
+	manager.hub.UseDemand(demand)
+	registry := ws.NewRegistry()
+	if err := ws.Register(registry, "pairs", manager, ws.HubConfig[any]{Path: "/ws/pairs"}); err != nil {
+		t.Fatal(err)
+	}
+	hub, _ := registry.Get("pairs")
+
+	mux := http.NewServeMux()
+	mux.Handle("/ws/pairs", hub)
+	mux.Handle("/webhook", ingest.NewHandler(secret, registry, map[string]string{
+		ingest.WebhookTypeTokenPairEvent: "pairs",
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provisioner := webhooks.NewProvisioner(
+		codex.NewClient(codex.Options{Endpoint: stub.URL(), Token: "codex-key"}),
+		server.URL+"/webhook",
+		secret,
+		webhooks.BucketID("test", "e2e"),
+		webhooks.NewStore(filepath.Join(t.TempDir(), "webhooks.json")),
+	)
+	reconciler := webhooks.NewReconciler(provisioner, []settings.WebhookConfig{{
+		Name:        "e2e",
+		NetworkIds:  []int{1},
+		PairAddress: pairAddress,
+	}}, false)
+	if _, err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(stub.Webhooks()); n != 1 {
+		t.Fatalf("%d webhooks provisioned, want 1", n)
+	}
+
+	c := New(Options{
+		URL:    "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/pairs",
+		Topics: []string{ingest.PairTopic(pairAddress, 1)},
+	})
+	run(t, c)
+	wait(t, demand.demanded, "subscribe")
+
+	deliveries := stub.Deliver(context.Background(), []ingest.TokenPairEventData{
+		swap(pairAddress),
+		swap("0x0000000000000000000000000000000000000002"),
+	})
+	if len(deliveries) != 1 || deliveries[0].Err != nil || deliveries[0].Events != 1 {
+		t.Fatalf("want one accepted delivery of one event, got %+v", deliveries)
+	}
+
+	event := wait(t, c.TokenPairEvents(), "event")
+	if event.Pair.Address != pairAddress || event.Event.TransactionHash != "0xtx" || event.Seq != 1 {
+		t.Fatalf("got event of %s seq %d, want the delivered event of %s", event.Pair.Address, event.Seq, pairAddress)
+	}
+}
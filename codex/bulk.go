@@ -0,0 +1,345 @@
+package codex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Khan/genqlient/graphql"
+)
+
+// ErrNotCreated is the error of a bulk item Codex answered without creating,
+// and without saying why.
+var ErrNotCreated = errors.New("codex: webhook not created")
+
+type BulkOptions struct {
+	// ChunkSize is the most webhooks sent in one mutation, across types.
+	// Defaults to 25.
+	ChunkSize int
+	// Interval is the least time between the start of two mutations.
+	// Defaults to 250ms.
+	Interval time.Duration
+	// Attempts is how many times a webhook is tried at most. Defaults to 3.
+	Attempts int
+	// RetryDelay is waited before retrying the webhooks that failed.
+	// Defaults to 2s.
+	RetryDelay time.Duration
+}
+
+// BulkItem is the outcome of creating one webhook of a bulk input.
+type BulkItem struct {
+	Type WebhookType
+	// Index of the webhook in its list of the input.
+	Index int
+	Name  string
+	// Webhook is nil if the webhook wasn't created.
+	Webhook  *CreatedWebhook
+	Attempts int
+	Err      error
+}
+
+// BulkResult holds an item per webhook of a bulk input, token pair webhooks
+// first, then price and market cap webhooks, in input order.
+type BulkResult struct {
+	Items []BulkItem
+}
+
+// Created returns the webhooks that were created, in input order.
+func (r BulkResult) Created() []*CreatedWebhook {
+	var created []*CreatedWebhook
+	for _, item := range r.Items {
+		if item.Webhook != nil {
+			created = append(created, item.Webhook)
+		}
+	}
+	return created
+}
+
+// Failed returns the items that weren't created.
+func (r BulkResult) Failed() []BulkItem {
+	var failed []BulkItem
+	for _, item := range r.Items {
+		if item.Webhook == nil {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// Err summarises the failed items, or returns nil if every webhook was
+// created.
+func (r BulkResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	errs := make([]error, len(failed))
+	for i, item := range failed {
+		errs[i] = fmt.Errorf("%s webhook %q: %w", item.Type, item.Name, item.Err)
+	}
+	return fmt.Errorf("failed to create %d of %d webhooks: %w", len(failed), len(r.Items), errors.Join(errs...))
+}
+
+// bulkItem is a webhook waiting to be created. Exactly one of the args is set.
+type bulkItem struct {
+	result    *BulkItem
+	tokenPair *CreateTokenPairEventWebhookArgs
+	price     *CreatePriceWebhookArgs
+	marketCap *CreateMarketCapWebhookArgs
+	// alone is set once the item was sent or rejected on its own. Items in
+	// a mutation rejected as a whole for bad input are retried alone to find
+	// out which of them was the culprit.
+	alone bool
+}
+
+// CreateWebhooksBulk creates the token pair, price and market cap webhooks of
+// input in mutations of at most opts.ChunkSize webhooks, throttled to one per
+// opts.Interval. A failed webhook doesn't fail the others: webhooks Codex
+// didn't create are retried up to opts.Attempts times, except for ones
+// rejected as bad input. Every webhook is sent with Deduplicate set, so
+// retrying a mutation that was applied returns the webhooks it created.
+// NFT and raw transaction webhooks aren't supported and fail with
+// ErrBadInput.
+func CreateWebhooksBulk(ctx context.Context, client graphql.Client, input CreateWebhooksInput, opts BulkOptions) BulkResult {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 25
+	}
+	if opts.Interval == 0 {
+		opts.Interval = 250 * time.Millisecond
+	}
+	if opts.Attempts <= 0 {
+		opts.Attempts = 3
+	}
+	if opts.RetryDelay == 0 {
+		opts.RetryDelay = 2 * time.Second
+	}
+
+	result := BulkResult{}
+	var pending []*bulkItem
+	add := func(webhookType WebhookType, index int, name string, item bulkItem) {
+		result.Items = append(result.Items, BulkItem{Type: webhookType, Index: index, Name: name})
+		pending = append(pending, &item)
+	}
+	if input.TokenPairEventWebhooksInput != nil {
+		for i, args := range input.TokenPairEventWebhooksInput.Webhooks {
+			args.Deduplicate = Ptr(true)
+			add(WebhookTypeTokenPairEvent, i, args.Name, bulkItem{tokenPair: &args})
+		}
+	}
+	if input.PriceWebhooksInput != nil {
+		for i, args := range input.PriceWebhooksInput.Webhooks {
+			args.Deduplicate = Ptr(true)
+			add(WebhookTypePriceEvent, i, args.Name, bulkItem{price: &args})
+		}
+	}
+	if input.MarketCapWebhooksInput != nil {
+		for i, args := range input.MarketCapWebhooksInput.Webhooks {
+			args.Deduplicate = Ptr(true)
+			add(WebhookTypeMarketCapEvent, i, args.Name, bulkItem{marketCap: &args})
+		}
+	}
+	unsupported := func(webhookType WebhookType, count int) {
+		for i := range count {
+			result.Items = append(result.Items, BulkItem{
+				Type:  webhookType,
+				Index: i,
+				Err:   fmt.Errorf("%w: %s webhooks can't be created in bulk", ErrBadInput, webhookType),
+			})
+		}
+	}
+	if input.NftEventWebhooksInput != nil {
+		unsupported(WebhookTypeNftEvent, len(input.NftEventWebhooksInput.Webhooks))
+	}
+	if input.RawTransactionWebhooksInput != nil {
+		unsupported(WebhookTypeRawTransaction, len(input.RawTransactionWebhooksInput.Webhooks))
+	}
+	// Items point into result.Items only once it stops growing.
+	for i := range pending {
+		pending[i].result = &result.Items[i]
+	}
+
+	var last time.Time
+	throttle := func() bool {
+		if !sleepCtx(ctx, time.Until(last.Add(opts.Interval))) {
+			return false
+		}
+		last = time.Now()
+		return true
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		if attempt > 1 && !sleepCtx(ctx, opts.RetryDelay) {
+			break
+		}
+		var retry []*bulkItem
+		for _, chunk := range chunkBulkItems(pending, opts.ChunkSize) {
+			if !throttle() {
+				break
+			}
+			createChunk(ctx, client, chunk)
+			for _, item := range chunk {
+				if item.result.Webhook == nil && item.result.Attempts < opts.Attempts && retryable(item) {
+					retry = append(retry, item)
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		pending = retry
+	}
+
+	if err := ctx.Err(); err != nil {
+		for i := range result.Items {
+			if item := &result.Items[i]; item.Webhook == nil && item.Err == nil {
+				item.Err = err
+			}
+		}
+	}
+	return result
+}
+
+// retryable reports whether a failed item may succeed when tried again.
+// Items rejected on their own for bad input won't.
+func retryable(item *bulkItem) bool {
+	if !errors.Is(item.result.Err, ErrBadInput) {
+		return true
+	}
+	if item.alone {
+		return false
+	}
+	item.alone = true
+	return true
+}
+
+// chunkBulkItems splits items into chunks of at most size items. Items to be
+// retried alone get a chunk of their own.
+func chunkBulkItems(items []*bulkItem, size int) [][]*bulkItem {
+	var chunks [][]*bulkItem
+	var chunk []*bulkItem
+	for _, item := range items {
+		if item.alone {
+			chunks = append(chunks, []*bulkItem{item})
+			continue
+		}
+		chunk = append(chunk, item)
+		if len(chunk) == size {
+			chunks = append(chunks, chunk)
+			chunk = nil
+		}
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// createChunk sends one mutation for chunk and records the outcome in each
+// item's result.
+func createChunk(ctx context.Context, client graphql.Client, chunk []*bulkItem) {
+	input := CreateWebhooksInput{}
+	var tokenPairs, prices, marketCaps []*bulkItem
+	for _, item := range chunk {
+		item.result.Attempts++
+		item.alone = item.alone || len(chunk) == 1
+		switch {
+		case item.tokenPair != nil:
+			if input.TokenPairEventWebhooksInput == nil {
+				input.TokenPairEventWebhooksInput = &CreateTokenPairEventWebhooksInput{}
+			}
+			input.TokenPairEventWebhooksInput.Webhooks = append(input.TokenPairEventWebhooksInput.Webhooks, *item.tokenPair)
+			tokenPairs = append(tokenPairs, item)
+		case item.price != nil:
+			if input.PriceWebhooksInput == nil {
+				input.PriceWebhooksInput = &CreatePriceWebhooksInput{}
+			}
+			input.PriceWebhooksInput.Webhooks = append(input.PriceWebhooksInput.Webhooks, *item.price)
+			prices = append(prices, item)
+		case item.marketCap != nil:
+			if input.MarketCapWebhooksInput == nil {
+				input.MarketCapWebhooksInput = &CreateMarketCapWebhooksInput{}
+			}
+			input.MarketCapWebhooksInput.Webhooks = append(input.MarketCapWebhooksInput.Webhooks, *item.marketCap)
+			marketCaps = append(marketCaps, item)
+		}
+	}
+
+	// Codex may create part of a mutation and report errors for the rest at
+	// their path, e.g. "createWebhooks.priceWebhooks[2]".
+	resp, err := CreateWebhooks(ctx, client, input)
+	itemErrs := bulkItemErrors(err)
+	record := func(field string, items []*bulkItem, webhooks []*CreatedWebhook) {
+		for i, item := range items {
+			if i < len(webhooks) && webhooks[i] != nil {
+				item.result.Webhook = webhooks[i]
+				item.result.Err = nil
+				continue
+			}
+			switch itemErr := itemErrs[fmt.Sprintf("%s[%d]", field, i)]; {
+			case itemErr != nil:
+				// Rejected on its own account.
+				item.result.Err = itemErr
+				item.alone = true
+			case err != nil:
+				item.result.Err = err
+			default:
+				item.result.Err = ErrNotCreated
+			}
+		}
+	}
+	var tokenPairWebhooks, priceWebhooks, marketCapWebhooks []*CreatedWebhook
+	if resp != nil {
+		tokenPairWebhooks = resp.CreateWebhooks.TokenPairEventWebhooks
+		priceWebhooks = resp.CreateWebhooks.PriceWebhooks
+		marketCapWebhooks = resp.CreateWebhooks.MarketCapWebhooks
+	}
+	record("tokenPairEventWebhooks", tokenPairs, tokenPairWebhooks)
+	record("priceWebhooks", prices, priceWebhooks)
+	record("marketCapWebhooks", marketCaps, marketCapWebhooks)
+}
+
+// bulkItemErrors returns the GraphQL errors of err reported at the path of a
+// single webhook, by "<list>[<index>]".
+func bulkItemErrors(err error) map[string]error {
+	var codexErr *Error
+	if !errors.As(err, &codexErr) {
+		return nil
+	}
+	errs := make(map[string]error)
+	for _, gqlErr := range codexErr.Errors {
+		path, ok := strings.CutPrefix(gqlErr.Path, "createWebhooks.")
+		if !ok {
+			continue
+		}
+		field, index, ok := strings.Cut(path, "[")
+		if !ok {
+			continue
+		}
+		index, _, _ = strings.Cut(index, "]")
+		if _, convErr := strconv.Atoi(index); convErr != nil {
+			continue
+		}
+		key := field + "[" + index + "]"
+		if _, ok := errs[key]; !ok {
+			errs[key] = &Error{Operation: codexErr.Operation, StatusCode: codexErr.StatusCode, Errors: []GraphQLError{gqlErr}}
+		}
+	}
+	return errs
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,190 @@
+package codex_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/codex/codexstub"
+	"github.com/Khan/genqlient/graphql"
+)
+
+func pairWebhook(i int) codex.CreateTokenPairEventWebhookArgs {
+	return codex.CreateTokenPairEventWebhookArgs{
+		Name:          fmt.Sprintf("pair-%d", i),
+		CallbackUrl:   "http://localhost/send-data",
+		SecurityToken: "secret",
+		Conditions: codex.TokenPairEventWebhookConditionInput{
+			PairAddress: &codex.StringEqualsConditionInput{Eq: fmt.Sprintf("0xpair%d", i)},
+		},
+	}
+}
+
+func priceWebhook(i int) codex.CreatePriceWebhookArgs {
+	return codex.CreatePriceWebhookArgs{
+		Name:            fmt.Sprintf("price-%d", i),
+		CallbackUrl:     "http://localhost/send-data",
+		SecurityToken:   "secret",
+		AlertRecurrence: codex.AlertRecurrenceIndefinite,
+		Conditions: codex.PriceEventWebhookConditionInput{
+			TokenAddress: codex.StringEqualsConditionInput{Eq: fmt.Sprintf("0xtoken%d", i)},
+			NetworkId:    codex.IntEqualsConditionInput{Eq: 1},
+			PriceUsd:     codex.ComparisonOperatorInput{Gt: codex.Ptr("1")},
+		},
+	}
+}
+
+func bulkInput(pairs int, prices int) codex.CreateWebhooksInput {
+	input := codex.CreateWebhooksInput{
+		TokenPairEventWebhooksInput: &codex.CreateTokenPairEventWebhooksInput{},
+		PriceWebhooksInput:          &codex.CreatePriceWebhooksInput{},
+	}
+	for i := range pairs {
+		input.TokenPairEventWebhooksInput.Webhooks = append(input.TokenPairEventWebhooksInput.Webhooks, pairWebhook(i))
+	}
+	for i := range prices {
+		input.PriceWebhooksInput.Webhooks = append(input.PriceWebhooksInput.Webhooks, priceWebhook(i))
+	}
+	return input
+}
+
+func newStubClient(stub *codexstub.Server) graphql.Client {
+	return codex.NewClient(codex.Options{
+		Endpoint:   stub.URL(),
+		Token:      "codex-key",
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+	})
+}
+
+func count(operations []string, name string) int {
+	n := 0
+	for _, operation := range operations {
+		if operation == name {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBulkChunks(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+
+	start := time.Now()
+	result := codex.CreateWebhooksBulk(context.Background(), newStubClient(stub), bulkInput(3, 2), codex.BulkOptions{
+		ChunkSize: 2,
+		Interval:  50 * time.Millisecond,
+	})
+	if err := result.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(stub.Operations(), "CreateWebhooks"); n != 3 {
+		t.Fatalf("sent %d mutations, want 3", n)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("three mutations took %s, want them at least 50ms apart", elapsed)
+	}
+
+	wantNames := []string{"pair-0", "pair-1", "pair-2", "price-0", "price-1"}
+	created := result.Created()
+	if len(result.Items) != len(wantNames) || len(created) != len(wantNames) {
+		t.Fatalf("got %d items and %d created, want %d", len(result.Items), len(created), len(wantNames))
+	}
+	webhooks := stub.Webhooks()
+	for i, item := range result.Items {
+		wantType, wantIndex := codex.WebhookTypeTokenPairEvent, i
+		if i >= 3 {
+			wantType, wantIndex = codex.WebhookTypePriceEvent, i-3
+		}
+		if item.Type != wantType || item.Index != wantIndex || item.Name != wantNames[i] || item.Attempts != 1 || item.Err != nil {
+			t.Fatalf("item %d is %+v", i, item)
+		}
+		if item.Webhook == nil || item.Webhook.Id != webhooks[i].ID || webhooks[i].Name != wantNames[i] {
+			t.Fatalf("item %d created %+v, want %+v", i, item.Webhook, webhooks[i])
+		}
+	}
+	if len(result.Failed()) != 0 {
+		t.Fatalf("failed items %+v", result.Failed())
+	}
+}
+
+func TestBulkRetriesServerErrors(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	stub.FailNext("CreateWebhooks", http.StatusBadGateway)
+
+	result := codex.CreateWebhooksBulk(context.Background(), newStubClient(stub), bulkInput(2, 0), codex.BulkOptions{
+		Interval:   time.Millisecond,
+		RetryDelay: time.Millisecond,
+	})
+	if err := result.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range result.Items {
+		if item.Attempts != 2 || item.Webhook == nil {
+			t.Fatalf("item %+v, want it created on the second attempt", item)
+		}
+	}
+	if n := len(stub.Webhooks()); n != 2 {
+		t.Fatalf("%d webhooks created, want 2", n)
+	}
+}
+
+func TestBulkGivesUpAfterAttempts(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	stub.FailNext("CreateWebhooks", http.StatusBadGateway, http.StatusBadGateway)
+
+	result := codex.CreateWebhooksBulk(context.Background(), newStubClient(stub), bulkInput(1, 0), codex.BulkOptions{
+		Interval:   time.Millisecond,
+		Attempts:   2,
+		RetryDelay: time.Millisecond,
+	})
+	failed := result.Failed()
+	if len(failed) != 1 || failed[0].Attempts != 2 || failed[0].Err == nil {
+		t.Fatalf("failed items %+v, want the webhook failed after 2 attempts", failed)
+	}
+	if result.Err() == nil {
+		t.Fatal("want an error")
+	}
+}
+
+func TestBulkIsolatesBadInput(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	input := bulkInput(3, 1)
+	// The stub rejects the whole mutation, after creating the webhooks
+	// before the bad one.
+	input.TokenPairEventWebhooksInput.Webhooks[1].CallbackUrl = "not a url"
+
+	result := codex.CreateWebhooksBulk(context.Background(), newStubClient(stub), input, codex.BulkOptions{
+		Interval:   time.Millisecond,
+		RetryDelay: time.Millisecond,
+	})
+	failed := result.Failed()
+	if len(failed) != 1 || failed[0].Name != "pair-1" || !errors.Is(failed[0].Err, codex.ErrBadInput) {
+		t.Fatalf("failed items %+v, want only pair-1 rejected as bad input", failed)
+	}
+	// Rejected in the chunk and once more alone, but not retried after.
+	if failed[0].Attempts != 2 {
+		t.Fatalf("bad item tried %d times, want 2", failed[0].Attempts)
+	}
+
+	var names []string
+	for _, webhook := range stub.Webhooks() {
+		names = append(names, webhook.Name)
+	}
+	// Deduplicate keeps retrying pair-0 from creating it twice.
+	if !slices.Equal(names, []string{"pair-0", "pair-2", "price-0"}) {
+		t.Fatalf("created %v", names)
+	}
+	if created := result.Created(); len(created) != 3 {
+		t.Fatalf("result has %d created webhooks, want 3", len(created))
+	}
+}
@@ -0,0 +1,378 @@
+package codex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/utils"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const DefaultEndpoint = "https://graph.codex.io/graphql"
+
+var (
+	ErrUnauthenticated = errors.New("codex: unauthenticated")
+	ErrForbidden       = errors.New("codex: forbidden")
+	ErrRateLimited     = errors.New("codex: rate limited")
+	ErrBadInput        = errors.New("codex: bad input")
+	ErrNotFound        = errors.New("codex: not found")
+	ErrUnavailable     = errors.New("codex: unavailable")
+)
+
+// GraphQLError is one entry of a GraphQL response's errors.
+type GraphQLError struct {
+	Message string
+	Code    string
+	Path    string
+}
+
+// Error is returned by every operation made through a client from NewClient
+// when Codex answers with GraphQL errors or a non-200 status. Use errors.Is
+// with the Err* values to check what kind of error it was.
+type Error struct {
+	Operation string
+	// StatusCode is the HTTP status, or 200 for GraphQL errors in an
+	// otherwise successful response.
+	StatusCode int
+	Errors     []GraphQLError
+}
+
+func (e *Error) Error() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "codex %s failed", e.Operation)
+	if e.StatusCode != http.StatusOK {
+		fmt.Fprintf(&b, " with status %d", e.StatusCode)
+	}
+	for i, gqlErr := range e.Errors {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(gqlErr.Message)
+		if gqlErr.Code != "" {
+			fmt.Fprintf(&b, " [%s]", gqlErr.Code)
+		}
+		if gqlErr.Path != "" {
+			fmt.Fprintf(&b, " at %s", gqlErr.Path)
+		}
+	}
+	return b.String()
+}
+
+// Unwrap returns the Err* value matching the status or the first error code
+// that has one, or nil.
+func (e *Error) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthenticated
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrUnavailable
+	}
+	for _, gqlErr := range e.Errors {
+		switch gqlErr.Code {
+		case "UNAUTHENTICATED":
+			return ErrUnauthenticated
+		case "FORBIDDEN":
+			return ErrForbidden
+		case "RATE_LIMITED", "TOO_MANY_REQUESTS":
+			return ErrRateLimited
+		case "BAD_USER_INPUT", "GRAPHQL_VALIDATION_FAILED", "GRAPHQL_PARSE_FAILED":
+			return ErrBadInput
+		case "NOT_FOUND":
+			return ErrNotFound
+		case "INTERNAL_SERVER_ERROR", "SERVICE_UNAVAILABLE":
+			return ErrUnavailable
+		}
+	}
+	if e.StatusCode == http.StatusBadRequest {
+		return ErrBadInput
+	}
+	return nil
+}
+
+// RequestInfo describes one operation, passed to Options.OnRequest once the
+// operation has finished, after any retries.
+type RequestInfo struct {
+	Operation string
+	// Attempts is the number of HTTP requests made, including retries.
+	Attempts int
+	// StatusCode of the last attempt, or 0 if none got a response.
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+type Options struct {
+	// Endpoint defaults to DefaultEndpoint.
+	Endpoint string
+	// Token is the Codex API key or a short-lived bearer token, sent as-is
+	// in the Authorization header.
+	Token string
+	// Timeout of each HTTP attempt. Defaults to 15s.
+	Timeout time.Duration
+	// MaxRetries on 429, and for queries also on 5xx and network errors.
+	// Defaults to 3; set a negative value to disable retries.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the jittered exponential delay between
+	// retries. MaxBackoff also caps the delay Codex asks for with
+	// Retry-After. They default to 250ms and 10s, and MaxBackoff is raised
+	// to MinBackoff if below it.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnRequest is called after every operation, e.g. to record metrics.
+	// Defaults to LogRequest.
+	OnRequest func(RequestInfo)
+	// Transport defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+// LogRequest logs failed operations at warn level and the rest at debug.
+func LogRequest(info RequestInfo) {
+	event := log.Debug()
+	if info.Err != nil {
+		event = log.Warn().Err(info.Err)
+	}
+	event.
+		Str("operation", info.Operation).
+		Int("attempts", info.Attempts).
+		Int("status", info.StatusCode).
+		Dur("duration", info.Duration).
+		Msg("codex request")
+}
+
+type client struct {
+	inner     graphql.Client
+	onRequest func(RequestInfo)
+}
+
+// NewClient returns the graphql.Client shared by the generated codex
+// operations. It authenticates with opts.Token, retries rate limited and
+// failed requests and returns GraphQL errors as *Error.
+func NewClient(opts Options) graphql.Client {
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultEndpoint
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 15 * time.Second
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff = 250 * time.Millisecond
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = 10 * time.Second
+	}
+	opts.MaxBackoff = max(opts.MaxBackoff, opts.MinBackoff)
+	if opts.OnRequest == nil {
+		opts.OnRequest = LogRequest
+	}
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport
+	}
+
+	httpClient := &http.Client{
+		Transport: &retryTransport{
+			token:      opts.Token,
+			base:       opts.Transport,
+			timeout:    opts.Timeout,
+			maxRetries: max(opts.MaxRetries, 0),
+			minBackoff: opts.MinBackoff,
+			maxBackoff: opts.MaxBackoff,
+		},
+	}
+	return &client{
+		inner:     graphql.NewClient(opts.Endpoint, httpClient),
+		onRequest: opts.OnRequest,
+	}
+}
+
+type attemptsKey struct{}
+
+// attempts is filled in by retryTransport for the client to report.
+// mutation is set by the client so retryTransport knows what it may retry.
+type attempts struct {
+	mutation   bool
+	count      int
+	statusCode int
+}
+
+func (c *client) MakeRequest(ctx context.Context, req *graphql.Request, resp *graphql.Response) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tracked := &attempts{mutation: strings.HasPrefix(strings.TrimSpace(req.Query), "mutation")}
+	ctx = context.WithValue(ctx, attemptsKey{}, tracked)
+
+	start := time.Now()
+	err := mapError(req.OpName, c.inner.MakeRequest(ctx, req, resp))
+	c.onRequest(RequestInfo{
+		Operation:  req.OpName,
+		Attempts:   tracked.count,
+		StatusCode: tracked.statusCode,
+		Duration:   time.Since(start),
+		Err:        err,
+	})
+	return err
+}
+
+func mapError(operation string, err error) error {
+	var httpErr *graphql.HTTPError
+	var gqlErrs gqlerror.List
+	switch {
+	case errors.As(err, &httpErr):
+		return &Error{
+			Operation:  operation,
+			StatusCode: httpErr.StatusCode,
+			Errors:     graphQLErrors(httpErr.Response.Errors),
+		}
+	case errors.As(err, &gqlErrs):
+		return &Error{
+			Operation:  operation,
+			StatusCode: http.StatusOK,
+			Errors:     graphQLErrors(gqlErrs),
+		}
+	case err != nil:
+		return fmt.Errorf("codex %s failed: %w", operation, err)
+	}
+	return nil
+}
+
+func graphQLErrors(list gqlerror.List) []GraphQLError {
+	errs := make([]GraphQLError, 0, len(list))
+	for _, gqlErr := range list {
+		if gqlErr == nil {
+			continue
+		}
+		code, _ := gqlErr.Extensions["code"].(string)
+		errs = append(errs, GraphQLError{
+			Message: gqlErr.Message,
+			Code:    code,
+			Path:    gqlErr.Path.String(),
+		})
+	}
+	return errs
+}
+
+// retryTransport authenticates requests and retries them on 429, and queries
+// also on 5xx and network errors. A mutation that failed that way may have
+// been applied anyway, so it is left to the caller to check before trying
+// again.
+type retryTransport struct {
+	token      string
+	base       http.RoundTripper
+	timeout    time.Duration
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	tracked, _ := ctx.Value(attemptsKey{}).(*attempts)
+	if tracked == nil {
+		tracked = &attempts{}
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.Clone(ctx)
+		attemptReq.Header.Set("Authorization", t.token)
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, t.timeout)
+		resp, err := t.base.RoundTrip(attemptReq.WithContext(attemptCtx))
+		tracked.count++
+		if err == nil {
+			tracked.statusCode = resp.StatusCode
+			// The body is read after RoundTrip returns, so the attempt's
+			// timeout is released when it is closed.
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		} else {
+			cancel()
+		}
+
+		retryable := err == nil && resp.StatusCode == http.StatusTooManyRequests
+		if !tracked.mutation {
+			retryable = retryable || err != nil || resp.StatusCode >= 500
+		}
+		canRewind := attempt == 0 || req.Body == nil || req.GetBody != nil
+		if !retryable || attempt >= t.maxRetries || !canRewind || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = min(retryAfter, t.maxBackoff)
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			// The retry couldn't finish in time, so fail with this attempt.
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	return utils.Backoff(t.minBackoff, t.maxBackoff, attempt)
+}
+
+// parseRetryAfter accepts both forms of Retry-After: delay seconds and an
+// HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// Ptr returns a pointer to v, for filling optional input fields.
+func Ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,100 @@
+package codex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Khan/genqlient/graphql"
+)
+
+// failingServer answers every request with status, and Retry-After if set.
+func failingServer(t *testing.T, status int, retryAfter string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	requests := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func makeRequest(client graphql.Client, ctx context.Context, query string) error {
+	return client.MakeRequest(ctx, &graphql.Request{OpName: "Test", Query: query}, &graphql.Response{})
+}
+
+func TestRetries(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		status int
+		query  string
+		want   int32
+	}{
+		{"query on 5xx", http.StatusBadGateway, "query Test { a }", 3},
+		{"query on 429", http.StatusTooManyRequests, "query Test { a }", 3},
+		{"mutation on 5xx", http.StatusBadGateway, "\nmutation Test { a }", 1},
+		{"mutation on 429", http.StatusTooManyRequests, "\nmutation Test { a }", 3},
+		{"bad input", http.StatusBadRequest, "query Test { a }", 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := failingServer(t, tt.status, "")
+			client := NewClient(Options{
+				Endpoint:   server.URL,
+				MaxRetries: 2,
+				MinBackoff: time.Millisecond,
+				MaxBackoff: 2 * time.Millisecond,
+			})
+			if err := makeRequest(client, context.Background(), tt.query); err == nil {
+				t.Fatal("want an error")
+			}
+			if got := requests.Load(); got != tt.want {
+				t.Fatalf("made %d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	server, requests := failingServer(t, http.StatusTooManyRequests, "3600")
+	client := NewClient(Options{
+		Endpoint:   server.URL,
+		MaxRetries: 1,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+	})
+	start := time.Now()
+	if err := makeRequest(client, context.Background(), "query Test { a }"); err == nil {
+		t.Fatal("want an error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("waited %s for Retry-After, want at most MaxBackoff", elapsed)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("made %d requests, want 2", got)
+	}
+}
+
+func TestRetryAfterPastDeadline(t *testing.T) {
+	server, requests := failingServer(t, http.StatusTooManyRequests, "5")
+	client := NewClient(Options{Endpoint: server.URL, MaxBackoff: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := makeRequest(client, ctx, "query Test { a }"); err == nil {
+		t.Fatal("want an error")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("waited %s for a retry that couldn't finish before the deadline", elapsed)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("made %d requests, want 1", got)
+	}
+}
@@ -0,0 +1,802 @@
+// Package codexstub is a minimal in-process Codex GraphQL server for
+// exercising the codex operations, the webhook provisioner and the ingest
+// handler without the real API. It keeps webhooks and API tokens in memory and
+// delivers token pair events to the callback URLs of matching webhooks, hashed
+// the way Codex does, with configurable retries, duplicates and delays.
+package codexstub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+)
+
+// Webhook is a webhook created through the server.
+type Webhook struct {
+	ID             string
+	Name           string
+	Type           codex.WebhookType
+	CallbackURL    string
+	SecurityToken  string
+	Status         string
+	Created        int
+	GroupID        string
+	BucketID       string
+	BucketSortkey  string
+	PublishingType codex.PublishingType
+	// Conditions is the conditions input the webhook was created with. Only
+	// token pair event conditions are matched by Deliver.
+	Conditions json.RawMessage
+	// recurrence is only compared when deduplicating.
+	recurrence codex.AlertRecurrence
+}
+
+// ApiToken is a short-lived API token created through the server.
+type ApiToken struct {
+	ID           string
+	Token        string
+	ExpiresAt    time.Time
+	RequestLimit string
+}
+
+// DeliveryOptions control how Deliver sends deliveries to callback URLs.
+type DeliveryOptions struct {
+	// Delay is waited before the first attempt of every delivery, plus a
+	// random duration up to Jitter so concurrent deliveries can arrive out
+	// of order.
+	Delay  time.Duration
+	Jitter time.Duration
+	// Retries is how many more times a delivery is attempted after a
+	// network error or a non-2xx response, RetryDelay apart.
+	Retries    int
+	RetryDelay time.Duration
+	// Duplicates is how many more times a delivery is sent after it was
+	// accepted, with the same deduplication ID and hash.
+	Duplicates int
+	// Timeout of each attempt. Defaults to 5s.
+	Timeout time.Duration
+}
+
+// Delivery is the outcome of sending one webhook body to a callback URL.
+type Delivery struct {
+	WebhookID       string
+	DeduplicationID string
+	Events          int
+	// Attempts counts every request made, including retries and duplicates.
+	Attempts int
+	// StatusCode of the last attempt, or 0 if none got a response.
+	StatusCode int
+	Err        error
+}
+
+type Server struct {
+	server   *httptest.Server
+	token    string
+	delivery *http.Client
+
+	mu         sync.Mutex
+	opts       DeliveryOptions
+	webhooks   map[string]*Webhook
+	order      []string
+	apiTokens  map[string]*ApiToken
+	nextID     int
+	failures   map[string][]int
+	operations []string
+	deliveries []Delivery
+}
+
+// Start listens on a random local port. If token is non-empty, requests must
+// send it as their Authorization header.
+func Start(token string) *Server {
+	s := &Server{
+		token:     token,
+		delivery:  &http.Client{},
+		webhooks:  make(map[string]*Webhook),
+		apiTokens: make(map[string]*ApiToken),
+		failures:  make(map[string][]int),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// URL returns the GraphQL endpoint of the server, for codex.Options.Endpoint.
+func (s *Server) URL() string {
+	return s.server.URL + "/graphql"
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetDeliveryOptions replaces the options used by later calls to Deliver.
+func (s *Server) SetDeliveryOptions(opts DeliveryOptions) {
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opts = opts
+}
+
+// FailNext answers the next requests for operationName with statusCode, one
+// per status given, before serving it normally again.
+func (s *Server) FailNext(operationName string, statusCodes ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[operationName] = append(s.failures[operationName], statusCodes...)
+}
+
+// SetStatus changes the status of the webhook with the given ID, e.g. to
+// mimic Codex disabling a webhook whose callbacks keep failing. Deliver only
+// sends to ACTIVE webhooks. It reports whether the webhook exists.
+func (s *Server) SetStatus(id string, status string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhook, ok := s.webhooks[id]
+	if ok {
+		webhook.Status = status
+	}
+	return ok
+}
+
+// Operations returns the names of the operations requested so far, in order,
+// including failed ones.
+func (s *Server) Operations() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.operations...)
+}
+
+// Webhooks returns the webhooks that exist, in creation order.
+func (s *Server) Webhooks() []Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhooks := make([]Webhook, 0, len(s.order))
+	for _, id := range s.order {
+		webhooks = append(webhooks, *s.webhooks[id])
+	}
+	return webhooks
+}
+
+// ApiTokens returns the API tokens that weren't deleted.
+func (s *Server) ApiTokens() []ApiToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := make([]ApiToken, 0, len(s.apiTokens))
+	for _, token := range s.apiTokens {
+		tokens = append(tokens, *token)
+	}
+	return tokens
+}
+
+// Deliveries returns the outcome of every delivery made so far.
+func (s *Server) Deliveries() []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Delivery(nil), s.deliveries...)
+}
+
+// Deliver sends data to the callback URL of every active token pair event
+// webhook whose conditions match, one body per webhook holding the events
+// it matched, and waits for the deliveries to finish.
+func (s *Server) Deliver(ctx context.Context, data []ingest.TokenPairEventData) []Delivery {
+	type pending struct {
+		webhook Webhook
+		body    ingest.TokenPairWebhookBody
+	}
+	s.mu.Lock()
+	opts := s.opts
+	var bodies []pending
+	for _, id := range s.order {
+		webhook := s.webhooks[id]
+		if webhook.Type != codex.WebhookTypeTokenPairEvent || webhook.Status != "ACTIVE" {
+			continue
+		}
+		var matched []ingest.TokenPairEventData
+		for _, d := range data {
+			if matchTokenPair(webhook.Conditions, d) {
+				matched = append(matched, d)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		s.nextID++
+		deduplicationID := fmt.Sprintf("%s-%d", webhook.ID, s.nextID)
+		bodies = append(bodies, pending{
+			webhook: *webhook,
+			body: ingest.TokenPairWebhookBody{
+				DeduplicationID: deduplicationID,
+				Hash:            ingest.Hash(webhook.SecurityToken, deduplicationID),
+				Type:            ingest.WebhookTypeTokenPairEvent,
+				WebhookID:       webhook.ID,
+				Data:            matched,
+			},
+		})
+	}
+	s.mu.Unlock()
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	deliveries := make([]Delivery, len(bodies))
+	wg := sync.WaitGroup{}
+	for i, p := range bodies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			deliveries[i] = s.send(ctx, opts, p.webhook.CallbackURL, p.body)
+		}()
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	s.deliveries = append(s.deliveries, deliveries...)
+	s.mu.Unlock()
+	return deliveries
+}
+
+func (s *Server) send(ctx context.Context, opts DeliveryOptions, callbackURL string, body ingest.TokenPairWebhookBody) Delivery {
+	delivery := Delivery{
+		WebhookID:       body.WebhookID,
+		DeduplicationID: body.DeduplicationID,
+		Events:          len(body.Data),
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		delivery.Err = err
+		return delivery
+	}
+
+	delay := opts.Delay
+	if opts.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(opts.Jitter)))
+	}
+	if !sleep(ctx, delay) {
+		delivery.Err = ctx.Err()
+		return delivery
+	}
+
+	accepted := false
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		if attempt > 0 && !sleep(ctx, opts.RetryDelay) {
+			delivery.Err = ctx.Err()
+			return delivery
+		}
+		delivery.Attempts++
+		delivery.StatusCode, delivery.Err = s.post(ctx, opts.Timeout, callbackURL, payload)
+		if delivery.Err == nil {
+			accepted = true
+			break
+		}
+	}
+	if !accepted {
+		return delivery
+	}
+	for range opts.Duplicates {
+		delivery.Attempts++
+		s.post(ctx, opts.Timeout, callbackURL, payload)
+	}
+	return delivery
+}
+
+func (s *Server) post(ctx context.Context, timeout time.Duration, callbackURL string, payload []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.delivery.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// matchTokenPair reports whether d meets the network, pair, token, exchange,
+// maker and event type conditions. Swap value conditions are ignored.
+func matchTokenPair(raw json.RawMessage, d ingest.TokenPairEventData) bool {
+	conditions := codex.TokenPairEventWebhookConditionInput{}
+	if len(raw) > 0 && json.Unmarshal(raw, &conditions) != nil {
+		return false
+	}
+	eq := func(condition *codex.StringEqualsConditionInput, values ...string) bool {
+		if condition == nil {
+			return true
+		}
+		for _, value := range values {
+			if strings.EqualFold(condition.Eq, value) {
+				return true
+			}
+		}
+		return false
+	}
+	if conditions.NetworkId != nil && len(conditions.NetworkId.OneOf) > 0 {
+		found := false
+		for _, networkID := range conditions.NetworkId.OneOf {
+			found = found || networkID == d.Pair.NetworkID
+		}
+		if !found {
+			return false
+		}
+	}
+	if conditions.EventType != nil && len(conditions.EventType.OneOf) > 0 {
+		found := false
+		for _, eventType := range conditions.EventType.OneOf {
+			found = found || strings.EqualFold(string(eventType), d.Event.EventType)
+		}
+		if !found {
+			return false
+		}
+	}
+	return eq(conditions.PairAddress, d.Pair.Address) &&
+		eq(conditions.TokenAddress, d.Pair.Token0, d.Pair.Token1) &&
+		eq(conditions.ExchangeAddress, d.Pair.ExchangeHash) &&
+		eq(conditions.Maker, d.Event.Maker)
+}
+
+type request struct {
+	OperationName string          `json:"operationName"`
+	Variables     json.RawMessage `json:"variables"`
+}
+
+// gqlError answers with GraphQL errors, the way Codex reports bad input.
+type gqlError struct {
+	message string
+	code    string
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" && r.Header.Get("Authorization") != s.token {
+		writeErrors(w, http.StatusUnauthorized, gqlError{"Unauthorized", "UNAUTHENTICATED"})
+		return
+	}
+	req := request{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrors(w, http.StatusBadRequest, gqlError{"Invalid request body", "GRAPHQL_PARSE_FAILED"})
+		return
+	}
+	if len(req.Variables) == 0 || string(req.Variables) == "null" {
+		req.Variables = json.RawMessage("{}")
+	}
+
+	s.mu.Lock()
+	s.operations = append(s.operations, req.OperationName)
+	var failWith int
+	if statusCodes := s.failures[req.OperationName]; len(statusCodes) > 0 {
+		failWith = statusCodes[0]
+		s.failures[req.OperationName] = statusCodes[1:]
+	}
+	s.mu.Unlock()
+	if failWith != 0 {
+		if failWith == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		writeErrors(w, failWith, gqlError{http.StatusText(failWith), ""})
+		return
+	}
+
+	var data any
+	var err *gqlError
+	switch req.OperationName {
+	case "CreateWebhooks":
+		data, err = s.createWebhooks(req.Variables)
+	case "GetWebhooks":
+		data, err = s.getWebhooks(req.Variables)
+	case "DeleteWebhooks":
+		data, err = s.deleteWebhooks(req.Variables)
+	case "CreateApiTokens":
+		data, err = s.createApiTokens(req.Variables)
+	case "ApiTokens":
+		data, err = s.listApiTokens()
+	case "DeleteApiToken":
+		data, err = s.deleteApiToken(req.Variables)
+	default:
+		err = &gqlError{fmt.Sprintf("Operation %q is not supported by codexstub", req.OperationName), "GRAPHQL_VALIDATION_FAILED"}
+	}
+	if err != nil {
+		writeErrors(w, http.StatusOK, *err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func writeErrors(w http.ResponseWriter, statusCode int, errs ...gqlError) {
+	list := make([]map[string]any, 0, len(errs))
+	for _, err := range errs {
+		entry := map[string]any{"message": err.message}
+		if err.code != "" {
+			entry["extensions"] = map[string]string{"code": err.code}
+		}
+		list = append(list, entry)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]any{"data": nil, "errors": list})
+}
+
+func badInput(format string, args ...any) *gqlError {
+	return &gqlError{fmt.Sprintf(format, args...), "BAD_USER_INPUT"}
+}
+
+// webhookArgs are the fields shared by every kind of webhook input.
+type webhookArgs struct {
+	Name            string                `json:"name"`
+	CallbackUrl     string                `json:"callbackUrl"`
+	SecurityToken   string                `json:"securityToken"`
+	AlertRecurrence codex.AlertRecurrence `json:"alertRecurrence"`
+	GroupId         *string               `json:"groupId"`
+	Conditions      json.RawMessage       `json:"conditions"`
+	BucketId        *string               `json:"bucketId"`
+	BucketSortkey   *string               `json:"bucketSortkey"`
+	PublishingType  *codex.PublishingType `json:"publishingType"`
+	Deduplicate     *bool                 `json:"deduplicate"`
+}
+
+func (s *Server) createWebhooks(variables json.RawMessage) (any, *gqlError) {
+	vars := struct {
+		Input struct {
+			TokenPairEventWebhooksInput *struct {
+				Webhooks []webhookArgs `json:"webhooks"`
+			} `json:"tokenPairEventWebhooksInput"`
+			PriceWebhooksInput *struct {
+				Webhooks []webhookArgs `json:"webhooks"`
+			} `json:"priceWebhooksInput"`
+			MarketCapWebhooksInput *struct {
+				Webhooks []webhookArgs `json:"webhooks"`
+			} `json:"marketCapWebhooksInput"`
+		} `json:"input"`
+	}{}
+	if err := json.Unmarshal(variables, &vars); err != nil {
+		return nil, badInput("Invalid input: %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	create := func(webhookType codex.WebhookType, list []webhookArgs) ([]map[string]any, *gqlError) {
+		created := make([]map[string]any, 0, len(list))
+		for _, args := range list {
+			webhook, err := s.createWebhook(webhookType, args)
+			if err != nil {
+				return nil, err
+			}
+			created = append(created, createdWebhook(webhook))
+		}
+		return created, nil
+	}
+
+	result := map[string]any{
+		"tokenPairEventWebhooks": []any{},
+		"priceWebhooks":          []any{},
+		"marketCapWebhooks":      []any{},
+	}
+	input := vars.Input
+	if input.TokenPairEventWebhooksInput != nil {
+		created, err := create(codex.WebhookTypeTokenPairEvent, input.TokenPairEventWebhooksInput.Webhooks)
+		if err != nil {
+			return nil, err
+		}
+		result["tokenPairEventWebhooks"] = created
+	}
+	if input.PriceWebhooksInput != nil {
+		created, err := create(codex.WebhookTypePriceEvent, input.PriceWebhooksInput.Webhooks)
+		if err != nil {
+			return nil, err
+		}
+		result["priceWebhooks"] = created
+	}
+	if input.MarketCapWebhooksInput != nil {
+		created, err := create(codex.WebhookTypeMarketCapEvent, input.MarketCapWebhooksInput.Webhooks)
+		if err != nil {
+			return nil, err
+		}
+		result["marketCapWebhooks"] = created
+	}
+	return map[string]any{"createWebhooks": result}, nil
+}
+
+// createWebhook must be called with s.mu held.
+func (s *Server) createWebhook(webhookType codex.WebhookType, args webhookArgs) (*Webhook, *gqlError) {
+	switch {
+	case args.Name == "" || len(args.Name) > 128:
+		return nil, badInput("name must be 1 to 128 characters")
+	case !strings.HasPrefix(args.CallbackUrl, "http://") && !strings.HasPrefix(args.CallbackUrl, "https://"):
+		return nil, badInput("callbackUrl must be an http(s) URL")
+	case args.SecurityToken == "":
+		return nil, badInput("securityToken is required")
+	}
+	publishingType := codex.PublishingTypeSingle
+	if args.PublishingType != nil {
+		publishingType = *args.PublishingType
+	}
+	conditions, err := compactJSON(args.Conditions)
+	if err != nil {
+		return nil, badInput("Invalid conditions: %s", err)
+	}
+
+	if args.Deduplicate != nil && *args.Deduplicate {
+		for _, id := range s.order {
+			existing := s.webhooks[id]
+			if existing.Type == webhookType && existing.CallbackURL == args.CallbackUrl &&
+				bytes.Equal(existing.Conditions, conditions) && existing.PublishingType == publishingType &&
+				existing.recurrence == args.AlertRecurrence && existing.BucketID == deref(args.BucketId) {
+				return existing, nil
+			}
+		}
+	}
+
+	s.nextID++
+	webhook := &Webhook{
+		ID:             fmt.Sprintf("webhook-%d", s.nextID),
+		Name:           args.Name,
+		Type:           webhookType,
+		CallbackURL:    args.CallbackUrl,
+		SecurityToken:  args.SecurityToken,
+		Status:         "ACTIVE",
+		Created:        int(time.Now().Unix()),
+		GroupID:        deref(args.GroupId),
+		BucketID:       deref(args.BucketId),
+		BucketSortkey:  deref(args.BucketSortkey),
+		PublishingType: publishingType,
+		Conditions:     conditions,
+		recurrence:     args.AlertRecurrence,
+	}
+	s.webhooks[webhook.ID] = webhook
+	s.order = append(s.order, webhook.ID)
+	return webhook, nil
+}
+
+func createdWebhook(webhook *Webhook) map[string]any {
+	return map[string]any{
+		"id":             webhook.ID,
+		"name":           webhook.Name,
+		"callbackUrl":    webhook.CallbackURL,
+		"status":         webhook.Status,
+		"groupId":        nullable(webhook.GroupID),
+		"bucketId":       nullable(webhook.BucketID),
+		"bucketSortkey":  nullable(webhook.BucketSortkey),
+		"publishingType": webhook.PublishingType,
+	}
+}
+
+func (s *Server) getWebhooks(variables json.RawMessage) (any, *gqlError) {
+	vars := struct {
+		Cursor        *string `json:"cursor"`
+		Limit         *int    `json:"limit"`
+		WebhookID     *string `json:"webhookId"`
+		BucketID      *string `json:"bucketId"`
+		BucketSortkey *string `json:"bucketSortkey"`
+	}{}
+	if err := json.Unmarshal(variables, &vars); err != nil {
+		return nil, badInput("Invalid input: %s", err)
+	}
+	offset := 0
+	if vars.Cursor != nil && *vars.Cursor != "" {
+		n, err := strconv.Atoi(*vars.Cursor)
+		if err != nil || n < 0 {
+			return nil, badInput("Invalid cursor")
+		}
+		offset = n
+	}
+	limit := 100
+	if vars.Limit != nil && *vars.Limit > 0 {
+		limit = *vars.Limit
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The offset counts the webhooks matching the filters.
+	var matching []*Webhook
+	for _, id := range s.order {
+		webhook := s.webhooks[id]
+		if (vars.WebhookID != nil && *vars.WebhookID != webhook.ID) ||
+			(vars.BucketID != nil && *vars.BucketID != webhook.BucketID) ||
+			(vars.BucketSortkey != nil && *vars.BucketSortkey != webhook.BucketSortkey) {
+			continue
+		}
+		matching = append(matching, webhook)
+	}
+	items := []map[string]any{}
+	end := min(offset+limit, len(matching))
+	for _, webhook := range matching[min(offset, end):end] {
+		items = append(items, map[string]any{
+			"id":            webhook.ID,
+			"name":          webhook.Name,
+			"webhookType":   webhook.Type,
+			"callbackUrl":   webhook.CallbackURL,
+			"status":        webhook.Status,
+			"created":       webhook.Created,
+			"bucketId":      nullable(webhook.BucketID),
+			"bucketSortkey": nullable(webhook.BucketSortkey),
+			"conditions":    conditionsOutput(webhook),
+		})
+	}
+	var cursor any
+	if end < len(matching) {
+		cursor = strconv.Itoa(end)
+	}
+	return map[string]any{"getWebhooks": map[string]any{"items": items, "cursor": cursor}}, nil
+}
+
+// conditionsOutput returns the conditions of webhook as Codex lists them.
+// Condition inputs mirror the output types, so they are returned as-is with
+// their typename.
+func conditionsOutput(webhook *Webhook) map[string]any {
+	conditions := map[string]any{}
+	if len(webhook.Conditions) > 0 {
+		json.Unmarshal(webhook.Conditions, &conditions)
+	}
+	switch webhook.Type {
+	case codex.WebhookTypeTokenPairEvent:
+		conditions["__typename"] = "TokenPairEventWebhookCondition"
+	case codex.WebhookTypePriceEvent:
+		conditions["__typename"] = "PriceEventWebhookCondition"
+	case codex.WebhookTypeMarketCapEvent:
+		conditions["__typename"] = "MarketCapEventWebhookCondition"
+	}
+	return conditions
+}
+
+func (s *Server) deleteWebhooks(variables json.RawMessage) (any, *gqlError) {
+	vars := struct {
+		Input codex.DeleteWebhooksInput `json:"input"`
+	}{}
+	if err := json.Unmarshal(variables, &vars); err != nil {
+		return nil, badInput("Invalid input: %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := []string{}
+	for _, id := range vars.Input.WebhookIds {
+		if _, ok := s.webhooks[id]; !ok {
+			continue
+		}
+		delete(s.webhooks, id)
+		deleted = append(deleted, id)
+	}
+	order := s.order[:0]
+	for _, id := range s.order {
+		if _, ok := s.webhooks[id]; ok {
+			order = append(order, id)
+		}
+	}
+	s.order = order
+	return map[string]any{"deleteWebhooks": map[string]any{"deletedIds": deleted}}, nil
+}
+
+func (s *Server) createApiTokens(variables json.RawMessage) (any, *gqlError) {
+	vars := struct {
+		Input codex.CreateApiTokensInput `json:"input"`
+	}{}
+	if err := json.Unmarshal(variables, &vars); err != nil {
+		return nil, badInput("Invalid input: %s", err)
+	}
+	count := 1
+	if vars.Input.Count != nil {
+		count = *vars.Input.Count
+	}
+	requestLimit := "5000"
+	if vars.Input.RequestLimit != nil {
+		requestLimit = *vars.Input.RequestLimit
+	}
+	expiresIn := 3600
+	if vars.Input.ExpiresIn != nil {
+		expiresIn = *vars.Input.ExpiresIn
+	}
+	if count < 1 || count > 25 {
+		return nil, badInput("count must be between 1 and 25")
+	}
+	if expiresIn < 1 {
+		return nil, badInput("expiresIn must be positive")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := make([]map[string]any, 0, count)
+	for range count {
+		s.nextID++
+		token := &ApiToken{
+			ID:           fmt.Sprintf("apitoken-%d", s.nextID),
+			Token:        fmt.Sprintf("codexstub-token-%d", s.nextID),
+			ExpiresAt:    time.Now().Add(time.Duration(expiresIn) * time.Second).UTC(),
+			RequestLimit: requestLimit,
+		}
+		s.apiTokens[token.ID] = token
+		tokens = append(tokens, apiTokenOutput(token))
+	}
+	return map[string]any{"createApiTokens": tokens}, nil
+}
+
+func (s *Server) listApiTokens() (any, *gqlError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := make([]map[string]any, 0, len(s.apiTokens))
+	for _, token := range s.apiTokens {
+		tokens = append(tokens, apiTokenOutput(token))
+	}
+	return map[string]any{"apiTokens": tokens}, nil
+}
+
+func (s *Server) deleteApiToken(variables json.RawMessage) (any, *gqlError) {
+	vars := struct {
+		ID string `json:"id"`
+	}{}
+	if err := json.Unmarshal(variables, &vars); err != nil {
+		return nil, badInput("Invalid input: %s", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.apiTokens[vars.ID]; !ok {
+		return nil, &gqlError{"Api token not found", "NOT_FOUND"}
+	}
+	delete(s.apiTokens, vars.ID)
+	return map[string]any{"deleteApiToken": vars.ID}, nil
+}
+
+func apiTokenOutput(token *ApiToken) map[string]any {
+	return map[string]any{
+		"id":                token.ID,
+		"token":             token.Token,
+		"expiresTimeString": token.ExpiresAt.Format(time.RFC3339),
+		"requestLimit":      token.RequestLimit,
+		"remaining":         token.RequestLimit,
+	}
+}
+
+func compactJSON(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	b := bytes.Buffer{}
+	if err := json.Compact(&b, raw); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,320 @@
+package codexstub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Khan/genqlient/graphql"
+)
+
+func newClient(s *Server, token string) graphql.Client {
+	return codex.NewClient(codex.Options{Endpoint: s.URL(), Token: token, MaxRetries: -1})
+}
+
+func pairWebhook(name string, pairAddress string, callbackURL string) codex.CreateTokenPairEventWebhookArgs {
+	return codex.CreateTokenPairEventWebhookArgs{
+		Name:            name,
+		CallbackUrl:     callbackURL,
+		SecurityToken:   "secret",
+		AlertRecurrence: codex.AlertRecurrenceIndefinite,
+		Conditions: codex.TokenPairEventWebhookConditionInput{
+			PairAddress: &codex.StringEqualsConditionInput{Eq: pairAddress},
+		},
+		Deduplicate: codex.Ptr(true),
+	}
+}
+
+func createWebhooks(t *testing.T, client graphql.Client, webhooks ...codex.CreateTokenPairEventWebhookArgs) []string {
+	t.Helper()
+	resp, err := codex.CreateWebhooks(context.Background(), client, codex.CreateWebhooksInput{
+		TokenPairEventWebhooksInput: &codex.CreateTokenPairEventWebhooksInput{Webhooks: webhooks},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, webhook := range resp.CreateWebhooks.TokenPairEventWebhooks {
+		ids = append(ids, webhook.Id)
+	}
+	return ids
+}
+
+// callback counts the deliveries it receives and fails the first fail of
+// them with a 500.
+type callback struct {
+	mu     sync.Mutex
+	fail   int
+	bodies []ingest.TokenPairWebhookBody
+}
+
+func (c *callback) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body := ingest.TokenPairWebhookBody{}
+	json.NewDecoder(r.Body).Decode(&body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bodies = append(c.bodies, body)
+	if c.fail > 0 {
+		c.fail--
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (c *callback) received() []ingest.TokenPairWebhookBody {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ingest.TokenPairWebhookBody(nil), c.bodies...)
+}
+
+func pairEvent(pairAddress string) ingest.TokenPairEventData {
+	return ingest.TokenPairEventData{
+		Event: ingest.TokenPairEvent{Address: pairAddress, EventType: "Swap", TransactionHash: "0xtx"},
+		Pair:  ingest.Pair{Address: pairAddress, NetworkID: 1},
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	s := Start("codex-key")
+	defer s.Close()
+	if _, err := codex.ApiTokens(context.Background(), newClient(s, "wrong")); err == nil {
+		t.Fatal("want an error with the wrong token")
+	}
+	if _, err := codex.ApiTokens(context.Background(), newClient(s, "codex-key")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	s := Start("codex-key")
+	defer s.Close()
+	client := newClient(s, "codex-key")
+	s.FailNext("ApiTokens", http.StatusBadGateway, http.StatusTooManyRequests)
+
+	for _, want := range []bool{false, false, true} {
+		_, err := codex.ApiTokens(context.Background(), client)
+		if (err == nil) != want {
+			t.Fatalf("got %v, want success %t", err, want)
+		}
+	}
+	// Failures are per operation.
+	if _, err := codex.GetWebhooks(context.Background(), client, nil, nil, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Operations(); !slices.Equal(got, []string{"ApiTokens", "ApiTokens", "ApiTokens", "GetWebhooks"}) {
+		t.Fatalf("operations %v", got)
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	s := Start("codex-key")
+	defer s.Close()
+	client := newClient(s, "codex-key")
+	webhook := pairWebhook("a", "0xa", "http://localhost/a")
+	first := createWebhooks(t, client, webhook)
+
+	renamed := webhook
+	renamed.Name = "renamed"
+	if again := createWebhooks(t, client, renamed); !slices.Equal(again, first) {
+		t.Fatalf("created %v for an identical webhook, want %v", again, first)
+	}
+
+	otherBucket := webhook
+	otherBucket.BucketId = codex.Ptr("bucket")
+	otherPair := webhook
+	otherPair.Conditions.PairAddress = &codex.StringEqualsConditionInput{Eq: "0xb"}
+	otherCallback := webhook
+	otherCallback.CallbackUrl = "http://localhost/b"
+	notDeduplicated := webhook
+	notDeduplicated.Deduplicate = nil
+	for name, args := range map[string]codex.CreateTokenPairEventWebhookArgs{
+		"bucket":              otherBucket,
+		"conditions":          otherPair,
+		"callback":            otherCallback,
+		"without deduplicate": notDeduplicated,
+	} {
+		if ids := createWebhooks(t, client, args); ids[0] == first[0] {
+			t.Fatalf("webhook with another %s was deduplicated", name)
+		}
+	}
+	if n := len(s.Webhooks()); n != 5 {
+		t.Fatalf("%d webhooks exist, want 5", n)
+	}
+}
+
+func TestDeleteWebhooks(t *testing.T) {
+	s := Start("codex-key")
+	defer s.Close()
+	client := newClient(s, "codex-key")
+	ids := createWebhooks(t, client, pairWebhook("a", "0xa", "http://localhost/a"), pairWebhook("b", "0xb", "http://localhost/a"))
+
+	resp, err := codex.DeleteWebhooks(context.Background(), client, codex.DeleteWebhooksInput{WebhookIds: []string{ids[0], "missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted := resp.DeleteWebhooks.DeletedIds; len(deleted) != 1 || *deleted[0] != ids[0] {
+		t.Fatalf("deleted %v, want only %s", deleted, ids[0])
+	}
+	if webhooks := s.Webhooks(); len(webhooks) != 1 || webhooks[0].ID != ids[1] {
+		t.Fatalf("webhooks %+v, want only %s", webhooks, ids[1])
+	}
+}
+
+func TestApiTokens(t *testing.T) {
+	s := Start("codex-key")
+	defer s.Close()
+	client := newClient(s, "codex-key")
+	ctx := context.Background()
+
+	created, err := codex.CreateApiTokens(ctx, client, codex.CreateApiTokensInput{
+		Count:        codex.Ptr(2),
+		RequestLimit: codex.Ptr("100"),
+		ExpiresIn:    codex.Ptr(60),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created.CreateApiTokens) != 2 {
+		t.Fatalf("created %d tokens, want 2", len(created.CreateApiTokens))
+	}
+	token := created.CreateApiTokens[0]
+	expiresAt, err := time.Parse(time.RFC3339, token.ExpiresTimeString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(expiresAt); until < 58*time.Second || until > time.Minute {
+		t.Fatalf("token expires in %s, want 1m", until)
+	}
+	if token.RequestLimit != "100" || token.Token == "" {
+		t.Fatalf("created %+v", token)
+	}
+
+	if _, err := codex.DeleteApiToken(ctx, client, token.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := codex.DeleteApiToken(ctx, client, token.Id); !errors.Is(err, codex.ErrNotFound) {
+		t.Fatalf("deleting a deleted token: got %v, want ErrNotFound", err)
+	}
+	listed, err := codex.ApiTokens(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.ApiTokens) != 1 || listed.ApiTokens[0].Id != created.CreateApiTokens[1].Id {
+		t.Fatalf("listed %+v, want the remaining token", listed.ApiTokens)
+	}
+
+	if _, err := codex.CreateApiTokens(ctx, client, codex.CreateApiTokensInput{Count: codex.Ptr(26)}); !errors.Is(err, codex.ErrBadInput) {
+		t.Fatalf("creating 26 tokens: got %v, want ErrBadInput", err)
+	}
+}
+
+func TestDeliverMatchesConditions(t *testing.T) {
+	s := Start("codex-key")
+	defer s.Close()
+	cb := &callback{}
+	target := httptest.NewServer(cb)
+	defer target.Close()
+	ids := createWebhooks(t, newClient(s, "codex-key"), pairWebhook("a", "0xA", target.URL), pairWebhook("b", "0xb", target.URL), pairWebhook("c", "0xc", target.URL))
+	s.SetStatus(ids[2], "DISABLED")
+
+	deliveries := s.Deliver(context.Background(), []ingest.TokenPairEventData{pairEvent("0xa"), pairEvent("0xa"), pairEvent("0xc"), pairEvent("0xd")})
+	if len(deliveries) != 1 || deliveries[0].WebhookID != ids[0] || deliveries[0].Events != 2 || deliveries[0].Err != nil {
+		t.Fatalf("deliveries %+v, want the two events of 0xa to the first webhook", deliveries)
+	}
+	bodies := cb.received()
+	if len(bodies) != 1 || bodies[0].Hash != ingest.Hash("secret", bodies[0].DeduplicationID) || bodies[0].WebhookID != ids[0] {
+		t.Fatalf("received %+v, want one body hashed with the security token", bodies)
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		retries  int
+		accepted bool
+	}{
+		{"until accepted", 2, true},
+		{"gives up", 1, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Start("")
+			defer s.Close()
+			cb := &callback{fail: 2}
+			target := httptest.NewServer(cb)
+			defer target.Close()
+			createWebhooks(t, newClient(s, ""), pairWebhook("a", "0xa", target.URL))
+			s.SetDeliveryOptions(DeliveryOptions{Retries: tt.retries, RetryDelay: time.Millisecond})
+
+			delivery := s.Deliver(context.Background(), []ingest.TokenPairEventData{pairEvent("0xa")})[0]
+			if delivery.Attempts != tt.retries+1 || (delivery.Err == nil) != tt.accepted {
+				t.Fatalf("delivery %+v after %d retries, want accepted %t", delivery, tt.retries, tt.accepted)
+			}
+			if tt.accepted && delivery.StatusCode != http.StatusOK {
+				t.Fatalf("last status %d, want 200", delivery.StatusCode)
+			}
+			if n := len(cb.received()); n != tt.retries+1 {
+				t.Fatalf("callback got %d requests, want %d", n, tt.retries+1)
+			}
+			if got := s.Deliveries(); len(got) != 1 {
+				t.Fatalf("recorded %d deliveries, want 1", len(got))
+			}
+		})
+	}
+}
+
+func TestDeliverDuplicates(t *testing.T) {
+	s := Start("")
+	defer s.Close()
+	cb := &callback{}
+	target := httptest.NewServer(cb)
+	defer target.Close()
+	createWebhooks(t, newClient(s, ""), pairWebhook("a", "0xa", target.URL))
+	s.SetDeliveryOptions(DeliveryOptions{Duplicates: 2})
+
+	delivery := s.Deliver(context.Background(), []ingest.TokenPairEventData{pairEvent("0xa")})[0]
+	if delivery.Attempts != 3 || delivery.Err != nil {
+		t.Fatalf("delivery %+v, want 3 attempts", delivery)
+	}
+	bodies := cb.received()
+	if len(bodies) != 3 {
+		t.Fatalf("callback got %d requests, want 3", len(bodies))
+	}
+	for _, body := range bodies {
+		if body.DeduplicationID != delivery.DeduplicationID || body.Hash != bodies[0].Hash {
+			t.Fatalf("duplicate %+v differs from the delivery", body)
+		}
+	}
+}
+
+func TestDeliverDelay(t *testing.T) {
+	s := Start("")
+	defer s.Close()
+	target := httptest.NewServer(&callback{})
+	defer target.Close()
+	createWebhooks(t, newClient(s, ""), pairWebhook("a", "0xa", target.URL), pairWebhook("b", "0xb", target.URL))
+	s.SetDeliveryOptions(DeliveryOptions{Delay: 50 * time.Millisecond, Jitter: 50 * time.Millisecond})
+
+	start := time.Now()
+	deliveries := s.Deliver(context.Background(), []ingest.TokenPairEventData{pairEvent("0xa"), pairEvent("0xb")})
+	elapsed := time.Since(start)
+	if len(deliveries) != 2 {
+		t.Fatalf("made %d deliveries, want 2", len(deliveries))
+	}
+	// Deliveries wait concurrently, each for Delay plus up to Jitter.
+	if elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("delivering took %s, want between Delay and Delay+Jitter", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if delivery := s.Deliver(ctx, []ingest.TokenPairEventData{pairEvent("0xa")})[0]; !errors.Is(delivery.Err, context.Canceled) || delivery.Attempts != 0 {
+		t.Fatalf("delivery %+v, want it canceled before the first attempt", delivery)
+	}
+}
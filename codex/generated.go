@@ -4,6 +4,9 @@ package codex
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/Khan/genqlient/graphql"
 )
@@ -21,34 +24,99 @@ var AllAlertRecurrence = []AlertRecurrence{
 	AlertRecurrenceOnce,
 }
 
+// ApiToken includes the GraphQL fields of ApiToken requested by the fragment ApiToken.
+type ApiToken struct {
+	// Unique identifier for the token
+	Id string `json:"id"`
+	// JWT to be passed into the Authorization header for API requests
+	Token string `json:"token"`
+	// ISO time string for the expiry of the token
+	ExpiresTimeString string `json:"expiresTimeString"`
+	// Number of root fields this api token is allowed to resolve before it's rate limited
+	RequestLimit string `json:"requestLimit"`
+	// Approximate number of remaining resolutions before this token is rate limited
+	Remaining *string `json:"remaining"`
+}
+
+// GetId returns ApiToken.Id, and is useful for accessing the field via an interface.
+func (v *ApiToken) GetId() string { return v.Id }
+
+// GetToken returns ApiToken.Token, and is useful for accessing the field via an interface.
+func (v *ApiToken) GetToken() string { return v.Token }
+
+// GetExpiresTimeString returns ApiToken.ExpiresTimeString, and is useful for accessing the field via an interface.
+func (v *ApiToken) GetExpiresTimeString() string { return v.ExpiresTimeString }
+
+// GetRequestLimit returns ApiToken.RequestLimit, and is useful for accessing the field via an interface.
+func (v *ApiToken) GetRequestLimit() string { return v.RequestLimit }
+
+// GetRemaining returns ApiToken.Remaining, and is useful for accessing the field via an interface.
+func (v *ApiToken) GetRemaining() *string { return v.Remaining }
+
+// ApiTokensResponse is returned by ApiTokens on success.
+type ApiTokensResponse struct {
+	// Get all active short-lived api tokens for this api key
+	ApiTokens []ApiToken `json:"apiTokens"`
+}
+
+// GetApiTokens returns ApiTokensResponse.ApiTokens, and is useful for accessing the field via an interface.
+func (v *ApiTokensResponse) GetApiTokens() []ApiToken { return v.ApiTokens }
+
 // Input for comparison operators.
 type ComparisonOperatorInput struct {
 	// Greater than.
-	Gt string `json:"gt"`
+	Gt *string `json:"gt"`
 	// Greater than or equal.
-	Gte string `json:"gte"`
+	Gte *string `json:"gte"`
 	// Less than.
-	Lt string `json:"lt"`
+	Lt *string `json:"lt"`
 	// Less than or equal.
-	Lte string `json:"lte"`
+	Lte *string `json:"lte"`
 	// Equal to.
-	Eq string `json:"eq"`
+	Eq *string `json:"eq"`
 }
 
 // GetGt returns ComparisonOperatorInput.Gt, and is useful for accessing the field via an interface.
-func (v *ComparisonOperatorInput) GetGt() string { return v.Gt }
+func (v *ComparisonOperatorInput) GetGt() *string { return v.Gt }
 
 // GetGte returns ComparisonOperatorInput.Gte, and is useful for accessing the field via an interface.
-func (v *ComparisonOperatorInput) GetGte() string { return v.Gte }
+func (v *ComparisonOperatorInput) GetGte() *string { return v.Gte }
 
 // GetLt returns ComparisonOperatorInput.Lt, and is useful for accessing the field via an interface.
-func (v *ComparisonOperatorInput) GetLt() string { return v.Lt }
+func (v *ComparisonOperatorInput) GetLt() *string { return v.Lt }
 
 // GetLte returns ComparisonOperatorInput.Lte, and is useful for accessing the field via an interface.
-func (v *ComparisonOperatorInput) GetLte() string { return v.Lte }
+func (v *ComparisonOperatorInput) GetLte() *string { return v.Lte }
 
 // GetEq returns ComparisonOperatorInput.Eq, and is useful for accessing the field via an interface.
-func (v *ComparisonOperatorInput) GetEq() string { return v.Eq }
+func (v *ComparisonOperatorInput) GetEq() *string { return v.Eq }
+
+type CreateApiTokensInput struct {
+	// Number of tokens to create, default is 1
+	Count *int `json:"count"`
+	// Number of requests allowed per token, represented as a string, default is 5000
+	RequestLimit *string `json:"requestLimit"`
+	// Number of seconds until the token expires, defaults to 1 hour (3600)
+	ExpiresIn *int `json:"expiresIn"`
+}
+
+// GetCount returns CreateApiTokensInput.Count, and is useful for accessing the field via an interface.
+func (v *CreateApiTokensInput) GetCount() *int { return v.Count }
+
+// GetRequestLimit returns CreateApiTokensInput.RequestLimit, and is useful for accessing the field via an interface.
+func (v *CreateApiTokensInput) GetRequestLimit() *string { return v.RequestLimit }
+
+// GetExpiresIn returns CreateApiTokensInput.ExpiresIn, and is useful for accessing the field via an interface.
+func (v *CreateApiTokensInput) GetExpiresIn() *int { return v.ExpiresIn }
+
+// CreateApiTokensResponse is returned by CreateApiTokens on success.
+type CreateApiTokensResponse struct {
+	// Create a new set of short-lived api access tokens
+	CreateApiTokens []ApiToken `json:"createApiTokens"`
+}
+
+// GetCreateApiTokens returns CreateApiTokensResponse.CreateApiTokens, and is useful for accessing the field via an interface.
+func (v *CreateApiTokensResponse) GetCreateApiTokens() []ApiToken { return v.CreateApiTokens }
 
 // Input for creating a market cap webhook.
 type CreateMarketCapWebhookArgs struct {
@@ -61,19 +129,19 @@ type CreateMarketCapWebhookArgs struct {
 	// The recurrence of the webhook. Can be `INDEFINITE` or `ONCE`.
 	AlertRecurrence AlertRecurrence `json:"alertRecurrence"`
 	// A webhook group ID (max 64 characters). Can be used to group webhooks so that their messages are kept in order as a group rather than by individual webhook.
-	GroupId string `json:"groupId"`
+	GroupId *string `json:"groupId"`
 	// The conditions which must be met in order for the webhook to send a message.
 	Conditions MarketCapEventWebhookConditionInput `json:"conditions"`
 	// The settings for retrying failed webhook messages.
-	RetrySettings RetrySettingsInput `json:"retrySettings"`
+	RetrySettings *RetrySettingsInput `json:"retrySettings"`
 	// An optional bucket ID (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketId string `json:"bucketId"`
+	BucketId *string `json:"bucketId"`
 	// An optional bucket sort key (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketSortkey string `json:"bucketSortkey"`
+	BucketSortkey *string `json:"bucketSortkey"`
 	// The type of publishing for the webhook. If not set, it defaults to `SINGLE`.
-	PublishingType PublishingType `json:"publishingType"`
+	PublishingType *PublishingType `json:"publishingType"`
 	// If enabled, new webhooks won't be created if a webhook with the same parameters already exists. If callbackUrl, conditions, publishingType, and alertRecurrence all match, then we return the existing webhook.
-	Deduplicate bool `json:"deduplicate"`
+	Deduplicate *bool `json:"deduplicate"`
 }
 
 // GetName returns CreateMarketCapWebhookArgs.Name, and is useful for accessing the field via an interface.
@@ -89,7 +157,7 @@ func (v *CreateMarketCapWebhookArgs) GetSecurityToken() string { return v.Securi
 func (v *CreateMarketCapWebhookArgs) GetAlertRecurrence() AlertRecurrence { return v.AlertRecurrence }
 
 // GetGroupId returns CreateMarketCapWebhookArgs.GroupId, and is useful for accessing the field via an interface.
-func (v *CreateMarketCapWebhookArgs) GetGroupId() string { return v.GroupId }
+func (v *CreateMarketCapWebhookArgs) GetGroupId() *string { return v.GroupId }
 
 // GetConditions returns CreateMarketCapWebhookArgs.Conditions, and is useful for accessing the field via an interface.
 func (v *CreateMarketCapWebhookArgs) GetConditions() MarketCapEventWebhookConditionInput {
@@ -97,19 +165,19 @@ func (v *CreateMarketCapWebhookArgs) GetConditions() MarketCapEventWebhookCondit
 }
 
 // GetRetrySettings returns CreateMarketCapWebhookArgs.RetrySettings, and is useful for accessing the field via an interface.
-func (v *CreateMarketCapWebhookArgs) GetRetrySettings() RetrySettingsInput { return v.RetrySettings }
+func (v *CreateMarketCapWebhookArgs) GetRetrySettings() *RetrySettingsInput { return v.RetrySettings }
 
 // GetBucketId returns CreateMarketCapWebhookArgs.BucketId, and is useful for accessing the field via an interface.
-func (v *CreateMarketCapWebhookArgs) GetBucketId() string { return v.BucketId }
+func (v *CreateMarketCapWebhookArgs) GetBucketId() *string { return v.BucketId }
 
 // GetBucketSortkey returns CreateMarketCapWebhookArgs.BucketSortkey, and is useful for accessing the field via an interface.
-func (v *CreateMarketCapWebhookArgs) GetBucketSortkey() string { return v.BucketSortkey }
+func (v *CreateMarketCapWebhookArgs) GetBucketSortkey() *string { return v.BucketSortkey }
 
 // GetPublishingType returns CreateMarketCapWebhookArgs.PublishingType, and is useful for accessing the field via an interface.
-func (v *CreateMarketCapWebhookArgs) GetPublishingType() PublishingType { return v.PublishingType }
+func (v *CreateMarketCapWebhookArgs) GetPublishingType() *PublishingType { return v.PublishingType }
 
 // GetDeduplicate returns CreateMarketCapWebhookArgs.Deduplicate, and is useful for accessing the field via an interface.
-func (v *CreateMarketCapWebhookArgs) GetDeduplicate() bool { return v.Deduplicate }
+func (v *CreateMarketCapWebhookArgs) GetDeduplicate() *bool { return v.Deduplicate }
 
 // Input for creating market cap webhooks.
 type CreateMarketCapWebhooksInput struct {
@@ -131,19 +199,19 @@ type CreateNftEventWebhookArgs struct {
 	// The recurrence of the webhook. Can be `INDEFINITE` or `ONCE`.
 	AlertRecurrence AlertRecurrence `json:"alertRecurrence"`
 	// A webhook group ID (max 64 characters). Can be used to group webhooks so that their messages are kept in order as a group rather than by individual webhook.
-	GroupId string `json:"groupId"`
+	GroupId *string `json:"groupId"`
 	// The conditions which must be met in order for the webhook to send a message.
 	Conditions NftEventWebhookConditionInput `json:"conditions"`
 	// The settings for retrying failed webhook messages.
-	RetrySettings RetrySettingsInput `json:"retrySettings"`
+	RetrySettings *RetrySettingsInput `json:"retrySettings"`
 	// An optional bucket ID (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketId string `json:"bucketId"`
+	BucketId *string `json:"bucketId"`
 	// An optional bucket sort key (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketSortkey string `json:"bucketSortkey"`
+	BucketSortkey *string `json:"bucketSortkey"`
 	// The type of publishing for the webhook. If not set, it defaults to `SINGLE`.
-	PublishingType PublishingType `json:"publishingType"`
+	PublishingType *PublishingType `json:"publishingType"`
 	// If enabled, new webhooks won't be created if a webhook with the same parameters already exists. If callbackUrl, conditions, publishingType, and alertRecurrence all match, then we return the existing webhook.
-	Deduplicate bool `json:"deduplicate"`
+	Deduplicate *bool `json:"deduplicate"`
 }
 
 // GetName returns CreateNftEventWebhookArgs.Name, and is useful for accessing the field via an interface.
@@ -159,7 +227,7 @@ func (v *CreateNftEventWebhookArgs) GetSecurityToken() string { return v.Securit
 func (v *CreateNftEventWebhookArgs) GetAlertRecurrence() AlertRecurrence { return v.AlertRecurrence }
 
 // GetGroupId returns CreateNftEventWebhookArgs.GroupId, and is useful for accessing the field via an interface.
-func (v *CreateNftEventWebhookArgs) GetGroupId() string { return v.GroupId }
+func (v *CreateNftEventWebhookArgs) GetGroupId() *string { return v.GroupId }
 
 // GetConditions returns CreateNftEventWebhookArgs.Conditions, and is useful for accessing the field via an interface.
 func (v *CreateNftEventWebhookArgs) GetConditions() NftEventWebhookConditionInput {
@@ -167,19 +235,19 @@ func (v *CreateNftEventWebhookArgs) GetConditions() NftEventWebhookConditionInpu
 }
 
 // GetRetrySettings returns CreateNftEventWebhookArgs.RetrySettings, and is useful for accessing the field via an interface.
-func (v *CreateNftEventWebhookArgs) GetRetrySettings() RetrySettingsInput { return v.RetrySettings }
+func (v *CreateNftEventWebhookArgs) GetRetrySettings() *RetrySettingsInput { return v.RetrySettings }
 
 // GetBucketId returns CreateNftEventWebhookArgs.BucketId, and is useful for accessing the field via an interface.
-func (v *CreateNftEventWebhookArgs) GetBucketId() string { return v.BucketId }
+func (v *CreateNftEventWebhookArgs) GetBucketId() *string { return v.BucketId }
 
 // GetBucketSortkey returns CreateNftEventWebhookArgs.BucketSortkey, and is useful for accessing the field via an interface.
-func (v *CreateNftEventWebhookArgs) GetBucketSortkey() string { return v.BucketSortkey }
+func (v *CreateNftEventWebhookArgs) GetBucketSortkey() *string { return v.BucketSortkey }
 
 // GetPublishingType returns CreateNftEventWebhookArgs.PublishingType, and is useful for accessing the field via an interface.
-func (v *CreateNftEventWebhookArgs) GetPublishingType() PublishingType { return v.PublishingType }
+func (v *CreateNftEventWebhookArgs) GetPublishingType() *PublishingType { return v.PublishingType }
 
 // GetDeduplicate returns CreateNftEventWebhookArgs.Deduplicate, and is useful for accessing the field via an interface.
-func (v *CreateNftEventWebhookArgs) GetDeduplicate() bool { return v.Deduplicate }
+func (v *CreateNftEventWebhookArgs) GetDeduplicate() *bool { return v.Deduplicate }
 
 // Input for creating NFT event webhooks.
 type CreateNftEventWebhooksInput struct {
@@ -201,19 +269,19 @@ type CreatePriceWebhookArgs struct {
 	// The recurrence of the webhook. Can be `INDEFINITE` or `ONCE`.
 	AlertRecurrence AlertRecurrence `json:"alertRecurrence"`
 	// A webhook group ID (max 64 characters). Can be used to group webhooks so that their messages are kept in order as a group rather than by individual webhook.
-	GroupId string `json:"groupId"`
+	GroupId *string `json:"groupId"`
 	// The conditions which must be met in order for the webhook to send a message.
 	Conditions PriceEventWebhookConditionInput `json:"conditions"`
 	// The settings for retrying failed webhook messages.
-	RetrySettings RetrySettingsInput `json:"retrySettings"`
+	RetrySettings *RetrySettingsInput `json:"retrySettings"`
 	// An optional bucket ID (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketId string `json:"bucketId"`
+	BucketId *string `json:"bucketId"`
 	// An optional bucket sort key (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketSortkey string `json:"bucketSortkey"`
+	BucketSortkey *string `json:"bucketSortkey"`
 	// The type of publishing for the webhook. If not set, it defaults to `SINGLE`.
-	PublishingType PublishingType `json:"publishingType"`
+	PublishingType *PublishingType `json:"publishingType"`
 	// If enabled, new webhooks won't be created if a webhook with the same parameters already exists. If callbackUrl, conditions, publishingType, and alertRecurrence all match, then we return the existing webhook.
-	Deduplicate bool `json:"deduplicate"`
+	Deduplicate *bool `json:"deduplicate"`
 }
 
 // GetName returns CreatePriceWebhookArgs.Name, and is useful for accessing the field via an interface.
@@ -229,25 +297,25 @@ func (v *CreatePriceWebhookArgs) GetSecurityToken() string { return v.SecurityTo
 func (v *CreatePriceWebhookArgs) GetAlertRecurrence() AlertRecurrence { return v.AlertRecurrence }
 
 // GetGroupId returns CreatePriceWebhookArgs.GroupId, and is useful for accessing the field via an interface.
-func (v *CreatePriceWebhookArgs) GetGroupId() string { return v.GroupId }
+func (v *CreatePriceWebhookArgs) GetGroupId() *string { return v.GroupId }
 
 // GetConditions returns CreatePriceWebhookArgs.Conditions, and is useful for accessing the field via an interface.
 func (v *CreatePriceWebhookArgs) GetConditions() PriceEventWebhookConditionInput { return v.Conditions }
 
 // GetRetrySettings returns CreatePriceWebhookArgs.RetrySettings, and is useful for accessing the field via an interface.
-func (v *CreatePriceWebhookArgs) GetRetrySettings() RetrySettingsInput { return v.RetrySettings }
+func (v *CreatePriceWebhookArgs) GetRetrySettings() *RetrySettingsInput { return v.RetrySettings }
 
 // GetBucketId returns CreatePriceWebhookArgs.BucketId, and is useful for accessing the field via an interface.
-func (v *CreatePriceWebhookArgs) GetBucketId() string { return v.BucketId }
+func (v *CreatePriceWebhookArgs) GetBucketId() *string { return v.BucketId }
 
 // GetBucketSortkey returns CreatePriceWebhookArgs.BucketSortkey, and is useful for accessing the field via an interface.
-func (v *CreatePriceWebhookArgs) GetBucketSortkey() string { return v.BucketSortkey }
+func (v *CreatePriceWebhookArgs) GetBucketSortkey() *string { return v.BucketSortkey }
 
 // GetPublishingType returns CreatePriceWebhookArgs.PublishingType, and is useful for accessing the field via an interface.
-func (v *CreatePriceWebhookArgs) GetPublishingType() PublishingType { return v.PublishingType }
+func (v *CreatePriceWebhookArgs) GetPublishingType() *PublishingType { return v.PublishingType }
 
 // GetDeduplicate returns CreatePriceWebhookArgs.Deduplicate, and is useful for accessing the field via an interface.
-func (v *CreatePriceWebhookArgs) GetDeduplicate() bool { return v.Deduplicate }
+func (v *CreatePriceWebhookArgs) GetDeduplicate() *bool { return v.Deduplicate }
 
 // Input for creating price webhooks.
 type CreatePriceWebhooksInput struct {
@@ -269,19 +337,19 @@ type CreateRawTransactionWebhookArgs struct {
 	// The recurrence of the webhook. Can be `INDEFINITE` or `ONCE`.
 	AlertRecurrence AlertRecurrence `json:"alertRecurrence"`
 	// A webhook group ID (max 64 characters). Can be used to group webhooks so that their messages are kept in order as a group rather than by individual webhook.
-	GroupId string `json:"groupId"`
+	GroupId *string `json:"groupId"`
 	// The conditions which must be met in order for the webhook to send a message.
 	Conditions RawTransactionWebhookConditionInput `json:"conditions"`
 	// The settings for retrying failed webhook messages.
-	RetrySettings RetrySettingsInput `json:"retrySettings"`
+	RetrySettings *RetrySettingsInput `json:"retrySettings"`
 	// An optional bucket ID (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketId string `json:"bucketId"`
+	BucketId *string `json:"bucketId"`
 	// An optional bucket sort key (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketSortkey string `json:"bucketSortkey"`
+	BucketSortkey *string `json:"bucketSortkey"`
 	// The type of publishing for the webhook. If not set, it defaults to `SINGLE`.
-	PublishingType PublishingType `json:"publishingType"`
+	PublishingType *PublishingType `json:"publishingType"`
 	// If enabled, new webhooks won't be created if a webhook with the same parameters already exists. If callbackUrl, conditions, publishingType, and alertRecurrence all match, then we return the existing webhook.
-	Deduplicate bool `json:"deduplicate"`
+	Deduplicate *bool `json:"deduplicate"`
 }
 
 // GetName returns CreateRawTransactionWebhookArgs.Name, and is useful for accessing the field via an interface.
@@ -299,7 +367,7 @@ func (v *CreateRawTransactionWebhookArgs) GetAlertRecurrence() AlertRecurrence {
 }
 
 // GetGroupId returns CreateRawTransactionWebhookArgs.GroupId, and is useful for accessing the field via an interface.
-func (v *CreateRawTransactionWebhookArgs) GetGroupId() string { return v.GroupId }
+func (v *CreateRawTransactionWebhookArgs) GetGroupId() *string { return v.GroupId }
 
 // GetConditions returns CreateRawTransactionWebhookArgs.Conditions, and is useful for accessing the field via an interface.
 func (v *CreateRawTransactionWebhookArgs) GetConditions() RawTransactionWebhookConditionInput {
@@ -307,21 +375,23 @@ func (v *CreateRawTransactionWebhookArgs) GetConditions() RawTransactionWebhookC
 }
 
 // GetRetrySettings returns CreateRawTransactionWebhookArgs.RetrySettings, and is useful for accessing the field via an interface.
-func (v *CreateRawTransactionWebhookArgs) GetRetrySettings() RetrySettingsInput {
+func (v *CreateRawTransactionWebhookArgs) GetRetrySettings() *RetrySettingsInput {
 	return v.RetrySettings
 }
 
 // GetBucketId returns CreateRawTransactionWebhookArgs.BucketId, and is useful for accessing the field via an interface.
-func (v *CreateRawTransactionWebhookArgs) GetBucketId() string { return v.BucketId }
+func (v *CreateRawTransactionWebhookArgs) GetBucketId() *string { return v.BucketId }
 
 // GetBucketSortkey returns CreateRawTransactionWebhookArgs.BucketSortkey, and is useful for accessing the field via an interface.
-func (v *CreateRawTransactionWebhookArgs) GetBucketSortkey() string { return v.BucketSortkey }
+func (v *CreateRawTransactionWebhookArgs) GetBucketSortkey() *string { return v.BucketSortkey }
 
 // GetPublishingType returns CreateRawTransactionWebhookArgs.PublishingType, and is useful for accessing the field via an interface.
-func (v *CreateRawTransactionWebhookArgs) GetPublishingType() PublishingType { return v.PublishingType }
+func (v *CreateRawTransactionWebhookArgs) GetPublishingType() *PublishingType {
+	return v.PublishingType
+}
 
 // GetDeduplicate returns CreateRawTransactionWebhookArgs.Deduplicate, and is useful for accessing the field via an interface.
-func (v *CreateRawTransactionWebhookArgs) GetDeduplicate() bool { return v.Deduplicate }
+func (v *CreateRawTransactionWebhookArgs) GetDeduplicate() *bool { return v.Deduplicate }
 
 // Input for creating Raw Transaction webhooks.
 type CreateRawTransactionWebhooksInput struct {
@@ -345,19 +415,19 @@ type CreateTokenPairEventWebhookArgs struct {
 	// The recurrence of the webhook. Can be `INDEFINITE` or `ONCE`.
 	AlertRecurrence AlertRecurrence `json:"alertRecurrence"`
 	// A webhook group ID (max 64 characters). Can be used to group webhooks so that their messages are kept in order as a group rather than by individual webhook.
-	GroupId string `json:"groupId"`
+	GroupId *string `json:"groupId"`
 	// The conditions which must be met in order for the webhook to send a message.
 	Conditions TokenPairEventWebhookConditionInput `json:"conditions"`
 	// The settings for retrying failed webhook messages.
-	RetrySettings RetrySettingsInput `json:"retrySettings"`
+	RetrySettings *RetrySettingsInput `json:"retrySettings"`
 	// An optional bucket ID (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketId string `json:"bucketId"`
+	BucketId *string `json:"bucketId"`
 	// An optional bucket sort key (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketSortkey string `json:"bucketSortkey"`
+	BucketSortkey *string `json:"bucketSortkey"`
 	// The type of publishing for the webhook. If not set, it defaults to `SINGLE`.
-	PublishingType PublishingType `json:"publishingType"`
+	PublishingType *PublishingType `json:"publishingType"`
 	// If enabled, new webhooks won't be created if a webhook with the same parameters already exists. If callbackUrl, conditions, publishingType, and alertRecurrence all match, then we return the existing webhook.
-	Deduplicate bool `json:"deduplicate"`
+	Deduplicate *bool `json:"deduplicate"`
 }
 
 // GetName returns CreateTokenPairEventWebhookArgs.Name, and is useful for accessing the field via an interface.
@@ -375,7 +445,7 @@ func (v *CreateTokenPairEventWebhookArgs) GetAlertRecurrence() AlertRecurrence {
 }
 
 // GetGroupId returns CreateTokenPairEventWebhookArgs.GroupId, and is useful for accessing the field via an interface.
-func (v *CreateTokenPairEventWebhookArgs) GetGroupId() string { return v.GroupId }
+func (v *CreateTokenPairEventWebhookArgs) GetGroupId() *string { return v.GroupId }
 
 // GetConditions returns CreateTokenPairEventWebhookArgs.Conditions, and is useful for accessing the field via an interface.
 func (v *CreateTokenPairEventWebhookArgs) GetConditions() TokenPairEventWebhookConditionInput {
@@ -383,21 +453,23 @@ func (v *CreateTokenPairEventWebhookArgs) GetConditions() TokenPairEventWebhookC
 }
 
 // GetRetrySettings returns CreateTokenPairEventWebhookArgs.RetrySettings, and is useful for accessing the field via an interface.
-func (v *CreateTokenPairEventWebhookArgs) GetRetrySettings() RetrySettingsInput {
+func (v *CreateTokenPairEventWebhookArgs) GetRetrySettings() *RetrySettingsInput {
 	return v.RetrySettings
 }
 
 // GetBucketId returns CreateTokenPairEventWebhookArgs.BucketId, and is useful for accessing the field via an interface.
-func (v *CreateTokenPairEventWebhookArgs) GetBucketId() string { return v.BucketId }
+func (v *CreateTokenPairEventWebhookArgs) GetBucketId() *string { return v.BucketId }
 
 // GetBucketSortkey returns CreateTokenPairEventWebhookArgs.BucketSortkey, and is useful for accessing the field via an interface.
-func (v *CreateTokenPairEventWebhookArgs) GetBucketSortkey() string { return v.BucketSortkey }
+func (v *CreateTokenPairEventWebhookArgs) GetBucketSortkey() *string { return v.BucketSortkey }
 
 // GetPublishingType returns CreateTokenPairEventWebhookArgs.PublishingType, and is useful for accessing the field via an interface.
-func (v *CreateTokenPairEventWebhookArgs) GetPublishingType() PublishingType { return v.PublishingType }
+func (v *CreateTokenPairEventWebhookArgs) GetPublishingType() *PublishingType {
+	return v.PublishingType
+}
 
 // GetDeduplicate returns CreateTokenPairEventWebhookArgs.Deduplicate, and is useful for accessing the field via an interface.
-func (v *CreateTokenPairEventWebhookArgs) GetDeduplicate() bool { return v.Deduplicate }
+func (v *CreateTokenPairEventWebhookArgs) GetDeduplicate() *bool { return v.Deduplicate }
 
 // Input for creating token pair event webhooks.
 type CreateTokenPairEventWebhooksInput struct {
@@ -416,19 +488,180 @@ func (v *CreateTokenPairEventWebhooksInput) GetWebhooks() []CreateTokenPairEvent
 // Result returned by `createWebhooks`.
 type CreateWebhooksCreateWebhooksCreateWebhooksOutput struct {
 	// The list of token pair event webhooks that were created.
-	TokenPairEventWebhooks []CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook `json:"tokenPairEventWebhooks"`
+	TokenPairEventWebhooks []*CreatedWebhook `json:"tokenPairEventWebhooks"`
+	// The list of price webhooks that were created.
+	PriceWebhooks []*CreatedWebhook `json:"priceWebhooks"`
+	// The list of market cap event webhooks that were created.
+	MarketCapWebhooks []*CreatedWebhook `json:"marketCapWebhooks"`
 }
 
 // GetTokenPairEventWebhooks returns CreateWebhooksCreateWebhooksCreateWebhooksOutput.TokenPairEventWebhooks, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutput) GetTokenPairEventWebhooks() []CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook {
+func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutput) GetTokenPairEventWebhooks() []*CreatedWebhook {
 	return v.TokenPairEventWebhooks
 }
 
-// CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook includes the requested fields of the GraphQL type Webhook.
+// GetPriceWebhooks returns CreateWebhooksCreateWebhooksCreateWebhooksOutput.PriceWebhooks, and is useful for accessing the field via an interface.
+func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutput) GetPriceWebhooks() []*CreatedWebhook {
+	return v.PriceWebhooks
+}
+
+// GetMarketCapWebhooks returns CreateWebhooksCreateWebhooksCreateWebhooksOutput.MarketCapWebhooks, and is useful for accessing the field via an interface.
+func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutput) GetMarketCapWebhooks() []*CreatedWebhook {
+	return v.MarketCapWebhooks
+}
+
+// Input for creating webhooks.
+type CreateWebhooksInput struct {
+	// Input for creating price webhooks.
+	PriceWebhooksInput *CreatePriceWebhooksInput `json:"priceWebhooksInput"`
+	// Input for creating token pair event webhooks.
+	TokenPairEventWebhooksInput *CreateTokenPairEventWebhooksInput `json:"tokenPairEventWebhooksInput"`
+	// Input for creating NFT event webhooks.
+	NftEventWebhooksInput *CreateNftEventWebhooksInput `json:"nftEventWebhooksInput"`
+	// Input for creating raw transaction webhooks.
+	RawTransactionWebhooksInput *CreateRawTransactionWebhooksInput `json:"rawTransactionWebhooksInput"`
+	// Input for creating market cap webhooks.
+	MarketCapWebhooksInput *CreateMarketCapWebhooksInput `json:"marketCapWebhooksInput"`
+}
+
+// GetPriceWebhooksInput returns CreateWebhooksInput.PriceWebhooksInput, and is useful for accessing the field via an interface.
+func (v *CreateWebhooksInput) GetPriceWebhooksInput() *CreatePriceWebhooksInput {
+	return v.PriceWebhooksInput
+}
+
+// GetTokenPairEventWebhooksInput returns CreateWebhooksInput.TokenPairEventWebhooksInput, and is useful for accessing the field via an interface.
+func (v *CreateWebhooksInput) GetTokenPairEventWebhooksInput() *CreateTokenPairEventWebhooksInput {
+	return v.TokenPairEventWebhooksInput
+}
+
+// GetNftEventWebhooksInput returns CreateWebhooksInput.NftEventWebhooksInput, and is useful for accessing the field via an interface.
+func (v *CreateWebhooksInput) GetNftEventWebhooksInput() *CreateNftEventWebhooksInput {
+	return v.NftEventWebhooksInput
+}
+
+// GetRawTransactionWebhooksInput returns CreateWebhooksInput.RawTransactionWebhooksInput, and is useful for accessing the field via an interface.
+func (v *CreateWebhooksInput) GetRawTransactionWebhooksInput() *CreateRawTransactionWebhooksInput {
+	return v.RawTransactionWebhooksInput
+}
+
+// GetMarketCapWebhooksInput returns CreateWebhooksInput.MarketCapWebhooksInput, and is useful for accessing the field via an interface.
+func (v *CreateWebhooksInput) GetMarketCapWebhooksInput() *CreateMarketCapWebhooksInput {
+	return v.MarketCapWebhooksInput
+}
+
+// CreateWebhooksResponse is returned by CreateWebhooks on success.
+type CreateWebhooksResponse struct {
+	// Create price, raw transaction, token/pair event, and NFT event webhooks.
+	CreateWebhooks CreateWebhooksCreateWebhooksCreateWebhooksOutput `json:"createWebhooks"`
+}
+
+// GetCreateWebhooks returns CreateWebhooksResponse.CreateWebhooks, and is useful for accessing the field via an interface.
+func (v *CreateWebhooksResponse) GetCreateWebhooks() CreateWebhooksCreateWebhooksCreateWebhooksOutput {
+	return v.CreateWebhooks
+}
+
+// CreatedEvent includes the requested fields of the GraphQL type Event.
+// The GraphQL type's documentation follows.
+//
+// A token transaction.
+type CreatedEvent struct {
+	// The contract address of the token's top pair.
+	Address string `json:"address"`
+	// The type of transaction event. Can be `Burn`, `Mint`, `Swap`, `Sync`, `Collect`, or `CollectProtocol`.
+	EventType EventType `json:"eventType"`
+	// A more specific breakdown of `eventType`. Splits `Swap` into `Buy` or `Sell`.
+	EventDisplayType *EventDisplayType `json:"eventDisplayType"`
+	// The contract address of the token with higher liquidity in the token's top pair.
+	LiquidityToken *string `json:"liquidityToken"`
+	// The wallet address that performed the transaction.
+	Maker *string `json:"maker"`
+	// The network ID that the token is deployed on.
+	NetworkId int `json:"networkId"`
+	// The token of interest within the token's top pair. Can be `token0` or `token1`.
+	QuoteToken *QuoteToken `json:"quoteToken"`
+	// The unix timestamp for when the transaction occurred.
+	Timestamp int `json:"timestamp"`
+	// The address of the event's token0.
+	Token0Address *string `json:"token0Address"`
+	// The address of the event's token1.
+	Token1Address *string `json:"token1Address"`
+	// The price of `token0` paid/received in USD, including any fees.
+	Token0SwapValueUsd *string `json:"token0SwapValueUsd"`
+	// The price of `token1` paid/received in USD, including any fees.
+	Token1SwapValueUsd *string `json:"token1SwapValueUsd"`
+	// The price of `token0` paid/received in the network's base token, including fees.
+	Token0ValueBase *string `json:"token0ValueBase"`
+	// The price of `token1` paid/received in the network's base token, including fees.
+	Token1ValueBase *string `json:"token1ValueBase"`
+	// The updated price of `token0` in USD, calculated after the transaction.
+	Token0PoolValueUsd *string `json:"token0PoolValueUsd"`
+	// The updated price of `token1` in USD, calculated after the transaction.
+	Token1PoolValueUsd *string `json:"token1PoolValueUsd"`
+	// The unique hash for the transaction.
+	TransactionHash string `json:"transactionHash"`
+	// The index of the log in the block.
+	LogIndex int `json:"logIndex"`
+}
+
+// GetAddress returns CreatedEvent.Address, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetAddress() string { return v.Address }
+
+// GetEventType returns CreatedEvent.EventType, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetEventType() EventType { return v.EventType }
+
+// GetEventDisplayType returns CreatedEvent.EventDisplayType, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetEventDisplayType() *EventDisplayType { return v.EventDisplayType }
+
+// GetLiquidityToken returns CreatedEvent.LiquidityToken, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetLiquidityToken() *string { return v.LiquidityToken }
+
+// GetMaker returns CreatedEvent.Maker, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetMaker() *string { return v.Maker }
+
+// GetNetworkId returns CreatedEvent.NetworkId, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetNetworkId() int { return v.NetworkId }
+
+// GetQuoteToken returns CreatedEvent.QuoteToken, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetQuoteToken() *QuoteToken { return v.QuoteToken }
+
+// GetTimestamp returns CreatedEvent.Timestamp, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetTimestamp() int { return v.Timestamp }
+
+// GetToken0Address returns CreatedEvent.Token0Address, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetToken0Address() *string { return v.Token0Address }
+
+// GetToken1Address returns CreatedEvent.Token1Address, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetToken1Address() *string { return v.Token1Address }
+
+// GetToken0SwapValueUsd returns CreatedEvent.Token0SwapValueUsd, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetToken0SwapValueUsd() *string { return v.Token0SwapValueUsd }
+
+// GetToken1SwapValueUsd returns CreatedEvent.Token1SwapValueUsd, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetToken1SwapValueUsd() *string { return v.Token1SwapValueUsd }
+
+// GetToken0ValueBase returns CreatedEvent.Token0ValueBase, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetToken0ValueBase() *string { return v.Token0ValueBase }
+
+// GetToken1ValueBase returns CreatedEvent.Token1ValueBase, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetToken1ValueBase() *string { return v.Token1ValueBase }
+
+// GetToken0PoolValueUsd returns CreatedEvent.Token0PoolValueUsd, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetToken0PoolValueUsd() *string { return v.Token0PoolValueUsd }
+
+// GetToken1PoolValueUsd returns CreatedEvent.Token1PoolValueUsd, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetToken1PoolValueUsd() *string { return v.Token1PoolValueUsd }
+
+// GetTransactionHash returns CreatedEvent.TransactionHash, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetTransactionHash() string { return v.TransactionHash }
+
+// GetLogIndex returns CreatedEvent.LogIndex, and is useful for accessing the field via an interface.
+func (v *CreatedEvent) GetLogIndex() int { return v.LogIndex }
+
+// CreatedWebhook includes the GraphQL fields of Webhook requested by the fragment CreatedWebhook.
 // The GraphQL type's documentation follows.
 //
 // Metadata for a webhook.
-type CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook struct {
+type CreatedWebhook struct {
 	// The ID of the webhook.
 	Id string `json:"id"`
 	// The given name of the webhook.
@@ -438,103 +671,285 @@ type CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebho
 	// The status of the webhook. Can be `ACTIVE` or `INACTIVE`.
 	Status string `json:"status"`
 	// The webhook group ID used to group webhooks together for ordered message sending.
-	GroupId string `json:"groupId"`
+	GroupId *string `json:"groupId"`
 	// An optional bucket ID (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketId string `json:"bucketId"`
+	BucketId *string `json:"bucketId"`
 	// An optional bucket sort key (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
-	BucketSortkey string `json:"bucketSortkey"`
+	BucketSortkey *string `json:"bucketSortkey"`
 	// The type of publishing for the webhook. If not set, it defaults to `SINGLE`.
-	PublishingType PublishingType `json:"publishingType"`
+	PublishingType *PublishingType `json:"publishingType"`
 }
 
-// GetId returns CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook.Id, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook) GetId() string {
-	return v.Id
-}
+// GetId returns CreatedWebhook.Id, and is useful for accessing the field via an interface.
+func (v *CreatedWebhook) GetId() string { return v.Id }
+
+// GetName returns CreatedWebhook.Name, and is useful for accessing the field via an interface.
+func (v *CreatedWebhook) GetName() string { return v.Name }
 
-// GetName returns CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook.Name, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook) GetName() string {
-	return v.Name
+// GetCallbackUrl returns CreatedWebhook.CallbackUrl, and is useful for accessing the field via an interface.
+func (v *CreatedWebhook) GetCallbackUrl() string { return v.CallbackUrl }
+
+// GetStatus returns CreatedWebhook.Status, and is useful for accessing the field via an interface.
+func (v *CreatedWebhook) GetStatus() string { return v.Status }
+
+// GetGroupId returns CreatedWebhook.GroupId, and is useful for accessing the field via an interface.
+func (v *CreatedWebhook) GetGroupId() *string { return v.GroupId }
+
+// GetBucketId returns CreatedWebhook.BucketId, and is useful for accessing the field via an interface.
+func (v *CreatedWebhook) GetBucketId() *string { return v.BucketId }
+
+// GetBucketSortkey returns CreatedWebhook.BucketSortkey, and is useful for accessing the field via an interface.
+func (v *CreatedWebhook) GetBucketSortkey() *string { return v.BucketSortkey }
+
+// GetPublishingType returns CreatedWebhook.PublishingType, and is useful for accessing the field via an interface.
+func (v *CreatedWebhook) GetPublishingType() *PublishingType { return v.PublishingType }
+
+// DeleteApiTokenResponse is returned by DeleteApiToken on success.
+type DeleteApiTokenResponse struct {
+	// Delete a single short-lived api access token by id
+	DeleteApiToken string `json:"deleteApiToken"`
 }
 
-// GetCallbackUrl returns CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook.CallbackUrl, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook) GetCallbackUrl() string {
-	return v.CallbackUrl
+// GetDeleteApiToken returns DeleteApiTokenResponse.DeleteApiToken, and is useful for accessing the field via an interface.
+func (v *DeleteApiTokenResponse) GetDeleteApiToken() string { return v.DeleteApiToken }
+
+// DeleteWebhooksDeleteWebhooksDeleteWebhooksOutput includes the requested fields of the GraphQL type DeleteWebhooksOutput.
+// The GraphQL type's documentation follows.
+//
+// Result returned by `deleteWebhooks`.
+type DeleteWebhooksDeleteWebhooksDeleteWebhooksOutput struct {
+	// The list of webhook IDs that were deleted.
+	DeletedIds []*string `json:"deletedIds"`
 }
 
-// GetStatus returns CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook.Status, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook) GetStatus() string {
-	return v.Status
+// GetDeletedIds returns DeleteWebhooksDeleteWebhooksDeleteWebhooksOutput.DeletedIds, and is useful for accessing the field via an interface.
+func (v *DeleteWebhooksDeleteWebhooksDeleteWebhooksOutput) GetDeletedIds() []*string {
+	return v.DeletedIds
 }
 
-// GetGroupId returns CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook.GroupId, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook) GetGroupId() string {
-	return v.GroupId
+// Input for deleting webhooks.
+type DeleteWebhooksInput struct {
+	// A list of webhook IDs to delete.
+	WebhookIds []string `json:"webhookIds"`
 }
 
-// GetBucketId returns CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook.BucketId, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook) GetBucketId() string {
-	return v.BucketId
+// GetWebhookIds returns DeleteWebhooksInput.WebhookIds, and is useful for accessing the field via an interface.
+func (v *DeleteWebhooksInput) GetWebhookIds() []string { return v.WebhookIds }
+
+// DeleteWebhooksResponse is returned by DeleteWebhooks on success.
+type DeleteWebhooksResponse struct {
+	// Delete multiple webhooks.
+	DeleteWebhooks *DeleteWebhooksDeleteWebhooksDeleteWebhooksOutput `json:"deleteWebhooks"`
 }
 
-// GetBucketSortkey returns CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook.BucketSortkey, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook) GetBucketSortkey() string {
-	return v.BucketSortkey
+// GetDeleteWebhooks returns DeleteWebhooksResponse.DeleteWebhooks, and is useful for accessing the field via an interface.
+func (v *DeleteWebhooksResponse) GetDeleteWebhooks() *DeleteWebhooksDeleteWebhooksDeleteWebhooksOutput {
+	return v.DeleteWebhooks
 }
 
-// GetPublishingType returns CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook.PublishingType, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksCreateWebhooksCreateWebhooksOutputTokenPairEventWebhooksWebhook) GetPublishingType() PublishingType {
-	return v.PublishingType
+// A more specific breakdown of `EventType`. Splits `Swap` into `Buy` and `Sell`.
+type EventDisplayType string
+
+const (
+	EventDisplayTypeBurn            EventDisplayType = "Burn"
+	EventDisplayTypeMint            EventDisplayType = "Mint"
+	EventDisplayTypeBuy             EventDisplayType = "Buy"
+	EventDisplayTypeSell            EventDisplayType = "Sell"
+	EventDisplayTypeSync            EventDisplayType = "Sync"
+	EventDisplayTypeCollect         EventDisplayType = "Collect"
+	EventDisplayTypeCollectprotocol EventDisplayType = "CollectProtocol"
+)
+
+var AllEventDisplayType = []EventDisplayType{
+	EventDisplayTypeBurn,
+	EventDisplayTypeMint,
+	EventDisplayTypeBuy,
+	EventDisplayTypeSell,
+	EventDisplayTypeSync,
+	EventDisplayTypeCollect,
+	EventDisplayTypeCollectprotocol,
 }
 
-// Input for creating webhooks.
-type CreateWebhooksInput struct {
-	// Input for creating price webhooks.
-	PriceWebhooksInput CreatePriceWebhooksInput `json:"priceWebhooksInput"`
-	// Input for creating token pair event webhooks.
-	TokenPairEventWebhooksInput CreateTokenPairEventWebhooksInput `json:"tokenPairEventWebhooksInput"`
-	// Input for creating NFT event webhooks.
-	NftEventWebhooksInput CreateNftEventWebhooksInput `json:"nftEventWebhooksInput"`
-	// Input for creating raw transaction webhooks.
-	RawTransactionWebhooksInput CreateRawTransactionWebhooksInput `json:"rawTransactionWebhooksInput"`
-	// Input for creating market cap webhooks.
-	MarketCapWebhooksInput CreateMarketCapWebhooksInput `json:"marketCapWebhooksInput"`
+// Input type of `EventQueryTimestamp`.
+type EventQueryTimestampInput struct {
+	// The unix timestamp for the start of the requested range.
+	From int `json:"from"`
+	// The unix timestamp for the end of the requested range.
+	To int `json:"to"`
 }
 
-// GetPriceWebhooksInput returns CreateWebhooksInput.PriceWebhooksInput, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksInput) GetPriceWebhooksInput() CreatePriceWebhooksInput {
-	return v.PriceWebhooksInput
+// GetFrom returns EventQueryTimestampInput.From, and is useful for accessing the field via an interface.
+func (v *EventQueryTimestampInput) GetFrom() int { return v.From }
+
+// GetTo returns EventQueryTimestampInput.To, and is useful for accessing the field via an interface.
+func (v *EventQueryTimestampInput) GetTo() int { return v.To }
+
+// The event type for a token transaction.
+type EventType string
+
+const (
+	EventTypeBurn               EventType = "Burn"
+	EventTypeMint               EventType = "Mint"
+	EventTypeSwap               EventType = "Swap"
+	EventTypeSync               EventType = "Sync"
+	EventTypeCollect            EventType = "Collect"
+	EventTypeCollectprotocol    EventType = "CollectProtocol"
+	EventTypePoolbalancechanged EventType = "PoolBalanceChanged"
+)
+
+var AllEventType = []EventType{
+	EventTypeBurn,
+	EventTypeMint,
+	EventTypeSwap,
+	EventTypeSync,
+	EventTypeCollect,
+	EventTypeCollectprotocol,
+	EventTypePoolbalancechanged,
+}
+
+// Input type of `EventsQuery`.
+type EventsQueryInput struct {
+	// The pair contract address to filter by. If you pass a token address in here, it will instead find the top pair for that token and use that.
+	Address string `json:"address"`
+	// The token of interest. Can be `token0` or `token1`.
+	QuoteToken *QuoteToken `json:"quoteToken"`
+	// The amount of `quoteToken` involved in the swap.
+	AmountNonLiquidityToken *NumberFilter `json:"amountNonLiquidityToken"`
+	// The list of event display types to filter by.
+	EventDisplayType []*EventDisplayType `json:"eventDisplayType"`
+	// The specific event type to filter by.
+	EventType *EventType `json:"eventType"`
+	// The specific wallet address to filter by.
+	Maker *string `json:"maker"`
+	// The network ID to filter by.
+	NetworkId int `json:"networkId"`
+	// The time range to filter by.
+	Timestamp *EventQueryTimestampInput `json:"timestamp"`
+	// The price per `quoteToken` at the time of the swap in the network's base token.
+	PriceBaseToken *NumberFilter `json:"priceBaseToken"`
+	// The total amount of `quoteToken` involved in the swap in the network's base token (`amountNonLiquidityToken` x `priceBaseToken`).
+	PriceBaseTokenTotal *NumberFilter `json:"priceBaseTokenTotal"`
+	// The price per `quoteToken` at the time of the swap in USD.
+	PriceUsd *NumberFilter `json:"priceUsd"`
+	// The total amount of `quoteToken` involved in the swap in USD (`amountNonLiquidityToken` x `priceUsd`).
+	PriceUsdTotal *NumberFilter `json:"priceUsdTotal"`
+	// Specify the type of symbol you want to fetch values for (TOKEN | POOL)
+	SymbolType *SymbolType `json:"symbolType"`
+}
+
+// GetAddress returns EventsQueryInput.Address, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetAddress() string { return v.Address }
+
+// GetQuoteToken returns EventsQueryInput.QuoteToken, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetQuoteToken() *QuoteToken { return v.QuoteToken }
+
+// GetAmountNonLiquidityToken returns EventsQueryInput.AmountNonLiquidityToken, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetAmountNonLiquidityToken() *NumberFilter {
+	return v.AmountNonLiquidityToken
+}
+
+// GetEventDisplayType returns EventsQueryInput.EventDisplayType, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetEventDisplayType() []*EventDisplayType { return v.EventDisplayType }
+
+// GetEventType returns EventsQueryInput.EventType, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetEventType() *EventType { return v.EventType }
+
+// GetMaker returns EventsQueryInput.Maker, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetMaker() *string { return v.Maker }
+
+// GetNetworkId returns EventsQueryInput.NetworkId, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetNetworkId() int { return v.NetworkId }
+
+// GetTimestamp returns EventsQueryInput.Timestamp, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetTimestamp() *EventQueryTimestampInput { return v.Timestamp }
+
+// GetPriceBaseToken returns EventsQueryInput.PriceBaseToken, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetPriceBaseToken() *NumberFilter { return v.PriceBaseToken }
+
+// GetPriceBaseTokenTotal returns EventsQueryInput.PriceBaseTokenTotal, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetPriceBaseTokenTotal() *NumberFilter { return v.PriceBaseTokenTotal }
+
+// GetPriceUsd returns EventsQueryInput.PriceUsd, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetPriceUsd() *NumberFilter { return v.PriceUsd }
+
+// GetPriceUsdTotal returns EventsQueryInput.PriceUsdTotal, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetPriceUsdTotal() *NumberFilter { return v.PriceUsdTotal }
+
+// GetSymbolType returns EventsQueryInput.SymbolType, and is useful for accessing the field via an interface.
+func (v *EventsQueryInput) GetSymbolType() *SymbolType { return v.SymbolType }
+
+// GetNetworkStatusResponse is returned by GetNetworkStatus on success.
+type GetNetworkStatusResponse struct {
+	// Returns the status of a list of networks supported on Codex.
+	GetNetworkStatus []NetworkStatus `json:"getNetworkStatus"`
+}
+
+// GetGetNetworkStatus returns GetNetworkStatusResponse.GetNetworkStatus, and is useful for accessing the field via an interface.
+func (v *GetNetworkStatusResponse) GetGetNetworkStatus() []NetworkStatus { return v.GetNetworkStatus }
+
+// GetTokenEventsGetTokenEventsEventConnection includes the requested fields of the GraphQL type EventConnection.
+// The GraphQL type's documentation follows.
+//
+// Response returned by `getTokenEvents`.
+type GetTokenEventsGetTokenEventsEventConnection struct {
+	// A list of transactions for a token's top pair.
+	Items []*CreatedEvent `json:"items"`
+	// A cursor for use in pagination.
+	Cursor *string `json:"cursor"`
 }
 
-// GetTokenPairEventWebhooksInput returns CreateWebhooksInput.TokenPairEventWebhooksInput, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksInput) GetTokenPairEventWebhooksInput() CreateTokenPairEventWebhooksInput {
-	return v.TokenPairEventWebhooksInput
+// GetItems returns GetTokenEventsGetTokenEventsEventConnection.Items, and is useful for accessing the field via an interface.
+func (v *GetTokenEventsGetTokenEventsEventConnection) GetItems() []*CreatedEvent { return v.Items }
+
+// GetCursor returns GetTokenEventsGetTokenEventsEventConnection.Cursor, and is useful for accessing the field via an interface.
+func (v *GetTokenEventsGetTokenEventsEventConnection) GetCursor() *string { return v.Cursor }
+
+// GetTokenEventsResponse is returned by GetTokenEvents on success.
+type GetTokenEventsResponse struct {
+	// Returns transactions for a pair.
+	GetTokenEvents *GetTokenEventsGetTokenEventsEventConnection `json:"getTokenEvents"`
 }
 
-// GetNftEventWebhooksInput returns CreateWebhooksInput.NftEventWebhooksInput, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksInput) GetNftEventWebhooksInput() CreateNftEventWebhooksInput {
-	return v.NftEventWebhooksInput
+// GetGetTokenEvents returns GetTokenEventsResponse.GetTokenEvents, and is useful for accessing the field via an interface.
+func (v *GetTokenEventsResponse) GetGetTokenEvents() *GetTokenEventsGetTokenEventsEventConnection {
+	return v.GetTokenEvents
 }
 
-// GetRawTransactionWebhooksInput returns CreateWebhooksInput.RawTransactionWebhooksInput, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksInput) GetRawTransactionWebhooksInput() CreateRawTransactionWebhooksInput {
-	return v.RawTransactionWebhooksInput
+// GetTokensResponse is returned by GetTokens on success.
+type GetTokensResponse struct {
+	// Returns a list of tokens by their addresses & network id, with pagination.
+	Tokens []*Token `json:"tokens"`
 }
 
-// GetMarketCapWebhooksInput returns CreateWebhooksInput.MarketCapWebhooksInput, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksInput) GetMarketCapWebhooksInput() CreateMarketCapWebhooksInput {
-	return v.MarketCapWebhooksInput
+// GetTokens returns GetTokensResponse.Tokens, and is useful for accessing the field via an interface.
+func (v *GetTokensResponse) GetTokens() []*Token { return v.Tokens }
+
+// GetWebhooksGetWebhooksGetWebhooksResponse includes the requested fields of the GraphQL type GetWebhooksResponse.
+// The GraphQL type's documentation follows.
+//
+// Response returned by `getWebhooks`.
+type GetWebhooksGetWebhooksGetWebhooksResponse struct {
+	// A list of webhooks belonging to a user.
+	Items []*Webhook `json:"items"`
+	// A cursor for use in pagination.
+	Cursor *string `json:"cursor"`
 }
 
-// CreateWebhooksResponse is returned by CreateWebhooks on success.
-type CreateWebhooksResponse struct {
-	// Create price, raw transaction, token/pair event, and NFT event webhooks.
-	CreateWebhooks CreateWebhooksCreateWebhooksCreateWebhooksOutput `json:"createWebhooks"`
+// GetItems returns GetWebhooksGetWebhooksGetWebhooksResponse.Items, and is useful for accessing the field via an interface.
+func (v *GetWebhooksGetWebhooksGetWebhooksResponse) GetItems() []*Webhook { return v.Items }
+
+// GetCursor returns GetWebhooksGetWebhooksGetWebhooksResponse.Cursor, and is useful for accessing the field via an interface.
+func (v *GetWebhooksGetWebhooksGetWebhooksResponse) GetCursor() *string { return v.Cursor }
+
+// GetWebhooksResponse is returned by GetWebhooks on success.
+type GetWebhooksResponse struct {
+	// Returns a user's list of webhooks.
+	GetWebhooks *GetWebhooksGetWebhooksGetWebhooksResponse `json:"getWebhooks"`
 }
 
-// GetCreateWebhooks returns CreateWebhooksResponse.CreateWebhooks, and is useful for accessing the field via an interface.
-func (v *CreateWebhooksResponse) GetCreateWebhooks() CreateWebhooksCreateWebhooksCreateWebhooksOutput {
-	return v.CreateWebhooks
+// GetGetWebhooks returns GetWebhooksResponse.GetWebhooks, and is useful for accessing the field via an interface.
+func (v *GetWebhooksResponse) GetGetWebhooks() *GetWebhooksGetWebhooksGetWebhooksResponse {
+	return v.GetWebhooks
 }
 
 // Input for integer equals condition.
@@ -553,11 +968,11 @@ type MarketCapEventWebhookConditionInput struct {
 	// The network ID to listen on.
 	NetworkId IntEqualsConditionInput `json:"networkId"`
 	// The price conditions to listen for.
-	FdvMarketCapUsd ComparisonOperatorInput `json:"fdvMarketCapUsd"`
+	FdvMarketCapUsd *ComparisonOperatorInput `json:"fdvMarketCapUsd"`
 	// The circulating market cap conditions to listen for.
-	CirculatingMarketCapUsd ComparisonOperatorInput `json:"circulatingMarketCapUsd"`
+	CirculatingMarketCapUsd *ComparisonOperatorInput `json:"circulatingMarketCapUsd"`
 	// The contract address of the pair to listen for.
-	PairAddress StringEqualsConditionInput `json:"pairAddress"`
+	PairAddress *StringEqualsConditionInput `json:"pairAddress"`
 }
 
 // GetTokenAddress returns MarketCapEventWebhookConditionInput.TokenAddress, and is useful for accessing the field via an interface.
@@ -571,20 +986,47 @@ func (v *MarketCapEventWebhookConditionInput) GetNetworkId() IntEqualsConditionI
 }
 
 // GetFdvMarketCapUsd returns MarketCapEventWebhookConditionInput.FdvMarketCapUsd, and is useful for accessing the field via an interface.
-func (v *MarketCapEventWebhookConditionInput) GetFdvMarketCapUsd() ComparisonOperatorInput {
+func (v *MarketCapEventWebhookConditionInput) GetFdvMarketCapUsd() *ComparisonOperatorInput {
 	return v.FdvMarketCapUsd
 }
 
 // GetCirculatingMarketCapUsd returns MarketCapEventWebhookConditionInput.CirculatingMarketCapUsd, and is useful for accessing the field via an interface.
-func (v *MarketCapEventWebhookConditionInput) GetCirculatingMarketCapUsd() ComparisonOperatorInput {
+func (v *MarketCapEventWebhookConditionInput) GetCirculatingMarketCapUsd() *ComparisonOperatorInput {
 	return v.CirculatingMarketCapUsd
 }
 
 // GetPairAddress returns MarketCapEventWebhookConditionInput.PairAddress, and is useful for accessing the field via an interface.
-func (v *MarketCapEventWebhookConditionInput) GetPairAddress() StringEqualsConditionInput {
+func (v *MarketCapEventWebhookConditionInput) GetPairAddress() *StringEqualsConditionInput {
 	return v.PairAddress
 }
 
+// NetworkStatus includes the requested fields of the GraphQL type MetadataResponse.
+// The GraphQL type's documentation follows.
+//
+// The status for a network supported on Defined.
+type NetworkStatus struct {
+	// The network ID.
+	NetworkId int `json:"networkId"`
+	// The name of the network.
+	NetworkName string `json:"networkName"`
+	// The last processed block on the network.
+	LastProcessedBlock *int `json:"lastProcessedBlock"`
+	// The unix timestamp for the last processed block on the network.
+	LastProcessedTimestamp *int `json:"lastProcessedTimestamp"`
+}
+
+// GetNetworkId returns NetworkStatus.NetworkId, and is useful for accessing the field via an interface.
+func (v *NetworkStatus) GetNetworkId() int { return v.NetworkId }
+
+// GetNetworkName returns NetworkStatus.NetworkName, and is useful for accessing the field via an interface.
+func (v *NetworkStatus) GetNetworkName() string { return v.NetworkName }
+
+// GetLastProcessedBlock returns NetworkStatus.LastProcessedBlock, and is useful for accessing the field via an interface.
+func (v *NetworkStatus) GetLastProcessedBlock() *int { return v.LastProcessedBlock }
+
+// GetLastProcessedTimestamp returns NetworkStatus.LastProcessedTimestamp, and is useful for accessing the field via an interface.
+func (v *NetworkStatus) GetLastProcessedTimestamp() *int { return v.LastProcessedTimestamp }
+
 // Input for NFT event fill source condition.
 type NftEventFillSourceConditionInput struct {
 	// The list of NFT marketplace to equal.
@@ -606,52 +1048,147 @@ func (v *NftEventTypeConditionInput) GetEq() WebhookNftEventType { return v.Eq }
 // Input conditions for an NFT event webhook.
 type NftEventWebhookConditionInput struct {
 	// A list of network IDs to listen on.
-	NetworkId OneOfNumberConditionInput `json:"networkId"`
+	NetworkId *OneOfNumberConditionInput `json:"networkId"`
 	// The maker wallet address to listen for.
-	Maker StringEqualsConditionInput `json:"maker"`
+	Maker *StringEqualsConditionInput `json:"maker"`
 	// The NFT collection contract address to listen for.
-	ContractAddress StringEqualsConditionInput `json:"contractAddress"`
+	ContractAddress *StringEqualsConditionInput `json:"contractAddress"`
 	// The exchange contract address to listen for.
-	ExchangeAddress StringEqualsConditionInput `json:"exchangeAddress"`
+	ExchangeAddress *StringEqualsConditionInput `json:"exchangeAddress"`
 	// The token ID to listen for.
-	TokenId StringEqualsConditionInput `json:"tokenId"`
+	TokenId *StringEqualsConditionInput `json:"tokenId"`
 	// The NFT event type to listen for.
-	EventType NftEventTypeConditionInput `json:"eventType"`
+	EventType *NftEventTypeConditionInput `json:"eventType"`
 	// The NFT marketplaces to listen for.
-	FillSource NftEventFillSourceConditionInput `json:"fillSource"`
+	FillSource *NftEventFillSourceConditionInput `json:"fillSource"`
 	// Option to ignore all nft transfer events
-	IgnoreTransfers bool `json:"ignoreTransfers"`
+	IgnoreTransfers *bool `json:"ignoreTransfers"`
 }
 
 // GetNetworkId returns NftEventWebhookConditionInput.NetworkId, and is useful for accessing the field via an interface.
-func (v *NftEventWebhookConditionInput) GetNetworkId() OneOfNumberConditionInput { return v.NetworkId }
+func (v *NftEventWebhookConditionInput) GetNetworkId() *OneOfNumberConditionInput { return v.NetworkId }
 
 // GetMaker returns NftEventWebhookConditionInput.Maker, and is useful for accessing the field via an interface.
-func (v *NftEventWebhookConditionInput) GetMaker() StringEqualsConditionInput { return v.Maker }
+func (v *NftEventWebhookConditionInput) GetMaker() *StringEqualsConditionInput { return v.Maker }
 
 // GetContractAddress returns NftEventWebhookConditionInput.ContractAddress, and is useful for accessing the field via an interface.
-func (v *NftEventWebhookConditionInput) GetContractAddress() StringEqualsConditionInput {
+func (v *NftEventWebhookConditionInput) GetContractAddress() *StringEqualsConditionInput {
 	return v.ContractAddress
 }
 
 // GetExchangeAddress returns NftEventWebhookConditionInput.ExchangeAddress, and is useful for accessing the field via an interface.
-func (v *NftEventWebhookConditionInput) GetExchangeAddress() StringEqualsConditionInput {
+func (v *NftEventWebhookConditionInput) GetExchangeAddress() *StringEqualsConditionInput {
 	return v.ExchangeAddress
 }
 
 // GetTokenId returns NftEventWebhookConditionInput.TokenId, and is useful for accessing the field via an interface.
-func (v *NftEventWebhookConditionInput) GetTokenId() StringEqualsConditionInput { return v.TokenId }
+func (v *NftEventWebhookConditionInput) GetTokenId() *StringEqualsConditionInput { return v.TokenId }
 
 // GetEventType returns NftEventWebhookConditionInput.EventType, and is useful for accessing the field via an interface.
-func (v *NftEventWebhookConditionInput) GetEventType() NftEventTypeConditionInput { return v.EventType }
+func (v *NftEventWebhookConditionInput) GetEventType() *NftEventTypeConditionInput {
+	return v.EventType
+}
 
 // GetFillSource returns NftEventWebhookConditionInput.FillSource, and is useful for accessing the field via an interface.
-func (v *NftEventWebhookConditionInput) GetFillSource() NftEventFillSourceConditionInput {
+func (v *NftEventWebhookConditionInput) GetFillSource() *NftEventFillSourceConditionInput {
 	return v.FillSource
 }
 
 // GetIgnoreTransfers returns NftEventWebhookConditionInput.IgnoreTransfers, and is useful for accessing the field via an interface.
-func (v *NftEventWebhookConditionInput) GetIgnoreTransfers() bool { return v.IgnoreTransfers }
+func (v *NftEventWebhookConditionInput) GetIgnoreTransfers() *bool { return v.IgnoreTransfers }
+
+// Input type of `NumberFilter`.
+type NumberFilter struct {
+	// Greater than or equal to.
+	Gte *float64 `json:"gte"`
+	// Greater than.
+	Gt *float64 `json:"gt"`
+	// Less than or equal to.
+	Lte *float64 `json:"lte"`
+	// Less than.
+	Lt *float64 `json:"lt"`
+}
+
+// GetGte returns NumberFilter.Gte, and is useful for accessing the field via an interface.
+func (v *NumberFilter) GetGte() *float64 { return v.Gte }
+
+// GetGt returns NumberFilter.Gt, and is useful for accessing the field via an interface.
+func (v *NumberFilter) GetGt() *float64 { return v.Gt }
+
+// GetLte returns NumberFilter.Lte, and is useful for accessing the field via an interface.
+func (v *NumberFilter) GetLte() *float64 { return v.Lte }
+
+// GetLt returns NumberFilter.Lt, and is useful for accessing the field via an interface.
+func (v *NumberFilter) GetLt() *float64 { return v.Lt }
+
+// OnEventsCreatedOnEventsCreatedAddEventsOutput includes the requested fields of the GraphQL type AddEventsOutput.
+// The GraphQL type's documentation follows.
+//
+// Response returned by `onEventsCreated`.
+type OnEventsCreatedOnEventsCreatedAddEventsOutput struct {
+	// The contract address of the pair.
+	Address string `json:"address"`
+	// The network ID that the token is deployed on.
+	NetworkId int `json:"networkId"`
+	// The ID of the event (`address`:`networkId`). For example, `0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2:1`.
+	Id string `json:"id"`
+	// The token of interest within the pair. Can be `token0` or `token1`.
+	QuoteToken *QuoteToken `json:"quoteToken"`
+	// A list of transactions for the token.
+	Events []*CreatedEvent `json:"events"`
+}
+
+// GetAddress returns OnEventsCreatedOnEventsCreatedAddEventsOutput.Address, and is useful for accessing the field via an interface.
+func (v *OnEventsCreatedOnEventsCreatedAddEventsOutput) GetAddress() string { return v.Address }
+
+// GetNetworkId returns OnEventsCreatedOnEventsCreatedAddEventsOutput.NetworkId, and is useful for accessing the field via an interface.
+func (v *OnEventsCreatedOnEventsCreatedAddEventsOutput) GetNetworkId() int { return v.NetworkId }
+
+// GetId returns OnEventsCreatedOnEventsCreatedAddEventsOutput.Id, and is useful for accessing the field via an interface.
+func (v *OnEventsCreatedOnEventsCreatedAddEventsOutput) GetId() string { return v.Id }
+
+// GetQuoteToken returns OnEventsCreatedOnEventsCreatedAddEventsOutput.QuoteToken, and is useful for accessing the field via an interface.
+func (v *OnEventsCreatedOnEventsCreatedAddEventsOutput) GetQuoteToken() *QuoteToken {
+	return v.QuoteToken
+}
+
+// GetEvents returns OnEventsCreatedOnEventsCreatedAddEventsOutput.Events, and is useful for accessing the field via an interface.
+func (v *OnEventsCreatedOnEventsCreatedAddEventsOutput) GetEvents() []*CreatedEvent { return v.Events }
+
+// OnEventsCreatedResponse is returned by OnEventsCreated on success.
+type OnEventsCreatedResponse struct {
+	// Live-streamed transactions for a token.
+	OnEventsCreated *OnEventsCreatedOnEventsCreatedAddEventsOutput `json:"onEventsCreated"`
+}
+
+// GetOnEventsCreated returns OnEventsCreatedResponse.OnEventsCreated, and is useful for accessing the field via an interface.
+func (v *OnEventsCreatedResponse) GetOnEventsCreated() *OnEventsCreatedOnEventsCreatedAddEventsOutput {
+	return v.OnEventsCreated
+}
+
+type OnPricesUpdatedInput struct {
+	Address           string  `json:"address"`
+	NetworkId         int     `json:"networkId"`
+	SourcePairAddress *string `json:"sourcePairAddress"`
+}
+
+// GetAddress returns OnPricesUpdatedInput.Address, and is useful for accessing the field via an interface.
+func (v *OnPricesUpdatedInput) GetAddress() string { return v.Address }
+
+// GetNetworkId returns OnPricesUpdatedInput.NetworkId, and is useful for accessing the field via an interface.
+func (v *OnPricesUpdatedInput) GetNetworkId() int { return v.NetworkId }
+
+// GetSourcePairAddress returns OnPricesUpdatedInput.SourcePairAddress, and is useful for accessing the field via an interface.
+func (v *OnPricesUpdatedInput) GetSourcePairAddress() *string { return v.SourcePairAddress }
+
+// OnPricesUpdatedResponse is returned by OnPricesUpdated on success.
+type OnPricesUpdatedResponse struct {
+	// Live-streamed price updates for multiple tokens.
+	OnPricesUpdated UpdatedPrice `json:"onPricesUpdated"`
+}
+
+// GetOnPricesUpdated returns OnPricesUpdatedResponse.OnPricesUpdated, and is useful for accessing the field via an interface.
+func (v *OnPricesUpdatedResponse) GetOnPricesUpdated() UpdatedPrice { return v.OnPricesUpdated }
 
 // Input for integer list condition.
 type OneOfNumberConditionInput struct {
@@ -671,7 +1208,7 @@ type PriceEventWebhookConditionInput struct {
 	// The price conditions to listen for.
 	PriceUsd ComparisonOperatorInput `json:"priceUsd"`
 	// The contract address of the pair to listen for.
-	PairAddress StringEqualsConditionInput `json:"pairAddress"`
+	PairAddress *StringEqualsConditionInput `json:"pairAddress"`
 }
 
 // GetTokenAddress returns PriceEventWebhookConditionInput.TokenAddress, and is useful for accessing the field via an interface.
@@ -686,7 +1223,7 @@ func (v *PriceEventWebhookConditionInput) GetNetworkId() IntEqualsConditionInput
 func (v *PriceEventWebhookConditionInput) GetPriceUsd() ComparisonOperatorInput { return v.PriceUsd }
 
 // GetPairAddress returns PriceEventWebhookConditionInput.PairAddress, and is useful for accessing the field via an interface.
-func (v *PriceEventWebhookConditionInput) GetPairAddress() StringEqualsConditionInput {
+func (v *PriceEventWebhookConditionInput) GetPairAddress() *StringEqualsConditionInput {
 	return v.PairAddress
 }
 
@@ -703,74 +1240,89 @@ var AllPublishingType = []PublishingType{
 	PublishingTypeSingle,
 }
 
+// The quote token within the pair.
+type QuoteToken string
+
+const (
+	QuoteTokenToken0 QuoteToken = "token0"
+	QuoteTokenToken1 QuoteToken = "token1"
+)
+
+var AllQuoteToken = []QuoteToken{
+	QuoteTokenToken0,
+	QuoteTokenToken1,
+}
+
 // Input conditions for a Raw Transaction webhook.
 type RawTransactionWebhookConditionInput struct {
 	// A list of network IDs to listen on.
-	NetworkId OneOfNumberConditionInput `json:"networkId"`
+	NetworkId *OneOfNumberConditionInput `json:"networkId"`
 	// The to address to listen for.
-	To StringEqualsConditionInput `json:"to"`
+	To *StringEqualsConditionInput `json:"to"`
 	// The from address to listen for.
-	From StringEqualsConditionInput `json:"from"`
+	From *StringEqualsConditionInput `json:"from"`
 	// Trigger the webhook if either the to or the from address matches.
-	ToOrFrom StringEqualsConditionInput `json:"toOrFrom"`
+	ToOrFrom *StringEqualsConditionInput `json:"toOrFrom"`
 	// Trigger the webhook if the input contains or doesn't contain the specified string.
-	Input StringContainsConditionInput `json:"input"`
+	Input *StringContainsConditionInput `json:"input"`
 	// Do not trigger the webhook if the raw transaction is handled by the TokenPairEvent webhook.
-	IgnoreTokenPairEvents bool `json:"ignoreTokenPairEvents"`
+	IgnoreTokenPairEvents *bool `json:"ignoreTokenPairEvents"`
 	// Do not trigger the webhook if the raw transaction is handled by the NftEvent webhook.
-	IgnoreNftEvents bool `json:"ignoreNftEvents"`
+	IgnoreNftEvents *bool `json:"ignoreNftEvents"`
 }
 
 // GetNetworkId returns RawTransactionWebhookConditionInput.NetworkId, and is useful for accessing the field via an interface.
-func (v *RawTransactionWebhookConditionInput) GetNetworkId() OneOfNumberConditionInput {
+func (v *RawTransactionWebhookConditionInput) GetNetworkId() *OneOfNumberConditionInput {
 	return v.NetworkId
 }
 
 // GetTo returns RawTransactionWebhookConditionInput.To, and is useful for accessing the field via an interface.
-func (v *RawTransactionWebhookConditionInput) GetTo() StringEqualsConditionInput { return v.To }
+func (v *RawTransactionWebhookConditionInput) GetTo() *StringEqualsConditionInput { return v.To }
 
 // GetFrom returns RawTransactionWebhookConditionInput.From, and is useful for accessing the field via an interface.
-func (v *RawTransactionWebhookConditionInput) GetFrom() StringEqualsConditionInput { return v.From }
+func (v *RawTransactionWebhookConditionInput) GetFrom() *StringEqualsConditionInput { return v.From }
 
 // GetToOrFrom returns RawTransactionWebhookConditionInput.ToOrFrom, and is useful for accessing the field via an interface.
-func (v *RawTransactionWebhookConditionInput) GetToOrFrom() StringEqualsConditionInput {
+func (v *RawTransactionWebhookConditionInput) GetToOrFrom() *StringEqualsConditionInput {
 	return v.ToOrFrom
 }
 
 // GetInput returns RawTransactionWebhookConditionInput.Input, and is useful for accessing the field via an interface.
-func (v *RawTransactionWebhookConditionInput) GetInput() StringContainsConditionInput { return v.Input }
+func (v *RawTransactionWebhookConditionInput) GetInput() *StringContainsConditionInput {
+	return v.Input
+}
 
 // GetIgnoreTokenPairEvents returns RawTransactionWebhookConditionInput.IgnoreTokenPairEvents, and is useful for accessing the field via an interface.
-func (v *RawTransactionWebhookConditionInput) GetIgnoreTokenPairEvents() bool {
+func (v *RawTransactionWebhookConditionInput) GetIgnoreTokenPairEvents() *bool {
 	return v.IgnoreTokenPairEvents
 }
 
 // GetIgnoreNftEvents returns RawTransactionWebhookConditionInput.IgnoreNftEvents, and is useful for accessing the field via an interface.
-func (v *RawTransactionWebhookConditionInput) GetIgnoreNftEvents() bool { return v.IgnoreNftEvents }
+func (v *RawTransactionWebhookConditionInput) GetIgnoreNftEvents() *bool { return v.IgnoreNftEvents }
 
 // Config input for retrying failed webhook messages.
 type RetrySettingsInput struct {
 	// The maximum time in seconds that the webhook will retry sending a message
-	MaxTimeElapsed int `json:"maxTimeElapsed"`
+	MaxTimeElapsed *int `json:"maxTimeElapsed"`
 	// The minimum time in seconds that the webhook will wait before retrying a failed message
-	MinRetryDelay int `json:"minRetryDelay"`
+	MinRetryDelay *int `json:"minRetryDelay"`
 	// The maximum time in seconds that the webhook will wait before retrying a failed message
-	MaxRetryDelay int `json:"maxRetryDelay"`
+	MaxRetryDelay *int `json:"maxRetryDelay"`
 	// The maximum number of times the webhook will retry sending a message
-	MaxRetries int `json:"maxRetries"`
+	MaxRetries *int `json:"maxRetries"`
 }
 
 // GetMaxTimeElapsed returns RetrySettingsInput.MaxTimeElapsed, and is useful for accessing the field via an interface.
-func (v *RetrySettingsInput) GetMaxTimeElapsed() int { return v.MaxTimeElapsed }
+func (v *RetrySettingsInput) GetMaxTimeElapsed() *int { return v.MaxTimeElapsed }
 
 // GetMinRetryDelay returns RetrySettingsInput.MinRetryDelay, and is useful for accessing the field via an interface.
-func (v *RetrySettingsInput) GetMinRetryDelay() int { return v.MinRetryDelay }
+func (v *RetrySettingsInput) GetMinRetryDelay() *int { return v.MinRetryDelay }
 
 // GetMaxRetryDelay returns RetrySettingsInput.MaxRetryDelay, and is useful for accessing the field via an interface.
-func (v *RetrySettingsInput) GetMaxRetryDelay() int { return v.MaxRetryDelay }
+func (v *RetrySettingsInput) GetMaxRetryDelay() *int { return v.MaxRetryDelay }
 
 // GetMaxRetries returns RetrySettingsInput.MaxRetries, and is useful for accessing the field via an interface.
-func (v *RetrySettingsInput) GetMaxRetries() int { return v.MaxRetries }
+func (v *RetrySettingsInput) GetMaxRetries() *int { return v.MaxRetries }
 
 // Input for string contains condition.
 type StringContainsConditionInput struct {
@@ -795,113 +1347,809 @@ type StringEqualsConditionInput struct {
 // GetEq returns StringEqualsConditionInput.Eq, and is useful for accessing the field via an interface.
 func (v *StringEqualsConditionInput) GetEq() string { return v.Eq }
 
-// Token pair event types.
-type TokenPairEventType string
+type SymbolType string
 
 const (
-	TokenPairEventTypeSwap            TokenPairEventType = "SWAP"
-	TokenPairEventTypeMint            TokenPairEventType = "MINT"
-	TokenPairEventTypeBurn            TokenPairEventType = "BURN"
-	TokenPairEventTypeSync            TokenPairEventType = "SYNC"
-	TokenPairEventTypeBuy             TokenPairEventType = "BUY"
-	TokenPairEventTypeSell            TokenPairEventType = "SELL"
-	TokenPairEventTypeCollect         TokenPairEventType = "COLLECT"
-	TokenPairEventTypeCollectProtocol TokenPairEventType = "COLLECT_PROTOCOL"
+	SymbolTypeToken SymbolType = "TOKEN"
+	SymbolTypePool  SymbolType = "POOL"
 )
 
-var AllTokenPairEventType = []TokenPairEventType{
-	TokenPairEventTypeSwap,
-	TokenPairEventTypeMint,
-	TokenPairEventTypeBurn,
-	TokenPairEventTypeSync,
-	TokenPairEventTypeBuy,
-	TokenPairEventTypeSell,
-	TokenPairEventTypeCollect,
-	TokenPairEventTypeCollectProtocol,
+var AllSymbolType = []SymbolType{
+	SymbolTypeToken,
+	SymbolTypePool,
 }
 
-// Input for token pair event type condition.
-type TokenPairEventTypeConditionInput struct {
-	// The list of token event types to equal.
-	OneOf []TokenPairEventType `json:"oneOf"`
+// Token includes the requested fields of the GraphQL type EnhancedToken.
+// The GraphQL type's documentation follows.
+//
+// Metadata for a token.
+type Token struct {
+	// The contract address of the token.
+	Address string `json:"address"`
+	// The network ID the token is deployed on.
+	NetworkId int `json:"networkId"`
+	// The token name. For example, `ApeCoin`.
+	Name *string `json:"name"`
+	// The token symbol. For example, `APE`.
+	Symbol *string `json:"symbol"`
+	// The precision to which the token can be divided. For example, the smallest unit for USDC is 0.000001 (6 decimals).
+	Decimals int `json:"decimals"`
+	// The small token logo URL.
+	ImageSmallUrl *string `json:"imageSmallUrl"`
+	// More metadata about the token.
+	Info *TokenInfo `json:"info"`
+}
+
+// GetAddress returns Token.Address, and is useful for accessing the field via an interface.
+func (v *Token) GetAddress() string { return v.Address }
+
+// GetNetworkId returns Token.NetworkId, and is useful for accessing the field via an interface.
+func (v *Token) GetNetworkId() int { return v.NetworkId }
+
+// GetName returns Token.Name, and is useful for accessing the field via an interface.
+func (v *Token) GetName() *string { return v.Name }
+
+// GetSymbol returns Token.Symbol, and is useful for accessing the field via an interface.
+func (v *Token) GetSymbol() *string { return v.Symbol }
+
+// GetDecimals returns Token.Decimals, and is useful for accessing the field via an interface.
+func (v *Token) GetDecimals() int { return v.Decimals }
+
+// GetImageSmallUrl returns Token.ImageSmallUrl, and is useful for accessing the field via an interface.
+func (v *Token) GetImageSmallUrl() *string { return v.ImageSmallUrl }
+
+// GetInfo returns Token.Info, and is useful for accessing the field via an interface.
+func (v *Token) GetInfo() *TokenInfo { return v.Info }
+
+// TokenInfo includes the requested fields of the GraphQL type TokenInfo.
+// The GraphQL type's documentation follows.
+//
+// Metadata for a token.
+type TokenInfo struct {
+	// The small token logo URL.
+	ImageSmallUrl *string `json:"imageSmallUrl"`
 }
 
-// GetOneOf returns TokenPairEventTypeConditionInput.OneOf, and is useful for accessing the field via an interface.
-func (v *TokenPairEventTypeConditionInput) GetOneOf() []TokenPairEventType { return v.OneOf }
+// GetImageSmallUrl returns TokenInfo.ImageSmallUrl, and is useful for accessing the field via an interface.
+func (v *TokenInfo) GetImageSmallUrl() *string { return v.ImageSmallUrl }
 
-// Input conditions for a token pair event webhook.
-type TokenPairEventWebhookConditionInput struct {
-	// A list of network IDs to listen on.
-	NetworkId OneOfNumberConditionInput `json:"networkId"`
-	// The maker wallet address to listen for.
-	Maker StringEqualsConditionInput `json:"maker"`
-	// The pair contract address to listen for.
-	PairAddress StringEqualsConditionInput `json:"pairAddress"`
-	// The exchange contract address to listen for.
-	ExchangeAddress StringEqualsConditionInput `json:"exchangeAddress"`
-	// The token contract address to listen for.
-	TokenAddress StringEqualsConditionInput `json:"tokenAddress"`
-	// The swap values to listen for.
-	SwapValue ComparisonOperatorInput `json:"swapValue"`
-	// The token event type to listen for.
-	EventType TokenPairEventTypeConditionInput `json:"eventType"`
+// Input type of `token` and `tokens`.
+type TokenInput struct {
+	// The contract address of the token.
+	Address string `json:"address"`
+	// The network ID the token is deployed on.
+	NetworkId int `json:"networkId"`
 }
 
-// GetNetworkId returns TokenPairEventWebhookConditionInput.NetworkId, and is useful for accessing the field via an interface.
-func (v *TokenPairEventWebhookConditionInput) GetNetworkId() OneOfNumberConditionInput {
+// GetAddress returns TokenInput.Address, and is useful for accessing the field via an interface.
+func (v *TokenInput) GetAddress() string { return v.Address }
+
+// GetNetworkId returns TokenInput.NetworkId, and is useful for accessing the field via an interface.
+func (v *TokenInput) GetNetworkId() int { return v.NetworkId }
+
+// TokenPairConditions includes the GraphQL fields of TokenPairEventWebhookCondition requested by the fragment TokenPairConditions.
+// The GraphQL type's documentation follows.
+//
+// Webhook conditions for a token pair event.
+type TokenPairConditions struct {
+	// The list of network IDs the webhook is listening on.
+	NetworkId *TokenPairConditionsNetworkIdOneOfNumberCondition `json:"networkId"`
+	// The pair contract address the webhook is listening for.
+	PairAddress *TokenPairConditionsPairAddressStringEqualsCondition `json:"pairAddress"`
+	// The token contract address the webhook is listening for.
+	TokenAddress *TokenPairConditionsTokenAddressStringEqualsCondition `json:"tokenAddress"`
+	// The exchange contract address the webhook is listening for.
+	ExchangeAddress *TokenPairConditionsExchangeAddressStringEqualsCondition `json:"exchangeAddress"`
+	// The maker wallet address the webhook is listening for.
+	Maker *TokenPairConditionsMakerStringEqualsCondition `json:"maker"`
+	// The event type the webhook is listening for.
+	EventType *TokenPairConditionsEventTypeTokenPairEventTypeCondition `json:"eventType"`
+	// The swap values the webhook is listening for.
+	SwapValue *TokenPairConditionsSwapValueComparisonOperator `json:"swapValue"`
+}
+
+// GetNetworkId returns TokenPairConditions.NetworkId, and is useful for accessing the field via an interface.
+func (v *TokenPairConditions) GetNetworkId() *TokenPairConditionsNetworkIdOneOfNumberCondition {
 	return v.NetworkId
 }
 
-// GetMaker returns TokenPairEventWebhookConditionInput.Maker, and is useful for accessing the field via an interface.
-func (v *TokenPairEventWebhookConditionInput) GetMaker() StringEqualsConditionInput { return v.Maker }
-
-// GetPairAddress returns TokenPairEventWebhookConditionInput.PairAddress, and is useful for accessing the field via an interface.
-func (v *TokenPairEventWebhookConditionInput) GetPairAddress() StringEqualsConditionInput {
+// GetPairAddress returns TokenPairConditions.PairAddress, and is useful for accessing the field via an interface.
+func (v *TokenPairConditions) GetPairAddress() *TokenPairConditionsPairAddressStringEqualsCondition {
 	return v.PairAddress
 }
 
-// GetExchangeAddress returns TokenPairEventWebhookConditionInput.ExchangeAddress, and is useful for accessing the field via an interface.
-func (v *TokenPairEventWebhookConditionInput) GetExchangeAddress() StringEqualsConditionInput {
-	return v.ExchangeAddress
+// GetTokenAddress returns TokenPairConditions.TokenAddress, and is useful for accessing the field via an interface.
+func (v *TokenPairConditions) GetTokenAddress() *TokenPairConditionsTokenAddressStringEqualsCondition {
+	return v.TokenAddress
 }
 
-// GetTokenAddress returns TokenPairEventWebhookConditionInput.TokenAddress, and is useful for accessing the field via an interface.
-func (v *TokenPairEventWebhookConditionInput) GetTokenAddress() StringEqualsConditionInput {
-	return v.TokenAddress
+// GetExchangeAddress returns TokenPairConditions.ExchangeAddress, and is useful for accessing the field via an interface.
+func (v *TokenPairConditions) GetExchangeAddress() *TokenPairConditionsExchangeAddressStringEqualsCondition {
+	return v.ExchangeAddress
 }
 
-// GetSwapValue returns TokenPairEventWebhookConditionInput.SwapValue, and is useful for accessing the field via an interface.
-func (v *TokenPairEventWebhookConditionInput) GetSwapValue() ComparisonOperatorInput {
-	return v.SwapValue
+// GetMaker returns TokenPairConditions.Maker, and is useful for accessing the field via an interface.
+func (v *TokenPairConditions) GetMaker() *TokenPairConditionsMakerStringEqualsCondition {
+	return v.Maker
 }
 
-// GetEventType returns TokenPairEventWebhookConditionInput.EventType, and is useful for accessing the field via an interface.
-func (v *TokenPairEventWebhookConditionInput) GetEventType() TokenPairEventTypeConditionInput {
+// GetEventType returns TokenPairConditions.EventType, and is useful for accessing the field via an interface.
+func (v *TokenPairConditions) GetEventType() *TokenPairConditionsEventTypeTokenPairEventTypeCondition {
 	return v.EventType
 }
 
-// NFT marketplace names.
-type WebhookNftEventFillSource string
+// GetSwapValue returns TokenPairConditions.SwapValue, and is useful for accessing the field via an interface.
+func (v *TokenPairConditions) GetSwapValue() *TokenPairConditionsSwapValueComparisonOperator {
+	return v.SwapValue
+}
 
-const (
-	WebhookNftEventFillSourceGem            WebhookNftEventFillSource = "GEM"
-	WebhookNftEventFillSourceEnsvision      WebhookNftEventFillSource = "ENSVISION"
-	WebhookNftEventFillSourceBlur           WebhookNftEventFillSource = "BLUR"
-	WebhookNftEventFillSourceNftnerds       WebhookNftEventFillSource = "NFTNERDS"
-	WebhookNftEventFillSourceOpensea        WebhookNftEventFillSource = "OPENSEA"
-	WebhookNftEventFillSourceRarible        WebhookNftEventFillSource = "RARIBLE"
-	WebhookNftEventFillSourceFlipxyz        WebhookNftEventFillSource = "FLIPXYZ"
-	WebhookNftEventFillSourceEchelon        WebhookNftEventFillSource = "ECHELON"
-	WebhookNftEventFillSourceSoundxyz       WebhookNftEventFillSource = "SOUNDXYZ"
-	WebhookNftEventFillSourceCoinbase       WebhookNftEventFillSource = "COINBASE"
-	WebhookNftEventFillSourceMagiceden      WebhookNftEventFillSource = "MAGICEDEN"
-	WebhookNftEventFillSourceElement        WebhookNftEventFillSource = "ELEMENT"
-	WebhookNftEventFillSourceKodex          WebhookNftEventFillSource = "KODEX"
-	WebhookNftEventFillSourceReservoirtools WebhookNftEventFillSource = "RESERVOIRTOOLS"
-	WebhookNftEventFillSourceGenie          WebhookNftEventFillSource = "GENIE"
-)
+// TokenPairConditionsEventTypeTokenPairEventTypeCondition includes the requested fields of the GraphQL type TokenPairEventTypeCondition.
+// The GraphQL type's documentation follows.
+//
+// Webhook condition for token pair event type.
+type TokenPairConditionsEventTypeTokenPairEventTypeCondition struct {
+	// The list of token pair event types.
+	OneOf []TokenPairEventType `json:"oneOf"`
+}
 
-var AllWebhookNftEventFillSource = []WebhookNftEventFillSource{
+// GetOneOf returns TokenPairConditionsEventTypeTokenPairEventTypeCondition.OneOf, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsEventTypeTokenPairEventTypeCondition) GetOneOf() []TokenPairEventType {
+	return v.OneOf
+}
+
+// TokenPairConditionsExchangeAddressStringEqualsCondition includes the requested fields of the GraphQL type StringEqualsCondition.
+// The GraphQL type's documentation follows.
+//
+// String equals condition.
+type TokenPairConditionsExchangeAddressStringEqualsCondition struct {
+	// The string to equal.
+	Eq string `json:"eq"`
+}
+
+// GetEq returns TokenPairConditionsExchangeAddressStringEqualsCondition.Eq, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsExchangeAddressStringEqualsCondition) GetEq() string { return v.Eq }
+
+// TokenPairConditionsMakerStringEqualsCondition includes the requested fields of the GraphQL type StringEqualsCondition.
+// The GraphQL type's documentation follows.
+//
+// String equals condition.
+type TokenPairConditionsMakerStringEqualsCondition struct {
+	// The string to equal.
+	Eq string `json:"eq"`
+}
+
+// GetEq returns TokenPairConditionsMakerStringEqualsCondition.Eq, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsMakerStringEqualsCondition) GetEq() string { return v.Eq }
+
+// TokenPairConditionsNetworkIdOneOfNumberCondition includes the requested fields of the GraphQL type OneOfNumberCondition.
+// The GraphQL type's documentation follows.
+//
+// Integer list condition.
+type TokenPairConditionsNetworkIdOneOfNumberCondition struct {
+	// The list of integers.
+	OneOf []int `json:"oneOf"`
+}
+
+// GetOneOf returns TokenPairConditionsNetworkIdOneOfNumberCondition.OneOf, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsNetworkIdOneOfNumberCondition) GetOneOf() []int { return v.OneOf }
+
+// TokenPairConditionsPairAddressStringEqualsCondition includes the requested fields of the GraphQL type StringEqualsCondition.
+// The GraphQL type's documentation follows.
+//
+// String equals condition.
+type TokenPairConditionsPairAddressStringEqualsCondition struct {
+	// The string to equal.
+	Eq string `json:"eq"`
+}
+
+// GetEq returns TokenPairConditionsPairAddressStringEqualsCondition.Eq, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsPairAddressStringEqualsCondition) GetEq() string { return v.Eq }
+
+// TokenPairConditionsSwapValueComparisonOperator includes the requested fields of the GraphQL type ComparisonOperator.
+// The GraphQL type's documentation follows.
+//
+// Comparison operators.
+type TokenPairConditionsSwapValueComparisonOperator struct {
+	// Greater than.
+	Gt *string `json:"gt"`
+	// Greater than or equal to.
+	Gte *string `json:"gte"`
+	// Less than.
+	Lt *string `json:"lt"`
+	// Less than or equal to.
+	Lte *string `json:"lte"`
+	// Equal to.
+	Eq *string `json:"eq"`
+}
+
+// GetGt returns TokenPairConditionsSwapValueComparisonOperator.Gt, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsSwapValueComparisonOperator) GetGt() *string { return v.Gt }
+
+// GetGte returns TokenPairConditionsSwapValueComparisonOperator.Gte, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsSwapValueComparisonOperator) GetGte() *string { return v.Gte }
+
+// GetLt returns TokenPairConditionsSwapValueComparisonOperator.Lt, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsSwapValueComparisonOperator) GetLt() *string { return v.Lt }
+
+// GetLte returns TokenPairConditionsSwapValueComparisonOperator.Lte, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsSwapValueComparisonOperator) GetLte() *string { return v.Lte }
+
+// GetEq returns TokenPairConditionsSwapValueComparisonOperator.Eq, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsSwapValueComparisonOperator) GetEq() *string { return v.Eq }
+
+// TokenPairConditionsTokenAddressStringEqualsCondition includes the requested fields of the GraphQL type StringEqualsCondition.
+// The GraphQL type's documentation follows.
+//
+// String equals condition.
+type TokenPairConditionsTokenAddressStringEqualsCondition struct {
+	// The string to equal.
+	Eq string `json:"eq"`
+}
+
+// GetEq returns TokenPairConditionsTokenAddressStringEqualsCondition.Eq, and is useful for accessing the field via an interface.
+func (v *TokenPairConditionsTokenAddressStringEqualsCondition) GetEq() string { return v.Eq }
+
+// Token pair event types.
+type TokenPairEventType string
+
+const (
+	TokenPairEventTypeSwap            TokenPairEventType = "SWAP"
+	TokenPairEventTypeMint            TokenPairEventType = "MINT"
+	TokenPairEventTypeBurn            TokenPairEventType = "BURN"
+	TokenPairEventTypeSync            TokenPairEventType = "SYNC"
+	TokenPairEventTypeBuy             TokenPairEventType = "BUY"
+	TokenPairEventTypeSell            TokenPairEventType = "SELL"
+	TokenPairEventTypeCollect         TokenPairEventType = "COLLECT"
+	TokenPairEventTypeCollectProtocol TokenPairEventType = "COLLECT_PROTOCOL"
+)
+
+var AllTokenPairEventType = []TokenPairEventType{
+	TokenPairEventTypeSwap,
+	TokenPairEventTypeMint,
+	TokenPairEventTypeBurn,
+	TokenPairEventTypeSync,
+	TokenPairEventTypeBuy,
+	TokenPairEventTypeSell,
+	TokenPairEventTypeCollect,
+	TokenPairEventTypeCollectProtocol,
+}
+
+// Input for token pair event type condition.
+type TokenPairEventTypeConditionInput struct {
+	// The list of token event types to equal.
+	OneOf []TokenPairEventType `json:"oneOf"`
+}
+
+// GetOneOf returns TokenPairEventTypeConditionInput.OneOf, and is useful for accessing the field via an interface.
+func (v *TokenPairEventTypeConditionInput) GetOneOf() []TokenPairEventType { return v.OneOf }
+
+// Input conditions for a token pair event webhook.
+type TokenPairEventWebhookConditionInput struct {
+	// A list of network IDs to listen on.
+	NetworkId *OneOfNumberConditionInput `json:"networkId"`
+	// The maker wallet address to listen for.
+	Maker *StringEqualsConditionInput `json:"maker"`
+	// The pair contract address to listen for.
+	PairAddress *StringEqualsConditionInput `json:"pairAddress"`
+	// The exchange contract address to listen for.
+	ExchangeAddress *StringEqualsConditionInput `json:"exchangeAddress"`
+	// The token contract address to listen for.
+	TokenAddress *StringEqualsConditionInput `json:"tokenAddress"`
+	// The swap values to listen for.
+	SwapValue *ComparisonOperatorInput `json:"swapValue"`
+	// The token event type to listen for.
+	EventType *TokenPairEventTypeConditionInput `json:"eventType"`
+}
+
+// GetNetworkId returns TokenPairEventWebhookConditionInput.NetworkId, and is useful for accessing the field via an interface.
+func (v *TokenPairEventWebhookConditionInput) GetNetworkId() *OneOfNumberConditionInput {
+	return v.NetworkId
+}
+
+// GetMaker returns TokenPairEventWebhookConditionInput.Maker, and is useful for accessing the field via an interface.
+func (v *TokenPairEventWebhookConditionInput) GetMaker() *StringEqualsConditionInput { return v.Maker }
+
+// GetPairAddress returns TokenPairEventWebhookConditionInput.PairAddress, and is useful for accessing the field via an interface.
+func (v *TokenPairEventWebhookConditionInput) GetPairAddress() *StringEqualsConditionInput {
+	return v.PairAddress
+}
+
+// GetExchangeAddress returns TokenPairEventWebhookConditionInput.ExchangeAddress, and is useful for accessing the field via an interface.
+func (v *TokenPairEventWebhookConditionInput) GetExchangeAddress() *StringEqualsConditionInput {
+	return v.ExchangeAddress
+}
+
+// GetTokenAddress returns TokenPairEventWebhookConditionInput.TokenAddress, and is useful for accessing the field via an interface.
+func (v *TokenPairEventWebhookConditionInput) GetTokenAddress() *StringEqualsConditionInput {
+	return v.TokenAddress
+}
+
+// GetSwapValue returns TokenPairEventWebhookConditionInput.SwapValue, and is useful for accessing the field via an interface.
+func (v *TokenPairEventWebhookConditionInput) GetSwapValue() *ComparisonOperatorInput {
+	return v.SwapValue
+}
+
+// GetEventType returns TokenPairEventWebhookConditionInput.EventType, and is useful for accessing the field via an interface.
+func (v *TokenPairEventWebhookConditionInput) GetEventType() *TokenPairEventTypeConditionInput {
+	return v.EventType
+}
+
+// UpdatedPrice includes the requested fields of the GraphQL type Price.
+// The GraphQL type's documentation follows.
+//
+// Real-time or historical prices for a token.
+type UpdatedPrice struct {
+	// The contract address of the token.
+	Address string `json:"address"`
+	// The network ID the token is deployed on.
+	NetworkId int `json:"networkId"`
+	// The token price in USD.
+	PriceUsd float64 `json:"priceUsd"`
+	// The unix timestamp for the price.
+	Timestamp *int `json:"timestamp"`
+	// The pool that emitted the swap generating this price
+	PoolAddress string `json:"poolAddress"`
+	// Ratio of how confident we are in the price
+	Confidence *float64 `json:"confidence"`
+}
+
+// GetAddress returns UpdatedPrice.Address, and is useful for accessing the field via an interface.
+func (v *UpdatedPrice) GetAddress() string { return v.Address }
+
+// GetNetworkId returns UpdatedPrice.NetworkId, and is useful for accessing the field via an interface.
+func (v *UpdatedPrice) GetNetworkId() int { return v.NetworkId }
+
+// GetPriceUsd returns UpdatedPrice.PriceUsd, and is useful for accessing the field via an interface.
+func (v *UpdatedPrice) GetPriceUsd() float64 { return v.PriceUsd }
+
+// GetTimestamp returns UpdatedPrice.Timestamp, and is useful for accessing the field via an interface.
+func (v *UpdatedPrice) GetTimestamp() *int { return v.Timestamp }
+
+// GetPoolAddress returns UpdatedPrice.PoolAddress, and is useful for accessing the field via an interface.
+func (v *UpdatedPrice) GetPoolAddress() string { return v.PoolAddress }
+
+// GetConfidence returns UpdatedPrice.Confidence, and is useful for accessing the field via an interface.
+func (v *UpdatedPrice) GetConfidence() *float64 { return v.Confidence }
+
+// Webhook includes the requested fields of the GraphQL type Webhook.
+// The GraphQL type's documentation follows.
+//
+// Metadata for a webhook.
+type Webhook struct {
+	// The ID of the webhook.
+	Id string `json:"id"`
+	// The given name of the webhook.
+	Name string `json:"name"`
+	// The type of webhook. Can be `PRICE_EVENT`, `NFT_EVENT`, or `TOKEN_PAIR_EVENT`.
+	WebhookType WebhookType `json:"webhookType"`
+	// The url to which the webhook message should be sent.
+	CallbackUrl string `json:"callbackUrl"`
+	// The status of the webhook. Can be `ACTIVE` or `INACTIVE`.
+	Status string `json:"status"`
+	// The unix timestamp for the time the webhook was created.
+	Created int `json:"created"`
+	// An optional bucket ID (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
+	BucketId *string `json:"bucketId"`
+	// An optional bucket sort key (max 64 characters). Can be used to query for subgroups of webhooks (useful if you have a large number of webhooks).
+	BucketSortkey *string `json:"bucketSortkey"`
+	// The conditions which must be met in order for the webhook to send a message.
+	Conditions WebhookConditionsWebhookCondition `json:"-"`
+}
+
+// GetId returns Webhook.Id, and is useful for accessing the field via an interface.
+func (v *Webhook) GetId() string { return v.Id }
+
+// GetName returns Webhook.Name, and is useful for accessing the field via an interface.
+func (v *Webhook) GetName() string { return v.Name }
+
+// GetWebhookType returns Webhook.WebhookType, and is useful for accessing the field via an interface.
+func (v *Webhook) GetWebhookType() WebhookType { return v.WebhookType }
+
+// GetCallbackUrl returns Webhook.CallbackUrl, and is useful for accessing the field via an interface.
+func (v *Webhook) GetCallbackUrl() string { return v.CallbackUrl }
+
+// GetStatus returns Webhook.Status, and is useful for accessing the field via an interface.
+func (v *Webhook) GetStatus() string { return v.Status }
+
+// GetCreated returns Webhook.Created, and is useful for accessing the field via an interface.
+func (v *Webhook) GetCreated() int { return v.Created }
+
+// GetBucketId returns Webhook.BucketId, and is useful for accessing the field via an interface.
+func (v *Webhook) GetBucketId() *string { return v.BucketId }
+
+// GetBucketSortkey returns Webhook.BucketSortkey, and is useful for accessing the field via an interface.
+func (v *Webhook) GetBucketSortkey() *string { return v.BucketSortkey }
+
+// GetConditions returns Webhook.Conditions, and is useful for accessing the field via an interface.
+func (v *Webhook) GetConditions() WebhookConditionsWebhookCondition { return v.Conditions }
+
+func (v *Webhook) UnmarshalJSON(b []byte) error {
+
+	if string(b) == "null" {
+		return nil
+	}
+
+	var firstPass struct {
+		*Webhook
+		Conditions json.RawMessage `json:"conditions"`
+		graphql.NoUnmarshalJSON
+	}
+	firstPass.Webhook = v
+
+	err := json.Unmarshal(b, &firstPass)
+	if err != nil {
+		return err
+	}
+
+	{
+		dst := &v.Conditions
+		src := firstPass.Conditions
+		if len(src) != 0 && string(src) != "null" {
+			err = __unmarshalWebhookConditionsWebhookCondition(
+				src, dst)
+			if err != nil {
+				return fmt.Errorf(
+					"unable to unmarshal Webhook.Conditions: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+type __premarshalWebhook struct {
+	Id string `json:"id"`
+
+	Name string `json:"name"`
+
+	WebhookType WebhookType `json:"webhookType"`
+
+	CallbackUrl string `json:"callbackUrl"`
+
+	Status string `json:"status"`
+
+	Created int `json:"created"`
+
+	BucketId *string `json:"bucketId"`
+
+	BucketSortkey *string `json:"bucketSortkey"`
+
+	Conditions json.RawMessage `json:"conditions"`
+}
+
+func (v *Webhook) MarshalJSON() ([]byte, error) {
+	premarshaled, err := v.__premarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(premarshaled)
+}
+
+func (v *Webhook) __premarshalJSON() (*__premarshalWebhook, error) {
+	var retval __premarshalWebhook
+
+	retval.Id = v.Id
+	retval.Name = v.Name
+	retval.WebhookType = v.WebhookType
+	retval.CallbackUrl = v.CallbackUrl
+	retval.Status = v.Status
+	retval.Created = v.Created
+	retval.BucketId = v.BucketId
+	retval.BucketSortkey = v.BucketSortkey
+	{
+
+		dst := &retval.Conditions
+		src := v.Conditions
+		var err error
+		*dst, err = __marshalWebhookConditionsWebhookCondition(
+			&src)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to marshal Webhook.Conditions: %w", err)
+		}
+	}
+	return &retval, nil
+}
+
+// WebhookConditionsMarketCapEventWebhookCondition includes the requested fields of the GraphQL type MarketCapEventWebhookCondition.
+// The GraphQL type's documentation follows.
+//
+// Webhook conditions for a market cap event.
+type WebhookConditionsMarketCapEventWebhookCondition struct {
+	Typename *string `json:"__typename"`
+}
+
+// GetTypename returns WebhookConditionsMarketCapEventWebhookCondition.Typename, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsMarketCapEventWebhookCondition) GetTypename() *string { return v.Typename }
+
+// WebhookConditionsNftEventWebhookCondition includes the requested fields of the GraphQL type NftEventWebhookCondition.
+// The GraphQL type's documentation follows.
+//
+// Webhook conditions for an NFT event.
+type WebhookConditionsNftEventWebhookCondition struct {
+	Typename *string `json:"__typename"`
+}
+
+// GetTypename returns WebhookConditionsNftEventWebhookCondition.Typename, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsNftEventWebhookCondition) GetTypename() *string { return v.Typename }
+
+// WebhookConditionsPriceEventWebhookCondition includes the requested fields of the GraphQL type PriceEventWebhookCondition.
+// The GraphQL type's documentation follows.
+//
+// Webhook conditions for a price event.
+type WebhookConditionsPriceEventWebhookCondition struct {
+	Typename *string `json:"__typename"`
+}
+
+// GetTypename returns WebhookConditionsPriceEventWebhookCondition.Typename, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsPriceEventWebhookCondition) GetTypename() *string { return v.Typename }
+
+// WebhookConditionsRawTransactionWebhookCondition includes the requested fields of the GraphQL type RawTransactionWebhookCondition.
+// The GraphQL type's documentation follows.
+//
+// Webhook conditions for a raw transaction.
+type WebhookConditionsRawTransactionWebhookCondition struct {
+	Typename *string `json:"__typename"`
+}
+
+// GetTypename returns WebhookConditionsRawTransactionWebhookCondition.Typename, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsRawTransactionWebhookCondition) GetTypename() *string { return v.Typename }
+
+// WebhookConditionsTokenPairEventWebhookCondition includes the requested fields of the GraphQL type TokenPairEventWebhookCondition.
+// The GraphQL type's documentation follows.
+//
+// Webhook conditions for a token pair event.
+type WebhookConditionsTokenPairEventWebhookCondition struct {
+	Typename            *string `json:"__typename"`
+	TokenPairConditions `json:"-"`
+}
+
+// GetTypename returns WebhookConditionsTokenPairEventWebhookCondition.Typename, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsTokenPairEventWebhookCondition) GetTypename() *string { return v.Typename }
+
+// GetNetworkId returns WebhookConditionsTokenPairEventWebhookCondition.NetworkId, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsTokenPairEventWebhookCondition) GetNetworkId() *TokenPairConditionsNetworkIdOneOfNumberCondition {
+	return v.TokenPairConditions.NetworkId
+}
+
+// GetPairAddress returns WebhookConditionsTokenPairEventWebhookCondition.PairAddress, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsTokenPairEventWebhookCondition) GetPairAddress() *TokenPairConditionsPairAddressStringEqualsCondition {
+	return v.TokenPairConditions.PairAddress
+}
+
+// GetTokenAddress returns WebhookConditionsTokenPairEventWebhookCondition.TokenAddress, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsTokenPairEventWebhookCondition) GetTokenAddress() *TokenPairConditionsTokenAddressStringEqualsCondition {
+	return v.TokenPairConditions.TokenAddress
+}
+
+// GetExchangeAddress returns WebhookConditionsTokenPairEventWebhookCondition.ExchangeAddress, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsTokenPairEventWebhookCondition) GetExchangeAddress() *TokenPairConditionsExchangeAddressStringEqualsCondition {
+	return v.TokenPairConditions.ExchangeAddress
+}
+
+// GetMaker returns WebhookConditionsTokenPairEventWebhookCondition.Maker, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsTokenPairEventWebhookCondition) GetMaker() *TokenPairConditionsMakerStringEqualsCondition {
+	return v.TokenPairConditions.Maker
+}
+
+// GetEventType returns WebhookConditionsTokenPairEventWebhookCondition.EventType, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsTokenPairEventWebhookCondition) GetEventType() *TokenPairConditionsEventTypeTokenPairEventTypeCondition {
+	return v.TokenPairConditions.EventType
+}
+
+// GetSwapValue returns WebhookConditionsTokenPairEventWebhookCondition.SwapValue, and is useful for accessing the field via an interface.
+func (v *WebhookConditionsTokenPairEventWebhookCondition) GetSwapValue() *TokenPairConditionsSwapValueComparisonOperator {
+	return v.TokenPairConditions.SwapValue
+}
+
+func (v *WebhookConditionsTokenPairEventWebhookCondition) UnmarshalJSON(b []byte) error {
+
+	if string(b) == "null" {
+		return nil
+	}
+
+	var firstPass struct {
+		*WebhookConditionsTokenPairEventWebhookCondition
+		graphql.NoUnmarshalJSON
+	}
+	firstPass.WebhookConditionsTokenPairEventWebhookCondition = v
+
+	err := json.Unmarshal(b, &firstPass)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(
+		b, &v.TokenPairConditions)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type __premarshalWebhookConditionsTokenPairEventWebhookCondition struct {
+	Typename *string `json:"__typename"`
+
+	NetworkId *TokenPairConditionsNetworkIdOneOfNumberCondition `json:"networkId"`
+
+	PairAddress *TokenPairConditionsPairAddressStringEqualsCondition `json:"pairAddress"`
+
+	TokenAddress *TokenPairConditionsTokenAddressStringEqualsCondition `json:"tokenAddress"`
+
+	ExchangeAddress *TokenPairConditionsExchangeAddressStringEqualsCondition `json:"exchangeAddress"`
+
+	Maker *TokenPairConditionsMakerStringEqualsCondition `json:"maker"`
+
+	EventType *TokenPairConditionsEventTypeTokenPairEventTypeCondition `json:"eventType"`
+
+	SwapValue *TokenPairConditionsSwapValueComparisonOperator `json:"swapValue"`
+}
+
+func (v *WebhookConditionsTokenPairEventWebhookCondition) MarshalJSON() ([]byte, error) {
+	premarshaled, err := v.__premarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(premarshaled)
+}
+
+func (v *WebhookConditionsTokenPairEventWebhookCondition) __premarshalJSON() (*__premarshalWebhookConditionsTokenPairEventWebhookCondition, error) {
+	var retval __premarshalWebhookConditionsTokenPairEventWebhookCondition
+
+	retval.Typename = v.Typename
+	retval.NetworkId = v.TokenPairConditions.NetworkId
+	retval.PairAddress = v.TokenPairConditions.PairAddress
+	retval.TokenAddress = v.TokenPairConditions.TokenAddress
+	retval.ExchangeAddress = v.TokenPairConditions.ExchangeAddress
+	retval.Maker = v.TokenPairConditions.Maker
+	retval.EventType = v.TokenPairConditions.EventType
+	retval.SwapValue = v.TokenPairConditions.SwapValue
+	return &retval, nil
+}
+
+// WebhookConditionsWebhookCondition includes the requested fields of the GraphQL interface WebhookCondition.
+//
+// WebhookConditionsWebhookCondition is implemented by the following types:
+// WebhookConditionsMarketCapEventWebhookCondition
+// WebhookConditionsNftEventWebhookCondition
+// WebhookConditionsPriceEventWebhookCondition
+// WebhookConditionsRawTransactionWebhookCondition
+// WebhookConditionsTokenPairEventWebhookCondition
+// The GraphQL type's documentation follows.
+//
+// Webhook conditions that must be met for each webhook type.
+type WebhookConditionsWebhookCondition interface {
+	implementsGraphQLInterfaceWebhookConditionsWebhookCondition()
+	// GetTypename returns the receiver's concrete GraphQL type-name (see interface doc for possible values).
+	GetTypename() *string
+}
+
+func (v *WebhookConditionsMarketCapEventWebhookCondition) implementsGraphQLInterfaceWebhookConditionsWebhookCondition() {
+}
+func (v *WebhookConditionsNftEventWebhookCondition) implementsGraphQLInterfaceWebhookConditionsWebhookCondition() {
+}
+func (v *WebhookConditionsPriceEventWebhookCondition) implementsGraphQLInterfaceWebhookConditionsWebhookCondition() {
+}
+func (v *WebhookConditionsRawTransactionWebhookCondition) implementsGraphQLInterfaceWebhookConditionsWebhookCondition() {
+}
+func (v *WebhookConditionsTokenPairEventWebhookCondition) implementsGraphQLInterfaceWebhookConditionsWebhookCondition() {
+}
+
+func __unmarshalWebhookConditionsWebhookCondition(b []byte, v *WebhookConditionsWebhookCondition) error {
+	if string(b) == "null" {
+		return nil
+	}
+
+	var tn struct {
+		TypeName string `json:"__typename"`
+	}
+	err := json.Unmarshal(b, &tn)
+	if err != nil {
+		return err
+	}
+
+	switch tn.TypeName {
+	case "MarketCapEventWebhookCondition":
+		*v = new(WebhookConditionsMarketCapEventWebhookCondition)
+		return json.Unmarshal(b, *v)
+	case "NftEventWebhookCondition":
+		*v = new(WebhookConditionsNftEventWebhookCondition)
+		return json.Unmarshal(b, *v)
+	case "PriceEventWebhookCondition":
+		*v = new(WebhookConditionsPriceEventWebhookCondition)
+		return json.Unmarshal(b, *v)
+	case "RawTransactionWebhookCondition":
+		*v = new(WebhookConditionsRawTransactionWebhookCondition)
+		return json.Unmarshal(b, *v)
+	case "TokenPairEventWebhookCondition":
+		*v = new(WebhookConditionsTokenPairEventWebhookCondition)
+		return json.Unmarshal(b, *v)
+	case "":
+		return fmt.Errorf(
+			"response was missing WebhookCondition.__typename")
+	default:
+		return fmt.Errorf(
+			`unexpected concrete type for WebhookConditionsWebhookCondition: "%v"`, tn.TypeName)
+	}
+}
+
+func __marshalWebhookConditionsWebhookCondition(v *WebhookConditionsWebhookCondition) ([]byte, error) {
+
+	var typename string
+	switch v := (*v).(type) {
+	case *WebhookConditionsMarketCapEventWebhookCondition:
+		typename = "MarketCapEventWebhookCondition"
+
+		result := struct {
+			TypeName string `json:"__typename"`
+			*WebhookConditionsMarketCapEventWebhookCondition
+		}{typename, v}
+		return json.Marshal(result)
+	case *WebhookConditionsNftEventWebhookCondition:
+		typename = "NftEventWebhookCondition"
+
+		result := struct {
+			TypeName string `json:"__typename"`
+			*WebhookConditionsNftEventWebhookCondition
+		}{typename, v}
+		return json.Marshal(result)
+	case *WebhookConditionsPriceEventWebhookCondition:
+		typename = "PriceEventWebhookCondition"
+
+		result := struct {
+			TypeName string `json:"__typename"`
+			*WebhookConditionsPriceEventWebhookCondition
+		}{typename, v}
+		return json.Marshal(result)
+	case *WebhookConditionsRawTransactionWebhookCondition:
+		typename = "RawTransactionWebhookCondition"
+
+		result := struct {
+			TypeName string `json:"__typename"`
+			*WebhookConditionsRawTransactionWebhookCondition
+		}{typename, v}
+		return json.Marshal(result)
+	case *WebhookConditionsTokenPairEventWebhookCondition:
+		typename = "TokenPairEventWebhookCondition"
+
+		premarshaled, err := v.__premarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		result := struct {
+			TypeName string `json:"__typename"`
+			*__premarshalWebhookConditionsTokenPairEventWebhookCondition
+		}{typename, premarshaled}
+		return json.Marshal(result)
+	case nil:
+		return []byte("null"), nil
+	default:
+		return nil, fmt.Errorf(
+			`unexpected concrete type for WebhookConditionsWebhookCondition: "%T"`, v)
+	}
+}
+
+// NFT marketplace names.
+type WebhookNftEventFillSource string
+
+const (
+	WebhookNftEventFillSourceGem            WebhookNftEventFillSource = "GEM"
+	WebhookNftEventFillSourceEnsvision      WebhookNftEventFillSource = "ENSVISION"
+	WebhookNftEventFillSourceBlur           WebhookNftEventFillSource = "BLUR"
+	WebhookNftEventFillSourceNftnerds       WebhookNftEventFillSource = "NFTNERDS"
+	WebhookNftEventFillSourceOpensea        WebhookNftEventFillSource = "OPENSEA"
+	WebhookNftEventFillSourceRarible        WebhookNftEventFillSource = "RARIBLE"
+	WebhookNftEventFillSourceFlipxyz        WebhookNftEventFillSource = "FLIPXYZ"
+	WebhookNftEventFillSourceEchelon        WebhookNftEventFillSource = "ECHELON"
+	WebhookNftEventFillSourceSoundxyz       WebhookNftEventFillSource = "SOUNDXYZ"
+	WebhookNftEventFillSourceCoinbase       WebhookNftEventFillSource = "COINBASE"
+	WebhookNftEventFillSourceMagiceden      WebhookNftEventFillSource = "MAGICEDEN"
+	WebhookNftEventFillSourceElement        WebhookNftEventFillSource = "ELEMENT"
+	WebhookNftEventFillSourceKodex          WebhookNftEventFillSource = "KODEX"
+	WebhookNftEventFillSourceReservoirtools WebhookNftEventFillSource = "RESERVOIRTOOLS"
+	WebhookNftEventFillSourceGenie          WebhookNftEventFillSource = "GENIE"
+)
+
+var AllWebhookNftEventFillSource = []WebhookNftEventFillSource{
 	WebhookNftEventFillSourceGem,
 	WebhookNftEventFillSourceEnsvision,
 	WebhookNftEventFillSourceBlur,
@@ -919,45 +2167,251 @@ var AllWebhookNftEventFillSource = []WebhookNftEventFillSource{
 	WebhookNftEventFillSourceGenie,
 }
 
-// NFT event types.
-type WebhookNftEventType string
+// NFT event types.
+type WebhookNftEventType string
+
+const (
+	WebhookNftEventTypeMint     WebhookNftEventType = "MINT"
+	WebhookNftEventTypeSale     WebhookNftEventType = "SALE"
+	WebhookNftEventTypeTransfer WebhookNftEventType = "TRANSFER"
+)
+
+var AllWebhookNftEventType = []WebhookNftEventType{
+	WebhookNftEventTypeMint,
+	WebhookNftEventTypeSale,
+	WebhookNftEventTypeTransfer,
+}
+
+// The type of webhook.
+type WebhookType string
+
+const (
+	WebhookTypePriceEvent     WebhookType = "PRICE_EVENT"
+	WebhookTypeNftEvent       WebhookType = "NFT_EVENT"
+	WebhookTypeTokenPairEvent WebhookType = "TOKEN_PAIR_EVENT"
+	WebhookTypeRawTransaction WebhookType = "RAW_TRANSACTION"
+	WebhookTypeMarketCapEvent WebhookType = "MARKET_CAP_EVENT"
+)
+
+var AllWebhookType = []WebhookType{
+	WebhookTypePriceEvent,
+	WebhookTypeNftEvent,
+	WebhookTypeTokenPairEvent,
+	WebhookTypeRawTransaction,
+	WebhookTypeMarketCapEvent,
+}
+
+// __CreateApiTokensInput is used internally by genqlient
+type __CreateApiTokensInput struct {
+	Input CreateApiTokensInput `json:"input"`
+}
+
+// GetInput returns __CreateApiTokensInput.Input, and is useful for accessing the field via an interface.
+func (v *__CreateApiTokensInput) GetInput() CreateApiTokensInput { return v.Input }
+
+// __CreateWebhooksInput is used internally by genqlient
+type __CreateWebhooksInput struct {
+	Input CreateWebhooksInput `json:"input"`
+}
+
+// GetInput returns __CreateWebhooksInput.Input, and is useful for accessing the field via an interface.
+func (v *__CreateWebhooksInput) GetInput() CreateWebhooksInput { return v.Input }
+
+// __DeleteApiTokenInput is used internally by genqlient
+type __DeleteApiTokenInput struct {
+	Id string `json:"id"`
+}
+
+// GetId returns __DeleteApiTokenInput.Id, and is useful for accessing the field via an interface.
+func (v *__DeleteApiTokenInput) GetId() string { return v.Id }
+
+// __DeleteWebhooksInput is used internally by genqlient
+type __DeleteWebhooksInput struct {
+	Input DeleteWebhooksInput `json:"input"`
+}
+
+// GetInput returns __DeleteWebhooksInput.Input, and is useful for accessing the field via an interface.
+func (v *__DeleteWebhooksInput) GetInput() DeleteWebhooksInput { return v.Input }
+
+// __GetNetworkStatusInput is used internally by genqlient
+type __GetNetworkStatusInput struct {
+	NetworkIds []int `json:"networkIds"`
+}
+
+// GetNetworkIds returns __GetNetworkStatusInput.NetworkIds, and is useful for accessing the field via an interface.
+func (v *__GetNetworkStatusInput) GetNetworkIds() []int { return v.NetworkIds }
+
+// __GetTokenEventsInput is used internally by genqlient
+type __GetTokenEventsInput struct {
+	Query  EventsQueryInput `json:"query"`
+	Cursor *string          `json:"cursor"`
+	Limit  *int             `json:"limit"`
+}
+
+// GetQuery returns __GetTokenEventsInput.Query, and is useful for accessing the field via an interface.
+func (v *__GetTokenEventsInput) GetQuery() EventsQueryInput { return v.Query }
+
+// GetCursor returns __GetTokenEventsInput.Cursor, and is useful for accessing the field via an interface.
+func (v *__GetTokenEventsInput) GetCursor() *string { return v.Cursor }
+
+// GetLimit returns __GetTokenEventsInput.Limit, and is useful for accessing the field via an interface.
+func (v *__GetTokenEventsInput) GetLimit() *int { return v.Limit }
+
+// __GetTokensInput is used internally by genqlient
+type __GetTokensInput struct {
+	Ids []TokenInput `json:"ids"`
+}
+
+// GetIds returns __GetTokensInput.Ids, and is useful for accessing the field via an interface.
+func (v *__GetTokensInput) GetIds() []TokenInput { return v.Ids }
+
+// __GetWebhooksInput is used internally by genqlient
+type __GetWebhooksInput struct {
+	Cursor        *string `json:"cursor"`
+	Limit         *int    `json:"limit"`
+	WebhookId     *string `json:"webhookId"`
+	BucketId      *string `json:"bucketId"`
+	BucketSortkey *string `json:"bucketSortkey"`
+}
+
+// GetCursor returns __GetWebhooksInput.Cursor, and is useful for accessing the field via an interface.
+func (v *__GetWebhooksInput) GetCursor() *string { return v.Cursor }
 
-const (
-	WebhookNftEventTypeMint     WebhookNftEventType = "MINT"
-	WebhookNftEventTypeSale     WebhookNftEventType = "SALE"
-	WebhookNftEventTypeTransfer WebhookNftEventType = "TRANSFER"
-)
+// GetLimit returns __GetWebhooksInput.Limit, and is useful for accessing the field via an interface.
+func (v *__GetWebhooksInput) GetLimit() *int { return v.Limit }
 
-var AllWebhookNftEventType = []WebhookNftEventType{
-	WebhookNftEventTypeMint,
-	WebhookNftEventTypeSale,
-	WebhookNftEventTypeTransfer,
+// GetWebhookId returns __GetWebhooksInput.WebhookId, and is useful for accessing the field via an interface.
+func (v *__GetWebhooksInput) GetWebhookId() *string { return v.WebhookId }
+
+// GetBucketId returns __GetWebhooksInput.BucketId, and is useful for accessing the field via an interface.
+func (v *__GetWebhooksInput) GetBucketId() *string { return v.BucketId }
+
+// GetBucketSortkey returns __GetWebhooksInput.BucketSortkey, and is useful for accessing the field via an interface.
+func (v *__GetWebhooksInput) GetBucketSortkey() *string { return v.BucketSortkey }
+
+// __OnEventsCreatedInput is used internally by genqlient
+type __OnEventsCreatedInput struct {
+	Address   *string `json:"address"`
+	NetworkId *int    `json:"networkId"`
 }
 
-// __CreateWebhooksInput is used internally by genqlient
-type __CreateWebhooksInput struct {
-	Input CreateWebhooksInput `json:"input"`
+// GetAddress returns __OnEventsCreatedInput.Address, and is useful for accessing the field via an interface.
+func (v *__OnEventsCreatedInput) GetAddress() *string { return v.Address }
+
+// GetNetworkId returns __OnEventsCreatedInput.NetworkId, and is useful for accessing the field via an interface.
+func (v *__OnEventsCreatedInput) GetNetworkId() *int { return v.NetworkId }
+
+// __OnPricesUpdatedInput is used internally by genqlient
+type __OnPricesUpdatedInput struct {
+	Input []OnPricesUpdatedInput `json:"input"`
 }
 
-// GetInput returns __CreateWebhooksInput.Input, and is useful for accessing the field via an interface.
-func (v *__CreateWebhooksInput) GetInput() CreateWebhooksInput { return v.Input }
+// GetInput returns __OnPricesUpdatedInput.Input, and is useful for accessing the field via an interface.
+func (v *__OnPricesUpdatedInput) GetInput() []OnPricesUpdatedInput { return v.Input }
+
+// The query executed by ApiTokens.
+const ApiTokens_Operation = `
+query ApiTokens {
+	apiTokens {
+		... ApiToken
+	}
+}
+fragment ApiToken on ApiToken {
+	id
+	token
+	expiresTimeString
+	requestLimit
+	remaining
+}
+`
+
+func ApiTokens(
+	ctx_ context.Context,
+	client_ graphql.Client,
+) (data_ *ApiTokensResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "ApiTokens",
+		Query:  ApiTokens_Operation,
+	}
+
+	data_ = &ApiTokensResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The mutation executed by CreateApiTokens.
+const CreateApiTokens_Operation = `
+mutation CreateApiTokens ($input: CreateApiTokensInput!) {
+	createApiTokens(input: $input) {
+		... ApiToken
+	}
+}
+fragment ApiToken on ApiToken {
+	id
+	token
+	expiresTimeString
+	requestLimit
+	remaining
+}
+`
+
+func CreateApiTokens(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	input CreateApiTokensInput,
+) (data_ *CreateApiTokensResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "CreateApiTokens",
+		Query:  CreateApiTokens_Operation,
+		Variables: &__CreateApiTokensInput{
+			Input: input,
+		},
+	}
+
+	data_ = &CreateApiTokensResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
 
 // The mutation executed by CreateWebhooks.
 const CreateWebhooks_Operation = `
 mutation CreateWebhooks ($input: CreateWebhooksInput!) {
 	createWebhooks(input: $input) {
 		tokenPairEventWebhooks {
-			id
-			name
-			callbackUrl
-			status
-			groupId
-			bucketId
-			bucketSortkey
-			publishingType
+			... CreatedWebhook
+		}
+		priceWebhooks {
+			... CreatedWebhook
+		}
+		marketCapWebhooks {
+			... CreatedWebhook
 		}
 	}
 }
+fragment CreatedWebhook on Webhook {
+	id
+	name
+	callbackUrl
+	status
+	groupId
+	bucketId
+	bucketSortkey
+	publishingType
+}
 `
 
 func CreateWebhooks(
@@ -984,3 +2438,429 @@ func CreateWebhooks(
 
 	return data_, err_
 }
+
+// The mutation executed by DeleteApiToken.
+const DeleteApiToken_Operation = `
+mutation DeleteApiToken ($id: String!) {
+	deleteApiToken(id: $id)
+}
+`
+
+func DeleteApiToken(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	id string,
+) (data_ *DeleteApiTokenResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "DeleteApiToken",
+		Query:  DeleteApiToken_Operation,
+		Variables: &__DeleteApiTokenInput{
+			Id: id,
+		},
+	}
+
+	data_ = &DeleteApiTokenResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The mutation executed by DeleteWebhooks.
+const DeleteWebhooks_Operation = `
+mutation DeleteWebhooks ($input: DeleteWebhooksInput!) {
+	deleteWebhooks(input: $input) {
+		deletedIds
+	}
+}
+`
+
+func DeleteWebhooks(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	input DeleteWebhooksInput,
+) (data_ *DeleteWebhooksResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "DeleteWebhooks",
+		Query:  DeleteWebhooks_Operation,
+		Variables: &__DeleteWebhooksInput{
+			Input: input,
+		},
+	}
+
+	data_ = &DeleteWebhooksResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The query executed by GetNetworkStatus.
+const GetNetworkStatus_Operation = `
+query GetNetworkStatus ($networkIds: [Int!]!) {
+	getNetworkStatus(networkIds: $networkIds) {
+		networkId
+		networkName
+		lastProcessedBlock
+		lastProcessedTimestamp
+	}
+}
+`
+
+func GetNetworkStatus(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	networkIds []int,
+) (data_ *GetNetworkStatusResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "GetNetworkStatus",
+		Query:  GetNetworkStatus_Operation,
+		Variables: &__GetNetworkStatusInput{
+			NetworkIds: networkIds,
+		},
+	}
+
+	data_ = &GetNetworkStatusResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The query executed by GetTokenEvents.
+const GetTokenEvents_Operation = `
+query GetTokenEvents ($query: EventsQueryInput!, $cursor: String, $limit: Int) {
+	getTokenEvents(query: $query, cursor: $cursor, limit: $limit, direction: ASC) {
+		items {
+			address
+			eventType
+			eventDisplayType
+			liquidityToken
+			maker
+			networkId
+			quoteToken
+			timestamp
+			token0Address
+			token1Address
+			token0SwapValueUsd
+			token1SwapValueUsd
+			token0ValueBase
+			token1ValueBase
+			token0PoolValueUsd
+			token1PoolValueUsd
+			transactionHash
+			logIndex
+		}
+		cursor
+	}
+}
+`
+
+func GetTokenEvents(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	query EventsQueryInput,
+	cursor *string,
+	limit *int,
+) (data_ *GetTokenEventsResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "GetTokenEvents",
+		Query:  GetTokenEvents_Operation,
+		Variables: &__GetTokenEventsInput{
+			Query:  query,
+			Cursor: cursor,
+			Limit:  limit,
+		},
+	}
+
+	data_ = &GetTokenEventsResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The query executed by GetTokens.
+const GetTokens_Operation = `
+query GetTokens ($ids: [TokenInput!]) {
+	tokens(ids: $ids) {
+		address
+		networkId
+		name
+		symbol
+		decimals
+		imageSmallUrl
+		info {
+			imageSmallUrl
+		}
+	}
+}
+`
+
+func GetTokens(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	ids []TokenInput,
+) (data_ *GetTokensResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "GetTokens",
+		Query:  GetTokens_Operation,
+		Variables: &__GetTokensInput{
+			Ids: ids,
+		},
+	}
+
+	data_ = &GetTokensResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The query executed by GetWebhooks.
+const GetWebhooks_Operation = `
+query GetWebhooks ($cursor: String, $limit: Int, $webhookId: String, $bucketId: String, $bucketSortkey: String) {
+	getWebhooks(cursor: $cursor, limit: $limit, webhookId: $webhookId, bucketId: $bucketId, bucketSortkey: $bucketSortkey) {
+		items {
+			id
+			name
+			webhookType
+			callbackUrl
+			status
+			created
+			bucketId
+			bucketSortkey
+			conditions {
+				__typename
+				... on TokenPairEventWebhookCondition {
+					... TokenPairConditions
+				}
+			}
+		}
+		cursor
+	}
+}
+fragment TokenPairConditions on TokenPairEventWebhookCondition {
+	networkId {
+		oneOf
+	}
+	pairAddress {
+		eq
+	}
+	tokenAddress {
+		eq
+	}
+	exchangeAddress {
+		eq
+	}
+	maker {
+		eq
+	}
+	eventType {
+		oneOf
+	}
+	swapValue {
+		gt
+		gte
+		lt
+		lte
+		eq
+	}
+}
+`
+
+func GetWebhooks(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	cursor *string,
+	limit *int,
+	webhookId *string,
+	bucketId *string,
+	bucketSortkey *string,
+) (data_ *GetWebhooksResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "GetWebhooks",
+		Query:  GetWebhooks_Operation,
+		Variables: &__GetWebhooksInput{
+			Cursor:        cursor,
+			Limit:         limit,
+			WebhookId:     webhookId,
+			BucketId:      bucketId,
+			BucketSortkey: bucketSortkey,
+		},
+	}
+
+	data_ = &GetWebhooksResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
+// The subscription executed by OnEventsCreated.
+const OnEventsCreated_Operation = `
+subscription OnEventsCreated ($address: String, $networkId: Int) {
+	onEventsCreated(address: $address, networkId: $networkId) {
+		address
+		networkId
+		id
+		quoteToken
+		events {
+			address
+			eventType
+			eventDisplayType
+			liquidityToken
+			maker
+			networkId
+			quoteToken
+			timestamp
+			token0Address
+			token1Address
+			token0SwapValueUsd
+			token1SwapValueUsd
+			token0ValueBase
+			token1ValueBase
+			token0PoolValueUsd
+			token1PoolValueUsd
+			transactionHash
+			logIndex
+		}
+	}
+}
+`
+
+// To unsubscribe, use [graphql.WebSocketClient.Unsubscribe]
+func OnEventsCreated(
+	ctx_ context.Context,
+	client_ graphql.WebSocketClient,
+	address *string,
+	networkId *int,
+) (dataChan_ chan OnEventsCreatedWsResponse, subscriptionID_ string, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "OnEventsCreated",
+		Query:  OnEventsCreated_Operation,
+		Variables: &__OnEventsCreatedInput{
+			Address:   address,
+			NetworkId: networkId,
+		},
+	}
+
+	dataChan_ = make(chan OnEventsCreatedWsResponse)
+	subscriptionID_, err_ = client_.Subscribe(req_, dataChan_, OnEventsCreatedForwardData)
+
+	return dataChan_, subscriptionID_, err_
+}
+
+type OnEventsCreatedWsResponse graphql.BaseResponse[*OnEventsCreatedResponse]
+
+func OnEventsCreatedForwardData(interfaceChan interface{}, jsonRawMsg json.RawMessage) error {
+	var gqlResp graphql.Response
+	var wsResp OnEventsCreatedWsResponse
+	err := json.Unmarshal(jsonRawMsg, &gqlResp)
+	if err != nil {
+		return err
+	}
+	if len(gqlResp.Errors) == 0 {
+		err = json.Unmarshal(jsonRawMsg, &wsResp)
+		if err != nil {
+			return err
+		}
+	} else {
+		wsResp.Errors = gqlResp.Errors
+	}
+	dataChan_, ok := interfaceChan.(chan OnEventsCreatedWsResponse)
+	if !ok {
+		return errors.New("failed to cast interface into 'chan OnEventsCreatedWsResponse'")
+	}
+	dataChan_ <- wsResp
+	return nil
+}
+
+// The subscription executed by OnPricesUpdated.
+const OnPricesUpdated_Operation = `
+subscription OnPricesUpdated ($input: [OnPricesUpdatedInput!]!) {
+	onPricesUpdated(input: $input) {
+		address
+		networkId
+		priceUsd
+		timestamp
+		poolAddress
+		confidence
+	}
+}
+`
+
+// To unsubscribe, use [graphql.WebSocketClient.Unsubscribe]
+func OnPricesUpdated(
+	ctx_ context.Context,
+	client_ graphql.WebSocketClient,
+	input []OnPricesUpdatedInput,
+) (dataChan_ chan OnPricesUpdatedWsResponse, subscriptionID_ string, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "OnPricesUpdated",
+		Query:  OnPricesUpdated_Operation,
+		Variables: &__OnPricesUpdatedInput{
+			Input: input,
+		},
+	}
+
+	dataChan_ = make(chan OnPricesUpdatedWsResponse)
+	subscriptionID_, err_ = client_.Subscribe(req_, dataChan_, OnPricesUpdatedForwardData)
+
+	return dataChan_, subscriptionID_, err_
+}
+
+type OnPricesUpdatedWsResponse graphql.BaseResponse[*OnPricesUpdatedResponse]
+
+func OnPricesUpdatedForwardData(interfaceChan interface{}, jsonRawMsg json.RawMessage) error {
+	var gqlResp graphql.Response
+	var wsResp OnPricesUpdatedWsResponse
+	err := json.Unmarshal(jsonRawMsg, &gqlResp)
+	if err != nil {
+		return err
+	}
+	if len(gqlResp.Errors) == 0 {
+		err = json.Unmarshal(jsonRawMsg, &wsResp)
+		if err != nil {
+			return err
+		}
+	} else {
+		wsResp.Errors = gqlResp.Errors
+	}
+	dataChan_, ok := interfaceChan.(chan OnPricesUpdatedWsResponse)
+	if !ok {
+		return errors.New("failed to cast interface into 'chan OnPricesUpdatedWsResponse'")
+	}
+	dataChan_ <- wsResp
+	return nil
+}
@@ -0,0 +1,218 @@
+// Package enrich resolves token metadata through Codex for ingest to attach
+// to broadcast events.
+package enrich
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+// maxBatch is the most tokens looked up in one Codex request.
+const maxBatch = 50
+
+type Options struct {
+	// Size is how many tokens are cached. Defaults to 10000.
+	Size int
+	// TTL is how long metadata is used before it is looked up again. Expired
+	// metadata is still attached while the new lookup runs or if it fails.
+	// Defaults to 1h.
+	TTL time.Duration
+	// MissingTTL is how long a token Codex doesn't know is remembered.
+	// Defaults to 5m.
+	MissingTTL time.Duration
+	// Wait bounds how long publishing waits for lookups. Lookups finishing
+	// later are cached for the next events. Defaults to 250ms.
+	Wait time.Duration
+	// Timeout bounds each Codex request. Defaults to 5s.
+	Timeout time.Duration
+	// Backoff is how long lookups are skipped after a Codex request failed.
+	// Defaults to 30s.
+	Backoff time.Duration
+}
+
+// Enricher implements ingest.Enricher with an LRU cache in front of the
+// Codex tokens query. Concurrent lookups of a token share one request.
+type Enricher struct {
+	client graphql.Client
+	opts   Options
+
+	mu       sync.Mutex
+	cache    *lru
+	inflight map[ingest.TokenKey]*flight
+	// backoffUntil is when lookups resume after a failure.
+	backoffUntil time.Time
+}
+
+// flight is a lookup in progress. entry is set before done is closed.
+type flight struct {
+	done  chan struct{}
+	entry entry
+}
+
+func New(client graphql.Client, opts Options) *Enricher {
+	if opts.Size == 0 {
+		opts.Size = 10000
+	}
+	if opts.TTL == 0 {
+		opts.TTL = time.Hour
+	}
+	if opts.MissingTTL == 0 {
+		opts.MissingTTL = 5 * time.Minute
+	}
+	if opts.Wait == 0 {
+		opts.Wait = 250 * time.Millisecond
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.Backoff == 0 {
+		opts.Backoff = 30 * time.Second
+	}
+	return &Enricher{
+		client:   client,
+		opts:     opts,
+		cache:    newLRU(opts.Size),
+		inflight: make(map[ingest.TokenKey]*flight),
+	}
+}
+
+// Tokens returns the metadata of the keys that are cached or looked up within
+// Options.Wait.
+func (e *Enricher) Tokens(ctx context.Context, keys []ingest.TokenKey) map[ingest.TokenKey]ingest.TokenMetadata {
+	resolved := make(map[ingest.TokenKey]ingest.TokenMetadata, len(keys))
+	waiting := make(map[ingest.TokenKey]*flight)
+	var lookup []ingest.TokenKey
+
+	now := time.Now()
+	e.mu.Lock()
+	for _, key := range keys {
+		if key.Address == "" {
+			continue
+		}
+		if _, ok := resolved[key]; ok {
+			continue
+		}
+		if _, ok := waiting[key]; ok {
+			continue
+		}
+
+		cached, isCached := e.cache.get(key)
+		if isCached && cached.found {
+			resolved[key] = cached.metadata
+		}
+		if isCached && now.Before(cached.expires) {
+			continue
+		}
+		f, inFlight := e.inflight[key]
+		if !inFlight {
+			if now.Before(e.backoffUntil) {
+				continue
+			}
+			f = &flight{done: make(chan struct{})}
+			e.inflight[key] = f
+			lookup = append(lookup, key)
+		}
+		if !isCached || !cached.found {
+			// Expired metadata is served rather than waited on.
+			waiting[key] = f
+		}
+	}
+	e.mu.Unlock()
+
+	if len(lookup) > 0 {
+		go e.lookup(lookup)
+	}
+	if len(waiting) == 0 {
+		return resolved
+	}
+
+	timer := time.NewTimer(e.opts.Wait)
+	defer timer.Stop()
+	for key, f := range waiting {
+		select {
+		case <-f.done:
+			if f.entry.found {
+				resolved[key] = f.entry.metadata
+			}
+		case <-timer.C:
+			return resolved
+		case <-ctx.Done():
+			return resolved
+		}
+	}
+	return resolved
+}
+
+// lookup resolves keys through Codex and completes their flights. When Codex
+// fails, cached entries are left as they are and lookups back off.
+func (e *Enricher) lookup(keys []ingest.TokenKey) {
+	for start := 0; start < len(keys); start += maxBatch {
+		batch := keys[start:min(start+maxBatch, len(keys))]
+		tokens, err := e.fetch(batch)
+
+		e.mu.Lock()
+		now := time.Now()
+		if err != nil {
+			if now.After(e.backoffUntil) {
+				log.Warn().Err(err).Dur("backoff", e.opts.Backoff).Msg("failed to look up token metadata")
+			}
+			e.backoffUntil = now.Add(e.opts.Backoff)
+		}
+		for _, key := range batch {
+			result := entry{key: key, expires: now.Add(e.opts.MissingTTL)}
+			if err != nil {
+				result, _ = e.cache.get(key)
+			} else {
+				for _, token := range tokens {
+					if token != nil && token.NetworkId == key.NetworkID && strings.EqualFold(token.Address, key.Address) {
+						result = entry{key: key, metadata: metadata(token), found: true, expires: now.Add(e.opts.TTL)}
+						break
+					}
+				}
+				e.cache.put(result)
+			}
+			f := e.inflight[key]
+			delete(e.inflight, key)
+			f.entry = result
+			close(f.done)
+		}
+		e.mu.Unlock()
+	}
+}
+
+func (e *Enricher) fetch(keys []ingest.TokenKey) ([]*codex.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.opts.Timeout)
+	defer cancel()
+	ids := make([]codex.TokenInput, len(keys))
+	for i, key := range keys {
+		ids[i] = codex.TokenInput{Address: key.Address, NetworkId: key.NetworkID}
+	}
+	resp, err := codex.GetTokens(ctx, e.client, ids)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Tokens, nil
+}
+
+func metadata(token *codex.Token) ingest.TokenMetadata {
+	m := ingest.TokenMetadata{Decimals: token.Decimals}
+	if token.Name != nil {
+		m.Name = *token.Name
+	}
+	if token.Symbol != nil {
+		m.Symbol = *token.Symbol
+	}
+	if token.ImageSmallUrl != nil {
+		m.ImageUrl = *token.ImageSmallUrl
+	} else if token.Info != nil && token.Info.ImageSmallUrl != nil {
+		m.ImageUrl = *token.Info.ImageSmallUrl
+	}
+	return m
+}
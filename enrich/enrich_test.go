@@ -0,0 +1,293 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Khan/genqlient/graphql"
+)
+
+// fakeClient answers the tokens query with the names in tokens and counts
+// the requests it gets. While hold is set, requests wait for it to close.
+type fakeClient struct {
+	mu       sync.Mutex
+	tokens   map[ingest.TokenKey]string
+	err      error
+	hold     chan struct{}
+	requests int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{tokens: make(map[ingest.TokenKey]string)}
+}
+
+func (c *fakeClient) MakeRequest(ctx context.Context, req *graphql.Request, resp *graphql.Response) error {
+	c.mu.Lock()
+	c.requests++
+	hold := c.hold
+	c.mu.Unlock()
+	if hold != nil {
+		<-hold
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	encoded, err := json.Marshal(req.Variables)
+	if err != nil {
+		return err
+	}
+	vars := struct {
+		Ids []codex.TokenInput `json:"ids"`
+	}{}
+	if err := json.Unmarshal(encoded, &vars); err != nil {
+		return err
+	}
+	data := resp.Data.(*codex.GetTokensResponse)
+	for _, id := range vars.Ids {
+		for key, name := range c.tokens {
+			if key.NetworkID == id.NetworkId && strings.EqualFold(key.Address, id.Address) {
+				data.Tokens = append(data.Tokens, &codex.Token{Address: key.Address, NetworkId: key.NetworkID, Name: codex.Ptr(name)})
+			}
+		}
+	}
+	return nil
+}
+
+func (c *fakeClient) set(key ingest.TokenKey, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens[key] = name
+}
+
+func (c *fakeClient) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+// holdRequests makes requests wait until the returned func is called.
+func (c *fakeClient) holdRequests() func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hold := make(chan struct{})
+	c.hold = hold
+	return func() {
+		c.mu.Lock()
+		c.hold = nil
+		c.mu.Unlock()
+		close(hold)
+	}
+}
+
+func (c *fakeClient) requestCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests
+}
+
+var weth = ingest.TokenKey{Address: "0xWETH", NetworkID: 1}
+
+// waitFor polls until cond holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// cached waits for the lookup of key to finish.
+func cached(t *testing.T, e *Enricher, key ingest.TokenKey) {
+	t.Helper()
+	waitFor(t, "the lookup to finish", func() bool {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		_, ok := e.inflight[key]
+		return !ok
+	})
+}
+
+func TestLRUEviction(t *testing.T) {
+	c := newLRU(2)
+	a, b, d := ingest.TokenKey{Address: "a"}, ingest.TokenKey{Address: "b"}, ingest.TokenKey{Address: "d"}
+	c.put(entry{key: a})
+	c.put(entry{key: b})
+	c.get(a)
+	c.put(entry{key: d})
+	if _, ok := c.get(b); ok {
+		t.Fatal("least recently used entry was not evicted")
+	}
+	for _, key := range []ingest.TokenKey{a, d} {
+		if _, ok := c.get(key); !ok {
+			t.Fatalf("entry %s was evicted", key.Address)
+		}
+	}
+
+	c.put(entry{key: a, found: true})
+	if e, _ := c.get(a); !e.found || c.order.Len() != 2 {
+		t.Fatal("putting a cached key did not replace its entry")
+	}
+}
+
+func TestTokensCachesLookups(t *testing.T) {
+	client := newFakeClient()
+	client.set(weth, "Wrapped Ether")
+	e := New(client, Options{Wait: time.Second})
+
+	for range 2 {
+		got := e.Tokens(context.Background(), []ingest.TokenKey{weth, weth, {}})
+		if len(got) != 1 || got[weth].Name != "Wrapped Ether" {
+			t.Fatalf("got %+v", got)
+		}
+	}
+	if n := client.requestCount(); n != 1 {
+		t.Fatalf("made %d requests, want 1", n)
+	}
+}
+
+func TestTokensSharesLookups(t *testing.T) {
+	client := newFakeClient()
+	client.set(weth, "Wrapped Ether")
+	release := client.holdRequests()
+	e := New(client, Options{Wait: 5 * time.Second})
+
+	const callers = 5
+	results := make(chan map[ingest.TokenKey]ingest.TokenMetadata, callers)
+	for range callers {
+		go func() {
+			results <- e.Tokens(context.Background(), []ingest.TokenKey{weth})
+		}()
+	}
+	waitFor(t, "the lookup to start", func() bool { return client.requestCount() > 0 })
+	time.Sleep(20 * time.Millisecond)
+	release()
+
+	for range callers {
+		if got := <-results; got[weth].Name != "Wrapped Ether" {
+			t.Fatalf("got %+v", got)
+		}
+	}
+	if n := client.requestCount(); n != 1 {
+		t.Fatalf("made %d requests, want 1", n)
+	}
+}
+
+func TestTokensWait(t *testing.T) {
+	client := newFakeClient()
+	client.set(weth, "Wrapped Ether")
+	release := client.holdRequests()
+	e := New(client, Options{Wait: 20 * time.Millisecond})
+
+	start := time.Now()
+	if got := e.Tokens(context.Background(), []ingest.TokenKey{weth}); len(got) != 0 {
+		t.Fatalf("got %+v before the lookup finished", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("waited %s, want about Wait", elapsed)
+	}
+
+	// The late lookup is cached for the next events.
+	release()
+	cached(t, e, weth)
+	if got := e.Tokens(context.Background(), []ingest.TokenKey{weth}); got[weth].Name != "Wrapped Ether" {
+		t.Fatalf("got %+v", got)
+	}
+	if n := client.requestCount(); n != 1 {
+		t.Fatalf("made %d requests, want 1", n)
+	}
+}
+
+func TestTokensServesExpired(t *testing.T) {
+	client := newFakeClient()
+	client.set(weth, "Wrapped Ether")
+	e := New(client, Options{TTL: 10 * time.Millisecond, Wait: time.Second})
+	e.Tokens(context.Background(), []ingest.TokenKey{weth})
+	time.Sleep(20 * time.Millisecond)
+
+	client.set(weth, "Wrapped Ether v2")
+	release := client.holdRequests()
+	// Expired metadata is served without waiting for the lookup.
+	if got := e.Tokens(context.Background(), []ingest.TokenKey{weth}); got[weth].Name != "Wrapped Ether" {
+		t.Fatalf("got %+v, want the expired metadata", got)
+	}
+	release()
+	cached(t, e, weth)
+	if got := e.Tokens(context.Background(), []ingest.TokenKey{weth}); got[weth].Name != "Wrapped Ether v2" {
+		t.Fatalf("got %+v, want the refreshed metadata", got)
+	}
+	if n := client.requestCount(); n != 2 {
+		t.Fatalf("made %d requests, want 2", n)
+	}
+}
+
+func TestTokensMissingTTL(t *testing.T) {
+	client := newFakeClient()
+	e := New(client, Options{MissingTTL: 30 * time.Millisecond, Wait: time.Second})
+
+	for range 2 {
+		if got := e.Tokens(context.Background(), []ingest.TokenKey{weth}); len(got) != 0 {
+			t.Fatalf("got %+v for an unknown token", got)
+		}
+	}
+	if n := client.requestCount(); n != 1 {
+		t.Fatalf("made %d requests within MissingTTL, want 1", n)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	client.set(weth, "Wrapped Ether")
+	if got := e.Tokens(context.Background(), []ingest.TokenKey{weth}); got[weth].Name != "Wrapped Ether" {
+		t.Fatalf("got %+v after MissingTTL", got)
+	}
+	if n := client.requestCount(); n != 2 {
+		t.Fatalf("made %d requests, want 2", n)
+	}
+}
+
+func TestTokensBackoff(t *testing.T) {
+	client := newFakeClient()
+	client.set(weth, "Wrapped Ether")
+	e := New(client, Options{TTL: 10 * time.Millisecond, Backoff: 50 * time.Millisecond, Wait: time.Second})
+	e.Tokens(context.Background(), []ingest.TokenKey{weth})
+	time.Sleep(20 * time.Millisecond)
+
+	client.fail(errors.New("codex unavailable"))
+	usdc := ingest.TokenKey{Address: "0xUSDC", NetworkID: 1}
+	got := e.Tokens(context.Background(), []ingest.TokenKey{weth})
+	cached(t, e, weth)
+	if got[weth].Name != "Wrapped Ether" {
+		t.Fatalf("got %+v, want the expired metadata while Codex fails", got)
+	}
+
+	// Lookups are skipped while backing off, and cached metadata is kept.
+	got = e.Tokens(context.Background(), []ingest.TokenKey{weth, usdc})
+	if len(got) != 1 || got[weth].Name != "Wrapped Ether" {
+		t.Fatalf("got %+v while backing off", got)
+	}
+	if n := client.requestCount(); n != 2 {
+		t.Fatalf("made %d requests while backing off, want 2", n)
+	}
+
+	client.fail(nil)
+	client.set(usdc, "USD Coin")
+	time.Sleep(60 * time.Millisecond)
+	got = e.Tokens(context.Background(), []ingest.TokenKey{weth, usdc})
+	if got[usdc].Name != "USD Coin" {
+		t.Fatalf("got %+v after the backoff", got)
+	}
+	if n := client.requestCount(); n != 3 {
+		t.Fatalf("made %d requests, want 3", n)
+	}
+}
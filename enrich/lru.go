@@ -0,0 +1,56 @@
+package enrich
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+)
+
+// entry is a cached lookup. found is false for tokens Codex doesn't know.
+type entry struct {
+	key      ingest.TokenKey
+	metadata ingest.TokenMetadata
+	found    bool
+	expires  time.Time
+}
+
+// lru is a least recently used cache of lookups. Expired entries are kept
+// until evicted so they can be served while Codex is unavailable. It is not
+// safe for concurrent use.
+type lru struct {
+	size    int
+	order   *list.List
+	entries map[ingest.TokenKey]*list.Element
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[ingest.TokenKey]*list.Element),
+	}
+}
+
+func (c *lru) get(key ingest.TokenKey) (entry, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return entry{}, false
+	}
+	c.order.MoveToFront(element)
+	return *element.Value.(*entry), true
+}
+
+func (c *lru) put(e entry) {
+	if element, ok := c.entries[e.key]; ok {
+		*element.Value.(*entry) = e
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[e.key] = c.order.PushFront(&e)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
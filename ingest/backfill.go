@@ -0,0 +1,61 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+)
+
+// SourceCodexBackfill is the envelope source of events recovered from Codex's
+// event history after they were missed live.
+const SourceCodexBackfill = "codex-backfill"
+
+// Tracker sees every live token pair event before it is published, so it can
+// record progress per pair, notice gaps and drop events already published.
+type Tracker interface {
+	// Track returns the events of data that weren't published before.
+	Track(data []TokenPairEventData) []TokenPairEventData
+}
+
+// UseTracker passes live token pair events through tracker before they are
+// published.
+func (h *Handler) UseTracker(tracker Tracker) {
+	h.tracker = tracker
+}
+
+// Observe registers observe to be called with the token pair events of every
+// live delivery, before they are deduplicated. It must not block.
+func (h *Handler) Observe(observe func(data []TokenPairEventData)) {
+	h.observers = append(h.observers, observe)
+}
+
+func (h *Handler) track(body *TokenPairWebhookBody) {
+	for _, observe := range h.observers {
+		observe(body.Data)
+	}
+	if h.tracker != nil {
+		body.Data = h.tracker.Track(body.Data)
+	}
+}
+
+// PublishBackfill publishes events recovered from history to the hub routed
+// for token pair events, flagged as backfill. They don't pass through the
+// Tracker, so callers must drop events already published themselves.
+func (h *Handler) PublishBackfill(data []TokenPairEventData) error {
+	if len(data) == 0 {
+		return nil
+	}
+	body := TokenPairWebhookBody{
+		DeduplicationID: fmt.Sprintf("backfill:%s:%s:%d", data[0].Pair.ID, data[0].Event.TransactionHash, data[0].Event.LogIndex),
+		Type:            WebhookTypeTokenPairEvent,
+		Data:            data,
+	}
+	h.enrichTokenPairs(context.Background(), &body)
+	publications, err := splitTokenPairs(body)
+	if err != nil {
+		return err
+	}
+	for i := range publications {
+		publications[i].backfill = true
+	}
+	return h.publishRouted(WebhookTypeTokenPairEvent, SourceCodexBackfill, publications)
+}
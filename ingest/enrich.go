@@ -0,0 +1,91 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Enricher resolves the metadata of tokens in published events. It must
+// return promptly; tokens it can't resolve in time are left out of the
+// result and published without metadata.
+type Enricher interface {
+	Tokens(ctx context.Context, keys []TokenKey) map[TokenKey]TokenMetadata
+}
+
+// UseEnricher attaches token metadata resolved by enricher to token pair and
+// price events before they are published.
+func (h *Handler) UseEnricher(enricher Enricher) {
+	h.enricher = enricher
+}
+
+func (h *Handler) enrichTokenPairs(ctx context.Context, body *TokenPairWebhookBody) {
+	if h.enricher == nil {
+		return
+	}
+	keys := make([]TokenKey, 0, 2*len(body.Data))
+	for _, data := range body.Data {
+		keys = append(keys,
+			TokenKey{Address: data.Pair.Token0, NetworkID: data.Pair.NetworkID},
+			TokenKey{Address: data.Pair.Token1, NetworkID: data.Pair.NetworkID},
+		)
+	}
+	resolved := h.enricher.Tokens(ctx, keys)
+	for i := range body.Data {
+		pair := &body.Data[i].Pair
+		if metadata, ok := resolved[TokenKey{Address: pair.Token0, NetworkID: pair.NetworkID}]; ok {
+			pair.Token0Metadata = &metadata
+		}
+		if metadata, ok := resolved[TokenKey{Address: pair.Token1, NetworkID: pair.NetworkID}]; ok {
+			pair.Token1Metadata = &metadata
+		}
+	}
+}
+
+func (h *Handler) enrichPrice(ctx context.Context, data *PriceEventData) {
+	if h.enricher == nil {
+		return
+	}
+	key := TokenKey{Address: data.TokenAddress, NetworkID: data.NetworkID}
+	if metadata, ok := h.enricher.Tokens(ctx, []TokenKey{key})[key]; ok {
+		data.Token = &metadata
+	}
+}
+
+// enrichRawPrice adds the token to the data of a PRICE_EVENT delivery
+// forwarded as received, leaving its other fields untouched. body is returned
+// unchanged if it can't be enriched.
+func (h *Handler) enrichRawPrice(ctx context.Context, body []byte) []byte {
+	if h.enricher == nil {
+		return body
+	}
+	delivery := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &delivery); err != nil {
+		return body
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(delivery["data"], &fields); err != nil {
+		return body
+	}
+	data := PriceEventData{}
+	if err := json.Unmarshal(delivery["data"], &data); err != nil || data.TokenAddress == "" {
+		return body
+	}
+
+	h.enrichPrice(ctx, &data)
+	if data.Token == nil {
+		return body
+	}
+	token, err := json.Marshal(data.Token)
+	if err != nil {
+		return body
+	}
+	fields["token"] = token
+	if delivery["data"], err = json.Marshal(fields); err != nil {
+		return body
+	}
+	enriched, err := json.Marshal(delivery)
+	if err != nil {
+		return body
+	}
+	return enriched
+}
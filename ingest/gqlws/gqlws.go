@@ -0,0 +1,325 @@
+// Package gqlws is a client for GraphQL subscriptions over WebSocket using the
+// graphql-transport-ws protocol.
+package gqlws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Khan/genqlient/graphql"
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Subprotocol is the WebSocket subprotocol of graphql-transport-ws.
+const Subprotocol = "graphql-transport-ws"
+
+// Message types of graphql-transport-ws.
+const (
+	TypeConnectionInit = "connection_init"
+	TypeConnectionAck  = "connection_ack"
+	TypePing           = "ping"
+	TypePong           = "pong"
+	TypeSubscribe      = "subscribe"
+	TypeNext           = "next"
+	TypeError          = "error"
+	TypeComplete       = "complete"
+)
+
+var ErrClosed = errors.New("gqlws: connection closed")
+
+// Message is a graphql-transport-ws message.
+type Message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Result is one result of a subscription. Errors are the GraphQL errors sent
+// with a result, or the errors that ended the subscription. Done is set on
+// the last result of a subscription.
+type Result struct {
+	Data   json.RawMessage `json:"data"`
+	Errors gqlerror.List   `json:"errors,omitempty"`
+	Done   bool            `json:"-"`
+}
+
+type DialOptions struct {
+	Header http.Header
+	// InitPayload is sent with connection_init, e.g. to authenticate.
+	InitPayload any
+	// AckTimeout bounds the wait for connection_ack. Defaults to 10s.
+	AckTimeout time.Duration
+	// PingInterval is how often the client pings the server. Defaults to
+	// 30s.
+	PingInterval time.Duration
+	Dialer       *websocket.Dialer
+}
+
+// Conn is an acknowledged graphql-transport-ws connection.
+type Conn struct {
+	conn *websocket.Conn
+
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	nextID int
+	subs   map[string]func(Result)
+	err    error
+	done   chan struct{}
+}
+
+// Dial connects to url and completes the connection_init handshake.
+func Dial(ctx context.Context, url string, opts DialOptions) (*Conn, error) {
+	if opts.AckTimeout == 0 {
+		opts.AckTimeout = 10 * time.Second
+	}
+	if opts.PingInterval == 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	dialer := websocket.DefaultDialer
+	if opts.Dialer != nil {
+		dialer = opts.Dialer
+	}
+	withProtocol := *dialer
+	withProtocol.Subprotocols = []string{Subprotocol}
+
+	conn, resp, err := withProtocol.DialContext(ctx, url, opts.Header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to connect to [%s]: %s: %w", url, resp.Status, err)
+		}
+		return nil, fmt.Errorf("failed to connect to [%s]: %w", url, err)
+	}
+	if conn.Subprotocol() != Subprotocol {
+		conn.Close()
+		return nil, fmt.Errorf("server at [%s] does not speak %s", url, Subprotocol)
+	}
+
+	c := &Conn{
+		conn: conn,
+		subs: make(map[string]func(Result)),
+		done: make(chan struct{}),
+	}
+	if err := c.handshake(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go c.readLoop()
+	go c.pingLoop(opts.PingInterval)
+	return c, nil
+}
+
+func (c *Conn) handshake(opts DialOptions) error {
+	init := Message{Type: TypeConnectionInit}
+	if opts.InitPayload != nil {
+		payload, err := json.Marshal(opts.InitPayload)
+		if err != nil {
+			return fmt.Errorf("failed to encode connection_init payload: %w", err)
+		}
+		init.Payload = payload
+	}
+	if err := c.write(init); err != nil {
+		return err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(opts.AckTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return fmt.Errorf("failed waiting for connection_ack: %w", err)
+		}
+		switch msg.Type {
+		case TypeConnectionAck:
+			return nil
+		case TypePing:
+			if err := c.write(Message{Type: TypePong}); err != nil {
+				return err
+			}
+		case TypePong:
+		default:
+			return fmt.Errorf("unexpected %s message before connection_ack", msg.Type)
+		}
+	}
+}
+
+func (c *Conn) read() (Message, error) {
+	_, frame, err := c.conn.ReadMessage()
+	if err != nil {
+		return Message{}, err
+	}
+	msg := Message{}
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		return Message{}, fmt.Errorf("invalid message: %w", err)
+	}
+	return msg, nil
+}
+
+func (c *Conn) write(msg Message) error {
+	frame, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", msg.Type, err)
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+		return fmt.Errorf("failed to send %s message: %w", msg.Type, err)
+	}
+	return nil
+}
+
+// Subscribe starts the subscription in req. handle is called from the
+// connection's read loop with each result, so it must not block; it is not
+// called again after a result with Done set. It returns the subscription ID.
+func (c *Conn) Subscribe(req *graphql.Request, handle func(Result)) (string, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s subscription: %w", req.OpName, err)
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return "", c.err
+	}
+	c.nextID++
+	id := strconv.Itoa(c.nextID)
+	c.subs[id] = handle
+	c.mu.Unlock()
+
+	if err := c.write(Message{ID: id, Type: TypeSubscribe, Payload: payload}); err != nil {
+		c.mu.Lock()
+		delete(c.subs, id)
+		c.mu.Unlock()
+		return "", err
+	}
+	return id, nil
+}
+
+// Unsubscribe completes the subscription with the given ID.
+func (c *Conn) Unsubscribe(id string) error {
+	c.mu.Lock()
+	_, ok := c.subs[id]
+	delete(c.subs, id)
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return c.write(Message{ID: id, Type: TypeComplete})
+}
+
+// Done is closed when the connection ends. Err then returns why.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close closes the connection, ending every subscription.
+func (c *Conn) Close() error {
+	c.writeMu.Lock()
+	c.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second),
+	)
+	c.writeMu.Unlock()
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+func (c *Conn) readLoop() {
+	var err error
+	for {
+		var msg Message
+		msg, err = c.read()
+		if err != nil {
+			break
+		}
+		if err = c.handle(msg); err != nil {
+			break
+		}
+	}
+	c.conn.Close()
+
+	c.mu.Lock()
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		err = ErrClosed
+	}
+	c.err = err
+	subs := c.subs
+	c.subs = make(map[string]func(Result))
+	c.mu.Unlock()
+
+	for _, handle := range subs {
+		handle(Result{Errors: gqlerror.List{gqlerror.Wrap(err)}, Done: true})
+	}
+	close(c.done)
+}
+
+func (c *Conn) handle(msg Message) error {
+	switch msg.Type {
+	case TypePing:
+		return c.write(Message{Type: TypePong})
+	case TypePong:
+		return nil
+	case TypeNext, TypeError, TypeComplete:
+	default:
+		return fmt.Errorf("unexpected %s message", msg.Type)
+	}
+
+	c.mu.Lock()
+	handle, ok := c.subs[msg.ID]
+	if ok && msg.Type != TypeNext {
+		delete(c.subs, msg.ID)
+	}
+	c.mu.Unlock()
+	if !ok {
+		// Results may still arrive for a subscription we just completed.
+		return nil
+	}
+
+	result := Result{}
+	switch msg.Type {
+	case TypeNext:
+		if err := json.Unmarshal(msg.Payload, &result); err != nil {
+			return fmt.Errorf("invalid next payload for subscription %s: %w", msg.ID, err)
+		}
+	case TypeError:
+		if err := json.Unmarshal(msg.Payload, &result.Errors); err != nil {
+			return fmt.Errorf("invalid error payload for subscription %s: %w", msg.ID, err)
+		}
+		result.Done = true
+	case TypeComplete:
+		result.Done = true
+	}
+	handle(result)
+	return nil
+}
+
+func (c *Conn) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.write(Message{Type: TypePing}); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package gqlwsstub is a minimal in-process graphql-transport-ws server for
+// exercising subscription clients without Codex. It acknowledges connections
+// carrying the expected token, records subscriptions and lets the caller push
+// results to them by operation name.
+package gqlwsstub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/ingest/gqlws"
+	"github.com/gorilla/websocket"
+)
+
+// Subscription is a subscribe request received by the server.
+type Subscription struct {
+	ID            string
+	OperationName string
+	Query         string
+	Variables     json.RawMessage
+}
+
+type Server struct {
+	server   *httptest.Server
+	token    string
+	upgrader websocket.Upgrader
+
+	mu    sync.Mutex
+	conns map[*conn]struct{}
+}
+
+type conn struct {
+	ws   *websocket.Conn
+	mu   sync.Mutex
+	subs map[string]Subscription
+}
+
+func (c *conn) write(msg gqlws.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws.WriteJSON(msg)
+}
+
+// Start listens on a random local port. If token is non-empty, connections
+// must send it as the Authorization field of the connection_init payload.
+func Start(token string) *Server {
+	s := &Server{
+		token: token,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{gqlws.Subprotocol},
+			CheckOrigin:  func(r *http.Request) bool { return true },
+		},
+		conns: make(map[*conn]struct{}),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// URL returns the ws:// URL of the server.
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+// Close drops every connection and stops the server.
+func (s *Server) Close() {
+	s.DropConnections()
+	s.server.Close()
+}
+
+// DropConnections closes every client connection while keeping the server
+// listening, simulating a Codex restart.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.ws.Close()
+	}
+}
+
+// Subscriptions returns the active subscriptions of every connection.
+func (s *Server) Subscriptions() []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var subs []Subscription
+	for c := range s.conns {
+		c.mu.Lock()
+		for _, sub := range c.subs {
+			subs = append(subs, sub)
+		}
+		c.mu.Unlock()
+	}
+	return subs
+}
+
+// WaitForSubscriptions waits until there are at least n active
+// subscriptions, and reports whether there were before timeout.
+func (s *Server) WaitForSubscriptions(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if len(s.Subscriptions()) >= n {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+// Publish sends data as a next result to every subscription to the named
+// operation and returns how many it reached.
+func (s *Server) Publish(operationName string, data any) int {
+	payload, err := json.Marshal(map[string]any{"data": data})
+	if err != nil {
+		return 0
+	}
+	return s.each(operationName, func(c *conn, id string) error {
+		return c.write(gqlws.Message{ID: id, Type: gqlws.TypeNext, Payload: payload})
+	})
+}
+
+// Fail ends every subscription to the named operation with an error message.
+func (s *Server) Fail(operationName string, message string) int {
+	payload, _ := json.Marshal([]map[string]string{{"message": message}})
+	return s.each(operationName, func(c *conn, id string) error {
+		c.mu.Lock()
+		delete(c.subs, id)
+		c.mu.Unlock()
+		return c.write(gqlws.Message{ID: id, Type: gqlws.TypeError, Payload: payload})
+	})
+}
+
+// Ping sends a ping to every connection; clients must answer with a pong.
+func (s *Server) Ping() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.write(gqlws.Message{Type: gqlws.TypePing})
+	}
+}
+
+func (s *Server) each(operationName string, fn func(c *conn, id string) error) int {
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	reached := 0
+	for _, c := range conns {
+		c.mu.Lock()
+		var ids []string
+		for id, sub := range c.subs {
+			if sub.OperationName == operationName {
+				ids = append(ids, id)
+			}
+		}
+		c.mu.Unlock()
+		for _, id := range ids {
+			if fn(c, id) == nil {
+				reached++
+			}
+		}
+	}
+	return reached
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &conn{ws: ws, subs: make(map[string]Subscription)}
+	defer ws.Close()
+
+	if !s.handshake(c) {
+		ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(4403, "Forbidden"), time.Now().Add(time.Second))
+		return
+	}
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	for {
+		msg := gqlws.Message{}
+		if err := ws.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case gqlws.TypePing:
+			c.write(gqlws.Message{Type: gqlws.TypePong})
+		case gqlws.TypeSubscribe:
+			sub := Subscription{ID: msg.ID}
+			payload := struct {
+				OperationName string          `json:"operationName"`
+				Query         string          `json:"query"`
+				Variables     json.RawMessage `json:"variables"`
+			}{}
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				return
+			}
+			sub.OperationName = payload.OperationName
+			sub.Query = payload.Query
+			sub.Variables = payload.Variables
+			c.mu.Lock()
+			c.subs[msg.ID] = sub
+			c.mu.Unlock()
+		case gqlws.TypeComplete:
+			c.mu.Lock()
+			delete(c.subs, msg.ID)
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (s *Server) handshake(c *conn) bool {
+	msg := gqlws.Message{}
+	if err := c.ws.ReadJSON(&msg); err != nil || msg.Type != gqlws.TypeConnectionInit {
+		return false
+	}
+	if s.token != "" {
+		payload := struct {
+			Authorization string `json:"Authorization"`
+		}{}
+		if json.Unmarshal(msg.Payload, &payload) != nil || payload.Authorization != s.token {
+			return false
+		}
+	}
+	return c.write(gqlws.Message{Type: gqlws.TypeConnectionAck}) == nil
+}
@@ -0,0 +1,256 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// Hash returns the SHA 256 hash of "<secret><deduplicationId>" that Codex
+// sends with every delivery.
+func Hash(secret string, deduplicationID string) string {
+	h := sha256.New()
+	h.Write([]byte(secret + deduplicationID))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Handler verifies incoming Codex webhooks and broadcasts them to the hub
+// routed for their webhook type.
+type Handler struct {
+	secret    string
+	registry  *ws.Registry
+	routes    map[string]string
+	validate  *validator.Validate
+	stats     *Stats
+	enricher  Enricher
+	tracker   Tracker
+	owners    Owners
+	observers []func(data []TokenPairEventData)
+}
+
+// SourceCodexWebhook is the envelope source of events received through
+// Codex webhooks.
+const SourceCodexWebhook = "codex-webhook"
+
+// NewHandler creates a Handler. routes maps a webhook type (e.g.
+// WebhookTypeTokenPairEvent) to the name of a hub in registry.
+func NewHandler(secret string, registry *ws.Registry, routes map[string]string) *Handler {
+	return &Handler{
+		secret:   secret,
+		registry: registry,
+		routes:   routes,
+		validate: validator.New(),
+		stats:    NewStats(),
+	}
+}
+
+// Stats returns the per-webhook counts of deliveries received by ServeHTTP.
+func (h *Handler) Stats() *Stats {
+	return h.stats
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	header := WebhookHeader{}
+	if err := json.Unmarshal(body, &header); err != nil {
+		log.Warn().Err(err).Msg("Error parsing JSON")
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(header); err != nil {
+		log.Warn().Err(err).Msg("Validation error")
+		http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if Hash(h.secret, header.DeduplicationID) != header.Hash {
+		log.Warn().Str("deduplication_id", header.DeduplicationID).Msg("Hash mismatch")
+		http.Error(w, "Hash mismatch", http.StatusBadRequest)
+		return
+	}
+
+	// Deliveries without a type predate multi-hub routing and are token pair events.
+	webhookType := header.Type
+	if webhookType == "" {
+		webhookType = WebhookTypeTokenPairEvent
+	}
+
+	hubName, ok := h.routes[webhookType]
+	if !ok {
+		log.Warn().Str("type", webhookType).Msg("No hub routed for webhook type")
+		http.Error(w, "Unsupported webhook type", http.StatusBadRequest)
+		return
+	}
+
+	publications, err := h.decode(r.Context(), webhookType, body)
+	if err != nil {
+		log.Warn().Err(err).Str("type", webhookType).Msg("Validation error")
+		http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	owned, err := h.deliverOwned(r.Context(), header.WebhookID, webhookType, publications)
+	if err != nil {
+		log.Error().Err(err).Str("webhook_id", header.WebhookID).Msg("Failed to check webhook owner")
+		http.Error(w, "Failed to check webhook owner", http.StatusServiceUnavailable)
+		return
+	}
+	if !owned {
+		if err := h.publish(hubName, webhookType, SourceCodexWebhook, publications); err != nil {
+			log.Error().Err(err).Str("hub", hubName).Msg("Failed to broadcast webhook")
+			http.Error(w, "Failed to broadcast", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if header.WebhookID != "" {
+		events := 0
+		for _, publication := range publications {
+			events += publication.events
+		}
+		h.stats.Record(header.WebhookID, webhookType, events)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Data received and broadcasted"))
+}
+
+// PublishTokenPairs publishes body to the hub routed for token pair events as
+// if it had been delivered to ServeHTTP, with source as the envelope source.
+// body is not validated, so sources can leave out fields they don't have.
+func (h *Handler) PublishTokenPairs(source string, body TokenPairWebhookBody) error {
+	body.Type = WebhookTypeTokenPairEvent
+	h.track(&body)
+	h.enrichTokenPairs(context.Background(), &body)
+	publications, err := splitTokenPairs(body)
+	if err != nil {
+		return err
+	}
+	return h.publishRouted(WebhookTypeTokenPairEvent, source, publications)
+}
+
+// PublishPrice publishes body to the hub routed for price events, with source
+// as the envelope source.
+func (h *Handler) PublishPrice(source string, body PriceWebhookBody) error {
+	body.Type = WebhookTypePriceEvent
+	h.enrichPrice(context.Background(), &body.Data)
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return h.publishRouted(WebhookTypePriceEvent, source, []publication{{
+		key:    body.Data.TokenAddress,
+		data:   data,
+		events: 1,
+	}})
+}
+
+func (h *Handler) publishRouted(webhookType string, source string, publications []publication) error {
+	hubName, ok := h.routes[webhookType]
+	if !ok {
+		return fmt.Errorf("no hub routed for webhook type [%s]", webhookType)
+	}
+	return h.publish(hubName, webhookType, source, publications)
+}
+
+func (h *Handler) publish(hubName string, webhookType string, source string, publications []publication) error {
+	for _, publication := range publications {
+		// Events are published on a topic named after their webhook type,
+		// and token pair events also on their pair and token topics.
+		envelope := ws.NewEnvelope(ws.EnvelopeEvent, webhookType, publication.data)
+		envelope.Source = source
+		envelope.Backfill = publication.backfill
+		if _, err := h.registry.PublishKeyed(hubName, publication.key, envelope, publication.topics...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publication is one envelope's worth of data from a delivery. key is the
+// conflation key for latest-value subscribers, empty if the data can't be
+// conflated. topics are published to in addition to the webhook type's.
+// events is how many events data holds. backfill flags the envelopes.
+type publication struct {
+	key      string
+	topics   []string
+	data     json.RawMessage
+	events   int
+	backfill bool
+}
+
+// decode validates the typed body for webhook types we model and returns the
+// envelope data to broadcast. Token pair deliveries are split per pair so
+// they can be conflated by pair address. Other types are forwarded as
+// received, with price events enriched.
+func (h *Handler) decode(ctx context.Context, webhookType string, body []byte) ([]publication, error) {
+	if webhookType == WebhookTypePriceEvent {
+		return []publication{{data: h.enrichRawPrice(ctx, body), events: 1}}, nil
+	}
+	if webhookType != WebhookTypeTokenPairEvent {
+		return []publication{{data: body, events: 1}}, nil
+	}
+
+	verify := TokenPairWebhookBody{}
+	if err := json.Unmarshal(body, &verify); err != nil {
+		return nil, err
+	}
+	if err := h.validate.Struct(verify); err != nil {
+		return nil, err
+	}
+
+	h.track(&verify)
+	h.enrichTokenPairs(ctx, &verify)
+	return splitTokenPairs(verify)
+}
+
+// splitTokenPairs splits body into a publication per pair.
+func splitTokenPairs(body TokenPairWebhookBody) ([]publication, error) {
+	var pairs []string
+	byPair := make(map[string][]TokenPairEventData)
+	for _, data := range body.Data {
+		if _, ok := byPair[data.Pair.Address]; !ok {
+			pairs = append(pairs, data.Pair.Address)
+		}
+		byPair[data.Pair.Address] = append(byPair[data.Pair.Address], data)
+	}
+
+	publications := make([]publication, 0, len(pairs))
+	for _, pair := range pairs {
+		pairBody := body
+		pairBody.Data = byPair[pair]
+
+		// Remarshal the data to ensure it is in the correct format
+		buf := new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(pairBody); err != nil {
+			return nil, fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		info := pairBody.Data[0].Pair
+		publications = append(publications, publication{
+			key: pair,
+			topics: []string{
+				PairTopic(info.Address, info.NetworkID),
+				TokenTopic(info.Token0, info.NetworkID),
+				TokenTopic(info.Token1, info.NetworkID),
+			},
+			data:   buf.Bytes(),
+			events: len(pairBody.Data),
+		})
+	}
+	return publications, nil
+}
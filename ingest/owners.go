@@ -0,0 +1,39 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Owners claims the deliveries of webhooks that belong to a single user, such
+// as alerts, so they are sent to that user instead of being published on the
+// webhook type's topic.
+type Owners interface {
+	// Deliver sends data, a delivery from webhookID, to the webhook's owner
+	// and reports whether the webhook has one. Claimed deliveries are never
+	// published, even if the owner can't be reached. An error means
+	// ownership couldn't be told and the delivery should be retried.
+	Deliver(ctx context.Context, webhookID string, webhookType string, data json.RawMessage) (bool, error)
+}
+
+// UseOwners sends deliveries from webhooks owned by a user through owners.
+func (h *Handler) UseOwners(owners Owners) {
+	h.owners = owners
+}
+
+// deliverOwned reports whether publications came from an owned webhook and
+// were claimed by its owner.
+func (h *Handler) deliverOwned(ctx context.Context, webhookID string, webhookType string, publications []publication) (bool, error) {
+	if h.owners == nil || webhookID == "" {
+		return false, nil
+	}
+	claimed := false
+	for _, publication := range publications {
+		ok, err := h.owners.Deliver(ctx, webhookID, webhookType, publication.data)
+		if err != nil || !ok {
+			return claimed, err
+		}
+		claimed = true
+	}
+	return claimed, nil
+}
@@ -0,0 +1,68 @@
+package ingest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// WebhookStats counts what one Codex webhook has delivered since startup.
+type WebhookStats struct {
+	WebhookID       string    `json:"webhookId"`
+	Type            string    `json:"type"`
+	Deliveries      uint64    `json:"deliveries"`
+	Events          uint64    `json:"events"`
+	FirstReceivedAt time.Time `json:"firstReceivedAt"`
+	LastReceivedAt  time.Time `json:"lastReceivedAt"`
+}
+
+// Stats records verified deliveries per webhook ID.
+type Stats struct {
+	mu   sync.Mutex
+	byID map[string]*WebhookStats
+}
+
+func NewStats() *Stats {
+	return &Stats{byID: make(map[string]*WebhookStats)}
+}
+
+func (s *Stats) Record(webhookID string, webhookType string, events int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.byID[webhookID]
+	if !ok {
+		stats = &WebhookStats{WebhookID: webhookID}
+		s.byID[webhookID] = stats
+	}
+	stats.Type = webhookType
+	stats.Deliveries++
+	stats.Events += uint64(events)
+	stats.LastReceivedAt = time.Now().UTC()
+	if stats.FirstReceivedAt.IsZero() {
+		stats.FirstReceivedAt = stats.LastReceivedAt
+	}
+}
+
+func (s *Stats) Get(webhookID string) (WebhookStats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.byID[webhookID]
+	if !ok {
+		return WebhookStats{}, false
+	}
+	return *stats, true
+}
+
+// All returns the stats of every webhook, most recently received first.
+func (s *Stats) All() []WebhookStats {
+	s.mu.Lock()
+	all := make([]WebhookStats, 0, len(s.byID))
+	for _, stats := range s.byID {
+		all = append(all, *stats)
+	}
+	s.mu.Unlock()
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].LastReceivedAt.After(all[j].LastReceivedAt)
+	})
+	return all
+}
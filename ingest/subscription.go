@@ -0,0 +1,342 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest/gqlws"
+	"github.com/Acrylic125/webhook-ingest-ws/utils"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+// SourceCodexSubscription is the envelope source of events received through
+// Codex GraphQL subscriptions.
+const SourceCodexSubscription = "codex-subscription"
+
+// DefaultSubscriptionEndpoint is Codex's GraphQL over WebSocket endpoint.
+const DefaultSubscriptionEndpoint = "wss://graph.codex.io/graphql"
+
+// PairSubscription subscribes to onEventsCreated for one pair.
+type PairSubscription struct {
+	Address   string
+	NetworkID int
+}
+
+type SubscriptionOptions struct {
+	// Endpoint defaults to DefaultSubscriptionEndpoint.
+	Endpoint string
+	Token    string
+	Pairs    []PairSubscription
+	// Prices are subscribed to with a single onPricesUpdated subscription.
+	Prices []codex.OnPricesUpdatedInput
+	// MinBackoff and MaxBackoff bound the jittered reconnect delay. They
+	// default to 500ms and 30s, and MaxBackoff is raised to MinBackoff if
+	// below it.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// QueueSize bounds the results waiting to be published. Results arriving
+	// while it is full are dropped. Defaults to 1024.
+	QueueSize int
+}
+
+// SubscriptionSource ingests events from Codex GraphQL subscriptions instead
+// of webhooks, for environments Codex can't push to such as a laptop. Events
+// are normalised into the webhook bodies and published through a Handler.
+type SubscriptionSource struct {
+	handler *Handler
+	opts    SubscriptionOptions
+	// results are published off the connection's read loop, since
+	// publishing waits on enrichment and the hubs.
+	results chan func()
+}
+
+// operation is a subscription started on every connection.
+type operation struct {
+	req    *graphql.Request
+	handle func(gqlws.Result)
+}
+
+func NewSubscriptionSource(handler *Handler, opts SubscriptionOptions) *SubscriptionSource {
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultSubscriptionEndpoint
+	}
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff = 500 * time.Millisecond
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	opts.MaxBackoff = max(opts.MaxBackoff, opts.MinBackoff)
+	if opts.QueueSize == 0 {
+		opts.QueueSize = 1024
+	}
+	return &SubscriptionSource{handler: handler, opts: opts, results: make(chan func(), opts.QueueSize)}
+}
+
+// Run keeps the subscriptions open until ctx is done, reconnecting with
+// backoff, and publishes their results. It returns ctx.Err(). Run must only
+// be called once.
+func (s *SubscriptionSource) Run(ctx context.Context) error {
+	go s.publishLoop(ctx)
+
+	attempt := 0
+	for {
+		conn, err := s.connect(ctx)
+		if err == nil {
+			attempt = 0
+			select {
+			case <-ctx.Done():
+				conn.Close()
+			case <-conn.Done():
+				err = conn.Err()
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		delay := s.backoff(attempt)
+		attempt++
+		log.Warn().Err(err).Dur("retry_in", delay).Str("endpoint", s.opts.Endpoint).Msg("Codex subscription connection lost")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (s *SubscriptionSource) backoff(attempt int) time.Duration {
+	return utils.Backoff(s.opts.MinBackoff, s.opts.MaxBackoff, attempt)
+}
+
+func (s *SubscriptionSource) connect(ctx context.Context) (*gqlws.Conn, error) {
+	conn, err := gqlws.Dial(ctx, s.opts.Endpoint, gqlws.DialOptions{
+		InitPayload: map[string]string{"Authorization": s.opts.Token},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range s.operations() {
+		if err := s.subscribe(ctx, conn, op, 0); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	log.Info().Int("pairs", len(s.opts.Pairs)).Int("prices", len(s.opts.Prices)).Msg("Codex subscriptions started")
+	return conn, nil
+}
+
+func (s *SubscriptionSource) operations() []operation {
+	var ops []operation
+	for _, pair := range s.opts.Pairs {
+		ops = append(ops, operation{
+			req: &graphql.Request{
+				OpName: "OnEventsCreated",
+				Query:  codex.OnEventsCreated_Operation,
+				Variables: map[string]any{
+					"address":   pair.Address,
+					"networkId": pair.NetworkID,
+				},
+			},
+			handle: s.handleEvents,
+		})
+	}
+	if len(s.opts.Prices) > 0 {
+		ops = append(ops, operation{
+			req: &graphql.Request{
+				OpName:    "OnPricesUpdated",
+				Query:     codex.OnPricesUpdated_Operation,
+				Variables: map[string]any{"input": s.opts.Prices},
+			},
+			handle: s.handlePrices,
+		})
+	}
+	return ops
+}
+
+// subscribe starts op on conn. Its results are queued for publishing, and if
+// Codex ends it while the connection stays up, it is started again after a
+// backoff that grows with attempt until it delivers a result.
+func (s *SubscriptionSource) subscribe(ctx context.Context, conn *gqlws.Conn, op operation, attempt int) error {
+	_, err := conn.Subscribe(op.req, func(result gqlws.Result) {
+		// Called from the read loop, so it must not block.
+		if len(result.Data) > 0 {
+			attempt = 0
+		}
+		select {
+		case s.results <- func() { op.handle(result) }:
+		default:
+			log.Warn().Str("operation", op.req.OpName).Msg("Codex subscription queue full, dropping result")
+		}
+		if result.Done {
+			go s.resubscribe(ctx, conn, op, attempt)
+		}
+	})
+	return err
+}
+
+func (s *SubscriptionSource) resubscribe(ctx context.Context, conn *gqlws.Conn, op operation, attempt int) {
+	if conn.Err() != nil {
+		// The connection is over; Run subscribes again once reconnected.
+		return
+	}
+	delay := s.backoff(attempt)
+	log.Warn().Str("operation", op.req.OpName).Dur("retry_in", delay).Msg("Codex subscription ended")
+	select {
+	case <-ctx.Done():
+		return
+	case <-conn.Done():
+		return
+	case <-time.After(delay):
+	}
+	if err := s.subscribe(ctx, conn, op, attempt+1); err != nil {
+		// Failing to write means the connection is broken, and it is
+		// reconnected once the read loop notices.
+		log.Warn().Err(err).Str("operation", op.req.OpName).Msg("Failed to resubscribe to Codex")
+	}
+}
+
+func (s *SubscriptionSource) publishLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case publish := <-s.results:
+			publish()
+		}
+	}
+}
+
+func (s *SubscriptionSource) handleEvents(result gqlws.Result) {
+	if !s.usable(result, "OnEventsCreated") {
+		return
+	}
+	resp := codex.OnEventsCreatedResponse{}
+	if err := json.Unmarshal(result.Data, &resp); err != nil {
+		log.Warn().Err(err).Msg("Invalid OnEventsCreated result")
+		return
+	}
+	if resp.OnEventsCreated == nil {
+		return
+	}
+	body := NormalizeEvents(*resp.OnEventsCreated)
+	if len(body.Data) == 0 {
+		return
+	}
+	if err := s.handler.PublishTokenPairs(SourceCodexSubscription, body); err != nil {
+		log.Error().Err(err).Msg("Failed to publish subscription events")
+	}
+}
+
+func (s *SubscriptionSource) handlePrices(result gqlws.Result) {
+	if !s.usable(result, "OnPricesUpdated") {
+		return
+	}
+	resp := codex.OnPricesUpdatedResponse{}
+	if err := json.Unmarshal(result.Data, &resp); err != nil {
+		log.Warn().Err(err).Msg("Invalid OnPricesUpdated result")
+		return
+	}
+	if err := s.handler.PublishPrice(SourceCodexSubscription, NormalizePrice(resp.OnPricesUpdated)); err != nil {
+		log.Error().Err(err).Msg("Failed to publish subscription price")
+	}
+}
+
+// usable logs errors in result and reports whether it carries data.
+func (s *SubscriptionSource) usable(result gqlws.Result, operation string) bool {
+	if len(result.Errors) > 0 {
+		event := log.Warn()
+		if result.Done {
+			// The subscription is over; it is started again after a
+			// backoff, or on reconnect if the connection dropped.
+			event = log.Error()
+		}
+		event.Err(result.Errors).Str("operation", operation).Bool("done", result.Done).Msg("Codex subscription error")
+	}
+	return len(result.Data) > 0 && string(result.Data) != "null"
+}
+
+// NormalizeEvents converts an onEventsCreated result into the body of the
+// equivalent TOKEN_PAIR_EVENT webhook delivery. Fields subscriptions don't
+// carry, such as the exchange and protocol, are left empty.
+func NormalizeEvents(output codex.OnEventsCreatedOnEventsCreatedAddEventsOutput) TokenPairWebhookBody {
+	body := TokenPairWebhookBody{Type: WebhookTypeTokenPairEvent}
+	pair := Pair{Address: output.Address, ID: output.Id, NetworkID: output.NetworkId}
+	for _, event := range output.Events {
+		if event == nil {
+			continue
+		}
+		if body.DeduplicationID == "" {
+			body.DeduplicationID = output.Id + ":" + event.TransactionHash
+		}
+		body.Data = append(body.Data, NormalizeEvent(*event, pair))
+	}
+	return body
+}
+
+// NormalizeEvent converts a Codex event of pair into the event data of a
+// TOKEN_PAIR_EVENT webhook delivery. The pair's tokens are taken from event
+// when it has them.
+func NormalizeEvent(event codex.CreatedEvent, pair Pair) TokenPairEventData {
+	if event.Token0Address != nil {
+		pair.Token0 = *event.Token0Address
+	}
+	if event.Token1Address != nil {
+		pair.Token1 = *event.Token1Address
+	}
+	return TokenPairEventData{
+		Event: TokenPairEvent{
+			Address:            event.Address,
+			Data:               EventData{Type: string(event.EventType)},
+			EventDisplayType:   deref(event.EventDisplayType),
+			EventType:          string(event.EventType),
+			LiquidityToken:     deref(event.LiquidityToken),
+			LogIndex:           event.LogIndex,
+			Maker:              deref(event.Maker),
+			QuoteToken:         deref(event.QuoteToken),
+			Timestamp:          event.Timestamp,
+			Token0PoolValueUsd: deref(event.Token0PoolValueUsd),
+			Token0SwapValueUsd: deref(event.Token0SwapValueUsd),
+			Token0ValueBase:    deref(event.Token0ValueBase),
+			Token1PoolValueUsd: deref(event.Token1PoolValueUsd),
+			Token1SwapValueUsd: deref(event.Token1SwapValueUsd),
+			Token1ValueBase:    deref(event.Token1ValueBase),
+			TransactionHash:    event.TransactionHash,
+		},
+		Pair: pair,
+	}
+}
+
+// NormalizePrice converts an onPricesUpdated result into the body of the
+// equivalent PRICE_EVENT webhook delivery.
+func NormalizePrice(price codex.UpdatedPrice) PriceWebhookBody {
+	timestamp := 0
+	if price.Timestamp != nil {
+		timestamp = *price.Timestamp
+	}
+	return PriceWebhookBody{
+		DeduplicationID: fmt.Sprintf("%s:%d:%d", price.Address, price.NetworkId, timestamp),
+		Type:            WebhookTypePriceEvent,
+		Data: PriceEventData{
+			TokenAddress: price.Address,
+			NetworkID:    price.NetworkId,
+			PriceUsd:     strconv.FormatFloat(price.PriceUsd, 'f', -1, 64),
+			PairAddress:  price.PoolAddress,
+			Timestamp:    timestamp,
+		},
+	}
+}
+
+func deref[T ~string](v *T) string {
+	if v == nil {
+		return ""
+	}
+	return string(*v)
+}
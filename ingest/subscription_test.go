@@ -0,0 +1,159 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/ingest/gqlws/gqlwsstub"
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+)
+
+type testHubManager struct {
+	hub *ws.Hub[any]
+}
+
+func (m *testHubManager) GetHub() *ws.Hub[any]                                          { return m.hub }
+func (m *testHubManager) OnRegister(client *ws.UserClient[any]) error                   { return nil }
+func (m *testHubManager) OnUnregister(client *ws.UserClient[any]) error                 { return nil }
+func (m *testHubManager) OnReceiveMessage(client *ws.UserClient[any], msg []byte) error { return nil }
+
+// newTestHandler returns a handler publishing token pair events to a hub, and
+// a channel receiving the pair address of every event it publishes.
+func newTestHandler(t *testing.T) (*Handler, <-chan string) {
+	t.Helper()
+	registry := ws.NewRegistry()
+	if err := ws.Register(registry, "pairs", &testHubManager{hub: ws.NewHub[any]()}, ws.HubConfig[any]{Path: "/ws"}); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler("", registry, map[string]string{WebhookTypeTokenPairEvent: "pairs"})
+	published := make(chan string, 16)
+	handler.Observe(func(data []TokenPairEventData) {
+		for _, event := range data {
+			published <- event.Pair.Address
+		}
+	})
+	return handler, published
+}
+
+// blockingEnricher holds every publication until release is closed.
+type blockingEnricher struct {
+	release chan struct{}
+}
+
+func (e *blockingEnricher) Tokens(ctx context.Context, keys []TokenKey) map[TokenKey]TokenMetadata {
+	<-e.release
+	return nil
+}
+
+func eventsCreated(pairAddress string) map[string]any {
+	return map[string]any{
+		"onEventsCreated": map[string]any{
+			"address":   pairAddress,
+			"networkId": 1,
+			"id":        pairAddress + ":1",
+			"events": []map[string]any{{
+				"address":         pairAddress,
+				"eventType":       "Swap",
+				"networkId":       1,
+				"timestamp":       1,
+				"transactionHash": "0xtx",
+				"logIndex":        0,
+			}},
+		},
+	}
+}
+
+func startSubscriptionSource(t *testing.T, handler *Handler, stub *gqlwsstub.Server) {
+	t.Helper()
+	source := NewSubscriptionSource(handler, SubscriptionOptions{
+		Endpoint:   stub.URL(),
+		Token:      "token",
+		Pairs:      []PairSubscription{{Address: "0xa", NetworkID: 1}},
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		source.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	if !stub.WaitForSubscriptions(1, 5*time.Second) {
+		t.Fatal("timed out waiting for the subscription")
+	}
+}
+
+func waitPublished(t *testing.T, published <-chan string) string {
+	t.Helper()
+	select {
+	case address := <-published:
+		return address
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a published event")
+		return ""
+	}
+}
+
+func TestSubscriptionResubscribesAfterError(t *testing.T) {
+	stub := gqlwsstub.Start("token")
+	defer stub.Close()
+	handler, published := newTestHandler(t)
+	startSubscriptionSource(t, handler, stub)
+
+	if stub.Fail("OnEventsCreated", "internal error") != 1 {
+		t.Fatal("subscription was not failed")
+	}
+	if !stub.WaitForSubscriptions(1, 5*time.Second) {
+		t.Fatal("subscription was not started again")
+	}
+	stub.Publish("OnEventsCreated", eventsCreated("0xa"))
+	if address := waitPublished(t, published); address != "0xa" {
+		t.Fatalf("published %s, want 0xa", address)
+	}
+}
+
+func TestSubscriptionResubscribesAfterReconnect(t *testing.T) {
+	stub := gqlwsstub.Start("token")
+	defer stub.Close()
+	handler, published := newTestHandler(t)
+	startSubscriptionSource(t, handler, stub)
+
+	stub.DropConnections()
+	time.Sleep(50 * time.Millisecond)
+	if !stub.WaitForSubscriptions(1, 5*time.Second) {
+		t.Fatal("subscription was not started again")
+	}
+	stub.Publish("OnEventsCreated", eventsCreated("0xa"))
+	waitPublished(t, published)
+}
+
+func TestSubscriptionPublishesOffReadLoop(t *testing.T) {
+	stub := gqlwsstub.Start("token")
+	defer stub.Close()
+	handler, published := newTestHandler(t)
+	enricher := &blockingEnricher{release: make(chan struct{})}
+	handler.UseEnricher(enricher)
+	startSubscriptionSource(t, handler, stub)
+
+	// The first result is stuck in enrichment, which must not keep the
+	// connection from handling the error and resubscribing.
+	stub.Publish("OnEventsCreated", eventsCreated("0xa"))
+	if address := waitPublished(t, published); address != "0xa" {
+		t.Fatalf("published %s, want 0xa", address)
+	}
+	stub.Fail("OnEventsCreated", "internal error")
+	if !stub.WaitForSubscriptions(1, 5*time.Second) {
+		t.Fatal("subscription was not started again while publishing was blocked")
+	}
+	stub.Publish("OnEventsCreated", eventsCreated("0xb"))
+
+	close(enricher.release)
+	if address := waitPublished(t, published); address != "0xb" {
+		t.Fatalf("published %s, want 0xb", address)
+	}
+}
@@ -0,0 +1,69 @@
+package ingest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Token pair events are published on the WebhookTypeTokenPairEvent topic and
+// also on a topic per pair and per token, so clients watching a few pairs
+// don't receive everything.
+const (
+	TopicKindPair  = "pair"
+	TopicKindToken = "token"
+)
+
+// PairTopic returns the topic of token pair events for one pair,
+// "pair:<address>:<networkId>", with the address normalized.
+func PairTopic(address string, networkID int) string {
+	return fmt.Sprintf("%s:%s:%d", TopicKindPair, NormalizeAddress(address), networkID)
+}
+
+// TokenTopic returns the topic of token pair events for every pair of one
+// token, "token:<address>:<networkId>", with the address normalized.
+func TokenTopic(address string, networkID int) string {
+	return fmt.Sprintf("%s:%s:%d", TopicKindToken, NormalizeAddress(address), networkID)
+}
+
+// NormalizeAddress lower-cases hex addresses, which are case-insensitive.
+// Other addresses, such as Solana's base58 ones, are case-sensitive and
+// returned as is.
+func NormalizeAddress(address string) string {
+	if strings.HasPrefix(address, "0x") || strings.HasPrefix(address, "0X") {
+		return strings.ToLower(address)
+	}
+	return address
+}
+
+// NormalizeTopic returns a pair or token topic with its address normalized,
+// so every spelling of it is one topic. Other topics are returned as is.
+func NormalizeTopic(topic string) string {
+	kind, address, networkID, ok := ParseTopic(topic)
+	if !ok {
+		return topic
+	}
+	return fmt.Sprintf("%s:%s:%d", kind, NormalizeAddress(address), networkID)
+}
+
+// ParseTopic splits a topic from PairTopic or TokenTopic into its parts.
+func ParseTopic(topic string) (kind string, address string, networkID int, ok bool) {
+	parts := strings.Split(topic, ":")
+	if len(parts) != 3 || (parts[0] != TopicKindPair && parts[0] != TopicKindToken) || parts[1] == "" {
+		return "", "", 0, false
+	}
+	networkID, err := strconv.Atoi(parts[2])
+	if err != nil || networkID <= 0 {
+		return "", "", 0, false
+	}
+	return parts[0], parts[1], networkID, true
+}
+
+// IsTokenPairTopic reports whether events on topic are token pair events.
+func IsTokenPairTopic(topic string) bool {
+	if topic == WebhookTypeTokenPairEvent {
+		return true
+	}
+	_, _, _, ok := ParseTopic(topic)
+	return ok
+}
@@ -0,0 +1,19 @@
+package ingest
+
+import "testing"
+
+func TestNormalizeTopic(t *testing.T) {
+	for topic, want := range map[string]string{
+		"pair:0xAbC:1":     "pair:0xabc:1",
+		"token:0XDEF:56":   "token:0xdef:56",
+		"pair:So1AbC:1399": "pair:So1AbC:1399",
+		"TOKEN_PAIR_EVENT": "TOKEN_PAIR_EVENT",
+	} {
+		if got := NormalizeTopic(topic); got != want {
+			t.Errorf("NormalizeTopic(%q) = %q, want %q", topic, got, want)
+		}
+	}
+	if got := PairTopic("0xAbC", 1); got != "pair:0xabc:1" {
+		t.Errorf("PairTopic = %q, want pair:0xabc:1", got)
+	}
+}
@@ -0,0 +1,135 @@
+package ingest
+
+// Webhook types as sent by Codex in the `type` field of a delivery.
+const (
+	WebhookTypeTokenPairEvent = "TOKEN_PAIR_EVENT"
+	WebhookTypePriceEvent     = "PRICE_EVENT"
+	WebhookTypeNftEvent       = "NFT_EVENT"
+	WebhookTypeMarketCapEvent = "MARKET_CAP_EVENT"
+	WebhookTypeRawTransaction = "RAW_TRANSACTION"
+)
+
+// WebhookHeader holds the fields common to every webhook delivery. It is
+// decoded first to verify the hash and pick a route before the payload is
+// decoded into its typed body.
+type WebhookHeader struct {
+	DeduplicationID string `json:"deduplicationId" validate:"required"`
+	Hash            string `json:"hash" validate:"required"`
+	Type            string `json:"type,omitempty"`
+	WebhookID       string `json:"webhookId,omitempty"`
+}
+
+// TokenPairWebhookBody represents the top-level structure for a single
+// TOKEN_PAIR_EVENT webhook.
+type TokenPairWebhookBody struct {
+	DeduplicationID string `json:"deduplicationId" validate:"required"`
+	// GroupID         string               `json:"groupId" validate:"required"`
+	Hash string `json:"hash" validate:"required"`
+	Type string `json:"type,omitempty"`
+	// Webhook         TokenPairWebhookInfo `json:"webhook" validate:"required"`
+	WebhookID string               `json:"webhookId,omitempty"`
+	Data      []TokenPairEventData `json:"data" validate:"required,dive"`
+}
+
+// TokenPairWebhookInfo holds information about the webhook itself.
+// type TokenPairWebhookInfo struct {
+// 	ID            string `json:"id" validate:"required"`
+// 	Name          string `json:"name" validate:"required"`
+// }
+
+// TokenPairEventData holds the actual event and pair data for a token pair event.
+type TokenPairEventData struct {
+	Event TokenPairEvent `json:"event" validate:"required"`
+	Pair  Pair           `json:"pair" validate:"required"`
+}
+
+// TokenPairEvent represents a specific token pair event.
+type TokenPairEvent struct {
+	Address string `json:"address" validate:"required"`
+	// BaseTokenPrice     string                 `json:"baseTokenPrice" validate:"required"`
+	// BlockHash          string                 `json:"blockHash" validate:"required"`
+	// BlockNumber        int                    `json:"blockNumber" validate:"required"`
+	Data             EventData `json:"data" validate:"required"`
+	EventDisplayType string    `json:"eventDisplayType" validate:"required"`
+	EventType        string    `json:"eventType" validate:"required"`
+	EventType2       string    `json:"eventType2" validate:"required"`
+	// ID                 string                 `json:"id" validate:"required"`
+	// Labels             map[string]interface{} `json:"labels" validate:"required"` // Use interface{} for values if types vary
+	LiquidityToken string `json:"liquidityToken" validate:"required"`
+	// LogIndex and TransactionHash identify the event for deduplication.
+	LogIndex int    `json:"logIndex"`
+	Maker    string `json:"maker" validate:"required"`
+	// MakerHashKey       string                 `json:"makerHashKey" validate:"required"`
+	// NetworkID          int                    `json:"networkId" validate:"required"`
+	QuoteToken string `json:"quoteToken" validate:"required"`
+	// SortKey            string                 `json:"sortKey" validate:"required"`
+	// SupplementalIndex  int                    `json:"supplementalIndex" validate:"required"`
+	Timestamp          int    `json:"timestamp" validate:"required"`
+	Token0PoolValueUsd string `json:"token0PoolValueUsd" validate:"required"`
+	Token0SwapValueUsd string `json:"token0SwapValueUsd" validate:"required"`
+	Token0ValueBase    string `json:"token0ValueBase" validate:"required"`
+	Token0ValueUsd     string `json:"token0ValueUsd" validate:"required"`
+	Token1PoolValueUsd string `json:"token1PoolValueUsd" validate:"required"`
+	Token1SwapValueUsd string `json:"token1SwapValueUsd" validate:"required"`
+	Token1ValueBase    string `json:"token1ValueBase" validate:"required"`
+	Token1ValueUsd     string `json:"token1ValueUsd" validate:"required"`
+	TransactionHash    string `json:"transactionHash,omitempty"`
+	// TransactionIndex   int                    `json:"transactionIndex" validate:"required"`
+	// TTL                int                    `json:"ttl" validate:"required"`
+}
+
+// EventData represents the data specific to an event within a token pair event.
+// This struct will need to be flexible as the 'data' field can vary significantly
+// between different event types (e.g., "Swap" with different fields).
+// I've included fields from both "Swap" examples you provided.
+type EventData struct {
+	Protocol string `json:"protocol" validate:"required"`
+	Type     string `json:"type" validate:"required"` // e.g., "Swap"
+}
+
+// Pair represents the token pair information.
+type Pair struct {
+	Address      string `json:"address" validate:"required"`
+	ExchangeHash string `json:"exchangeHash" validate:"required"`
+	ID           string `json:"id" validate:"required"`
+	NetworkID    int    `json:"networkId" validate:"required"`
+	Token0       string `json:"token0" validate:"required"`
+	Token1       string `json:"token1" validate:"required"`
+	// Token0Metadata and Token1Metadata are attached by an Enricher.
+	Token0Metadata *TokenMetadata `json:"token0Metadata,omitempty"`
+	Token1Metadata *TokenMetadata `json:"token1Metadata,omitempty"`
+}
+
+// PriceWebhookBody is a PRICE_EVENT delivery.
+type PriceWebhookBody struct {
+	DeduplicationID string         `json:"deduplicationId"`
+	Hash            string         `json:"hash"`
+	Type            string         `json:"type,omitempty"`
+	WebhookID       string         `json:"webhookId,omitempty"`
+	Data            PriceEventData `json:"data"`
+}
+
+// PriceEventData is a token's price at a point in time.
+type PriceEventData struct {
+	TokenAddress string `json:"tokenAddress"`
+	NetworkID    int    `json:"networkId"`
+	PriceUsd     string `json:"priceUsd"`
+	PairAddress  string `json:"pairAddress,omitempty"`
+	Timestamp    int    `json:"timestamp"`
+	// Token is attached by an Enricher.
+	Token *TokenMetadata `json:"token,omitempty"`
+}
+
+// TokenKey identifies a token across networks.
+type TokenKey struct {
+	Address   string
+	NetworkID int
+}
+
+// TokenMetadata describes a token so clients don't have to look it up.
+type TokenMetadata struct {
+	Name     string `json:"name,omitempty"`
+	Symbol   string `json:"symbol,omitempty"`
+	Decimals int    `json:"decimals"`
+	ImageUrl string `json:"imageUrl,omitempty"`
+}
@@ -1,106 +1,33 @@
 package main
 
 import (
-	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
-	"fmt"
-	"io"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/Acrylic125/webhook-ingest-ws/admin"
+	"github.com/Acrylic125/webhook-ingest-ws/alerts"
+	"github.com/Acrylic125/webhook-ingest-ws/apitokens"
+	"github.com/Acrylic125/webhook-ingest-ws/backfill"
+	"github.com/Acrylic125/webhook-ingest-ws/backplane"
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/enrich"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/netstatus"
+	"github.com/Acrylic125/webhook-ingest-ws/settings"
+	"github.com/Acrylic125/webhook-ingest-ws/webhooks"
 	"github.com/Acrylic125/webhook-ingest-ws/ws"
-	"github.com/go-playground/validator/v10"
-	"github.com/gorilla/websocket"
+	zlog "github.com/rs/zerolog/log"
 )
 
-// TokenPairWebhookBody represents the top-level structure for a single
-// TOKEN_PAIR_EVENT webhook.
-type TokenPairWebhookBody struct {
-	DeduplicationID string `json:"deduplicationId" validate:"required"`
-	// GroupID         string               `json:"groupId" validate:"required"`
-	Hash string `json:"hash" validate:"required"`
-	// Type            string               `json:"type" validate:"required"`
-	// Webhook         TokenPairWebhookInfo `json:"webhook" validate:"required"`
-	// WebhookID       string               `json:"webhookId" validate:"required"`
-	Data []TokenPairEventData `json:"data" validate:"required,dive"`
-}
-
-// TokenPairWebhookInfo holds information about the webhook itself.
-// type TokenPairWebhookInfo struct {
-// 	ID            string `json:"id" validate:"required"`
-// 	Name          string `json:"name" validate:"required"`
-// }
-
-// TokenPairEventData holds the actual event and pair data for a token pair event.
-type TokenPairEventData struct {
-	Event TokenPairEvent `json:"event" validate:"required"`
-	Pair  Pair           `json:"pair" validate:"required"`
-}
-
-// TokenPairEvent represents a specific token pair event.
-type TokenPairEvent struct {
-	Address string `json:"address" validate:"required"`
-	// BaseTokenPrice     string                 `json:"baseTokenPrice" validate:"required"`
-	// BlockHash          string                 `json:"blockHash" validate:"required"`
-	// BlockNumber        int                    `json:"blockNumber" validate:"required"`
-	Data             EventData `json:"data" validate:"required"`
-	EventDisplayType string    `json:"eventDisplayType" validate:"required"`
-	EventType        string    `json:"eventType" validate:"required"`
-	EventType2       string    `json:"eventType2" validate:"required"`
-	// ID                 string                 `json:"id" validate:"required"`
-	// Labels             map[string]interface{} `json:"labels" validate:"required"` // Use interface{} for values if types vary
-	LiquidityToken string `json:"liquidityToken" validate:"required"`
-	// LogIndex           int                    `json:"logIndex" validate:"required"`
-	Maker string `json:"maker" validate:"required"`
-	// MakerHashKey       string                 `json:"makerHashKey" validate:"required"`
-	// NetworkID          int                    `json:"networkId" validate:"required"`
-	QuoteToken string `json:"quoteToken" validate:"required"`
-	// SortKey            string                 `json:"sortKey" validate:"required"`
-	// SupplementalIndex  int                    `json:"supplementalIndex" validate:"required"`
-	Timestamp          int    `json:"timestamp" validate:"required"`
-	Token0PoolValueUsd string `json:"token0PoolValueUsd" validate:"required"`
-	Token0SwapValueUsd string `json:"token0SwapValueUsd" validate:"required"`
-	Token0ValueBase    string `json:"token0ValueBase" validate:"required"`
-	Token0ValueUsd     string `json:"token0ValueUsd" validate:"required"`
-	Token1PoolValueUsd string `json:"token1PoolValueUsd" validate:"required"`
-	Token1SwapValueUsd string `json:"token1SwapValueUsd" validate:"required"`
-	Token1ValueBase    string `json:"token1ValueBase" validate:"required"`
-	Token1ValueUsd     string `json:"token1ValueUsd" validate:"required"`
-	// TransactionHash    string                 `json:"transactionHash" validate:"required"`
-	// TransactionIndex   int                    `json:"transactionIndex" validate:"required"`
-	// TTL                int                    `json:"ttl" validate:"required"`
-}
-
-// EventData represents the data specific to an event within a token pair event.
-// This struct will need to be flexible as the 'data' field can vary significantly
-// between different event types (e.g., "Swap" with different fields).
-// I've included fields from both "Swap" examples you provided.
-type EventData struct {
-	Protocol string `json:"protocol" validate:"required"`
-	Type     string `json:"type" validate:"required"` // e.g., "Swap"
-}
-
-// Pair represents the token pair information.
-type Pair struct {
-	Address      string `json:"address" validate:"required"`
-	ExchangeHash string `json:"exchangeHash" validate:"required"`
-	ID           string `json:"id" validate:"required"`
-	NetworkID    int    `json:"networkId" validate:"required"`
-	Token0       string `json:"token0" validate:"required"`
-	Token1       string `json:"token1" validate:"required"`
-}
-
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
 type HubManager struct {
-	hub *ws.Hub[any]
+	hub    *ws.Hub[any]
+	router *ws.Router[any]
 }
 
 func (h *HubManager) GetHub() *ws.Hub[any] {
@@ -116,79 +43,283 @@ func (h *HubManager) OnUnregister(client *ws.UserClient[any]) error {
 }
 
 func (h *HubManager) OnReceiveMessage(client *ws.UserClient[any], message []byte) error {
-	return nil
+	return h.router.Dispatch(client, message)
 }
 
+type PingRequest struct{}
+
+type PingResponse struct {
+	ServerTime int64 `json:"serverTime"`
+}
+
+func NewHubManager(router *ws.Router[any]) *HubManager {
+	return &HubManager{
+		hub:    ws.NewHub[any](),
+		router: router,
+	}
+}
+
+// newRouter returns a router with the message types every hub handles.
+func newRouter() *ws.Router[any] {
+	router := ws.NewRouter[any]()
+	ws.Handle(router, "ping", func(client *ws.UserClient[any], req PingRequest) (PingResponse, error) {
+		return PingResponse{ServerTime: time.Now().UnixMilli()}, nil
+	})
+	ws.HandleBatching(router)
+	ws.HandleSubscriptions(router)
+	return router
+}
+
+const (
+	HubPairs  = "pairs"
+	HubNft    = "nft"
+	HubAlerts = "alerts"
+)
+
 func main() {
-	// hub := NewHub()
-	hub := ws.NewHub[any]()
-	hubManager := &HubManager{
-		hub: hub,
+	settings.Init(os.Getenv("GO_ENV"))
+	configs := settings.Get().Configs
+	secrets := settings.Get().Secrets
+
+	registry := ws.NewRegistry()
+	hubConfigs := map[string]ws.HubConfig[any]{
+		HubPairs:  {Path: "/ws/pairs", MaxClients: 10000, MaxMessageSize: 4096},
+		HubNft:    {Path: "/ws/nft", MaxClients: 2000, MaxMessageSize: 4096},
+		HubAlerts: {Path: "/ws/alerts", MaxClients: 2000, MaxMessageSize: 4096},
+	}
+	// Shares broadcasts between replicas so a webhook received by one instance
+	// reaches clients connected to the others.
+	var bp backplane.Backplane
+	if addr := os.Getenv("BACKPLANE_REDIS_ADDR"); addr != "" {
+		bp = backplane.NewRedis(backplane.RedisOptions{
+			Addr:     addr,
+			Password: os.Getenv("BACKPLANE_REDIS_PASSWORD"),
+		})
 	}
-	go ws.Run(hubManager)
+	instanceID := ws.NewInstanceID()
 
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		ws.ConnectSocket(hubManager, w, r, nil)
+	// Signed-in clients of the alerts hub can create alerts of their own.
+	alertsEnabled := secrets.SessionSigningKey != "" && !configs.DisableAlerts
+	if alertsEnabled {
+		config := hubConfigs[HubAlerts]
+		config.Authenticate = alerts.Authenticate(apitokens.SessionAuthenticator([]byte(secrets.SessionSigningKey)))
+		hubConfigs[HubAlerts] = config
+	}
+
+	codexClient := codex.NewClient(codex.Options{
+		Endpoint: configs.CodexEndpoint,
+		Token:    secrets.CodexToken,
 	})
-	http.HandleFunc("/send-data", func(w http.ResponseWriter, r *http.Request) {
-		// Parse the incoming request body
-		body, err := io.ReadAll(r.Body)
-		defer r.Body.Close()
-		if err != nil {
-			http.Error(w, "Failed to read request body", http.StatusBadRequest)
-			return
-		}
+	bucketID := webhooks.BucketID(settings.Env(), configs.WebhookBucketKey)
+	provisioner := webhooks.NewProvisioner(
+		codexClient,
+		webhooks.CallbackURL(configs.WebhookTargetUrl),
+		secrets.WebhookSecurityToken,
+		bucketID,
+		webhooks.NewStore(configs.WebhookStateFile),
+	)
+	// Creates webhooks for pairs and tokens that clients subscribe to on the
+	// pairs hub.
+	var demand *webhooks.Demand
+	if !configs.DisableDemandWebhooks {
+		demand = webhooks.NewDemand(provisioner, webhooks.DemandOptions{
+			Grace:       time.Duration(configs.DemandWebhookGraceSeconds) * time.Second,
+			MaxWebhooks: configs.MaxDemandWebhooks,
+			Static:      configs.Webhooks,
+		})
+	}
 
-		// fmt.Println("Received data:", string(body))
-		verify := TokenPairWebhookBody{}
-		if err := json.Unmarshal(body, &verify); err != nil {
-			fmt.Println("Error parsing JSON:", err)
-			http.Error(w, "Invalid JSON format", http.StatusBadRequest)
-			return
+	// Each hub has a router of its own, so message types such as alerts are
+	// only handled on the hub they belong to.
+	var alertsHub *ws.Hub[any]
+	var alertsRouter *ws.Router[any]
+	for name, config := range hubConfigs {
+		router := newRouter()
+		hubManager := NewHubManager(router)
+		if name == HubAlerts {
+			alertsHub = hubManager.GetHub()
+			alertsRouter = router
 		}
-
-		if err := validator.New().Struct(verify); err != nil {
-			fmt.Println("Validation error:", err)
-			http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
-			return
+		if bp != nil {
+			if err := hubManager.GetHub().UseBackplane(context.Background(), bp, "wis:hub:"+name, instanceID); err != nil {
+				log.Fatal(err)
+			}
 		}
-
-		// SHA 256 hash "<secret><deduplicationId>"
-		secret := "hello world"
-		h := sha256.New()
-		h.Write([]byte(secret + verify.DeduplicationID))
-		hashInBytes := h.Sum(nil)
-		hashString := hex.EncodeToString(hashInBytes)
-		if hashString != verify.Hash {
-			fmt.Println("Hash mismatch vvvvv - ", hashString, verify.Hash)
-			http.Error(w, "Hash mismatch", http.StatusBadRequest)
-			return
+		if name == HubPairs {
+			hubManager.GetHub().UseTopicNormalizer(ingest.NormalizeTopic)
+			if demand != nil {
+				hubManager.GetHub().UseDemand(demand)
+			}
 		}
-
-		// Remarshal the data to ensure it is in the correct format
-		buf := new(bytes.Buffer)
-		if err := json.NewEncoder(buf).Encode(verify); err != nil {
-			fmt.Println("Error encoding JSON:", err)
-			http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
-			return
+		if err := ws.Register(registry, name, hubManager, config); err != nil {
+			log.Fatal(err)
 		}
+	}
+	registry.Mount(http.DefaultServeMux)
+
+	// Kept for clients connecting before hubs were split by path.
+	pairsHub, _ := registry.Get(HubPairs)
+	http.Handle("/ws", pairsHub)
 
-		// fmt.Println("Parsed data:", verify)
-		// Send the data to the WebSocket hub
-		hub.Broadcast(buf.Bytes())
+	http.HandleFunc("/schemas/envelope.v1.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(ws.EnvelopeSchema)
+	})
 
-		// Send a success response
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Data received and broadcasted"))
+	// SHA 256 hash "<secret><deduplicationId>"
+	ingestHandler := ingest.NewHandler(secrets.WebhookSecurityToken, registry, map[string]string{
+		ingest.WebhookTypeTokenPairEvent: HubPairs,
+		ingest.WebhookTypeNftEvent:       HubNft,
+		ingest.WebhookTypePriceEvent:     HubAlerts,
+		ingest.WebhookTypeMarketCapEvent: HubAlerts,
 	})
+	if !configs.DisableTokenEnrichment {
+		ingestHandler.UseEnricher(enrich.New(codexClient, enrich.Options{
+			Size: configs.TokenMetadataCacheSize,
+			TTL:  time.Duration(configs.TokenMetadataTTLSeconds) * time.Second,
+			Wait: time.Duration(configs.TokenMetadataWaitMillis) * time.Millisecond,
+		}))
+	}
+	var backfiller *backfill.Backfiller
+	if !configs.DisableBackfill {
+		backfiller = backfill.New(codexClient, ingestHandler, backfill.NewStore(configs.BackfillStateFile), backfill.Options{
+			Gap:         time.Duration(configs.BackfillGapSeconds) * time.Second,
+			GapCooldown: time.Duration(configs.BackfillGapCooldownSeconds) * time.Second,
+			MaxWindow:   time.Duration(configs.BackfillMaxWindowSeconds) * time.Second,
+			MaxEvents:   configs.BackfillMaxEvents,
+		})
+		ingestHandler.UseTracker(backfiller)
+	}
+	var lagMonitor *netstatus.Monitor
+	if !configs.DisableLagMonitor {
+		networks := append([]int{}, configs.LagNetworkIds...)
+		for _, webhook := range configs.Webhooks {
+			networks = append(networks, webhook.NetworkIds...)
+		}
+		for _, target := range append(configs.Subscriptions.Pairs, configs.Subscriptions.Prices...) {
+			networks = append(networks, target.NetworkId)
+		}
+		lagMonitor = netstatus.NewMonitor(codexClient, registry, netstatus.Options{
+			Networks:  networks,
+			Interval:  time.Duration(configs.LagCheckIntervalSeconds) * time.Second,
+			Threshold: time.Duration(configs.LagThresholdSeconds) * time.Second,
+		})
+		ingestHandler.Observe(lagMonitor.ObserveEvents)
+		http.Handle("/metrics", lagMonitor)
+	}
+	if alertsEnabled {
+		alertManager, err := alerts.New(codexClient, alertsHub, alerts.NewStore(configs.AlertStateFile), alerts.Options{
+			CallbackURL:   webhooks.CallbackURL(configs.WebhookTargetUrl),
+			SecurityToken: secrets.WebhookSecurityToken,
+			BucketID:      bucketID,
+			MaxPerUser:    configs.MaxAlertsPerUser,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		alerts.Handle(alertsRouter, alertManager)
+		ingestHandler.UseOwners(alertManager)
+	}
+	http.Handle("/send-data", ingestHandler)
+
+	if secrets.AdminToken != "" {
+		http.Handle("/admin/", admin.NewHandler(
+			secrets.AdminToken,
+			codexClient,
+			ingestHandler.Stats(),
+			webhooks.CallbackURL(configs.WebhookTargetUrl),
+			secrets.WebhookSecurityToken,
+			bucketID,
+		))
+	}
+
+	var tokenVendor *apitokens.Vendor
+	if secrets.SessionSigningKey != "" {
+		tokenVendor = apitokens.NewVendor(codexClient, apitokens.Options{
+			TTL:           time.Duration(configs.ApiTokenTTLSeconds) * time.Second,
+			RequestLimit:  configs.ApiTokenRequestLimit,
+			RefreshBefore: time.Duration(configs.ApiTokenRefreshSeconds) * time.Second,
+			Quota:         configs.ApiTokenQuota,
+			QuotaWindow:   time.Duration(configs.ApiTokenQuotaWindowSeconds) * time.Second,
+		})
+		http.Handle("/codex/token", apitokens.NewHandler(
+			tokenVendor,
+			apitokens.SessionAuthenticator([]byte(secrets.SessionSigningKey)),
+		))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reconciler := webhooks.NewReconciler(provisioner, configs.Webhooks, configs.ReconcileDryRun)
+	// Reconciling runs in the background so a Codex outage doesn't keep the
+	// feed from serving.
+	go reconciler.Run(ctx, time.Duration(configs.ReconcileIntervalSeconds)*time.Second)
+	if demand != nil {
+		go demand.Run(ctx)
+	}
+	if !configs.DisableWebhookWatchdog {
+		watchdog := webhooks.NewWatchdog(provisioner, ingestHandler.Stats(), configs.Webhooks, demand, webhooks.WatchdogOptions{
+			Interval:      time.Duration(configs.WebhookWatchdogIntervalSeconds) * time.Second,
+			Silence:       time.Duration(configs.WebhookSilenceSeconds) * time.Second,
+			InactiveGrace: time.Duration(configs.WebhookInactiveGraceSeconds) * time.Second,
+			AuditFile:     configs.WebhookAuditFile,
+		})
+		go watchdog.Run(ctx)
+	}
+	if tokenVendor != nil {
+		go tokenVendor.Run(ctx)
+	}
+	if backfiller != nil {
+		go backfiller.Run(ctx)
+	}
+	if lagMonitor != nil {
+		go lagMonitor.Run(ctx)
+	}
+
+	if subscriptions := configs.Subscriptions; len(subscriptions.Pairs) > 0 || len(subscriptions.Prices) > 0 {
+		opts := ingest.SubscriptionOptions{
+			Endpoint: configs.CodexSubscriptionEndpoint,
+			Token:    secrets.CodexToken,
+		}
+		for _, pair := range subscriptions.Pairs {
+			opts.Pairs = append(opts.Pairs, ingest.PairSubscription{Address: pair.Address, NetworkID: pair.NetworkId})
+		}
+		for _, token := range subscriptions.Prices {
+			opts.Prices = append(opts.Prices, codex.OnPricesUpdatedInput{Address: token.Address, NetworkId: token.NetworkId})
+		}
+		go ingest.NewSubscriptionSource(ingestHandler, opts).Run(ctx)
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
+	server := &http.Server{Addr: ":" + port}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	zlog.Info().Str("addr", server.Addr).Msg("server starting")
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
 
-	fmt.Println("WebSocket server starting on :8080")
-	fmt.Println("Open http://localhost:8080 in your browser to test")
+	if backfiller != nil {
+		if err := backfiller.Flush(); err != nil {
+			zlog.Error().Err(err).Msg("failed to save backfill checkpoints")
+		}
+	}
 
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	if configs.CleanupWebhooksOnShutdown {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := provisioner.Cleanup(cleanupCtx); err != nil {
+			zlog.Error().Err(err).Msg("failed to clean up webhooks")
+		}
+	}
 }
@@ -0,0 +1,265 @@
+// Package netstatus watches how far Codex's indexing lags behind the networks
+// we serve, so clients can tell a slow chain from a broken feed.
+package netstatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+// Topic is the topic network notices are published on, on every hub.
+const Topic = "system:networks"
+
+// SourceNetworkStatus is the envelope source of network notices.
+const SourceNetworkStatus = "codex-network-status"
+
+// Notice statuses.
+const (
+	StatusLagging   = "lagging"
+	StatusRecovered = "recovered"
+)
+
+type Options struct {
+	// Networks are always watched, in addition to the networks events are
+	// received for.
+	Networks []int
+	// Interval is how often Codex is polled. Defaults to 30s.
+	Interval time.Duration
+	// Threshold is the indexing lag over which a network is lagging. It
+	// recovers once the lag is under half of it. Defaults to 2m.
+	Threshold time.Duration
+}
+
+// Status is the latest known state of a network.
+type Status struct {
+	NetworkID   int    `json:"networkId"`
+	NetworkName string `json:"networkName,omitempty"`
+	// LastProcessedBlock and LastProcessedTimestamp are the newest block
+	// Codex has indexed.
+	LastProcessedBlock     int   `json:"lastProcessedBlock,omitempty"`
+	LastProcessedTimestamp int64 `json:"lastProcessedTimestamp,omitempty"`
+	// IndexLagSeconds is how long ago Codex's newest indexed block was
+	// produced.
+	IndexLagSeconds float64 `json:"indexLagSeconds"`
+	// LastEventTimestamp is the timestamp of the newest event we received,
+	// and FeedLagSeconds how long ago it happened.
+	LastEventTimestamp int64     `json:"lastEventTimestamp,omitempty"`
+	FeedLagSeconds     float64   `json:"feedLagSeconds,omitempty"`
+	Lagging            bool      `json:"lagging"`
+	CheckedAt          time.Time `json:"checkedAt"`
+}
+
+// Notice is published on Topic when a network starts or stops lagging.
+type Notice struct {
+	Status  string `json:"status"`
+	Network Status `json:"network"`
+}
+
+// Monitor polls Codex for the indexing status of the watched networks and
+// publishes a Notice on every hub of the registry when a network's lag
+// crosses the threshold. It serves the lag as Prometheus metrics.
+type Monitor struct {
+	client   graphql.Client
+	registry *ws.Registry
+	opts     Options
+
+	mu       sync.Mutex
+	networks map[int]*Status
+	// feedBehind are the networks already logged as having events delayed
+	// beyond Codex's indexing.
+	feedBehind map[int]bool
+}
+
+func NewMonitor(client graphql.Client, registry *ws.Registry, opts Options) *Monitor {
+	if opts.Interval == 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.Threshold == 0 {
+		opts.Threshold = 2 * time.Minute
+	}
+	m := &Monitor{
+		client:     client,
+		registry:   registry,
+		opts:       opts,
+		networks:   make(map[int]*Status),
+		feedBehind: make(map[int]bool),
+	}
+	for _, networkID := range opts.Networks {
+		m.networks[networkID] = &Status{NetworkID: networkID}
+	}
+	return m
+}
+
+// ObserveEvents records the timestamps of received events. It is meant to be
+// passed to ingest.Handler.Observe.
+func (m *Monitor) ObserveEvents(data []ingest.TokenPairEventData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range data {
+		status, ok := m.networks[d.Pair.NetworkID]
+		if !ok {
+			status = &Status{NetworkID: d.Pair.NetworkID}
+			m.networks[d.Pair.NetworkID] = status
+		}
+		status.LastEventTimestamp = max(status.LastEventTimestamp, int64(d.Event.Timestamp))
+	}
+}
+
+// Run polls Codex every Options.Interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := m.check(ctx); err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Msg("failed to check network status")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) check(ctx context.Context) error {
+	m.mu.Lock()
+	networkIDs := make([]int, 0, len(m.networks))
+	for networkID := range m.networks {
+		networkIDs = append(networkIDs, networkID)
+	}
+	m.mu.Unlock()
+	if len(networkIDs) == 0 {
+		return nil
+	}
+	slices.Sort(networkIDs)
+
+	ctx, cancel := context.WithTimeout(ctx, m.opts.Interval)
+	defer cancel()
+	resp, err := codex.GetNetworkStatus(ctx, m.client, networkIDs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	threshold := m.opts.Threshold.Seconds()
+	var notices []Notice
+	m.mu.Lock()
+	for _, network := range resp.GetNetworkStatus {
+		status, ok := m.networks[network.NetworkId]
+		if !ok || network.LastProcessedTimestamp == nil {
+			continue
+		}
+		status.NetworkName = network.NetworkName
+		if network.LastProcessedBlock != nil {
+			status.LastProcessedBlock = *network.LastProcessedBlock
+		}
+		status.LastProcessedTimestamp = int64(*network.LastProcessedTimestamp)
+		status.IndexLagSeconds = max(0, now.Sub(time.Unix(status.LastProcessedTimestamp, 0)).Seconds())
+		if status.LastEventTimestamp > 0 {
+			status.FeedLagSeconds = max(0, now.Sub(time.Unix(status.LastEventTimestamp, 0)).Seconds())
+		}
+		status.CheckedAt = now
+
+		switch {
+		case !status.Lagging && status.IndexLagSeconds > threshold:
+			status.Lagging = true
+			notices = append(notices, Notice{Status: StatusLagging, Network: *status})
+		case status.Lagging && status.IndexLagSeconds < threshold/2:
+			status.Lagging = false
+			notices = append(notices, Notice{Status: StatusRecovered, Network: *status})
+		}
+
+		// Events falling behind while Codex keeps up point at our webhooks
+		// rather than the chain.
+		behind := status.LastEventTimestamp > 0 && status.LastEventTimestamp < status.LastProcessedTimestamp &&
+			float64(status.LastProcessedTimestamp-status.LastEventTimestamp) > threshold
+		if behind && !m.feedBehind[status.NetworkID] {
+			log.Warn().Int("network_id", status.NetworkID).Int64("last_event", status.LastEventTimestamp).Int64("last_processed", status.LastProcessedTimestamp).Msg("received events are behind Codex indexing")
+		}
+		m.feedBehind[status.NetworkID] = behind
+	}
+	m.mu.Unlock()
+
+	for _, notice := range notices {
+		event := log.Warn()
+		if notice.Status == StatusRecovered {
+			event = log.Info()
+		}
+		event.Int("network_id", notice.Network.NetworkID).Str("network", notice.Network.NetworkName).Float64("lag_seconds", notice.Network.IndexLagSeconds).Msg("Codex network " + notice.Status)
+		m.publish(notice)
+	}
+	return nil
+}
+
+func (m *Monitor) publish(notice Notice) {
+	envelope := ws.NewEnvelope(ws.EnvelopeNotice, Topic, notice)
+	envelope.Source = SourceNetworkStatus
+	key := fmt.Sprint(notice.Network.NetworkID)
+	for _, name := range m.registry.Names() {
+		if _, err := m.registry.PublishKeyed(name, key, envelope); err != nil {
+			log.Error().Err(err).Str("hub", name).Msg("failed to publish network notice")
+		}
+	}
+}
+
+// Statuses returns the status of every watched network, by network ID.
+func (m *Monitor) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]Status, 0, len(m.networks))
+	for _, status := range m.networks {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NetworkID < statuses[j].NetworkID
+	})
+	return statuses
+}
+
+// ServeHTTP writes the lag of the networks checked so far in the Prometheus
+// text format.
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var checked []Status
+	for _, status := range m.Statuses() {
+		if !status.CheckedAt.IsZero() {
+			checked = append(checked, status)
+		}
+	}
+
+	b := strings.Builder{}
+	gauge := func(name string, help string, value func(Status) (float64, bool)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, status := range checked {
+			if v, ok := value(status); ok {
+				fmt.Fprintf(&b, "%s{network_id=\"%d\",network=%q} %g\n", name, status.NetworkID, status.NetworkName, v)
+			}
+		}
+	}
+	gauge("codex_network_index_lag_seconds", "How long ago the newest block Codex indexed was produced.", func(s Status) (float64, bool) {
+		return s.IndexLagSeconds, true
+	})
+	gauge("codex_network_feed_lag_seconds", "How long ago the newest event received for the network happened.", func(s Status) (float64, bool) {
+		return s.FeedLagSeconds, s.LastEventTimestamp > 0
+	})
+	gauge("codex_network_lagging", "Whether Codex indexing lags over the threshold.", func(s Status) (float64, bool) {
+		if s.Lagging {
+			return 1, true
+		}
+		return 0, true
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
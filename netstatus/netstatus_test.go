@@ -0,0 +1,220 @@
+package netstatus
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/ws"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/gorilla/websocket"
+)
+
+// statusClient answers GetNetworkStatus with the networks it was given a lag
+// for, as if Codex's newest indexed block was produced that long ago.
+type statusClient struct {
+	mu  sync.Mutex
+	lag map[int]time.Duration
+}
+
+func (c *statusClient) setLag(networkID int, lag time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lag[networkID] = lag
+}
+
+func (c *statusClient) MakeRequest(ctx context.Context, req *graphql.Request, resp *graphql.Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var networks []codex.NetworkStatus
+	for networkID, lag := range c.lag {
+		networks = append(networks, codex.NetworkStatus{
+			NetworkId:              networkID,
+			NetworkName:            "network-" + strconv.Itoa(networkID),
+			LastProcessedBlock:     codex.Ptr(100),
+			LastProcessedTimestamp: codex.Ptr(int(time.Now().Add(-lag).Unix())),
+		})
+	}
+	resp.Data.(*codex.GetNetworkStatusResponse).GetNetworkStatus = networks
+	return nil
+}
+
+type testHubManager struct {
+	hub *ws.Hub[any]
+}
+
+func (m *testHubManager) GetHub() *ws.Hub[any]                                          { return m.hub }
+func (m *testHubManager) OnRegister(client *ws.UserClient[any]) error                   { return nil }
+func (m *testHubManager) OnUnregister(client *ws.UserClient[any]) error                 { return nil }
+func (m *testHubManager) OnReceiveMessage(client *ws.UserClient[any], msg []byte) error { return nil }
+
+type noticeEnvelope struct {
+	Topic  string `json:"topic"`
+	Source string `json:"source"`
+	Data   Notice `json:"data"`
+}
+
+// connect registers a hub named name on registry and returns a channel
+// receiving every envelope a client of the hub gets.
+func connect(t *testing.T, registry *ws.Registry, name string) <-chan noticeEnvelope {
+	t.Helper()
+	if err := ws.Register(registry, name, &testHubManager{hub: ws.NewHub[any]()}, ws.HubConfig[any]{Path: "/" + name}); err != nil {
+		t.Fatal(err)
+	}
+	hub, _ := registry.Get(name)
+	server := httptest.NewServer(hub)
+	t.Cleanup(server.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	waitFor(t, "the client to connect", func() bool { return hub.ClientCount() == 1 })
+
+	envelopes := make(chan noticeEnvelope, 16)
+	go func() {
+		for {
+			var envelope noticeEnvelope
+			if err := conn.ReadJSON(&envelope); err != nil {
+				return
+			}
+			envelopes <- envelope
+		}
+	}()
+	return envelopes
+}
+
+// waitFor polls until cond holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// expectNotice fails unless the next envelope is a notice with status for
+// networkID.
+func expectNotice(t *testing.T, envelopes <-chan noticeEnvelope, status string, networkID int) {
+	t.Helper()
+	select {
+	case envelope := <-envelopes:
+		if envelope.Topic != Topic || envelope.Source != SourceNetworkStatus || envelope.Data.Status != status || envelope.Data.Network.NetworkID != networkID {
+			t.Fatalf("got %+v, want a %s notice for network %d", envelope, status, networkID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a %s notice", status)
+	}
+}
+
+// assertNoMore fails if another envelope arrives shortly.
+func assertNoMore(t *testing.T, envelopes <-chan noticeEnvelope) {
+	t.Helper()
+	select {
+	case envelope := <-envelopes:
+		t.Fatalf("got unexpected envelope %+v", envelope)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLagHysteresis(t *testing.T) {
+	registry := ws.NewRegistry()
+	hubs := []<-chan noticeEnvelope{connect(t, registry, "pairs"), connect(t, registry, "prices")}
+	client := &statusClient{lag: map[int]time.Duration{1: 30 * time.Second}}
+	m := NewMonitor(client, registry, Options{Networks: []int{1}, Threshold: 2 * time.Minute})
+	ctx := context.Background()
+
+	for _, step := range []struct {
+		lag     time.Duration
+		notice  string
+		lagging bool
+	}{
+		{30 * time.Second, "", false},
+		{5 * time.Minute, StatusLagging, true},
+		// Under the threshold, but not under half of it.
+		{90 * time.Second, "", true},
+		{5 * time.Minute, "", true},
+		{30 * time.Second, StatusRecovered, false},
+		{90 * time.Second, "", false},
+		{5 * time.Minute, StatusLagging, true},
+	} {
+		client.setLag(1, step.lag)
+		if err := m.check(ctx); err != nil {
+			t.Fatal(err)
+		}
+		for _, envelopes := range hubs {
+			if step.notice != "" {
+				expectNotice(t, envelopes, step.notice, 1)
+			}
+			assertNoMore(t, envelopes)
+		}
+		if lagging := m.Statuses()[0].Lagging; lagging != step.lagging {
+			t.Fatalf("network lagging is %t after a lag of %s", lagging, step.lag)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	registry := ws.NewRegistry()
+	client := &statusClient{lag: map[int]time.Duration{1: 5 * time.Minute, 56: 10 * time.Second}}
+	// Network 137 is watched but Codex has no status for it.
+	m := NewMonitor(client, registry, Options{Networks: []int{1, 56, 137}, Threshold: 2 * time.Minute})
+	m.ObserveEvents([]ingest.TokenPairEventData{{
+		Event: ingest.TokenPairEvent{Timestamp: int(time.Now().Add(-time.Minute).Unix())},
+		Pair:  ingest.Pair{NetworkID: 1},
+	}})
+	if err := m.check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/plain; version=0.0.4" {
+		t.Fatalf("content type %q", contentType)
+	}
+	samples := make(map[string]float64)
+	for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, _ := strings.Cut(line, " ")
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			t.Fatalf("bad sample %q: %v", line, err)
+		}
+		samples[name] = v
+	}
+
+	for name, want := range map[string]float64{
+		`codex_network_index_lag_seconds{network_id="1",network="network-1"}`:   300,
+		`codex_network_index_lag_seconds{network_id="56",network="network-56"}`: 10,
+		`codex_network_feed_lag_seconds{network_id="1",network="network-1"}`:    60,
+		`codex_network_lagging{network_id="1",network="network-1"}`:             1,
+		`codex_network_lagging{network_id="56",network="network-56"}`:           0,
+	} {
+		got, ok := samples[name]
+		if !ok {
+			t.Fatalf("no sample %s in\n%s", name, w.Body)
+		}
+		if got < want || got > want+2 {
+			t.Fatalf("%s is %g, want %g", name, got, want)
+		}
+	}
+	if len(samples) != 5 {
+		t.Fatalf("got %d samples, want 5 for the checked networks:\n%s", len(samples), w.Body)
+	}
+	for _, help := range []string{"codex_network_index_lag_seconds", "codex_network_feed_lag_seconds", "codex_network_lagging"} {
+		if !strings.Contains(w.Body.String(), "# TYPE "+help+" gauge\n") {
+			t.Fatalf("no TYPE line for %s", help)
+		}
+	}
+}
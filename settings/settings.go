@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/Acrylic125/webhook-ingest-ws/utils"
 	"github.com/go-playground/validator/v10"
@@ -18,21 +19,148 @@ const (
 	EnvLocal      = "local"
 )
 
+// EnvPrefix starts the environment variables that override single fields of
+// Configs and Secrets, followed by the field's `env` tag, e.g.
+// WIS_WEBHOOK_TARGET_URL. See utils.MapEnv for how values are parsed.
+const EnvPrefix = "WIS_"
+
 type Settings struct {
 	Secrets *Secrets
 	Configs *Configs
 }
 
+// FieldSource tells where the value of a field of Configs or Secrets came
+// from: the file or injected blob it was read from, "env <VAR>", "default"
+// or "unset".
+type FieldSource struct {
+	Field  string `json:"field"`
+	Source string `json:"source"`
+}
+
 type Configs struct {
-	WebhookTargetUrl string `json:",omitempty" validate:"required" default:"staging-api.limbolabs.xyz/watchlist"`
+	WebhookTargetUrl string `json:",omitempty" env:"WEBHOOK_TARGET_URL" validate:"required" default:"staging-api.limbolabs.xyz/watchlist"`
+	CodexEndpoint    string `json:",omitempty" env:"CODEX_ENDPOINT" validate:"required,url" default:"https://graph.codex.io/graphql"`
+	// Token pair webhooks kept in place by the reconciler, identified by name.
+	Webhooks []WebhookConfig `json:",omitempty" env:"WEBHOOKS" validate:"dive"`
+	// Tells apart deployments sharing a Codex account in one environment,
+	// e.g. a tenant or instance name. Webhooks are created, listed and deleted
+	// in a bucket of the environment and this key only, so deployments never
+	// touch each other's webhooks.
+	WebhookBucketKey string `json:",omitempty" env:"WEBHOOK_BUCKET_KEY" validate:"required,max=32" default:"default"`
+	// File recording the IDs of the webhooks we created.
+	WebhookStateFile string `json:",omitempty" env:"WEBHOOK_STATE_FILE" validate:"required" default:"webhooks_state.json"`
+	// Delete the webhooks we created when the server shuts down.
+	CleanupWebhooksOnShutdown bool `json:",omitempty" env:"CLEANUP_WEBHOOKS_ON_SHUTDOWN"`
+	// How often webhooks are reconciled with Codex.
+	ReconcileIntervalSeconds int `json:",omitempty" env:"RECONCILE_INTERVAL_SECONDS" validate:"min=10" default:"300"`
+	// Only log the reconcile plan instead of applying it.
+	ReconcileDryRun bool `json:",omitempty" env:"RECONCILE_DRY_RUN"`
+	// Don't recreate webhooks that stopped delivering.
+	DisableWebhookWatchdog         bool `json:",omitempty" env:"DISABLE_WEBHOOK_WATCHDOG"`
+	WebhookWatchdogIntervalSeconds int  `json:",omitempty" env:"WEBHOOK_WATCHDOG_INTERVAL_SECONDS" validate:"min=10" default:"60"`
+	// Least time a webhook that delivered before may be silent before it is
+	// recreated. Webhooks that usually deliver less often get longer. It is
+	// judged from the deliveries each replica receives, so replicas sharing
+	// deliveries behind a load balancer need a longer silence.
+	WebhookSilenceSeconds int `json:",omitempty" env:"WEBHOOK_SILENCE_SECONDS" validate:"min=60" default:"1800"`
+	// How long a webhook may be other than ACTIVE on Codex before it is
+	// recreated.
+	WebhookInactiveGraceSeconds int `json:",omitempty" env:"WEBHOOK_INACTIVE_GRACE_SECONDS" validate:"min=1" default:"120"`
+	// File the recreated webhooks are logged to, one JSON object per line.
+	WebhookAuditFile string `json:",omitempty" env:"WEBHOOK_AUDIT_FILE" validate:"required" default:"webhooks_audit.jsonl"`
+	// Don't create webhooks for pairs and tokens clients subscribe to.
+	DisableDemandWebhooks bool `json:",omitempty" env:"DISABLE_DEMAND_WEBHOOKS"`
+	// Most webhooks created for subscriptions at once.
+	MaxDemandWebhooks int `json:",omitempty" env:"MAX_DEMAND_WEBHOOKS" validate:"min=1" default:"100"`
+	// How long a subscription webhook is kept after its last subscriber leaves.
+	DemandWebhookGraceSeconds int    `json:",omitempty" env:"DEMAND_WEBHOOK_GRACE_SECONDS" validate:"min=0" default:"300"`
+	CodexSubscriptionEndpoint string `json:",omitempty" env:"CODEX_SUBSCRIPTION_ENDPOINT" validate:"required,url" default:"wss://graph.codex.io/graphql"`
+	// Events received through Codex GraphQL subscriptions, for when Codex
+	// can't reach WebhookTargetUrl, e.g. when developing locally.
+	Subscriptions SubscriptionConfig `json:",omitempty" env:"SUBSCRIPTIONS"`
+	// Short-lived Codex API tokens issued to signed-in browser clients.
+	ApiTokenTTLSeconds         int `json:",omitempty" env:"API_TOKEN_TTL_SECONDS" validate:"min=60" default:"3600"`
+	ApiTokenRequestLimit       int `json:",omitempty" env:"API_TOKEN_REQUEST_LIMIT" validate:"min=1" default:"5000"`
+	ApiTokenRefreshSeconds     int `json:",omitempty" env:"API_TOKEN_REFRESH_SECONDS" validate:"min=0" default:"300"`
+	ApiTokenQuota              int `json:",omitempty" env:"API_TOKEN_QUOTA" validate:"min=1" default:"10"`
+	ApiTokenQuotaWindowSeconds int `json:",omitempty" env:"API_TOKEN_QUOTA_WINDOW_SECONDS" validate:"min=60" default:"86400"`
+	// Don't attach token names, symbols, decimals and icons to events.
+	DisableTokenEnrichment bool `json:",omitempty" env:"DISABLE_TOKEN_ENRICHMENT"`
+	// How many tokens' metadata is cached, and for how long.
+	TokenMetadataCacheSize  int `json:",omitempty" env:"TOKEN_METADATA_CACHE_SIZE" validate:"min=1" default:"10000"`
+	TokenMetadataTTLSeconds int `json:",omitempty" env:"TOKEN_METADATA_TTL_SECONDS" validate:"min=1" default:"3600"`
+	// How long publishing an event waits for its tokens' metadata.
+	TokenMetadataWaitMillis int `json:",omitempty" env:"TOKEN_METADATA_WAIT_MILLIS" validate:"min=1" default:"250"`
+	// Don't recover events missed while down or while webhooks weren't
+	// delivering from Codex's history.
+	DisableBackfill bool `json:",omitempty" env:"DISABLE_BACKFILL"`
+	// File recording the latest event processed per pair.
+	BackfillStateFile string `json:",omitempty" env:"BACKFILL_STATE_FILE" validate:"required" default:"backfill_state.json"`
+	// How long a pair may go without events before a backfill is triggered.
+	BackfillGapSeconds int `json:",omitempty" env:"BACKFILL_GAP_SECONDS" validate:"min=1" default:"300"`
+	// Least time between two backfills of a pair triggered by gaps, so
+	// naturally quiet pairs don't query Codex on every event.
+	BackfillGapCooldownSeconds int `json:",omitempty" env:"BACKFILL_GAP_COOLDOWN_SECONDS" validate:"min=60" default:"3600"`
+	// How far back, and how many events, a backfill recovers at most.
+	BackfillMaxWindowSeconds int `json:",omitempty" env:"BACKFILL_MAX_WINDOW_SECONDS" validate:"min=60" default:"21600"`
+	BackfillMaxEvents        int `json:",omitempty" env:"BACKFILL_MAX_EVENTS" validate:"min=1" default:"1000"`
+	// Don't poll Codex for how far its indexing lags behind each network.
+	DisableLagMonitor bool `json:",omitempty" env:"DISABLE_LAG_MONITOR"`
+	// Networks watched for lag in addition to those of Webhooks,
+	// Subscriptions and received events.
+	LagNetworkIds           []int `json:",omitempty" env:"LAG_NETWORK_IDS" validate:"dive,min=1"`
+	LagCheckIntervalSeconds int   `json:",omitempty" env:"LAG_CHECK_INTERVAL_SECONDS" validate:"min=5" default:"30"`
+	// Indexing lag over which clients are told a network is lagging.
+	LagThresholdSeconds int `json:",omitempty" env:"LAG_THRESHOLD_SECONDS" validate:"min=1" default:"120"`
+	// Don't let signed-in clients create price and market cap alerts.
+	DisableAlerts bool `json:",omitempty" env:"DISABLE_ALERTS"`
+	// File recording which user owns each alert webhook. It isn't shared
+	// between replicas, so alerts need a single instance; the others drop
+	// alert deliveries.
+	AlertStateFile string `json:",omitempty" env:"ALERT_STATE_FILE" validate:"required" default:"alerts_state.json"`
+	// Most active alerts per user.
+	MaxAlertsPerUser int `json:",omitempty" env:"MAX_ALERTS_PER_USER" validate:"min=1" default:"20"`
+}
+
+// WebhookConfig declares the conditions of a token pair event webhook. Empty
+// conditions are not sent.
+type WebhookConfig struct {
+	Name            string   `json:",omitempty" validate:"required,max=128"`
+	NetworkIds      []int    `json:",omitempty"`
+	PairAddress     string   `json:",omitempty"`
+	TokenAddress    string   `json:",omitempty"`
+	ExchangeAddress string   `json:",omitempty"`
+	Maker           string   `json:",omitempty"`
+	EventTypes      []string `json:",omitempty" validate:"dive,oneof=SWAP MINT BURN SYNC BUY SELL COLLECT COLLECT_PROTOCOL"`
+	MinSwapValueUsd string   `json:",omitempty" validate:"omitempty,number"`
+}
+
+type SubscriptionConfig struct {
+	// Pairs to receive token pair events for.
+	Pairs []SubscriptionTarget `json:",omitempty" env:"PAIRS" validate:"dive"`
+	// Tokens to receive price updates for.
+	Prices []SubscriptionTarget `json:",omitempty" env:"PRICES" validate:"dive"`
+}
+
+type SubscriptionTarget struct {
+	Address   string `json:",omitempty" validate:"required"`
+	NetworkId int    `json:",omitempty" validate:"required,min=1"`
 }
 
 type Secrets struct {
-	CodexToken string `json:",omitempty" validate:"required"`
+	CodexToken string `json:",omitempty" env:"CODEX_TOKEN" validate:"required"`
+	// Hashed with each delivery's deduplicationId to authenticate webhooks.
+	WebhookSecurityToken string `json:",omitempty" env:"WEBHOOK_SECURITY_TOKEN" validate:"required"`
+	// Bearer token for the admin API, which is disabled when empty.
+	AdminToken string `json:",omitempty" env:"ADMIN_TOKEN" validate:"omitempty,min=16"`
+	// HS256 key of the session JWTs sent to get Codex API tokens. Tokens
+	// aren't vended when empty.
+	SessionSigningKey string `json:",omitempty" env:"SESSION_SIGNING_KEY" validate:"omitempty,min=32"`
 }
 
 var (
 	settings = Settings{}
+	sources  []FieldSource
 	env      = EnvLocal
 	logger   = log.Logger
 )
@@ -45,6 +173,12 @@ func Get() Settings {
 	return settings
 }
 
+// Sources returns where each field of the loaded settings came from, Secrets
+// first, in declaration order.
+func Sources() []FieldSource {
+	return sources
+}
+
 func Init(envp string) {
 	logger = log.With().
 		Str("env", envp).
@@ -65,60 +199,85 @@ func Init(envp string) {
 		// If there's err in loading settings, blow-up!
 		logger.Fatal().Err(err).Msg("failed to load ENV")
 	}
+	for _, source := range sources {
+		if strings.HasPrefix(source.Source, "env ") {
+			logger.Info().Str("field", source.Field).Str("source", source.Source).Msg("setting overridden from environment")
+		} else {
+			logger.Debug().Str("field", source.Field).Str("source", source.Source).Msg("setting loaded")
+		}
+	}
 
 	log.Info().Msg("settings initialized")
 }
 
 func LoadSettingsByEnv() error {
-	secrets, err := getSecrets()
+	secrets, secretSources, err := getSecrets()
 	if err != nil {
 		return fmt.Errorf("failed to load secrets: %w", err)
 	}
 	settings.Secrets = secrets
 
-	configs, err := getConfigs()
+	configs, configSources, err := getConfigs()
 	if err != nil {
 		return fmt.Errorf("failed to load configs: %w", err)
 	}
 	settings.Configs = configs
+	sources = append(secretSources, configSources...)
 
 	return nil
 }
 
-func getSecrets() (*Secrets, error) {
+func getSecrets() (*Secrets, []FieldSource, error) {
 	result := &Secrets{}
 	var secretsContent []byte
+	var origin string
 	if env != EnvProduction {
 		secretsFile := fmt.Sprintf("internal/settings/secrets_%s.json", env)
 		content, err := os.ReadFile(filepath.Clean(secretsFile))
 		if err != nil {
-			return nil, fmt.Errorf("error while reading secrets file [%s]: %w", secretsFile, err)
+			return nil, nil, fmt.Errorf("error while reading secrets file [%s]: %w", secretsFile, err)
 		}
 		secretsContent = content
+		origin = "file " + secretsFile
 	} else {
 		// If the environment is production, we will get the secrets from the injected env
 		injectedSecrets := os.Getenv("SECRETS")
+		if injectedSecrets == "" {
+			// Every secret may come from its own variable instead.
+			injectedSecrets = "{}"
+		}
 		secretsContent = []byte(injectedSecrets)
+		origin = "SECRETS"
 	}
 
 	if jsonErr := json.Unmarshal(secretsContent, result); jsonErr != nil {
-		return nil, fmt.Errorf(
+		return nil, nil, fmt.Errorf(
 			"error while parsing secretsContent for env[%s]: %w",
 			env,
 			jsonErr,
 		)
 	}
 
+	loaded, err := utils.JSONFields(secretsContent, result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while parsing secretsContent for env[%s]: %w", env, err)
+	}
+	overrides, err := utils.MapEnv(result, EnvPrefix, os.LookupEnv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to map environment overrides: %w", err)
+	}
+
 	if err := validator.New().Struct(result); err != nil {
-		return nil, fmt.Errorf("secrets content struct validation failed: %w", err)
+		return nil, nil, fmt.Errorf("secrets content struct validation failed: %w", err)
 	}
 
-	return result, nil
+	return result, fieldSources("Secrets", origin, loaded, overrides, utils.Fields(result)), nil
 }
 
-func getConfigs() (*Configs, error) {
+func getConfigs() (*Configs, []FieldSource, error) {
 	result := &Configs{}
 	var configsContent []byte
+	var origin string
 	if env != EnvProduction {
 		configFile := fmt.Sprintf("internal/settings/config_%s.json", env)
 		if _, err := os.Stat(configFile); os.IsNotExist(err) {
@@ -126,11 +285,12 @@ func getConfigs() (*Configs, error) {
 		} else {
 			content, err := os.ReadFile(filepath.Clean(configFile))
 			if err != nil {
-				return nil, fmt.Errorf("error while reading config file [%s]: %w", configFile, err)
+				return nil, nil, fmt.Errorf("error while reading config file [%s]: %w", configFile, err)
 			}
 
 			configsContent = content
 		}
+		origin = "file " + configFile
 	} else {
 		// If the environment is production, we will get the config from the injected env
 		injectedConfigs := os.Getenv("CONFIGS")
@@ -139,24 +299,59 @@ func getConfigs() (*Configs, error) {
 		} else {
 			configsContent = []byte(injectedConfigs)
 		}
+		origin = "CONFIGS"
 	}
 
 	if jsonErr := json.Unmarshal(configsContent, result); jsonErr != nil {
-		return nil, fmt.Errorf(
+		return nil, nil, fmt.Errorf(
 			"error while parsing configsContent for env[%s]: %w",
 			configsContent,
 			jsonErr,
 		)
 	}
 
-	// Map the default values
-	if err := utils.MapDefaults(result); err != nil {
-		return nil, fmt.Errorf("failed to map default values: %w", err)
+	// Defaults fill in the fields the file or blob left out, keeping those
+	// it set to zero, and then single fields may be overridden from the
+	// environment.
+	loaded, err := utils.JSONFields(configsContent, result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while parsing configsContent for env[%s]: %w", env, err)
+	}
+	if err := utils.MapDefaultsExcept(result, loaded); err != nil {
+		return nil, nil, fmt.Errorf("failed to map default values: %w", err)
+	}
+	overrides, err := utils.MapEnv(result, EnvPrefix, os.LookupEnv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to map environment overrides: %w", err)
 	}
 
 	if err := validator.New().Struct(result); err != nil {
-		return nil, fmt.Errorf("failed to validate struct: %w", err)
+		return nil, nil, fmt.Errorf("failed to validate struct: %w", err)
 	}
 
-	return result, nil
+	return result, fieldSources("Configs", origin, loaded, overrides, utils.Fields(result)), nil
+}
+
+// fieldSources tells where each of fields came from, given the paths of the
+// fields set by origin and the ones overridden from the environment. Fields
+// set otherwise got their default.
+func fieldSources(name string, origin string, fromOrigin map[string]bool, overrides []utils.EnvOverride, fields []utils.Field) []FieldSource {
+	fromEnv := make(map[string]string, len(overrides))
+	for _, override := range overrides {
+		fromEnv[override.Path] = override.Var
+	}
+
+	result := make([]FieldSource, len(fields))
+	for i, field := range fields {
+		source := "unset"
+		if key, ok := fromEnv[field.Path]; ok {
+			source = "env " + key
+		} else if fromOrigin[field.Path] {
+			source = origin
+		} else if field.Set {
+			source = "default"
+		}
+		result[i] = FieldSource{Field: name + "." + field.Path, Source: source}
+	}
+	return result
 }
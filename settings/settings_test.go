@@ -0,0 +1,47 @@
+package settings
+
+import (
+	"testing"
+)
+
+func TestGetConfigs(t *testing.T) {
+	previous := env
+	env = EnvProduction
+	t.Cleanup(func() { env = previous })
+	t.Setenv("CONFIGS", `{"DemandWebhookGraceSeconds": 0, "MaxDemandWebhooks": 5, "Subscriptions": {"Pairs": [{"Address": "0xa", "NetworkId": 1}]}}`)
+	t.Setenv(EnvPrefix+"API_TOKEN_REFRESH_SECONDS", "0")
+	t.Setenv(EnvPrefix+"MAX_DEMAND_WEBHOOKS", "7")
+	t.Setenv(EnvPrefix+"SUBSCRIPTIONS_PRICES", `[{"Address": "0xb", "NetworkId": 1}]`)
+
+	configs, sources, err := getConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configs.DemandWebhookGraceSeconds != 0 || configs.ApiTokenRefreshSeconds != 0 {
+		t.Fatalf("values set to 0 got defaults: grace %d, refresh %d", configs.DemandWebhookGraceSeconds, configs.ApiTokenRefreshSeconds)
+	}
+	if configs.MaxDemandWebhooks != 7 || configs.ReconcileIntervalSeconds != 300 {
+		t.Fatalf("got max demand webhooks %d and reconcile interval %d, want 7 and 300", configs.MaxDemandWebhooks, configs.ReconcileIntervalSeconds)
+	}
+	if len(configs.Subscriptions.Pairs) != 1 || len(configs.Subscriptions.Prices) != 1 {
+		t.Fatalf("unexpected subscriptions: %+v", configs.Subscriptions)
+	}
+
+	got := make(map[string]string, len(sources))
+	for _, source := range sources {
+		got[source.Field] = source.Source
+	}
+	for field, want := range map[string]string{
+		"Configs.DemandWebhookGraceSeconds": "CONFIGS",
+		"Configs.ApiTokenRefreshSeconds":    "env WIS_API_TOKEN_REFRESH_SECONDS",
+		"Configs.MaxDemandWebhooks":         "env WIS_MAX_DEMAND_WEBHOOKS",
+		"Configs.Subscriptions.Pairs":       "CONFIGS",
+		"Configs.Subscriptions.Prices":      "env WIS_SUBSCRIPTIONS_PRICES",
+		"Configs.ReconcileIntervalSeconds":  "default",
+		"Configs.CleanupWebhooksOnShutdown": "unset",
+	} {
+		if got[field] != want {
+			t.Errorf("%s: got source %q, want %q", field, got[field], want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff returns the jittered exponential delay before retry attempt (0 for
+// the first retry), drawn uniformly from [minDelay, min(maxDelay,
+// minDelay*2^attempt)]. A maxDelay below minDelay is raised to it.
+func Backoff(minDelay time.Duration, maxDelay time.Duration, attempt int) time.Duration {
+	minDelay = max(minDelay, 0)
+	maxDelay = max(maxDelay, minDelay)
+	ceiling := maxDelay
+	if attempt >= 0 && attempt < 63 && minDelay <= maxDelay>>attempt {
+		ceiling = minDelay << attempt
+	}
+	return minDelay + time.Duration(rand.Int63n(int64(ceiling-minDelay)+1))
+}
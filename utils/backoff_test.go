@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		min, max time.Duration
+		attempt  int
+		from, to time.Duration
+	}{
+		{"first retry", time.Second, time.Minute, 0, time.Second, time.Second},
+		{"grows", time.Second, time.Minute, 3, time.Second, 8 * time.Second},
+		{"capped", time.Second, time.Minute, 10, time.Second, time.Minute},
+		{"many attempts", 10 * time.Second, time.Minute, 1000, 10 * time.Second, time.Minute},
+		{"min above max", 20 * time.Second, 10 * time.Second, 2, 20 * time.Second, 20 * time.Second},
+		{"no min", 0, time.Second, 5, 0, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 100 {
+				if d := Backoff(tt.min, tt.max, tt.attempt); d < tt.from || d > tt.to {
+					t.Fatalf("got %s, want between %s and %s", d, tt.from, tt.to)
+				}
+			}
+		})
+	}
+}
@@ -1,53 +1,252 @@
 package utils
 
 import (
+	"encoding"
 	"errors"
+	"fmt"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 var ErrNotPointerToStruct error = errors.New("s must be a pointer to a struct")
 
+var ErrUnsupportedType error = errors.New("unsupported type")
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// MapDefaults sets the zero fields of the struct s points to from their
+// `default` tags. Nested structs, pointers to structs and the structs in
+// slices are filled in recursively; a nil pointer to a struct is allocated if
+// its type has defaults and isn't one of the structs it is nested in.
+// Defaults are parsed as described in ParseValue.
+// Errors name the path of the field, e.g. "Subscriptions.Pairs[0].NetworkId".
 func MapDefaults(s interface{}) error {
+	return MapDefaultsExcept(s, nil)
+}
+
+// MapDefaultsExcept is MapDefaults for structs whose zero values may have
+// been set on purpose: fields whose path is in set, such as those returned by
+// JSONFields, keep their value.
+func MapDefaultsExcept(s interface{}, set map[string]bool) error {
 	val := reflect.ValueOf(s)
 
 	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
 		return ErrNotPointerToStruct
 	}
+	return mapDefaults(val.Elem(), "", set, nil)
+}
 
-	val = val.Elem()
+// mapDefaults maps the defaults of val. enclosing are the types of the
+// structs val is nested in, so recursive types stop at nil pointers.
+func mapDefaults(val reflect.Value, path string, set map[string]bool, enclosing []reflect.Type) error {
 	typ := val.Type()
-
+	enclosing = append(enclosing, typ)
 	for i := 0; i < val.NumField(); i++ {
 		field := val.Field(i)
 		fieldType := typ.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		fieldPath := joinPath(path, fieldType.Name)
+
+		if defaultValue, ok := fieldType.Tag.Lookup("default"); ok && defaultValue != "" {
+			// Defaults are parsed even when unused so a bad one fails
+			// every time.
+			parsed := reflect.New(field.Type()).Elem()
+			if err := ParseValue(parsed, defaultValue); err != nil {
+				return fmt.Errorf("%s: invalid default %q: %w", fieldPath, defaultValue, err)
+			}
+			if field.IsZero() && !set[fieldPath] {
+				field.Set(parsed)
+			}
+			continue
+		}
+
+		if err := mapNestedDefaults(field, fieldPath, set, enclosing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapNestedDefaults fills in the defaults of the structs held by field.
+func mapNestedDefaults(field reflect.Value, path string, set map[string]bool, enclosing []reflect.Type) error {
+	if parsesText(field.Type()) {
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.Struct:
+		return mapDefaults(field, path, set, enclosing)
+	case reflect.Ptr:
+		if field.Type().Elem().Kind() != reflect.Struct || parsesText(field.Type().Elem()) {
+			return nil
+		}
+		if field.IsNil() {
+			if set[path] || slices.Contains(enclosing, field.Type().Elem()) || !hasDefaults(field.Type().Elem(), make(map[reflect.Type]bool)) {
+				return nil
+			}
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return mapDefaults(field.Elem(), path, set, enclosing)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			if err := mapNestedDefaults(field.Index(i), fmt.Sprintf("%s[%d]", path, i), set, enclosing); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasDefaults reports whether typ, a struct type, or a struct it holds has a
+// field with a default. seen guards against recursive types.
+func hasDefaults(typ reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[typ] {
+		return false
+	}
+	seen[typ] = true
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if value, ok := field.Tag.Lookup("default"); ok && value != "" {
+			return true
+		}
+		nested := field.Type
+		if nested.Kind() == reflect.Ptr {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && !parsesText(nested) && hasDefaults(nested, seen) {
+			return true
+		}
+	}
+	return false
+}
 
-		if field.CanSet() && field.IsZero() {
-			if defaultValue, ok := fieldType.Tag.Lookup("default"); ok {
-				if defaultValue == "" {
-					continue
-				}
-
-				switch field.Kind() {
-				case reflect.String:
-					field.SetString(defaultValue)
-				case reflect.Int:
-					if intValue, err := strconv.ParseInt(defaultValue, 10, 64); err == nil {
-						field.SetInt(intValue)
-					} else {
-						return err
-					}
-				case reflect.Bool:
-					if boolValue, err := strconv.ParseBool(defaultValue); err == nil {
-						field.SetBool(boolValue)
-					} else {
-						return err
-					}
-				default:
-					panic("unsupported type")
-				}
+// ParseValue parses s into dst, which must be settable. It supports strings,
+// bools, ints, uints and floats of every size, time.Duration, types
+// implementing encoding.TextUnmarshaler, pointers to any of these, slices
+// and arrays as comma-separated elements, and maps as comma-separated
+// key=value pairs.
+func ParseValue(dst reflect.Value, s string) error {
+	typ := dst.Type()
+	if parsesText(typ) {
+		return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if typ == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(int64(d))
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		dst.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, typ.Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, typ.Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, typ.Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+	case reflect.Ptr:
+		elem := reflect.New(typ.Elem())
+		if err := ParseValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		dst.Set(elem)
+	case reflect.Slice:
+		parts := splitList(s)
+		slice := reflect.MakeSlice(typ, len(parts), len(parts))
+		for i, part := range parts {
+			if err := ParseValue(slice.Index(i), part); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		dst.Set(slice)
+	case reflect.Array:
+		parts := splitList(s)
+		if len(parts) != typ.Len() {
+			return fmt.Errorf("want %d elements, got %d", typ.Len(), len(parts))
+		}
+		for i, part := range parts {
+			if err := ParseValue(dst.Index(i), part); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+	case reflect.Map:
+		m := reflect.MakeMap(typ)
+		for _, pair := range splitList(s) {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("entry %q is not key=value", pair)
 			}
+			k := reflect.New(typ.Key()).Elem()
+			if err := ParseValue(k, strings.TrimSpace(key)); err != nil {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+			v := reflect.New(typ.Elem()).Elem()
+			if err := ParseValue(v, strings.TrimSpace(value)); err != nil {
+				return fmt.Errorf("value of %q: %w", key, err)
+			}
+			m.SetMapIndex(k, v)
 		}
+		dst.Set(m)
+	default:
+		return fmt.Errorf("%w %s", ErrUnsupportedType, typ)
 	}
 	return nil
 }
+
+// parsesText reports whether a pointer to typ implements
+// encoding.TextUnmarshaler.
+func parsesText(typ reflect.Type) bool {
+	return typ.Kind() != reflect.Ptr && reflect.PointerTo(typ).Implements(textUnmarshalerType)
+}
+
+// splitList splits a comma-separated list, trimming spaces. An empty string
+// is an empty list.
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
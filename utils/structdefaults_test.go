@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recursiveDefaults struct {
+	Next *recursiveDefaults
+	V    int `default:"1"`
+}
+
+func TestMapDefaultsRecursiveType(t *testing.T) {
+	s := recursiveDefaults{Next: &recursiveDefaults{}}
+	if err := MapDefaults(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.V != 1 || s.Next.V != 1 {
+		t.Fatalf("defaults not mapped: V=%d Next.V=%d", s.V, s.Next.V)
+	}
+	if s.Next.Next != nil {
+		t.Fatal("nil pointer to an enclosing type was allocated")
+	}
+}
+
+type nestedDefaults struct {
+	Timeout time.Duration `default:"5s"`
+	Sizes   []uint8       `default:"1, 2"`
+	Inner   *struct {
+		Ratio float32 `default:"0.5"`
+	}
+	Items []struct {
+		Name string `default:"item"`
+	}
+}
+
+func TestMapDefaultsNested(t *testing.T) {
+	s := nestedDefaults{}
+	s.Items = make([]struct {
+		Name string `default:"item"`
+	}, 2)
+	s.Items[1].Name = "set"
+	if err := MapDefaults(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Timeout != 5*time.Second || len(s.Sizes) != 2 || s.Sizes[1] != 2 {
+		t.Fatalf("unexpected values: %+v", s)
+	}
+	if s.Inner == nil || s.Inner.Ratio != 0.5 {
+		t.Fatal("pointer to struct with defaults was not allocated")
+	}
+	if s.Items[0].Name != "item" || s.Items[1].Name != "set" {
+		t.Fatalf("unexpected items: %+v", s.Items)
+	}
+}
+
+func TestMapDefaultsUnsupportedType(t *testing.T) {
+	s := struct {
+		Inner struct {
+			C chan int `default:"1"`
+		}
+	}{}
+	err := MapDefaults(&s)
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("want ErrUnsupportedType, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Inner.C") {
+		t.Fatalf("error doesn't name the field: %v", err)
+	}
+}
+
+func TestMapDefaultsExcept(t *testing.T) {
+	data := []byte(`{"timeout": 0, "inner": null, "items": [{"name": ""}, {}]}`)
+	s := nestedDefaults{}
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	set, err := JSONFields(data, &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"Timeout", "Inner", "Items", "Items[0].Name"} {
+		if !set[path] {
+			t.Errorf("%s is not reported set", path)
+		}
+	}
+	if set["Sizes"] || set["Items[1].Name"] {
+		t.Errorf("fields left out are reported set: %v", set)
+	}
+
+	if err := MapDefaultsExcept(&s, set); err != nil {
+		t.Fatal(err)
+	}
+	if s.Timeout != 0 || s.Inner != nil || s.Items[0].Name != "" {
+		t.Fatalf("fields set to zero got defaults: %+v", s)
+	}
+	if len(s.Sizes) != 2 || s.Items[1].Name != "item" {
+		t.Fatalf("fields left out didn't get defaults: %+v", s)
+	}
+}
@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// EnvOverride is a field set from an environment variable.
+type EnvOverride struct {
+	// Path of the field, e.g. "Subscriptions.Pairs".
+	Path string
+	Var  string
+}
+
+// MapEnv sets the fields of the struct s points to from environment
+// variables, looked up with lookup, and returns the fields it set. A field's
+// variable is prefix followed by its `env` tag, or by its name in upper snake
+// case, e.g. WebhookTargetUrl is PREFIX_WEBHOOK_TARGET_URL for prefix
+// "PREFIX_". Fields of nested structs and pointers to structs add their own
+// name to their parent's, e.g. PREFIX_SUBSCRIPTIONS_PAIRS. Fields tagged
+// `env:"-"` and variables set to an empty string are ignored. Values are
+// parsed as described in ParseValue, or as JSON for types it doesn't support,
+// e.g. slices of structs.
+func MapEnv(s interface{}, prefix string, lookup func(key string) (string, bool)) ([]EnvOverride, error) {
+	val := reflect.ValueOf(s)
+
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotPointerToStruct
+	}
+	var overrides []EnvOverride
+	err := mapEnv(val.Elem(), "", prefix, lookup, &overrides, nil)
+	return overrides, err
+}
+
+// mapEnv maps the fields of val. enclosing are the types of the structs val
+// is nested in, so recursive types stop at nil pointers.
+func mapEnv(val reflect.Value, path string, prefix string, lookup func(string) (string, bool), overrides *[]EnvOverride, enclosing []reflect.Type) error {
+	typ := val.Type()
+	enclosing = append(enclosing, typ)
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		fieldType := typ.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		name, ok := fieldType.Tag.Lookup("env")
+		if name == "-" {
+			continue
+		}
+		if !ok || name == "" {
+			name = EnvName(fieldType.Name)
+		}
+		fieldPath := joinPath(path, fieldType.Name)
+		key := prefix + name
+
+		if nested := nestedStruct(field.Type()); nested != nil {
+			elem := field
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() && slices.Contains(enclosing, nested) {
+					continue
+				}
+				elem = reflect.New(nested).Elem()
+				if !field.IsNil() {
+					elem.Set(field.Elem())
+				}
+			}
+			before := len(*overrides)
+			if err := mapEnv(elem, fieldPath, key+"_", lookup, overrides, enclosing); err != nil {
+				return err
+			}
+			// A nil pointer is only allocated for fields set under it.
+			if field.Kind() == reflect.Ptr && len(*overrides) > before {
+				field.Set(elem.Addr())
+			}
+			continue
+		}
+
+		value, ok := lookup(key)
+		if !ok || value == "" {
+			continue
+		}
+		parsed := reflect.New(field.Type()).Elem()
+		err := ParseValue(parsed, value)
+		if errors.Is(err, ErrUnsupportedType) {
+			err = json.Unmarshal([]byte(value), parsed.Addr().Interface())
+		}
+		if err != nil {
+			return fmt.Errorf("%s (%s): invalid value: %w", key, fieldPath, err)
+		}
+		field.Set(parsed)
+		*overrides = append(*overrides, EnvOverride{Path: fieldPath, Var: key})
+	}
+	return nil
+}
+
+// nestedStruct returns the struct type whose fields are mapped one by one for
+// a field of type typ, or nil if the field is mapped as a whole.
+func nestedStruct(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || parsesText(typ) {
+		return nil
+	}
+	return typ
+}
+
+// EnvName returns a field name in upper snake case, keeping acronyms whole,
+// e.g. "ApiTokenTTLSeconds" is "API_TOKEN_TTL_SECONDS".
+func EnvName(name string) string {
+	runes := []rune(name)
+	b := strings.Builder{}
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			// The end of an acronym starts a word unless it is a plural,
+			// as in "IDs".
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1]) &&
+				!(runes[i+1] == 's' && (i+2 == len(runes) || !unicode.IsLower(runes[i+2])))
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// Field is a field of a struct that isn't itself a struct of fields.
+type Field struct {
+	// Path of the field, e.g. "Subscriptions.Pairs".
+	Path string
+	// Set is true unless the field has its zero value.
+	Set bool
+}
+
+// Fields returns the fields of the struct s points to, descending into
+// nested structs and non-nil pointers to structs, in declaration order.
+func Fields(s interface{}) []Field {
+	val := reflect.ValueOf(s)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []Field
+	collectFields(val.Elem(), "", &fields)
+	return fields
+}
+
+func collectFields(val reflect.Value, path string, fields *[]Field) {
+	typ := val.Type()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		fieldType := typ.Field(i)
+		if !fieldType.IsExported() {
+			continue
+		}
+		fieldPath := joinPath(path, fieldType.Name)
+		if nestedStruct(field.Type()) != nil {
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					*fields = append(*fields, Field{Path: fieldPath})
+					continue
+				}
+				field = field.Elem()
+			}
+			collectFields(field, fieldPath, fields)
+			continue
+		}
+		*fields = append(*fields, Field{Path: fieldPath, Set: !field.IsZero()})
+	}
+}
+
+// JSONFields returns the paths of the fields of the struct s points to that
+// data, a JSON object, sets, even to their zero value or null. Objects in
+// the fields of nested structs and in arrays are descended into, giving
+// paths such as "Subscriptions.Pairs[0].NetworkId". Keys are matched to
+// fields as encoding/json does, by `json` tag or case-insensitive name.
+func JSONFields(data []byte, s interface{}) (map[string]bool, error) {
+	val := reflect.ValueOf(s)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotPointerToStruct
+	}
+	set := make(map[string]bool)
+	err := jsonFields(data, val.Elem().Type(), "", set)
+	return set, err
+}
+
+func jsonFields(data []byte, typ reflect.Type, path string, set map[string]bool) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		fieldType := typ.Field(i)
+		if !fieldType.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(fieldType.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = fieldType.Name
+		}
+		raw, ok := object[name]
+		if !ok {
+			for key, value := range object {
+				if strings.EqualFold(key, name) {
+					raw, ok = value, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		fieldPath := joinPath(path, fieldType.Name)
+		set[fieldPath] = true
+		if err := jsonNestedFields(raw, fieldType.Type, fieldPath, set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonNestedFields adds the fields set by the objects in raw, a value of a
+// field of type typ.
+func jsonNestedFields(raw json.RawMessage, typ reflect.Type, path string, set map[string]bool) error {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil
+	}
+	switch {
+	case raw[0] == '{' && typ.Kind() == reflect.Struct && !parsesText(typ):
+		return jsonFields(raw, typ, path, set)
+	case raw[0] == '[' && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array):
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for i, elem := range elems {
+			if err := jsonNestedFields(elem, typ.Elem(), fmt.Sprintf("%s[%d]", path, i), set); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+// Sort keys of the webhooks in a bucket, by what manages them.
+const (
+	SortkeyConfigured = "configured"
+	SortkeyDemand     = "demand"
+	SortkeyAdmin      = "admin"
+	SortkeyAlert      = "alert"
+)
+
+// lookupLimit is the most webhooks checked one by one before a delete; more
+// are checked by listing the bucket.
+const lookupLimit = 10
+
+// BucketID returns the Codex bucket of the webhooks of this service in env,
+// for deployments told apart by key. Deployments sharing a Codex account only
+// list and delete the webhooks in their own bucket.
+func BucketID(env string, key string) string {
+	return "wis:" + env + ":" + key
+}
+
+// BucketSortkey returns the sort key of the webhook named name, by the prefix
+// that tells what manages it.
+func BucketSortkey(name string) string {
+	switch {
+	case isDemandWebhook(name):
+		return SortkeyDemand
+	case strings.HasPrefix(name, AdminPrefix):
+		return SortkeyAdmin
+	case strings.HasPrefix(name, AlertPrefix):
+		return SortkeyAlert
+	default:
+		return SortkeyConfigured
+	}
+}
+
+// inBucket reports whether webhook is in bucketID.
+func inBucket(webhook codex.Webhook, bucketID string) bool {
+	return webhook.BucketId != nil && *webhook.BucketId == bucketID
+}
+
+// unbucketed reports whether webhook was created without a bucket, i.e. by
+// a version of this service from before buckets.
+func unbucketed(webhook codex.Webhook) bool {
+	return webhook.BucketId == nil || *webhook.BucketId == ""
+}
+
+// GetWebhook returns the webhook with the given ID, in any bucket, or nil if
+// there is none.
+func GetWebhook(ctx context.Context, client graphql.Client, id string) (*codex.Webhook, error) {
+	resp, err := codex.GetWebhooks(ctx, client, nil, codex.Ptr(1), &id, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook [%s]: %w", id, err)
+	}
+	if resp.GetWebhooks == nil {
+		return nil, nil
+	}
+	for _, webhook := range resp.GetWebhooks.Items {
+		if webhook != nil && webhook.Id == id {
+			return webhook, nil
+		}
+	}
+	return nil, nil
+}
+
+// DeleteResult sorts the webhooks DeleteWebhooks was asked to delete.
+type DeleteResult struct {
+	Deleted []string
+	// Missing don't exist.
+	Missing []string
+	// Foreign are in another bucket, or in none, and were left alone.
+	Foreign []string
+}
+
+// DeleteWebhooks deletes the webhooks with the given IDs that are in
+// bucketID. Webhooks in other buckets, or in none, may belong to other
+// deployments or have been made by hand, and are never deleted.
+func DeleteWebhooks(ctx context.Context, client graphql.Client, bucketID string, ids []string) (DeleteResult, error) {
+	result := DeleteResult{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	// deletable holds the webhooks that exist, by whether they may be
+	// deleted.
+	deletable := make(map[string]bool, len(ids))
+	lookup := ids
+	if len(ids) > lookupLimit {
+		existing, err := ListWebhooks(ctx, client, bucketID)
+		if err != nil {
+			return result, err
+		}
+		for _, webhook := range existing {
+			deletable[webhook.Id] = true
+		}
+		lookup = nil
+		for _, id := range ids {
+			if _, ok := deletable[id]; !ok {
+				lookup = append(lookup, id)
+			}
+		}
+	}
+	for _, id := range lookup {
+		webhook, err := GetWebhook(ctx, client, id)
+		if err != nil {
+			return result, err
+		}
+		if webhook != nil {
+			deletable[id] = inBucket(*webhook, bucketID)
+		}
+	}
+
+	var scoped []string
+	for _, id := range ids {
+		ok, exists := deletable[id]
+		switch {
+		case !exists:
+			result.Missing = append(result.Missing, id)
+		case !ok:
+			result.Foreign = append(result.Foreign, id)
+			log.Warn().Str("webhook_id", id).Str("bucket_id", bucketID).Msg("not deleting webhook of another bucket")
+		default:
+			scoped = append(scoped, id)
+		}
+	}
+	if len(scoped) == 0 {
+		return result, nil
+	}
+
+	resp, err := codex.DeleteWebhooks(ctx, client, codex.DeleteWebhooksInput{WebhookIds: scoped})
+	if err != nil {
+		return result, fmt.Errorf("failed to delete webhooks: %w", err)
+	}
+	if resp.DeleteWebhooks != nil {
+		for _, id := range resp.DeleteWebhooks.DeletedIds {
+			if id != nil {
+				result.Deleted = append(result.Deleted, *id)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,342 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/settings"
+	"github.com/rs/zerolog/log"
+)
+
+// demandPrefix starts the name of every webhook created by Demand, followed
+// by the topic it serves.
+const demandPrefix = "demand:"
+
+// demandRetryDelay is how long Demand waits before retrying after Codex
+// failed to create or delete a webhook.
+const demandRetryDelay = 30 * time.Second
+
+func isDemandWebhook(name string) bool {
+	return strings.HasPrefix(name, demandPrefix)
+}
+
+type DemandOptions struct {
+	// Grace is how long a webhook outlives its last subscriber, so clients
+	// reconnecting or flipping between pairs don't churn webhooks.
+	Grace time.Duration
+	// MaxWebhooks caps how many demand webhooks exist at once. Topics over
+	// the limit wait until others are released.
+	MaxWebhooks int
+	// Static are the configured webhooks. Topics they already cover don't
+	// get a webhook of their own.
+	Static []settings.WebhookConfig
+}
+
+// Demand creates a token pair webhook for each pair or token topic that
+// clients subscribe to and no configured webhook covers, and deletes it once
+// the topic has had no subscribers for the grace period. It implements
+// ws.DemandListener; subscribers are counted per hub by the hubs and per topic
+// across hubs here.
+type Demand struct {
+	provisioner *Provisioner
+	opts        DemandOptions
+
+	mu sync.Mutex
+	// refs counts the hubs with subscribers to a topic.
+	refs map[string]int
+	// active maps a topic to the ID of its webhook.
+	active map[string]string
+	// pending holds topics whose webhook should be created (true) or
+	// deleted (false) by Run.
+	pending  map[string]bool
+	releases map[string]*time.Timer
+	// limited are the topics already logged as over MaxWebhooks.
+	limited map[string]struct{}
+	wake    chan struct{}
+}
+
+func NewDemand(provisioner *Provisioner, opts DemandOptions) *Demand {
+	return &Demand{
+		provisioner: provisioner,
+		opts:        opts,
+		refs:        make(map[string]int),
+		active:      make(map[string]string),
+		pending:     make(map[string]bool),
+		releases:    make(map[string]*time.Timer),
+		limited:     make(map[string]struct{}),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// TopicDemanded counts a hub's subscribers to topic. Spellings of a topic
+// that differ only in address case count as one.
+func (d *Demand) TopicDemanded(topic string) {
+	if _, _, _, ok := ingest.ParseTopic(topic); !ok {
+		return
+	}
+	topic = ingest.NormalizeTopic(topic)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.refs[topic]++
+	if timer, ok := d.releases[topic]; ok {
+		timer.Stop()
+		delete(d.releases, topic)
+	}
+	if _, ok := d.active[topic]; ok {
+		delete(d.pending, topic)
+		return
+	}
+	d.pending[topic] = true
+	d.signal()
+}
+
+func (d *Demand) TopicReleased(topic string) {
+	if _, _, _, ok := ingest.ParseTopic(topic); !ok {
+		return
+	}
+	topic = ingest.NormalizeTopic(topic)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.refs[topic] > 1 {
+		d.refs[topic]--
+		return
+	}
+	delete(d.refs, topic)
+	d.scheduleRelease(topic)
+}
+
+// scheduleRelease must be called with d.mu held.
+func (d *Demand) scheduleRelease(topic string) {
+	if _, ok := d.active[topic]; !ok {
+		// Never created, e.g. over the limit or still pending.
+		delete(d.pending, topic)
+		delete(d.limited, topic)
+		return
+	}
+	d.releases[topic] = time.AfterFunc(d.opts.Grace, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.releases, topic)
+		if d.refs[topic] == 0 {
+			d.pending[topic] = false
+			d.signal()
+		}
+	})
+}
+
+// signal must be called with d.mu held.
+func (d *Demand) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run adopts the demand webhooks recorded in the store, releasing them after
+// the grace period unless subscribed to again, and then creates and deletes
+// webhooks as demand changes until ctx is done.
+func (d *Demand) Run(ctx context.Context) {
+	state, err := d.provisioner.store.Load()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load demand webhooks")
+	} else {
+		d.mu.Lock()
+		for _, webhook := range state.Webhooks {
+			if !isDemandWebhook(webhook.Name) {
+				continue
+			}
+			topic := ingest.NormalizeTopic(strings.TrimPrefix(webhook.Name, demandPrefix))
+			d.active[topic] = webhook.ID
+			delete(d.pending, topic)
+			if d.refs[topic] == 0 {
+				d.scheduleRelease(topic)
+			}
+		}
+		d.mu.Unlock()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		}
+		if err := d.apply(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to update demand webhooks")
+			time.AfterFunc(demandRetryDelay, func() {
+				d.mu.Lock()
+				d.signal()
+				d.mu.Unlock()
+			})
+		}
+	}
+}
+
+// apply creates and deletes the pending webhooks. Deletes go first so they
+// free room under MaxWebhooks.
+func (d *Demand) apply(ctx context.Context) error {
+	d.mu.Lock()
+	var release, releaseIDs []string
+	for topic, create := range d.pending {
+		id, ok := d.active[topic]
+		if !create && ok && d.refs[topic] == 0 {
+			release = append(release, topic)
+			releaseIDs = append(releaseIDs, id)
+		}
+	}
+	d.mu.Unlock()
+
+	d.provisioner.mu.Lock()
+	defer d.provisioner.mu.Unlock()
+	state, err := d.provisioner.store.Load()
+	if err != nil {
+		return err
+	}
+
+	if len(releaseIDs) > 0 {
+		failed, err := d.provisioner.delete(ctx, state, releaseIDs)
+		if err != nil {
+			return err
+		}
+		recorded := make(map[string]struct{}, len(state.Webhooks))
+		for _, webhook := range state.Webhooks {
+			recorded[webhook.ID] = struct{}{}
+		}
+		d.mu.Lock()
+		for i, topic := range release {
+			if _, ok := recorded[releaseIDs[i]]; ok {
+				continue
+			}
+			if d.active[topic] != releaseIDs[i] {
+				// Recreated by the watchdog in the meantime.
+				continue
+			}
+			delete(d.active, topic)
+			if !d.pending[topic] {
+				delete(d.pending, topic)
+			}
+			if d.refs[topic] > 0 {
+				// Subscribed again while deleting.
+				d.pending[topic] = true
+			}
+		}
+		d.mu.Unlock()
+		if err := d.provisioner.store.Save(state); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to delete %d of %d demand webhooks", failed, len(releaseIDs))
+		}
+	}
+
+	d.mu.Lock()
+	var configs []settings.WebhookConfig
+	slots := d.opts.MaxWebhooks - len(d.active)
+	for topic, create := range d.pending {
+		if !create {
+			continue
+		}
+		if d.covered(topic) {
+			delete(d.pending, topic)
+			continue
+		}
+		if len(configs) >= slots {
+			if _, ok := d.limited[topic]; !ok {
+				d.limited[topic] = struct{}{}
+				log.Warn().Str("topic", topic).Int("max", d.opts.MaxWebhooks).Msg("demand webhook limit reached")
+			}
+			continue
+		}
+		configs = append(configs, demandWebhookConfig(topic))
+	}
+	d.mu.Unlock()
+	if len(configs) == 0 {
+		return nil
+	}
+
+	// Webhooks created before a failure are kept, and the rest retried on
+	// the next run.
+	created, createErr := d.provisioner.create(ctx, state, configs)
+	d.mu.Lock()
+	for _, webhook := range created {
+		topic := strings.TrimPrefix(webhook.Name, demandPrefix)
+		d.active[topic] = webhook.ID
+		delete(d.pending, topic)
+		delete(d.limited, topic)
+		if d.refs[topic] == 0 {
+			// Unsubscribed while creating.
+			d.scheduleRelease(topic)
+		}
+	}
+	d.mu.Unlock()
+	if err := d.provisioner.store.Save(state); err != nil {
+		return err
+	}
+	return createErr
+}
+
+// replaced records that the webhook of topic was recreated as newID by the
+// watchdog, or deleted if newID is empty.
+func (d *Demand) replaced(topic string, oldID string, newID string) {
+	topic = ingest.NormalizeTopic(topic)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.active[topic] != oldID {
+		return
+	}
+	if newID != "" {
+		d.active[topic] = newID
+		return
+	}
+	delete(d.active, topic)
+	if d.refs[topic] > 0 {
+		d.pending[topic] = true
+		d.signal()
+	}
+}
+
+// covered reports whether a configured webhook already delivers every event
+// on topic. d.mu must be held.
+func (d *Demand) covered(topic string) bool {
+	kind, address, networkID, _ := ingest.ParseTopic(topic)
+	for _, config := range d.opts.Static {
+		if config.Maker != "" || config.ExchangeAddress != "" || len(config.EventTypes) > 0 || config.MinSwapValueUsd != "" {
+			continue
+		}
+		if len(config.NetworkIds) > 0 && !slices.Contains(config.NetworkIds, networkID) {
+			continue
+		}
+		switch kind {
+		case ingest.TopicKindPair:
+			if config.TokenAddress == "" && (config.PairAddress == "" || strings.EqualFold(config.PairAddress, address)) {
+				return true
+			}
+		case ingest.TopicKindToken:
+			if config.PairAddress == "" && (config.TokenAddress == "" || strings.EqualFold(config.TokenAddress, address)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func demandWebhookConfig(topic string) settings.WebhookConfig {
+	kind, address, networkID, _ := ingest.ParseTopic(topic)
+	config := settings.WebhookConfig{
+		Name:       demandPrefix + topic,
+		NetworkIds: []int{networkID},
+	}
+	if kind == ingest.TopicKindPair {
+		config.PairAddress = address
+	} else {
+		config.TokenAddress = address
+	}
+	return config
+}
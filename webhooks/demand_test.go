@@ -0,0 +1,153 @@
+package webhooks
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/codex/codexstub"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+)
+
+func newTestProvisioner(t *testing.T, stub *codexstub.Server) *Provisioner {
+	t.Helper()
+	client := codex.NewClient(codex.Options{
+		Endpoint:   stub.URL(),
+		Token:      "codex-key",
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+	})
+	store := NewStore(filepath.Join(t.TempDir(), "webhooks.json"))
+	return NewProvisioner(client, "http://localhost/send-data", "secret", BucketID("test", "key"), store)
+}
+
+func startDemand(t *testing.T, provisioner *Provisioner, opts DemandOptions) *Demand {
+	t.Helper()
+	demand := NewDemand(provisioner, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		demand.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return demand
+}
+
+// waitFor polls until cond holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// webhookIDs maps the name of each webhook on stub to its ID.
+func webhookIDs(stub *codexstub.Server) map[string]string {
+	ids := make(map[string]string)
+	for _, webhook := range stub.Webhooks() {
+		ids[webhook.Name] = webhook.ID
+	}
+	return ids
+}
+
+func count(operations []string, name string) int {
+	n := 0
+	for _, operation := range operations {
+		if operation == name {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDemandCreatesAndReleases(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	provisioner := newTestProvisioner(t, stub)
+	demand := startDemand(t, provisioner, DemandOptions{Grace: 50 * time.Millisecond, MaxWebhooks: 10})
+	topic := ingest.PairTopic("0xA", 1)
+
+	demand.TopicDemanded("pair:0xA:1")
+	waitFor(t, "the webhook to be created", func() bool { return webhookIDs(stub)[demandPrefix+topic] != "" })
+	state, err := provisioner.store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state.ByName(demandPrefix + topic); !ok {
+		t.Fatal("created webhook was not recorded")
+	}
+
+	released := time.Now()
+	demand.TopicReleased(topic)
+	waitFor(t, "the webhook to be deleted", func() bool { return len(stub.Webhooks()) == 0 })
+	if elapsed := time.Since(released); elapsed < 50*time.Millisecond {
+		t.Fatalf("webhook deleted %s after release, want it kept for Grace", elapsed)
+	}
+	waitFor(t, "the deleted webhook to be forgotten", func() bool {
+		state, err := provisioner.store.Load()
+		return err == nil && len(state.Webhooks) == 0
+	})
+}
+
+func TestDemandResubscribeWithinGrace(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	demand := startDemand(t, newTestProvisioner(t, stub), DemandOptions{Grace: 100 * time.Millisecond, MaxWebhooks: 10})
+	topic := ingest.PairTopic("0xa", 1)
+
+	demand.TopicDemanded(topic)
+	waitFor(t, "the webhook to be created", func() bool { return len(stub.Webhooks()) == 1 })
+	id := webhookIDs(stub)[demandPrefix+topic]
+
+	// Subscribers of another hub keep the topic demanded.
+	demand.TopicDemanded(topic)
+	demand.TopicReleased(topic)
+	time.Sleep(150 * time.Millisecond)
+	if webhookIDs(stub)[demandPrefix+topic] != id {
+		t.Fatal("webhook deleted while still subscribed")
+	}
+
+	demand.TopicReleased(topic)
+	time.Sleep(50 * time.Millisecond)
+	demand.TopicDemanded(topic)
+	time.Sleep(150 * time.Millisecond)
+	if webhookIDs(stub)[demandPrefix+topic] != id {
+		t.Fatal("webhook not kept when subscribed again within Grace")
+	}
+	operations := stub.Operations()
+	if creates, deletes := count(operations, "CreateWebhooks"), count(operations, "DeleteWebhooks"); creates != 1 || deletes != 0 {
+		t.Fatalf("sent %d creates and %d deletes, want 1 and 0", creates, deletes)
+	}
+}
+
+func TestDemandMaxWebhooks(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	demand := startDemand(t, newTestProvisioner(t, stub), DemandOptions{Grace: 50 * time.Millisecond, MaxWebhooks: 1})
+	first, second := ingest.PairTopic("0xa", 1), ingest.TokenTopic("0xb", 1)
+
+	demand.TopicDemanded(first)
+	waitFor(t, "the first webhook to be created", func() bool { return len(stub.Webhooks()) == 1 })
+	demand.TopicDemanded(second)
+	time.Sleep(100 * time.Millisecond)
+	if ids := webhookIDs(stub); len(ids) != 1 || ids[demandPrefix+first] == "" {
+		t.Fatalf("webhooks %v, want only the first over MaxWebhooks", ids)
+	}
+
+	// The waiting topic gets the slot once the first is released.
+	demand.TopicReleased(first)
+	waitFor(t, "the second webhook to be created", func() bool {
+		ids := webhookIDs(stub)
+		return len(ids) == 1 && ids[demandPrefix+second] != ""
+	})
+}
@@ -0,0 +1,190 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/settings"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+// CallbackURL returns target as an absolute URL, defaulting to https.
+func CallbackURL(target string) string {
+	if strings.Contains(target, "://") {
+		return target
+	}
+	return "https://" + target
+}
+
+// TokenPairWebhookArgs builds the Codex input for config. Conditions left
+// empty in config are sent as null so they don't filter.
+func TokenPairWebhookArgs(config settings.WebhookConfig, callbackURL string, securityToken string) codex.CreateTokenPairEventWebhookArgs {
+	conditions := codex.TokenPairEventWebhookConditionInput{}
+	if len(config.NetworkIds) > 0 {
+		conditions.NetworkId = &codex.OneOfNumberConditionInput{OneOf: config.NetworkIds}
+	}
+	if config.PairAddress != "" {
+		conditions.PairAddress = &codex.StringEqualsConditionInput{Eq: config.PairAddress}
+	}
+	if config.TokenAddress != "" {
+		conditions.TokenAddress = &codex.StringEqualsConditionInput{Eq: config.TokenAddress}
+	}
+	if config.ExchangeAddress != "" {
+		conditions.ExchangeAddress = &codex.StringEqualsConditionInput{Eq: config.ExchangeAddress}
+	}
+	if config.Maker != "" {
+		conditions.Maker = &codex.StringEqualsConditionInput{Eq: config.Maker}
+	}
+	if len(config.EventTypes) > 0 {
+		eventTypes := make([]codex.TokenPairEventType, len(config.EventTypes))
+		for i, eventType := range config.EventTypes {
+			eventTypes[i] = codex.TokenPairEventType(eventType)
+		}
+		conditions.EventType = &codex.TokenPairEventTypeConditionInput{OneOf: eventTypes}
+	}
+	if config.MinSwapValueUsd != "" {
+		conditions.SwapValue = &codex.ComparisonOperatorInput{Gte: codex.Ptr(config.MinSwapValueUsd)}
+	}
+
+	return codex.CreateTokenPairEventWebhookArgs{
+		Name:            config.Name,
+		CallbackUrl:     callbackURL,
+		SecurityToken:   securityToken,
+		AlertRecurrence: codex.AlertRecurrenceIndefinite,
+		Conditions:      conditions,
+		Deduplicate:     codex.Ptr(true),
+	}
+}
+
+// Provisioner creates and deletes Codex webhooks in its bucket, recording
+// the IDs of the ones we created.
+type Provisioner struct {
+	// mu serialises changes to the store and the webhooks recorded in it.
+	mu            sync.Mutex
+	client        graphql.Client
+	callbackURL   string
+	securityToken string
+	bucketID      string
+	store         *Store
+}
+
+func NewProvisioner(client graphql.Client, callbackURL string, securityToken string, bucketID string, store *Store) *Provisioner {
+	return &Provisioner{
+		client:        client,
+		callbackURL:   callbackURL,
+		securityToken: securityToken,
+		bucketID:      bucketID,
+		store:         store,
+	}
+}
+
+// create creates a webhook for each config and records the ones created in
+// state. Webhooks are created in chunks, so some may be created while others
+// fail; the error then reports the failed ones. p.mu must be held.
+func (p *Provisioner) create(ctx context.Context, state *State, configs []settings.WebhookConfig) ([]ProvisionedWebhook, error) {
+	args := make([]codex.CreateTokenPairEventWebhookArgs, len(configs))
+	for i, config := range configs {
+		args[i] = TokenPairWebhookArgs(config, p.callbackURL, p.securityToken)
+		args[i].BucketId = codex.Ptr(p.bucketID)
+		args[i].BucketSortkey = codex.Ptr(BucketSortkey(config.Name))
+	}
+	result := codex.CreateWebhooksBulk(ctx, p.client, codex.CreateWebhooksInput{
+		TokenPairEventWebhooksInput: &codex.CreateTokenPairEventWebhooksInput{Webhooks: args},
+	}, codex.BulkOptions{})
+
+	now := time.Now().UTC()
+	created := make([]ProvisionedWebhook, 0, len(configs))
+	for _, webhook := range result.Created() {
+		provisioned := ProvisionedWebhook{
+			ID:          webhook.Id,
+			Name:        webhook.Name,
+			CallbackUrl: webhook.CallbackUrl,
+			CreatedAt:   now,
+		}
+		created = append(created, provisioned)
+		state.Webhooks = replaceByName(state.Webhooks, provisioned)
+		log.Info().Str("webhook_id", webhook.Id).Str("name", webhook.Name).Msg("webhook provisioned")
+	}
+	for _, item := range result.Failed() {
+		log.Warn().Err(item.Err).Str("name", item.Name).Int("attempts", item.Attempts).Msg("failed to provision webhook")
+	}
+	if err := result.Err(); err != nil {
+		return created, fmt.Errorf("failed to create webhooks: %w", err)
+	}
+	return created, nil
+}
+
+// delete deletes the webhooks with the given IDs and removes the ones gone
+// from state, including ones that no longer exist or are in another bucket.
+// It returns how many were not deleted. p.mu must be held.
+func (p *Provisioner) delete(ctx context.Context, state *State, ids []string) (int, error) {
+	result, err := DeleteWebhooks(ctx, p.client, p.bucketID, ids)
+	if err != nil {
+		return len(ids), err
+	}
+
+	gone := make(map[string]struct{})
+	for _, list := range [][]string{result.Deleted, result.Missing, result.Foreign} {
+		for _, id := range list {
+			gone[id] = struct{}{}
+		}
+	}
+	remaining := state.Webhooks[:0]
+	for _, webhook := range state.Webhooks {
+		if _, ok := gone[webhook.ID]; ok {
+			continue
+		}
+		remaining = append(remaining, webhook)
+	}
+	state.Webhooks = remaining
+
+	for _, id := range result.Deleted {
+		log.Info().Str("webhook_id", id).Msg("webhook deleted")
+	}
+	return len(ids) - len(gone), nil
+}
+
+// Cleanup deletes every webhook recorded in the store and clears it.
+func (p *Provisioner) Cleanup(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, err := p.store.Load()
+	if err != nil {
+		return err
+	}
+	if len(state.Webhooks) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(state.Webhooks))
+	for i, webhook := range state.Webhooks {
+		ids[i] = webhook.ID
+	}
+	failed, err := p.delete(ctx, state, ids)
+	if err != nil {
+		return err
+	}
+	if err := p.store.Save(state); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d webhooks", failed, len(ids))
+	}
+	return nil
+}
+
+func replaceByName(webhooks []ProvisionedWebhook, webhook ProvisionedWebhook) []ProvisionedWebhook {
+	for i := range webhooks {
+		if webhooks[i].Name == webhook.Name {
+			webhooks[i] = webhook
+			return webhooks
+		}
+	}
+	return append(webhooks, webhook)
+}
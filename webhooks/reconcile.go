@@ -0,0 +1,402 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/settings"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	listPageSize = 100
+	statusActive = "ACTIVE"
+)
+
+// AdminPrefix starts the name of every webhook created through the admin
+// API. The reconciler leaves them alone.
+const AdminPrefix = "admin:"
+
+// AlertPrefix starts the name of every webhook created for a user's alert.
+// The reconciler leaves them alone.
+const AlertPrefix = "alert:"
+
+// ListWebhooks returns every webhook in bucketID, following the pagination
+// cursor until it runs out.
+func ListWebhooks(ctx context.Context, client graphql.Client, bucketID string) ([]codex.Webhook, error) {
+	var (
+		webhooks []codex.Webhook
+		cursor   *string
+		seen     = make(map[string]struct{})
+	)
+	for {
+		resp, err := codex.GetWebhooks(ctx, client, cursor, codex.Ptr(listPageSize), nil, &bucketID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhooks: %w", err)
+		}
+		if resp.GetWebhooks == nil {
+			return webhooks, nil
+		}
+		for _, webhook := range resp.GetWebhooks.Items {
+			if webhook != nil && inBucket(*webhook, bucketID) {
+				webhooks = append(webhooks, *webhook)
+			}
+		}
+
+		next := resp.GetWebhooks.Cursor
+		if next == nil || *next == "" || len(resp.GetWebhooks.Items) == 0 {
+			return webhooks, nil
+		}
+		if _, ok := seen[*next]; ok {
+			return nil, fmt.Errorf("failed to list webhooks: cursor [%s] repeated", *next)
+		}
+		seen[*next] = struct{}{}
+		cursor = next
+	}
+}
+
+// PlannedDelete is a webhook the reconciler will delete, and why.
+type PlannedDelete struct {
+	ID     string
+	Name   string
+	Reason string
+}
+
+// Plan is the set of changes that converges Codex with the configured
+// webhooks.
+type Plan struct {
+	Create []settings.WebhookConfig
+	Delete []PlannedDelete
+	// Keep are the webhooks that already match their config.
+	Keep []ProvisionedWebhook
+	// Legacy are recorded webhooks created before webhooks were put in
+	// buckets. They are dropped from the store and recreated in the bucket
+	// if still wanted, but left for an operator to delete: a webhook outside
+	// the bucket may belong to another deployment.
+	Legacy []ProvisionedWebhook
+}
+
+// Empty reports whether the plan changes nothing on Codex.
+func (p Plan) Empty() bool {
+	return len(p.Create) == 0 && len(p.Delete) == 0
+}
+
+func (p Plan) String() string {
+	if p.Empty() && len(p.Legacy) == 0 {
+		return fmt.Sprintf("no changes, %d webhooks up to date", len(p.Keep))
+	}
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "%d to create, %d to delete, %d up to date", len(p.Create), len(p.Delete), len(p.Keep))
+	for _, config := range p.Create {
+		fmt.Fprintf(&b, "\n  + %s", config.Name)
+	}
+	for _, webhook := range p.Delete {
+		fmt.Fprintf(&b, "\n  - %s (%s): %s", webhook.Name, webhook.ID, webhook.Reason)
+	}
+	for _, webhook := range p.Legacy {
+		fmt.Fprintf(&b, "\n  ~ %s (%s): not in bucket, left for an operator to delete", webhook.Name, webhook.ID)
+	}
+	return b.String()
+}
+
+// Reconciler periodically converges the webhooks in the provisioner's bucket
+// with configs. Webhooks are ours if we recorded their ID or they call back
+// to our URL; others in the bucket, and every webhook outside it, are left
+// alone.
+type Reconciler struct {
+	provisioner *Provisioner
+	configs     []settings.WebhookConfig
+	dryRun      bool
+}
+
+// NewReconciler returns a Reconciler for configs. In dry-run mode it only logs
+// the plan.
+func NewReconciler(provisioner *Provisioner, configs []settings.WebhookConfig, dryRun bool) *Reconciler {
+	return &Reconciler{
+		provisioner: provisioner,
+		configs:     configs,
+		dryRun:      dryRun,
+	}
+}
+
+// Plan lists the webhooks in the bucket and diffs ours against configs by name,
+// conditions, callback URL and status.
+func (r *Reconciler) Plan(ctx context.Context) (Plan, error) {
+	state, err := r.provisioner.store.Load()
+	if err != nil {
+		return Plan{}, err
+	}
+	existing, err := ListWebhooks(ctx, r.provisioner.client, r.provisioner.bucketID)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	// Webhooks we recorded that aren't in the bucket were deleted, or
+	// created before webhooks were put in buckets.
+	listed := make(map[string]struct{}, len(existing))
+	for _, webhook := range existing {
+		listed[webhook.Id] = struct{}{}
+	}
+	var legacy []codex.Webhook
+	for _, provisioned := range state.Webhooks {
+		if _, ok := listed[provisioned.ID]; ok {
+			continue
+		}
+		webhook, err := GetWebhook(ctx, r.provisioner.client, provisioned.ID)
+		if err != nil {
+			return Plan{}, err
+		}
+		if webhook != nil && unbucketed(*webhook) {
+			legacy = append(legacy, *webhook)
+		}
+	}
+	return r.plan(state, existing, legacy), nil
+}
+
+// plan diffs the webhooks in the bucket against configs. Recorded webhooks
+// from before buckets are given up, so configured ones are recreated in the
+// bucket and demand ones when next subscribed to.
+func (r *Reconciler) plan(state *State, existing []codex.Webhook, legacy []codex.Webhook) Plan {
+	recorded := make(map[string]ProvisionedWebhook, len(state.Webhooks))
+	for _, webhook := range state.Webhooks {
+		recorded[webhook.ID] = webhook
+	}
+	desired := make(map[string]settings.WebhookConfig, len(r.configs))
+	for _, config := range r.configs {
+		desired[config.Name] = config
+	}
+
+	plan := Plan{}
+	kept := make(map[string]struct{})
+	for _, webhook := range existing {
+		provisioned, ours := recorded[webhook.Id]
+		if !ours && webhook.CallbackUrl != r.provisioner.callbackURL {
+			continue
+		}
+		// Demand webhooks come and go with subscribers and are managed by
+		// Demand. Ones we didn't record may belong to another replica.
+		// Admin webhooks are managed by operators and alert webhooks by
+		// their users.
+		if isDemandWebhook(webhook.Name) || strings.HasPrefix(webhook.Name, AdminPrefix) || strings.HasPrefix(webhook.Name, AlertPrefix) {
+			if ours {
+				plan.Keep = append(plan.Keep, provisioned)
+			}
+			continue
+		}
+
+		config, ok := desired[webhook.Name]
+		reason := ""
+		switch {
+		case !ok:
+			reason = "not configured"
+		case webhook.WebhookType != codex.WebhookTypeTokenPairEvent:
+			reason = "not a token pair webhook"
+		case webhook.CallbackUrl != r.provisioner.callbackURL:
+			reason = "callback URL changed"
+		case webhook.Status != statusActive:
+			reason = "status " + webhook.Status
+		case !conditionsMatch(config, webhook.Conditions):
+			reason = "conditions changed"
+		}
+		if _, ok := kept[webhook.Name]; ok && reason == "" {
+			reason = "duplicate"
+		}
+		if reason != "" {
+			plan.Delete = append(plan.Delete, PlannedDelete{ID: webhook.Id, Name: webhook.Name, Reason: reason})
+			continue
+		}
+
+		kept[webhook.Name] = struct{}{}
+		if !ours {
+			provisioned = ProvisionedWebhook{
+				ID:          webhook.Id,
+				Name:        webhook.Name,
+				CallbackUrl: webhook.CallbackUrl,
+				CreatedAt:   time.Unix(int64(webhook.Created), 0).UTC(),
+			}
+		}
+		plan.Keep = append(plan.Keep, provisioned)
+	}
+
+	for _, webhook := range legacy {
+		plan.Legacy = append(plan.Legacy, recorded[webhook.Id])
+	}
+
+	for _, config := range r.configs {
+		if _, ok := kept[config.Name]; !ok {
+			plan.Create = append(plan.Create, config)
+		}
+	}
+	return plan
+}
+
+// Reconcile plans and, unless in dry-run mode, applies the plan. Stale
+// webhooks are deleted before their replacements are created.
+func (r *Reconciler) Reconcile(ctx context.Context) (Plan, error) {
+	r.provisioner.mu.Lock()
+	defer r.provisioner.mu.Unlock()
+
+	plan, err := r.Plan(ctx)
+	if err != nil {
+		return plan, err
+	}
+	if r.dryRun {
+		log.Info().Bool("dry_run", true).Msg("webhook reconcile plan: " + plan.String())
+		return plan, nil
+	}
+
+	// The store is rebuilt from what Codex has, dropping webhooks that were
+	// deleted outside this service.
+	state := &State{Webhooks: slices.Clone(plan.Keep)}
+	for _, webhook := range plan.Legacy {
+		log.Warn().Str("webhook_id", webhook.ID).Str("name", webhook.Name).Msg("webhook is not in a bucket and no longer managed; delete it once its replacement delivers")
+	}
+	if plan.Empty() {
+		return plan, r.provisioner.store.Save(state)
+	}
+	log.Info().Msg("webhook reconcile plan: " + plan.String())
+
+	if len(plan.Delete) > 0 {
+		ids := make([]string, len(plan.Delete))
+		for i, webhook := range plan.Delete {
+			ids[i] = webhook.ID
+		}
+		failed, err := r.provisioner.delete(ctx, state, ids)
+		if err != nil {
+			return plan, err
+		}
+		if failed > 0 {
+			log.Warn().Int("failed", failed).Msg("some stale webhooks were not deleted")
+		}
+	}
+	if len(plan.Create) > 0 {
+		if _, err := r.provisioner.create(ctx, state, plan.Create); err != nil {
+			// Keep what we know so the next run doesn't recreate them.
+			if saveErr := r.provisioner.store.Save(state); saveErr != nil {
+				log.Error().Err(saveErr).Msg("failed to save webhook state")
+			}
+			return plan, err
+		}
+	}
+	return plan, r.provisioner.store.Save(state)
+}
+
+// Run reconciles immediately and then every interval until ctx is done.
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		runCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		if _, err := r.Reconcile(runCtx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to reconcile webhooks")
+		}
+		cancel()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// conditionsMatch reports whether the conditions Codex has for a webhook are
+// the ones config would create.
+func conditionsMatch(config settings.WebhookConfig, conditions codex.WebhookConditionsWebhookCondition) bool {
+	tokenPair, ok := conditions.(*codex.WebhookConditionsTokenPairEventWebhookCondition)
+	if !ok {
+		return false
+	}
+	want := normalizeConditions(TokenPairWebhookArgs(config, "", "").Conditions)
+	got := normalizeConditions(conditionsInput(tokenPair.TokenPairConditions))
+	return reflect.DeepEqual(want, got)
+}
+
+func conditionsInput(c codex.TokenPairConditions) codex.TokenPairEventWebhookConditionInput {
+	input := codex.TokenPairEventWebhookConditionInput{}
+	if c.NetworkId != nil {
+		input.NetworkId = &codex.OneOfNumberConditionInput{OneOf: c.NetworkId.OneOf}
+	}
+	if c.PairAddress != nil {
+		input.PairAddress = &codex.StringEqualsConditionInput{Eq: c.PairAddress.Eq}
+	}
+	if c.TokenAddress != nil {
+		input.TokenAddress = &codex.StringEqualsConditionInput{Eq: c.TokenAddress.Eq}
+	}
+	if c.ExchangeAddress != nil {
+		input.ExchangeAddress = &codex.StringEqualsConditionInput{Eq: c.ExchangeAddress.Eq}
+	}
+	if c.Maker != nil {
+		input.Maker = &codex.StringEqualsConditionInput{Eq: c.Maker.Eq}
+	}
+	if c.EventType != nil {
+		input.EventType = &codex.TokenPairEventTypeConditionInput{OneOf: c.EventType.OneOf}
+	}
+	if c.SwapValue != nil {
+		input.SwapValue = &codex.ComparisonOperatorInput{
+			Gt:  c.SwapValue.Gt,
+			Gte: c.SwapValue.Gte,
+			Lt:  c.SwapValue.Lt,
+			Lte: c.SwapValue.Lte,
+			Eq:  c.SwapValue.Eq,
+		}
+	}
+	return input
+}
+
+// normalizeConditions returns c with lists sorted, addresses lower-cased,
+// numbers in canonical form and empty conditions dropped, so that equivalent
+// conditions compare equal.
+func normalizeConditions(c codex.TokenPairEventWebhookConditionInput) codex.TokenPairEventWebhookConditionInput {
+	out := codex.TokenPairEventWebhookConditionInput{}
+	if c.NetworkId != nil && len(c.NetworkId.OneOf) > 0 {
+		ids := slices.Clone(c.NetworkId.OneOf)
+		slices.Sort(ids)
+		out.NetworkId = &codex.OneOfNumberConditionInput{OneOf: slices.Compact(ids)}
+	}
+	out.PairAddress = normalizeAddress(c.PairAddress)
+	out.TokenAddress = normalizeAddress(c.TokenAddress)
+	out.ExchangeAddress = normalizeAddress(c.ExchangeAddress)
+	out.Maker = normalizeAddress(c.Maker)
+	if c.EventType != nil && len(c.EventType.OneOf) > 0 {
+		eventTypes := slices.Clone(c.EventType.OneOf)
+		slices.Sort(eventTypes)
+		out.EventType = &codex.TokenPairEventTypeConditionInput{OneOf: slices.Compact(eventTypes)}
+	}
+	if c.SwapValue != nil {
+		swapValue := codex.ComparisonOperatorInput{
+			Gt:  normalizeNumber(c.SwapValue.Gt),
+			Gte: normalizeNumber(c.SwapValue.Gte),
+			Lt:  normalizeNumber(c.SwapValue.Lt),
+			Lte: normalizeNumber(c.SwapValue.Lte),
+			Eq:  normalizeNumber(c.SwapValue.Eq),
+		}
+		if swapValue != (codex.ComparisonOperatorInput{}) {
+			out.SwapValue = &swapValue
+		}
+	}
+	return out
+}
+
+func normalizeAddress(c *codex.StringEqualsConditionInput) *codex.StringEqualsConditionInput {
+	if c == nil || c.Eq == "" {
+		return nil
+	}
+	return &codex.StringEqualsConditionInput{Eq: strings.ToLower(c.Eq)}
+}
+
+func normalizeNumber(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	if f, err := strconv.ParseFloat(*value, 64); err == nil {
+		return codex.Ptr(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return value
+}
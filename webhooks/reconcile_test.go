@@ -0,0 +1,104 @@
+package webhooks
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/codex/codexstub"
+	"github.com/Acrylic125/webhook-ingest-ws/settings"
+)
+
+// createOnCodex creates a webhook for config directly on Codex, as another
+// deployment or an earlier run would have, in bucketID if set.
+func createOnCodex(t *testing.T, provisioner *Provisioner, config settings.WebhookConfig, callbackURL string, bucketID string) string {
+	t.Helper()
+	args := TokenPairWebhookArgs(config, callbackURL, "secret")
+	args.Deduplicate = nil
+	if bucketID != "" {
+		args.BucketId = codex.Ptr(bucketID)
+	}
+	resp, err := codex.CreateWebhooks(context.Background(), provisioner.client, codex.CreateWebhooksInput{
+		TokenPairEventWebhooksInput: &codex.CreateTokenPairEventWebhooksInput{Webhooks: []codex.CreateTokenPairEventWebhookArgs{args}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.CreateWebhooks.TokenPairEventWebhooks[0].Id
+}
+
+func names[T any](items []T, name func(T) string) []string {
+	var names []string
+	for _, item := range items {
+		names = append(names, name(item))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestReconcile(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	provisioner := newTestProvisioner(t, stub)
+	ours, bucket := provisioner.callbackURL, provisioner.bucketID
+	weth := settings.WebhookConfig{Name: "weth", TokenAddress: "0xweth", NetworkIds: []int{1}}
+	pair := settings.WebhookConfig{Name: "pair", PairAddress: "0xpair", NetworkIds: []int{1}}
+
+	changed := weth
+	changed.NetworkIds = []int{1, 56}
+	createOnCodex(t, provisioner, changed, ours, bucket)
+	disabled := createOnCodex(t, provisioner, pair, ours, bucket)
+	stub.SetStatus(disabled, "DISABLED")
+	createOnCodex(t, provisioner, settings.WebhookConfig{Name: "removed", TokenAddress: "0xold"}, ours, bucket)
+	createOnCodex(t, provisioner, settings.WebhookConfig{Name: "foreign", TokenAddress: "0xweth"}, "https://elsewhere.example/hook", bucket)
+	createOnCodex(t, provisioner, settings.WebhookConfig{Name: AdminPrefix + "ops", TokenAddress: "0xops"}, ours, bucket)
+	createOnCodex(t, provisioner, settings.WebhookConfig{Name: "unbucketed", TokenAddress: "0xweth"}, ours, "")
+	demandID := provision(t, provisioner, demandWebhookConfig("pair:0xa:1"))
+	before := len(stub.Webhooks())
+
+	// A dry run only plans.
+	plan, err := NewReconciler(provisioner, []settings.WebhookConfig{weth, pair}, true).Reconcile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(stub.Webhooks()); n != before {
+		t.Fatalf("dry run changed the webhooks from %d to %d", before, n)
+	}
+	wantDeletes := []string{"pair: status DISABLED", "removed: not configured", "weth: conditions changed"}
+	deletes := names(plan.Delete, func(d PlannedDelete) string { return d.Name + ": " + d.Reason })
+	creates := names(plan.Create, func(c settings.WebhookConfig) string { return c.Name })
+	if !slices.Equal(deletes, wantDeletes) || !slices.Equal(creates, []string{"pair", "weth"}) {
+		t.Fatalf("planned deletes %v and creates %v", deletes, creates)
+	}
+
+	reconciler := NewReconciler(provisioner, []settings.WebhookConfig{weth, pair}, false)
+	if _, err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	onCodex := names(stub.Webhooks(), func(w codexstub.Webhook) string { return w.Name })
+	if want := []string{AdminPrefix + "ops", "demand:pair:0xa:1", "foreign", "pair", "unbucketed", "weth"}; !slices.Equal(onCodex, want) {
+		t.Fatalf("codex has %v, want %v", onCodex, want)
+	}
+	state, err := provisioner.store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := names(state.Webhooks, func(w ProvisionedWebhook) string { return w.Name })
+	if !slices.Equal(recorded, []string{"demand:pair:0xa:1", "pair", "weth"}) {
+		t.Fatalf("recorded %v", recorded)
+	}
+	if webhook, _ := state.ByName("demand:pair:0xa:1"); webhook.ID != demandID {
+		t.Fatalf("demand webhook recorded as %s, want %s", webhook.ID, demandID)
+	}
+
+	// Once converged, there is nothing to do.
+	plan, err = reconciler.Reconcile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() || len(plan.Keep) != 3 {
+		t.Fatalf("second plan %s, want no changes to 3 webhooks", plan)
+	}
+}
@@ -0,0 +1,81 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ProvisionedWebhook is a Codex webhook created by this service.
+type ProvisionedWebhook struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	CallbackUrl string    `json:"callbackUrl"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type State struct {
+	Webhooks []ProvisionedWebhook `json:"webhooks"`
+}
+
+// ByName returns the provisioned webhook with the given name, if any.
+func (s *State) ByName(name string) (ProvisionedWebhook, bool) {
+	for _, webhook := range s.Webhooks {
+		if webhook.Name == name {
+			return webhook, true
+		}
+	}
+	return ProvisionedWebhook{}, false
+}
+
+// Store persists State as a JSON file.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Load returns the stored state, or an empty state if the file does not exist.
+func (s *Store) Load() (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := &State{}
+	content, err := os.ReadFile(filepath.Clean(s.path))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while reading webhook state file [%s]: %w", s.path, err)
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("error while parsing webhook state file [%s]: %w", s.path, err)
+	}
+	return state, nil
+}
+
+// Save replaces the stored state atomically.
+func (s *Store) Save(state *State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook state: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return fmt.Errorf("error while writing webhook state file [%s]: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("error while replacing webhook state file [%s]: %w", s.path, err)
+	}
+	return nil
+}
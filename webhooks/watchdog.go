@@ -0,0 +1,314 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/settings"
+	"github.com/rs/zerolog/log"
+)
+
+type WatchdogOptions struct {
+	// Interval is how often webhooks are checked. Defaults to 1m.
+	Interval time.Duration
+	// Silence is the least time a webhook must go without deliveries before
+	// it is recreated. Defaults to 30m.
+	Silence time.Duration
+	// SilenceFactor scales a webhook's average time between deliveries into
+	// how long it may be silent, so quiet pairs aren't recreated for being
+	// quiet. The larger of this and Silence applies. Defaults to 10.
+	SilenceFactor float64
+	// InactiveGrace is how long a webhook may have a status other than
+	// ACTIVE before it is recreated. Defaults to 2m.
+	InactiveGrace time.Duration
+	// AuditFile, if set, gets a JSON line per recreated webhook.
+	AuditFile string
+}
+
+// AuditEntry records a webhook the watchdog recreated, or failed to.
+type AuditEntry struct {
+	Time           time.Time  `json:"time"`
+	Name           string     `json:"name"`
+	WebhookID      string     `json:"webhookId"`
+	NewWebhookID   string     `json:"newWebhookId,omitempty"`
+	Reason         string     `json:"reason"`
+	Status         string     `json:"status"`
+	Deliveries     uint64     `json:"deliveries"`
+	LastReceivedAt *time.Time `json:"lastReceivedAt,omitempty"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// Watchdog recreates the webhooks we provisioned that Codex stopped
+// delivering. Codex may mark a webhook as failing after our endpoint was
+// down, and stop delivering without telling us. A webhook is recreated when
+// its status has been other than ACTIVE for the grace period, or when it
+// delivered before and has been silent for much longer than it usually is.
+// Webhooks that haven't delivered since startup have no expected activity
+// and are only judged by status.
+//
+// Silence is judged from the deliveries this replica received. Behind a load
+// balancer each replica only sees its share of a webhook's deliveries, which
+// SilenceFactor absorbs while they are spread evenly; but a replica that
+// stops getting a webhook's deliveries recreates it even if others still
+// do, so replicas sharing deliveries need a generous Silence.
+type Watchdog struct {
+	provisioner *Provisioner
+	stats       *ingest.Stats
+	configs     map[string]settings.WebhookConfig
+	demand      *Demand
+	opts        WatchdogOptions
+
+	// inactiveSince maps the ID of each webhook seen with a status other than
+	// ACTIVE to when it was first seen so. It is guarded by provisioner.mu.
+	inactiveSince map[string]time.Time
+}
+
+// NewWatchdog returns a Watchdog for the webhooks of provisioner, recreating
+// configured ones from configs. demand may be nil; if set, it is told about
+// its recreated webhooks.
+func NewWatchdog(provisioner *Provisioner, stats *ingest.Stats, configs []settings.WebhookConfig, demand *Demand, opts WatchdogOptions) *Watchdog {
+	if opts.Interval == 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.Silence == 0 {
+		opts.Silence = 30 * time.Minute
+	}
+	if opts.SilenceFactor == 0 {
+		opts.SilenceFactor = 10
+	}
+	if opts.InactiveGrace == 0 {
+		opts.InactiveGrace = 2 * time.Minute
+	}
+	byName := make(map[string]settings.WebhookConfig, len(configs))
+	for _, config := range configs {
+		byName[config.Name] = config
+	}
+	return &Watchdog{
+		provisioner:   provisioner,
+		stats:         stats,
+		configs:       byName,
+		demand:        demand,
+		opts:          opts,
+		inactiveSince: make(map[string]time.Time),
+	}
+}
+
+// Run checks webhooks every interval until ctx is done.
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		if _, err := w.Check(checkCtx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to check webhook liveness")
+		}
+		cancel()
+	}
+}
+
+// stalled is a webhook due to be recreated.
+type stalled struct {
+	webhook ProvisionedWebhook
+	config  settings.WebhookConfig
+	entry   AuditEntry
+}
+
+// Check recreates the stalled webhooks and returns an audit entry for each.
+func (w *Watchdog) Check(ctx context.Context) ([]AuditEntry, error) {
+	w.provisioner.mu.Lock()
+	defer w.provisioner.mu.Unlock()
+
+	state, err := w.provisioner.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	existing, err := ListWebhooks(ctx, w.provisioner.client, w.provisioner.bucketID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	due := w.stalled(state, existing, now)
+	if len(due) == 0 {
+		return nil, nil
+	}
+
+	entries := make([]AuditEntry, 0, len(due))
+	for _, s := range due {
+		entry := s.entry
+		newID, err := w.recreate(ctx, state, s)
+		if err != nil {
+			entry.Error = err.Error()
+			log.Error().Err(err).Str("webhook_id", s.webhook.ID).Str("name", s.webhook.Name).Str("reason", entry.Reason).Msg("failed to recreate stalled webhook")
+		} else {
+			entry.NewWebhookID = newID
+			delete(w.inactiveSince, s.webhook.ID)
+			log.Warn().Str("webhook_id", s.webhook.ID).Str("new_webhook_id", newID).Str("name", s.webhook.Name).Str("reason", entry.Reason).Msg("recreated stalled webhook")
+		}
+		entries = append(entries, entry)
+		w.audit(entry)
+	}
+	return entries, w.provisioner.store.Save(state)
+}
+
+// stalled returns the recorded webhooks that are due to be recreated.
+func (w *Watchdog) stalled(state *State, existing []codex.Webhook, now time.Time) []stalled {
+	byID := make(map[string]codex.Webhook, len(existing))
+	for _, webhook := range existing {
+		byID[webhook.Id] = webhook
+	}
+
+	// If nothing is delivering at all, the outage is more likely ours than
+	// Codex's, and recreating every webhook wouldn't help.
+	delivering := false
+	for _, stats := range w.stats.All() {
+		if now.Sub(stats.LastReceivedAt) < w.opts.Silence {
+			delivering = true
+			break
+		}
+	}
+
+	var due []stalled
+	seen := make(map[string]struct{}, len(state.Webhooks))
+	for _, provisioned := range state.Webhooks {
+		webhook, ok := byID[provisioned.ID]
+		if !ok {
+			// Deleted outside this service, or created before buckets;
+			// the reconciler and Demand take care of their own.
+			continue
+		}
+		seen[webhook.Id] = struct{}{}
+		config, ok := w.config(webhook.Name)
+		if !ok {
+			continue
+		}
+
+		stats, delivered := w.stats.Get(webhook.Id)
+		reason := ""
+		if webhook.Status != statusActive {
+			since, ok := w.inactiveSince[webhook.Id]
+			if !ok {
+				since = now
+				w.inactiveSince[webhook.Id] = now
+				log.Warn().Str("webhook_id", webhook.Id).Str("name", webhook.Name).Str("status", webhook.Status).Msg("webhook is not active")
+			}
+			if now.Sub(since) >= w.opts.InactiveGrace {
+				reason = fmt.Sprintf("status %s for %s", webhook.Status, now.Sub(since).Round(time.Second))
+			}
+		} else {
+			delete(w.inactiveSince, webhook.Id)
+			if delivered && delivering {
+				if silent := now.Sub(stats.LastReceivedAt); silent >= w.allowedSilence(stats) {
+					reason = fmt.Sprintf("silent for %s after %d deliveries", silent.Round(time.Second), stats.Deliveries)
+				}
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		entry := AuditEntry{
+			Time:       now,
+			Name:       webhook.Name,
+			WebhookID:  webhook.Id,
+			Reason:     reason,
+			Status:     webhook.Status,
+			Deliveries: stats.Deliveries,
+		}
+		if delivered {
+			entry.LastReceivedAt = &stats.LastReceivedAt
+		}
+		due = append(due, stalled{webhook: provisioned, config: config, entry: entry})
+	}
+
+	for id := range w.inactiveSince {
+		if _, ok := seen[id]; !ok {
+			delete(w.inactiveSince, id)
+		}
+	}
+	return due
+}
+
+// allowedSilence is how long a webhook with stats may go without
+// deliveries.
+func (w *Watchdog) allowedSilence(stats ingest.WebhookStats) time.Duration {
+	allowed := w.opts.Silence
+	if stats.Deliveries < 2 {
+		return allowed
+	}
+	average := stats.LastReceivedAt.Sub(stats.FirstReceivedAt) / time.Duration(stats.Deliveries-1)
+	if expected := time.Duration(float64(average) * w.opts.SilenceFactor); expected > allowed {
+		return expected
+	}
+	return allowed
+}
+
+// config returns the config a webhook named name is recreated from. Admin
+// and alert webhooks aren't recorded by the provisioner, so aren't watched.
+func (w *Watchdog) config(name string) (settings.WebhookConfig, bool) {
+	if isDemandWebhook(name) {
+		return demandWebhookConfig(strings.TrimPrefix(name, demandPrefix)), true
+	}
+	config, ok := w.configs[name]
+	return config, ok
+}
+
+// recreate deletes the webhook of s and creates it again from its config,
+// returning the new ID. w.provisioner.mu must be held.
+func (w *Watchdog) recreate(ctx context.Context, state *State, s stalled) (string, error) {
+	failed, err := w.provisioner.delete(ctx, state, []string{s.webhook.ID})
+	if err != nil {
+		return "", err
+	}
+	if failed > 0 {
+		return "", fmt.Errorf("failed to delete webhook [%s]", s.webhook.ID)
+	}
+	created, err := w.provisioner.create(ctx, state, []settings.WebhookConfig{s.config})
+	if len(created) == 0 {
+		if err == nil {
+			err = fmt.Errorf("webhook %q was not created", s.config.Name)
+		}
+		// Left for the reconciler or Demand to create again.
+		if w.demand != nil && isDemandWebhook(s.webhook.Name) {
+			w.demand.replaced(strings.TrimPrefix(s.webhook.Name, demandPrefix), s.webhook.ID, "")
+		}
+		return "", err
+	}
+	if w.demand != nil && isDemandWebhook(s.webhook.Name) {
+		w.demand.replaced(strings.TrimPrefix(s.webhook.Name, demandPrefix), s.webhook.ID, created[0].ID)
+	}
+	return created[0].ID, nil
+}
+
+// audit appends entry to the audit file. w.provisioner.mu must be held.
+func (w *Watchdog) audit(entry AuditEntry) {
+	if w.opts.AuditFile == "" {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode webhook audit entry")
+		return
+	}
+	f, err := os.OpenFile(filepath.Clean(w.opts.AuditFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Error().Err(err).Str("path", w.opts.AuditFile).Msg("failed to open webhook audit file")
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Error().Err(err).Str("path", w.opts.AuditFile).Msg("failed to write webhook audit entry")
+	}
+}
@@ -0,0 +1,110 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/codex/codexstub"
+	"github.com/Acrylic125/webhook-ingest-ws/ingest"
+	"github.com/Acrylic125/webhook-ingest-ws/settings"
+)
+
+// provision creates a webhook for config through provisioner and returns its
+// ID.
+func provision(t *testing.T, provisioner *Provisioner, config settings.WebhookConfig) string {
+	t.Helper()
+	provisioner.mu.Lock()
+	defer provisioner.mu.Unlock()
+	state, err := provisioner.store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := provisioner.create(context.Background(), state, []settings.WebhookConfig{config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := provisioner.store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+	return created[0].ID
+}
+
+func TestWatchdogRecreatesInactive(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	provisioner := newTestProvisioner(t, stub)
+	config := settings.WebhookConfig{Name: "weth", TokenAddress: "0xweth", NetworkIds: []int{1}}
+	id := provision(t, provisioner, config)
+	watchdog := NewWatchdog(provisioner, ingest.NewStats(), []settings.WebhookConfig{config}, nil, WatchdogOptions{
+		InactiveGrace: 20 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	stub.SetStatus(id, "DISABLED")
+	if entries, err := watchdog.Check(ctx); err != nil || len(entries) != 0 {
+		t.Fatalf("recreated %+v (%v) before InactiveGrace", entries, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	entries, err := watchdog.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].WebhookID != id || entries[0].NewWebhookID == "" || entries[0].Status != "DISABLED" {
+		t.Fatalf("audit entries %+v, want the disabled webhook recreated", entries)
+	}
+	webhooks := stub.Webhooks()
+	if len(webhooks) != 1 || webhooks[0].ID != entries[0].NewWebhookID || webhooks[0].Name != "weth" || webhooks[0].Status != statusActive {
+		t.Fatalf("webhooks %+v, want only the recreated one", webhooks)
+	}
+	state, err := provisioner.store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorded, _ := state.ByName("weth"); recorded.ID != entries[0].NewWebhookID {
+		t.Fatalf("recorded %+v, want the new ID", recorded)
+	}
+}
+
+func TestWatchdogRecreatesSilentDemandWebhook(t *testing.T) {
+	stub := codexstub.Start("codex-key")
+	defer stub.Close()
+	provisioner := newTestProvisioner(t, stub)
+	demand := startDemand(t, provisioner, DemandOptions{Grace: 50 * time.Millisecond, MaxWebhooks: 10})
+	topic := ingest.PairTopic("0xa", 1)
+	demand.TopicDemanded(topic)
+	waitFor(t, "the webhook to be created", func() bool { return len(stub.Webhooks()) == 1 })
+	id := stub.Webhooks()[0].ID
+
+	stats := ingest.NewStats()
+	stats.Record(id, ingest.WebhookTypeTokenPairEvent, 1)
+	stats.Record(id, ingest.WebhookTypeTokenPairEvent, 1)
+	watchdog := NewWatchdog(provisioner, stats, nil, demand, WatchdogOptions{Silence: 50 * time.Millisecond})
+	ctx := context.Background()
+	time.Sleep(60 * time.Millisecond)
+
+	// Nothing is delivering, so the outage is taken to be ours.
+	if entries, err := watchdog.Check(ctx); err != nil || len(entries) != 0 {
+		t.Fatalf("recreated %+v (%v) while nothing delivers", entries, err)
+	}
+
+	stats.Record("other", ingest.WebhookTypeTokenPairEvent, 1)
+	entries, err := watchdog.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].WebhookID != id || entries[0].NewWebhookID == "" || entries[0].Deliveries != 2 {
+		t.Fatalf("audit entries %+v, want the silent webhook recreated", entries)
+	}
+	newID := entries[0].NewWebhookID
+	demand.mu.Lock()
+	active := demand.active[topic]
+	demand.mu.Unlock()
+	if active != newID {
+		t.Fatalf("demand has webhook %s for the topic, want %s", active, newID)
+	}
+
+	// Releasing the topic deletes the recreated webhook.
+	demand.TopicReleased(topic)
+	waitFor(t, "the recreated webhook to be deleted", func() bool { return len(stub.Webhooks()) == 0 })
+}
@@ -0,0 +1,165 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Acrylic125/webhook-ingest-ws/backplane"
+	"github.com/rs/zerolog/log"
+)
+
+// backplaneMessage wraps a broadcast published to other instances. Instance
+// lets a hub ignore its own echo and Seq lets receivers drop duplicates and
+// detect gaps per publishing instance. Envelopes are sent without their seq,
+// and Key and Also passed through to Hub.PublishKeyed on the receiving side,
+// which numbers them on its own topics.
+type backplaneMessage struct {
+	Instance string          `json:"instance"`
+	Seq      uint64          `json:"seq"`
+	Key      string          `json:"key,omitempty"`
+	Also     []string        `json:"also,omitempty"`
+	Envelope json.RawMessage `json:"envelope"`
+}
+
+// backplaneQueueSize is how many messages may wait to be published to the
+// backplane. Messages published while it is full are dropped, which other
+// instances log as a sequence gap.
+const backplaneQueueSize = 1024
+
+type hubBackplane struct {
+	bp         backplane.Backplane
+	channel    string
+	instanceID string
+	// queue holds encoded messages in seq order until run publishes them,
+	// so publishing never waits on the backplane.
+	queue chan []byte
+
+	mu      sync.Mutex
+	seq     uint64
+	lastSeq map[string]uint64
+}
+
+// NewInstanceID returns an ID unique to this process, for use with
+// UseBackplane.
+func NewInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "instance"
+	}
+	return fmt.Sprintf("%s-%s", host, newClientID()[:8])
+}
+
+// UseBackplane shares this hub's Publish and PublishKeyed calls with every
+// other hub subscribed to channel on bp. It must be called before the hub is
+// used. Targeted sends (PublishWhere, SendTo, SendToMany, BroadcastWhere)
+// stay local. Messages are published to bp in the background until ctx is
+// done.
+func (h *Hub[T]) UseBackplane(ctx context.Context, bp backplane.Backplane, channel string, instanceID string) error {
+	hb := &hubBackplane{
+		bp:         bp,
+		channel:    channel,
+		instanceID: instanceID,
+		queue:      make(chan []byte, backplaneQueueSize),
+		lastSeq:    make(map[string]uint64),
+	}
+	if err := bp.Subscribe(ctx, channel, func(payload []byte) {
+		msg, ok := hb.receive(payload)
+		if !ok {
+			return
+		}
+		envelope, err := decodeEnvelope(msg.Envelope)
+		if err != nil {
+			log.Warn().Err(err).Str("channel", channel).Msg("Invalid backplane envelope")
+			return
+		}
+		h.send(&outbound[T]{
+			topic:    envelope.Topic,
+			also:     msg.Also,
+			key:      msg.Key,
+			envelope: envelope,
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to backplane channel [%s]: %w", channel, err)
+	}
+	go hb.run(ctx)
+	h.backplane = hb
+	return nil
+}
+
+// publish queues envelope to be shared with the other instances.
+func (hb *hubBackplane) publish(key string, also []string, envelope *Envelope) {
+	encoded, err := envelope.Marshal()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode backplane envelope")
+		return
+	}
+	msg := backplaneMessage{
+		Instance: hb.instanceID,
+		Key:      key,
+		Also:     also,
+		Envelope: encoded,
+	}
+
+	// The seq is taken and the message queued under one lock so the queue
+	// stays in seq order.
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	msg.Seq = hb.seq + 1
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode backplane message")
+		return
+	}
+	hb.seq = msg.Seq
+	select {
+	case hb.queue <- payload:
+	default:
+		log.Error().Str("channel", hb.channel).Uint64("seq", msg.Seq).Msg("Backplane queue full, dropping message")
+	}
+}
+
+// run publishes queued messages until ctx is done.
+func (hb *hubBackplane) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-hb.queue:
+			publishCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			if err := hb.bp.Publish(publishCtx, hb.channel, payload); err != nil && ctx.Err() == nil {
+				log.Error().Err(err).Str("channel", hb.channel).Msg("Backplane publish error")
+			}
+			cancel()
+		}
+	}
+}
+
+// receive returns the message to deliver locally, or false if it is our own
+// echo, a duplicate, or arrived out of order.
+func (hb *hubBackplane) receive(payload []byte) (*backplaneMessage, bool) {
+	msg := &backplaneMessage{}
+	if err := json.Unmarshal(payload, msg); err != nil {
+		log.Warn().Err(err).Str("channel", hb.channel).Msg("Invalid backplane message")
+		return nil, false
+	}
+	if msg.Instance == hb.instanceID {
+		return nil, false
+	}
+
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	last, seen := hb.lastSeq[msg.Instance]
+	if seen && msg.Seq <= last {
+		log.Debug().Str("instance", msg.Instance).Uint64("seq", msg.Seq).Uint64("last_seq", last).Msg("Dropping stale backplane message")
+		return nil, false
+	}
+	if seen && msg.Seq > last+1 {
+		log.Warn().Str("instance", msg.Instance).Uint64("missed", msg.Seq-last-1).Msg("Backplane sequence gap")
+	}
+	hb.lastSeq[msg.Instance] = msg.Seq
+	return msg, true
+}
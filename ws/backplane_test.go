@@ -0,0 +1,69 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// stalledBackplane blocks every Publish until release is closed.
+type stalledBackplane struct {
+	release   chan struct{}
+	published chan []byte
+}
+
+func (b *stalledBackplane) Publish(ctx context.Context, channel string, payload []byte) error {
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	b.published <- payload
+	return nil
+}
+
+func (b *stalledBackplane) Subscribe(ctx context.Context, channel string, handler func(payload []byte)) error {
+	return nil
+}
+
+func (b *stalledBackplane) Close() error { return nil }
+
+func TestBackplanePublishDoesNotWait(t *testing.T) {
+	bp := &stalledBackplane{release: make(chan struct{}), published: make(chan []byte, 8)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := NewHub[any]()
+	if err := hub.UseBackplane(ctx, bp, "ch", "local"); err != nil {
+		t.Fatal(err)
+	}
+
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			hub.backplane.publish("", nil, NewEnvelope(EnvelopeEvent, "topic", i))
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publish waited on the backplane")
+	}
+
+	close(bp.release)
+	for want := uint64(1); want <= 3; want++ {
+		select {
+		case payload := <-bp.published:
+			msg := backplaneMessage{}
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg.Seq != want {
+				t.Fatalf("published seq %d, want %d", msg.Seq, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the queued messages")
+		}
+	}
+}
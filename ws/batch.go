@@ -0,0 +1,122 @@
+package ws
+
+import (
+	"bytes"
+	"time"
+)
+
+// BatchConfig controls how a client's outgoing messages are coalesced. While
+// batching, queued messages are written as one JSON array frame once Window
+// has passed since the first of them, or as soon as MaxMessages are queued.
+// With MaxMessages but no Window, the messages already waiting to be written
+// are batched, up to MaxMessages per frame. Batching is disabled when both
+// are zero.
+type BatchConfig struct {
+	Window      time.Duration
+	MaxMessages int
+}
+
+// SetBatching changes the batching window of c. It is safe to call from any
+// goroutine; the latest config wins.
+func (c *UserClient[T]) SetBatching(config BatchConfig) {
+	for {
+		select {
+		case c.batchConfig <- config:
+			return
+		default:
+		}
+		// Replace a config writePump has not picked up yet.
+		select {
+		case <-c.batchConfig:
+		default:
+		}
+	}
+}
+
+type BatchRequest struct {
+	WindowMs    int `json:"windowMs" validate:"min=0,max=1000"`
+	MaxMessages int `json:"maxMessages" validate:"min=0,max=1000"`
+}
+
+type BatchResponse struct {
+	WindowMs    int `json:"windowMs"`
+	MaxMessages int `json:"maxMessages"`
+}
+
+// HandleBatching registers the "batch" message type on r, letting clients opt
+// into (or out of, with windowMs and maxMessages 0) frame batching.
+func HandleBatching[T any](r *Router[T]) {
+	Handle(r, "batch", func(client *UserClient[T], req BatchRequest) (BatchResponse, error) {
+		client.SetBatching(BatchConfig{
+			Window:      time.Duration(req.WindowMs) * time.Millisecond,
+			MaxMessages: req.MaxMessages,
+		})
+		return BatchResponse(req), nil
+	})
+}
+
+// frameBatch is owned by writePump.
+type frameBatch struct {
+	config  BatchConfig
+	pending [][]byte
+	timer   *time.Timer
+	armed   bool
+}
+
+func newFrameBatch() *frameBatch {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	return &frameBatch{timer: timer}
+}
+
+func (b *frameBatch) enabled() bool {
+	return b.config.Window > 0 || b.config.MaxMessages > 0
+}
+
+// arm starts the window timer if there is a window and it is not already
+// running.
+func (b *frameBatch) arm() {
+	if b.config.Window > 0 && !b.armed {
+		b.timer.Reset(b.config.Window)
+		b.armed = true
+	}
+}
+
+// add queues message and reports whether the batch is full.
+func (b *frameBatch) add(message []byte) bool {
+	b.pending = append(b.pending, message)
+	b.arm()
+	return b.config.MaxMessages > 0 && len(b.pending) >= b.config.MaxMessages
+}
+
+// flush returns the queued messages followed by extra as a JSON array, or nil
+// if there are none.
+func (b *frameBatch) flush(extra ...[]byte) []byte {
+	if !b.timer.Stop() {
+		select {
+		case <-b.timer.C:
+		default:
+		}
+	}
+	b.armed = false
+	b.pending = append(b.pending, extra...)
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	// Brackets and commas add one byte per message plus one.
+	size := 1 + len(b.pending)
+	for _, message := range b.pending {
+		size += len(message)
+	}
+	frame := make([]byte, 0, size)
+	frame = append(frame, '[')
+	frame = append(frame, bytes.Join(b.pending, []byte{','})...)
+	frame = append(frame, ']')
+	b.pending = b.pending[:0]
+	return frame
+}
+
+func (b *frameBatch) stop() {
+	b.timer.Stop()
+}
@@ -0,0 +1,45 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrameBatchEnabled(t *testing.T) {
+	for _, config := range []BatchConfig{
+		{Window: 10 * time.Millisecond},
+		{MaxMessages: 10},
+		{Window: 10 * time.Millisecond, MaxMessages: 10},
+	} {
+		if batch := (&frameBatch{config: config}); !batch.enabled() {
+			t.Errorf("batching with %+v is disabled", config)
+		}
+	}
+	if (&frameBatch{}).enabled() {
+		t.Error("batching with the zero config is enabled")
+	}
+}
+
+func TestFrameBatchFlush(t *testing.T) {
+	batch := newFrameBatch()
+	defer batch.stop()
+	batch.config = BatchConfig{MaxMessages: 3}
+	if batch.add([]byte(`{"a":1}`)) || batch.add([]byte(`{"b":22}`)) {
+		t.Fatal("batch full before MaxMessages")
+	}
+	if !batch.add([]byte(`{"c":333}`)) {
+		t.Fatal("batch not full at MaxMessages")
+	}
+
+	frame := batch.flush()
+	want := `[{"a":1},{"b":22},{"c":333}]`
+	if string(frame) != want {
+		t.Fatalf("flushed %s, want %s", frame, want)
+	}
+	if cap(frame) != len(want) {
+		t.Fatalf("frame capacity %d, want %d", cap(frame), len(want))
+	}
+	if batch.flush() != nil {
+		t.Fatal("flushed an empty batch")
+	}
+}
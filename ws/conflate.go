@@ -0,0 +1,87 @@
+package ws
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// SubscribeModeAll delivers every message on a topic.
+	SubscribeModeAll = "all"
+	// SubscribeModeLatest keeps only the newest pending message per key on a
+	// topic, so slow clients skip intermediate values instead of queueing
+	// them.
+	SubscribeModeLatest = "latest"
+)
+
+// latestValues holds a client's conflated messages until writePump flushes
+// them. Keys are flushed in the order they were first set.
+type latestValues struct {
+	mu      sync.Mutex
+	pending map[string][]byte
+	order   []string
+	notify  chan struct{}
+}
+
+func newLatestValues() *latestValues {
+	return &latestValues{
+		pending: make(map[string][]byte),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// set replaces the pending message for key and wakes writePump.
+func (l *latestValues) set(key string, message []byte) {
+	l.mu.Lock()
+	if _, ok := l.pending[key]; !ok {
+		l.order = append(l.order, key)
+	}
+	l.pending[key] = message
+	l.mu.Unlock()
+
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
+}
+
+// drain returns and clears every pending message.
+func (l *latestValues) drain() [][]byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	messages := make([][]byte, 0, len(l.order))
+	for _, key := range l.order {
+		messages = append(messages, l.pending[key])
+	}
+	clear(l.pending)
+	l.order = l.order[:0]
+	return messages
+}
+
+// PublishKeyed is Publish for envelopes carrying a conflation key, such as a
+// pair address. Clients subscribed to the topic in SubscribeModeLatest only
+// get the newest pending envelope per key. The envelope is also published on
+// each topic in also. Subscribers of several of the topics get it once, on
+// the first they are subscribed to; clients that haven't subscribed to any
+// topic only get it on envelope.Topic.
+func (h *Hub[T]) PublishKeyed(key string, envelope *Envelope, also ...string) (int, error) {
+	envelope, err := envelope.withRawData()
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode envelope data: %w", err)
+	}
+	reached := h.send(&outbound[T]{
+		topic:    envelope.Topic,
+		also:     also,
+		key:      key,
+		envelope: envelope,
+	})
+	if h.backplane != nil {
+		h.backplane.publish(key, also, envelope)
+	}
+	return reached, nil
+}
+
+// conflates must only be called from Run.
+func (c *UserClient[T]) conflates(topic string, key string) bool {
+	return key != "" && c.topics[topic] == SubscribeModeLatest
+}
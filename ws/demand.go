@@ -0,0 +1,39 @@
+package ws
+
+// DemandListener is told when a topic on a hub gains its first subscriber or
+// loses its last one. Its methods are called from Run and must not block.
+type DemandListener interface {
+	TopicDemanded(topic string)
+	TopicReleased(topic string)
+}
+
+// UseDemand reports the hub's per-topic subscriber demand to listener. It
+// must be called before the hub is used.
+func (h *Hub[T]) UseDemand(listener DemandListener) {
+	h.demand = listener
+	h.subscribers = make(map[string]int)
+}
+
+// addSubscriber must only be called from Run.
+func (h *Hub[T]) addSubscriber(topic string) {
+	if h.demand == nil {
+		return
+	}
+	h.subscribers[topic]++
+	if h.subscribers[topic] == 1 {
+		h.demand.TopicDemanded(topic)
+	}
+}
+
+// removeSubscriber must only be called from Run.
+func (h *Hub[T]) removeSubscriber(topic string) {
+	if h.demand == nil {
+		return
+	}
+	if h.subscribers[topic] <= 1 {
+		delete(h.subscribers, topic)
+		h.demand.TopicReleased(topic)
+		return
+	}
+	h.subscribers[topic]--
+}
@@ -0,0 +1,90 @@
+package ws
+
+import (
+	_ "embed"
+	"encoding/json"
+	"time"
+)
+
+// EnvelopeVersion is bumped on breaking changes to Envelope.
+const EnvelopeVersion = 1
+
+type EnvelopeType string
+
+const (
+	EnvelopeEvent    EnvelopeType = "event"
+	EnvelopeSnapshot EnvelopeType = "snapshot"
+	EnvelopeError    EnvelopeType = "error"
+	EnvelopeAck      EnvelopeType = "ack"
+	EnvelopeNotice   EnvelopeType = "notice"
+)
+
+// EnvelopeSchema is the JSON Schema describing Envelope.
+//
+//go:embed envelope.schema.json
+var EnvelopeSchema []byte
+
+// Envelope wraps every message sent to clients.
+type Envelope struct {
+	Version int          `json:"v"`
+	Type    EnvelopeType `json:"type"`
+	// Topic names the stream the message belongs to. Empty for acks and
+	// errors, which are replies to a single client.
+	Topic string `json:"topic,omitempty"`
+	// Seq increases by one per message on Topic, whatever its Source. Hubs
+	// assign it when publishing.
+	Seq uint64 `json:"seq,omitempty"`
+	// ServerTs is when the envelope was built, in Unix milliseconds.
+	ServerTs int64  `json:"serverTs"`
+	Source   string `json:"source,omitempty"`
+	// Backfill is set on events recovered from Codex's history after they
+	// were missed live. They may arrive out of order.
+	Backfill bool `json:"backfill,omitempty"`
+	// ID echoes the request ID for acks and errors.
+	ID    json.RawMessage `json:"id,omitempty"`
+	Data  any             `json:"data,omitempty"`
+	Error *Error          `json:"error,omitempty"`
+}
+
+func NewEnvelope(envelopeType EnvelopeType, topic string, data any) *Envelope {
+	return &Envelope{
+		Version:  EnvelopeVersion,
+		Type:     envelopeType,
+		Topic:    topic,
+		ServerTs: time.Now().UnixMilli(),
+		Data:     data,
+	}
+}
+
+func (e *Envelope) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// withRawData returns a copy of e with Data encoded, so the hub's Run loop
+// only has to encode the envelope around it.
+func (e *Envelope) withRawData() (*Envelope, error) {
+	c := *e
+	if _, ok := c.Data.(json.RawMessage); ok || c.Data == nil {
+		return &c, nil
+	}
+	data, err := json.Marshal(c.Data)
+	if err != nil {
+		return nil, err
+	}
+	c.Data = json.RawMessage(data)
+	return &c, nil
+}
+
+// decodeEnvelope decodes an envelope, leaving its data encoded.
+func decodeEnvelope(payload []byte) (*Envelope, error) {
+	var data json.RawMessage
+	e := &Envelope{Data: &data}
+	if err := json.Unmarshal(payload, e); err != nil {
+		return nil, err
+	}
+	e.Data = nil
+	if data != nil {
+		e.Data = data
+	}
+	return e, nil
+}
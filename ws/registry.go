@@ -0,0 +1,195 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrHubExists   = errors.New("hub already registered")
+	ErrPathInUse   = errors.New("hub path already in use")
+	ErrHubNotFound = errors.New("hub not found")
+)
+
+// HubConfig configures a hub mounted through a Registry.
+type HubConfig[T any] struct {
+	// Path the hub is served on, e.g. "/ws/pairs".
+	Path string
+	// MaxClients rejects new connections once reached. 0 means unlimited.
+	MaxClients int
+	// MaxMessageSize is the largest frame accepted from a client, in bytes.
+	// 0 means no limit.
+	MaxMessageSize int64
+	// Authenticate is called before the upgrade and returns the initial client
+	// data. Returning an error rejects the connection with 401. If nil, every
+	// connection is accepted with the zero value of T.
+	Authenticate func(r *http.Request) (T, error)
+}
+
+// MountedHub is the type-erased view of a hub held by a Registry.
+type MountedHub interface {
+	http.Handler
+	Name() string
+	Path() string
+	Publish(envelope *Envelope) (int, error)
+	PublishKeyed(key string, envelope *Envelope, also ...string) (int, error)
+	ClientCount() int
+}
+
+type mountedHub[T any] struct {
+	name    string
+	manager HubManager[T]
+	config  HubConfig[T]
+	// connections counts the connections being accepted or open, which the
+	// hub's client count only includes once Run registers them.
+	connections atomic.Int64
+}
+
+func (m *mountedHub[T]) Name() string {
+	return m.name
+}
+
+func (m *mountedHub[T]) Path() string {
+	return m.config.Path
+}
+
+func (m *mountedHub[T]) Publish(envelope *Envelope) (int, error) {
+	return m.manager.GetHub().Publish(envelope)
+}
+
+func (m *mountedHub[T]) PublishKeyed(key string, envelope *Envelope, also ...string) (int, error) {
+	return m.manager.GetHub().PublishKeyed(key, envelope, also...)
+}
+
+func (m *mountedHub[T]) ClientCount() int {
+	return m.manager.GetHub().ClientCount()
+}
+
+func (m *mountedHub[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !m.reserve() {
+		log.Warn().Str("hub", m.name).Int("max_clients", m.config.MaxClients).Msg("Hub full, rejecting connection")
+		http.Error(w, "Too many connections", http.StatusServiceUnavailable)
+		return
+	}
+
+	var data T
+	if m.config.Authenticate != nil {
+		authData, err := m.config.Authenticate(r)
+		if err != nil {
+			m.release()
+			log.Warn().Err(err).Str("hub", m.name).Msg("Authentication failed")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		data = authData
+	}
+
+	connectSocket(m.manager, w, r, data, m.config.MaxMessageSize, m.release)
+}
+
+// reserve takes a connection slot and reports whether one was free under
+// MaxClients.
+func (m *mountedHub[T]) reserve() bool {
+	n := m.connections.Add(1)
+	if m.config.MaxClients > 0 && n > int64(m.config.MaxClients) {
+		m.connections.Add(-1)
+		return false
+	}
+	return true
+}
+
+func (m *mountedHub[T]) release() {
+	m.connections.Add(-1)
+}
+
+// Registry holds independently configured hubs, each mounted on its own path.
+type Registry struct {
+	mu    sync.RWMutex
+	hubs  map[string]MountedHub
+	paths map[string]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		hubs:  make(map[string]MountedHub),
+		paths: make(map[string]string),
+	}
+}
+
+// Register adds a hub under name and starts its Run loop.
+func Register[T any](r *Registry, name string, manager HubManager[T], config HubConfig[T]) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.hubs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrHubExists, name)
+	}
+	if owner, ok := r.paths[config.Path]; ok {
+		return fmt.Errorf("%w: %s (hub %s)", ErrPathInUse, config.Path, owner)
+	}
+
+	r.hubs[name] = &mountedHub[T]{
+		name:    name,
+		manager: manager,
+		config:  config,
+	}
+	r.paths[config.Path] = name
+	go Run(manager)
+
+	log.Info().Str("hub", name).Str("path", config.Path).Msg("Hub registered")
+	return nil
+}
+
+func (r *Registry) Get(name string) (MountedHub, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	hub, ok := r.hubs[name]
+	return hub, ok
+}
+
+// Names returns the names of the registered hubs, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.hubs))
+	for name := range r.hubs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Publish sends envelope on its topic to the subscribers of the named hub.
+func (r *Registry) Publish(name string, envelope *Envelope) (int, error) {
+	hub, ok := r.Get(name)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrHubNotFound, name)
+	}
+	return hub.Publish(envelope)
+}
+
+// PublishKeyed sends envelope on its topic and the topics in also,
+// conflated by key, to the subscribers of the named hub. See
+// Hub.PublishKeyed.
+func (r *Registry) PublishKeyed(name string, key string, envelope *Envelope, also ...string) (int, error) {
+	hub, ok := r.Get(name)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrHubNotFound, name)
+	}
+	return hub.PublishKeyed(key, envelope, also...)
+}
+
+// Mount registers every hub's handler on mux under its configured path.
+func (r *Registry) Mount(mux *http.ServeMux) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, hub := range r.hubs {
+		mux.Handle(hub.Path(), hub)
+	}
+}
@@ -0,0 +1,87 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type testHubManager struct {
+	hub *Hub[any]
+}
+
+func (m *testHubManager) GetHub() *Hub[any]                                          { return m.hub }
+func (m *testHubManager) OnRegister(client *UserClient[any]) error                   { return nil }
+func (m *testHubManager) OnUnregister(client *UserClient[any]) error                 { return nil }
+func (m *testHubManager) OnReceiveMessage(client *UserClient[any], msg []byte) error { return nil }
+
+func TestMaxClientsConcurrentUpgrades(t *testing.T) {
+	registry := NewRegistry()
+	err := Register(registry, "test", &testHubManager{hub: NewHub[any]()}, HubConfig[any]{
+		Path:       "/ws",
+		MaxClients: 3,
+		// Holds every upgrade long enough for the others to be let in if
+		// slots weren't reserved.
+		Authenticate: func(r *http.Request) (any, error) {
+			time.Sleep(50 * time.Millisecond)
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub, _ := registry.Get("test")
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	var mu sync.Mutex
+	var conns []*websocket.Conn
+	rejected := 0
+	wg := sync.WaitGroup{}
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if resp != nil && resp.StatusCode == http.StatusServiceUnavailable {
+					rejected++
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}()
+	}
+	wg.Wait()
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	if len(conns) != 3 || rejected != 7 {
+		t.Fatalf("accepted %d and rejected %d connections, want 3 and 7", len(conns), rejected)
+	}
+
+	// Closing a connection frees its slot.
+	conns[0].Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err == nil {
+			conns[0] = conn
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("slot of a closed connection was not freed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,151 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// Error codes follow JSON-RPC 2.0.
+const (
+	ErrCodeParse          = -32700
+	ErrCodeInvalidRequest = -32600
+	ErrCodeUnknownType    = -32601
+	ErrCodeInvalidParams  = -32602
+	ErrCodeInternal       = -32603
+)
+
+// Request is the frame clients send to the hub. Requests without an ID are
+// notifications: the handler runs but nothing, not even an error, is sent
+// back.
+type Request struct {
+	ID   json.RawMessage `json:"id,omitempty"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Error is a structured error reply. Handlers may return it to control the
+// code and data sent to the client; any other error is logged and reported as
+// ErrCodeInternal without its details.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ws error %d: %s", e.Code, e.Message)
+}
+
+func NewError(code int, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// nullID is the ID of replies to frames whose ID couldn't be read.
+var nullID = json.RawMessage("null")
+
+type routeHandler[T any] func(client *UserClient[T], data json.RawMessage) (any, error)
+
+// Router dispatches incoming frames to handlers registered by message type.
+// It is meant to be called from HubManager.OnReceiveMessage.
+type Router[T any] struct {
+	handlers map[string]routeHandler[T]
+	validate *validator.Validate
+}
+
+func NewRouter[T any]() *Router[T] {
+	return &Router[T]{
+		handlers: make(map[string]routeHandler[T]),
+		validate: validator.New(),
+	}
+}
+
+// Handle registers fn for msgType. The request data is decoded into Req and,
+// if Req is a struct, validated with its `validate` tags before fn is called.
+// Handle must not be called once the router is dispatching.
+func Handle[T any, Req any, Res any](r *Router[T], msgType string, fn func(client *UserClient[T], req Req) (Res, error)) {
+	r.handlers[msgType] = func(client *UserClient[T], data json.RawMessage) (any, error) {
+		var req Req
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &req); err != nil {
+				return nil, NewError(ErrCodeInvalidParams, "invalid data: "+err.Error())
+			}
+		}
+		if reflect.TypeOf((*Req)(nil)).Elem().Kind() == reflect.Struct {
+			if err := r.validate.Struct(req); err != nil {
+				return nil, NewError(ErrCodeInvalidParams, "validation failed: "+err.Error())
+			}
+		}
+		return fn(client, req)
+	}
+}
+
+// Dispatch decodes message, runs the matching handler and replies to client
+// with an ack or error envelope carrying the request ID, unless it has none.
+// Invalid JSON is replied to with a parse error with a null ID.
+// The returned error is the one reported to the client, if any. Replies are
+// dropped rather than blocking the read loop if the client's send buffer is
+// full.
+func (r *Router[T]) Dispatch(client *UserClient[T], message []byte) error {
+	var req Request
+	if err := json.Unmarshal(message, &req); err != nil {
+		rpcErr := NewError(ErrCodeParse, "invalid JSON: "+err.Error())
+		r.replyError(client, nullID, rpcErr)
+		return rpcErr
+	}
+	if req.Type == "" {
+		rpcErr := NewError(ErrCodeInvalidRequest, "missing message type")
+		r.replyError(client, req.ID, rpcErr)
+		return rpcErr
+	}
+
+	handler, ok := r.handlers[req.Type]
+	if !ok {
+		rpcErr := NewError(ErrCodeUnknownType, "unknown message type: "+req.Type)
+		r.replyError(client, req.ID, rpcErr)
+		return rpcErr
+	}
+
+	result, err := handler(client, req.Data)
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			log.Error().Err(err).Str("client_id", client.id).Str("type", req.Type).Msg("Handler error")
+			rpcErr = NewError(ErrCodeInternal, "internal error")
+		}
+		r.replyError(client, req.ID, rpcErr)
+		return rpcErr
+	}
+
+	if len(req.ID) > 0 {
+		envelope := NewEnvelope(EnvelopeAck, "", result)
+		envelope.ID = req.ID
+		r.reply(client, envelope)
+	}
+	return nil
+}
+
+func (r *Router[T]) replyError(client *UserClient[T], id json.RawMessage, rpcErr *Error) {
+	if len(id) == 0 {
+		return
+	}
+	envelope := NewEnvelope(EnvelopeError, "", nil)
+	envelope.ID = id
+	envelope.Error = rpcErr
+	r.reply(client, envelope)
+}
+
+func (r *Router[T]) reply(client *UserClient[T], envelope *Envelope) {
+	b, err := envelope.Marshal()
+	if err != nil {
+		log.Error().Err(err).Str("type", string(envelope.Type)).Msg("Failed to encode reply")
+		return
+	}
+	if !client.Send(b) {
+		log.Warn().Str("client_id", client.id).Str("type", string(envelope.Type)).Msg("Client send buffer full, dropping reply")
+	}
+}
@@ -0,0 +1,83 @@
+package ws
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDispatchReplies(t *testing.T) {
+	router := NewRouter[any]()
+	Handle(router, "echo", func(client *UserClient[any], req string) (string, error) {
+		return req, nil
+	})
+	Handle(router, "fail", func(client *UserClient[any], req string) (string, error) {
+		return "", errors.New("connection refused by db-internal:5432")
+	})
+
+	for _, tt := range []struct {
+		name    string
+		message string
+		want    EnvelopeType
+		wantID  string
+		wantErr *Error
+	}{
+		{"ack", `{"id":1,"type":"echo","data":"hi"}`, EnvelopeAck, "1", nil},
+		{"error", `{"id":1,"type":"missing"}`, EnvelopeError, "1", NewError(ErrCodeUnknownType, "unknown message type: missing")},
+		{"notification", `{"type":"echo","data":"hi"}`, "", "", nil},
+		{"notification error", `{"type":"missing"}`, "", "", NewError(ErrCodeUnknownType, "unknown message type: missing")},
+		{"invalid JSON", `{"id":1,`, EnvelopeError, "null", NewError(ErrCodeParse, "invalid JSON: unexpected end of JSON input")},
+		// Details of unstructured errors stay in the logs.
+		{"internal error", `{"id":"a","type":"fail"}`, EnvelopeError, `"a"`, NewError(ErrCodeInternal, "internal error")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewUserClient[any](nil, nil)
+			err := router.Dispatch(client, []byte(tt.message))
+			if (err == nil) != (tt.wantErr == nil) || err != nil && err.Error() != tt.wantErr.Error() {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.want == "" {
+				if len(client.send) != 0 {
+					t.Fatalf("replied %s, want no reply", <-client.send)
+				}
+				return
+			}
+			if len(client.send) != 1 {
+				t.Fatalf("sent %d replies, want 1", len(client.send))
+			}
+			reply, err := decodeEnvelope(<-client.send)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reply.Type != tt.want || string(reply.ID) != tt.wantID {
+				t.Fatalf("replied %s with id %s, want %s with id %s", reply.Type, reply.ID, tt.want, tt.wantID)
+			}
+			if tt.wantErr != nil && (reply.Error == nil || *reply.Error != *tt.wantErr) {
+				t.Fatalf("replied error %+v, want %+v", reply.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDispatchDoesNotBlockOnFullBuffer(t *testing.T) {
+	router := NewRouter[any]()
+	Handle(router, "echo", func(client *UserClient[any], req string) (string, error) {
+		return req, nil
+	})
+	client := NewUserClient[any](nil, nil)
+	client.send = make(chan []byte, 1)
+	client.send <- []byte("queued")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- router.Dispatch(client, []byte(`{"id":1,"type":"echo","data":"hi"}`))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Dispatch blocked on the full send buffer")
+	}
+}
@@ -1,6 +1,8 @@
 package ws
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 
 	mapset "github.com/deckarep/golang-set/v2"
@@ -17,26 +19,113 @@ var upgrader = websocket.Upgrader{
 }
 
 type UserClient[T any] struct {
-	conn *websocket.Conn
-	send chan []byte
-	data T
+	id          string
+	hub         *Hub[T]
+	conn        *websocket.Conn
+	send        chan []byte
+	batchConfig chan BatchConfig
+	data        T
+	// topics maps a subscribed topic to its subscribe mode and is owned by
+	// the hub's Run loop. Until the client subscribes to a topic it receives
+	// every message.
+	topics   map[string]string
+	filtered bool
+	latest   *latestValues
 }
 
-func (c *UserClient[T]) Send(message []byte) {
-	c.send <- message
+func (c *UserClient[T]) ID() string {
+	return c.id
+}
+
+func (c *UserClient[T]) Data() T {
+	return c.data
+}
+
+// Send queues message for c without blocking and reports whether it fit in
+// c's send buffer. It is safe to call from HubManager.OnReceiveMessage; the
+// hub delivers everything else.
+func (c *UserClient[T]) Send(message []byte) bool {
+	select {
+	case c.send <- message:
+		return true
+	default:
+		return false
+	}
 }
 
 func (c *UserClient[T]) writePump() {
 	defer c.conn.Close()
 
-	for message := range c.send {
-		if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-			log.Error().Err(err).Msg("WriteMessage error")
-			return
+	batch := newFrameBatch()
+	defer batch.stop()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			if !ok {
+				c.writeBatch(batch)
+				return
+			}
+			if !batch.enabled() {
+				if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+					log.Error().Err(err).Msg("WriteMessage error")
+					return
+				}
+				continue
+			}
+			// Without a window, the batch holds what was already waiting.
+			full := batch.add(message) || (batch.config.Window == 0 && len(c.send) == 0)
+			if full && !c.writeBatch(batch) {
+				return
+			}
+		case <-batch.timer.C:
+			if !c.writeBatch(batch) {
+				return
+			}
+		case <-c.latest.notify:
+			// While batching with a window, latest values keep conflating
+			// until the batch is flushed.
+			if batch.config.Window > 0 {
+				batch.arm()
+				continue
+			}
+			if batch.enabled() {
+				if !c.writeBatch(batch) {
+					return
+				}
+				continue
+			}
+			for _, message := range c.latest.drain() {
+				if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+					log.Error().Err(err).Msg("WriteMessage error")
+					return
+				}
+			}
+		case config := <-c.batchConfig:
+			// Flush with the previous settings so no message waits longer than
+			// the window it was queued under.
+			if !c.writeBatch(batch) {
+				return
+			}
+			batch.config = config
 		}
 	}
 }
 
+// writeBatch flushes any queued messages as a single frame and reports
+// whether the connection is still usable.
+func (c *UserClient[T]) writeBatch(batch *frameBatch) bool {
+	frame := batch.flush(c.latest.drain()...)
+	if frame == nil {
+		return true
+	}
+	if err := c.conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+		log.Error().Err(err).Msg("WriteMessage error")
+		return false
+	}
+	return true
+}
+
 func (c *UserClient[T]) readPump(hubManager HubManager[T]) {
 	hub := hubManager.GetHub()
 	defer func() {
@@ -54,7 +143,9 @@ func (c *UserClient[T]) readPump(hubManager HubManager[T]) {
 		}
 
 		if err := hubManager.OnReceiveMessage(c, messageBytes); err != nil {
-			log.Warn().Err(err).Msg("HandleMessage error")
+			// Clients control what they send, so rejected frames are only
+			// worth a debug line.
+			log.Debug().Err(err).Str("client_id", c.id).Msg("HandleMessage error")
 			continue
 		}
 	}
@@ -68,42 +159,158 @@ type HubManager[T any] interface {
 }
 
 func ConnectSocket[T any](hubManager HubManager[T], w http.ResponseWriter, r *http.Request, initialData T) {
+	connectSocket(hubManager, w, r, initialData, 0, func() {})
+}
+
+// connectSocket calls closed once the connection is closed, or if the upgrade
+// fails.
+func connectSocket[T any](hubManager HubManager[T], w http.ResponseWriter, r *http.Request, initialData T, readLimit int64, closed func()) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		closed()
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
+	if readLimit > 0 {
+		conn.SetReadLimit(readLimit)
+	}
 
 	client := NewUserClient(conn, initialData)
+	client.hub = hubManager.GetHub()
 
 	hubManager.GetHub().register <- client
 
 	// Start goroutines for reading and writing
 	go client.writePump()
-	go client.readPump(hubManager)
+	go func() {
+		client.readPump(hubManager)
+		closed()
+	}()
+}
+
+// outbound is a message waiting to be delivered by the hub's Run loop to
+// every registered client accepted by match (all clients if match is nil).
+// Messages with a topic only reach clients subscribed to it. Envelopes are
+// numbered and encoded by Run; other messages are sent as is. Envelopes are
+// also published on the topics in also.
+type outbound[T any] struct {
+	topic    string
+	also     []string
+	key      string
+	envelope *Envelope
+	message  []byte
+	match    func(client *UserClient[T]) bool
+	reached  chan int
 }
 
 type Hub[T any] struct {
-	clients    mapset.Set[*UserClient[T]]
-	broadcast  chan []byte
-	register   chan *UserClient[T]
-	unregister chan *UserClient[T]
+	clients       mapset.Set[*UserClient[T]]
+	broadcast     chan *outbound[T]
+	register      chan *UserClient[T]
+	unregister    chan *UserClient[T]
+	subscriptions chan *subscriptionChange[T]
+	backplane     *hubBackplane
+	demand        DemandListener
+	normalize     func(topic string) string
+	// history and subscribers are owned by Run.
+	history      map[string]*topicHistory[T]
+	publications uint64
+	subscribers  map[string]int
 }
 
-func (h *Hub[T]) Broadcast(message []byte) {
+func (h *Hub[T]) ClientCount() int {
+	return h.clients.Cardinality()
+}
+
+// BroadcastWhere sends message as is to every connected client for which
+// match returns true and returns how many clients it reached. Envelopes
+// should be sent with PublishWhere instead, which numbers them.
+func (h *Hub[T]) BroadcastWhere(message []byte, match func(client *UserClient[T]) bool) int {
+	return h.send(&outbound[T]{
+		message: message,
+		match:   match,
+	})
+}
+
+func (h *Hub[T]) send(out *outbound[T]) int {
+	out.reached = make(chan int, 1)
+	h.broadcast <- out
+	return <-out.reached
+}
+
+// SendTo sends message to the client with the given ID and returns how many
+// clients it reached (0 or 1).
+func (h *Hub[T]) SendTo(clientID string, message []byte) int {
+	return h.BroadcastWhere(message, func(client *UserClient[T]) bool {
+		return client.id == clientID
+	})
+}
+
+// SendToMany sends message to every client whose ID is in clientIDs and
+// returns how many clients it reached.
+func (h *Hub[T]) SendToMany(clientIDs []string, message []byte) int {
+	ids := mapset.NewThreadUnsafeSet(clientIDs...)
+	return h.BroadcastWhere(message, func(client *UserClient[T]) bool {
+		return ids.Contains(client.id)
+	})
+}
+
+// deliver must only be called from Run, which owns closing client send
+// channels. Clients whose send buffer is full are skipped rather than
+// blocking the hub.
+func (h *Hub[T]) deliver(out *outbound[T]) int {
+	topics, messages := []string{out.topic}, [][]byte{out.message}
+	if out.envelope != nil {
+		var err error
+		topics, messages, err = h.record(out.envelope, out.also, out.match)
+		if err != nil {
+			log.Error().Err(err).Str("topic", out.topic).Msg("Failed to encode envelope")
+			return 0
+		}
+	}
+
+	// Each client gets the message once, on the first of its topics.
+	reached := 0
 	h.clients.Each(func(client *UserClient[T]) bool {
-		client.Send(message)
-		return true
+		if out.match != nil && !out.match(client) {
+			return false
+		}
+		i := client.receives(topics)
+		if i < 0 {
+			return false
+		}
+		if client.conflates(topics[i], out.key) {
+			client.latest.set(out.key, messages[i])
+			reached++
+			return false
+		}
+		select {
+		case client.send <- messages[i]:
+			reached++
+		default:
+			log.Warn().Str("client_id", client.id).Msg("Client send buffer full, dropping message")
+		}
+		return false
 	})
+	return reached
 }
 
-var ServerHub *Hub[any]
+func newClientID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
 
 func NewUserClient[T any](conn *websocket.Conn, data T) *UserClient[T] {
 	return &UserClient[T]{
-		conn: conn,
-		send: make(chan []byte, 256),
-		data: data,
+		id:          newClientID(),
+		conn:        conn,
+		send:        make(chan []byte, 256),
+		batchConfig: make(chan BatchConfig, 1),
+		data:        data,
+		latest:      newLatestValues(),
 	}
 }
 
@@ -121,32 +328,29 @@ func Run[T any](hubManager HubManager[T]) {
 			if c.clients.Contains(client) {
 				c.clients.Remove(client)
 				close(client.send)
+				for topic := range client.topics {
+					c.removeSubscriber(topic)
+				}
 				log.Info().Int("Client Count", c.clients.Cardinality()).Msg("Client disconnected")
 				if err := hubManager.OnUnregister(client); err != nil {
 					log.Error().Err(err).Msg("OnUnregister error")
 				}
 			}
-		case message := <-c.broadcast:
-			c.Broadcast(message)
+		case out := <-c.broadcast:
+			out.reached <- c.deliver(out)
+		case change := <-c.subscriptions:
+			change.replayed <- c.applySubscription(change)
 		}
 	}
 }
 
 func NewHub[T any]() *Hub[T] {
 	return &Hub[T]{
-		clients:    mapset.NewSet[*UserClient[T]](),
-		broadcast:  make(chan []byte),
-		register:   make(chan *UserClient[T]),
-		unregister: make(chan *UserClient[T]),
+		clients:       mapset.NewSet[*UserClient[T]](),
+		broadcast:     make(chan *outbound[T]),
+		register:      make(chan *UserClient[T]),
+		unregister:    make(chan *UserClient[T]),
+		subscriptions: make(chan *subscriptionChange[T]),
+		history:       make(map[string]*topicHistory[T]),
 	}
 }
-
-func Init() {
-	ServerHub = &Hub[any]{
-		clients:    mapset.NewSet[*UserClient[any]](),
-		broadcast:  make(chan []byte),
-		register:   make(chan *UserClient[any]),
-		unregister: make(chan *UserClient[any]),
-	}
-
-}
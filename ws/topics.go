@@ -0,0 +1,360 @@
+package ws
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// replayBufferSize is how many recent messages per topic are kept for clients
+// resuming after a reconnect.
+const replayBufferSize = 256
+
+type historyEntry[T any] struct {
+	seq       uint64
+	message   []byte
+	published *published[T]
+}
+
+// published records the seq an envelope got on each topic it was published
+// on. It is shared by the envelope's history entries.
+type published[T any] struct {
+	// order is the position of the envelope among those published on the
+	// hub.
+	order uint64
+	seqs  map[string]uint64
+	// match limits the clients the envelope is replayed to, like it limited
+	// those it was delivered to. nil for envelopes published to every
+	// subscriber.
+	match func(client *UserClient[T]) bool
+}
+
+// seenBy reports whether a client resuming from resume already received the
+// envelope, on any of its topics.
+func (p *published[T]) seenBy(resume map[string]uint64) bool {
+	for topic, seq := range p.seqs {
+		if last, ok := resume[topic]; ok && seq <= last {
+			return true
+		}
+	}
+	return false
+}
+
+// topicHistory numbers the envelopes published on a topic and keeps the
+// latest of them.
+type topicHistory[T any] struct {
+	seq     uint64
+	entries []historyEntry[T]
+}
+
+// append numbers envelope with the next seq on the topic, records it in
+// published, keeps it and returns it encoded.
+func (t *topicHistory[T]) append(envelope *Envelope, published *published[T]) ([]byte, error) {
+	numbered := *envelope
+	numbered.Seq = t.seq + 1
+	message, err := numbered.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	t.seq = numbered.Seq
+	published.seqs[envelope.Topic] = t.seq
+	t.entries = append(t.entries, historyEntry[T]{seq: t.seq, message: message, published: published})
+	if len(t.entries) > 2*replayBufferSize {
+		t.entries = append([]historyEntry[T](nil), t.entries[len(t.entries)-replayBufferSize:]...)
+	}
+	return message, nil
+}
+
+func (t *topicHistory[T]) after(seq uint64) []historyEntry[T] {
+	entries := t.entries
+	if len(entries) > replayBufferSize {
+		entries = entries[len(entries)-replayBufferSize:]
+	}
+	for i, entry := range entries {
+		if entry.seq > seq {
+			return entries[i:]
+		}
+	}
+	return nil
+}
+
+type subscriptionChange[T any] struct {
+	client      *UserClient[T]
+	topics      []string
+	mode        string
+	resume      map[string]uint64
+	unsubscribe bool
+	replayed    chan Replay
+}
+
+// Replay is what resuming topics on subscribe sent to the client.
+type Replay struct {
+	Replayed int
+	// Truncated are the topics whose replay stopped early because the
+	// client's send buffer filled up. The gap shows up on the client as a
+	// jump in seq.
+	Truncated []string
+}
+
+// receives returns the index of the first of topics c is subscribed to, or -1
+// if none. Clients that haven't subscribed to any topic only receive messages
+// on the first, and messages without a topic reach every client. It must only
+// be called from Run.
+func (c *UserClient[T]) receives(topics []string) int {
+	if topics[0] == "" || !c.filtered {
+		return 0
+	}
+	for i, topic := range topics {
+		if _, ok := c.topics[topic]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// Publish sends envelope on its topic to every subscribed client and returns
+// how many local clients it reached. The hub numbers envelopes per topic,
+// ignoring envelope.Seq, and keeps the latest for replay to resuming clients.
+func (h *Hub[T]) Publish(envelope *Envelope) (int, error) {
+	return h.PublishKeyed("", envelope)
+}
+
+// PublishWhere is Publish for the subscribed clients for which match returns
+// true, and is only replayed to them. The envelope shares the seq of its
+// topic with every other envelope published on it, so a client only getting
+// some of them sees jumps in seq. Unlike Publish, it stays on this instance
+// when the hub uses a backplane.
+func (h *Hub[T]) PublishWhere(envelope *Envelope, match func(client *UserClient[T]) bool) (int, error) {
+	envelope, err := envelope.withRawData()
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode envelope data: %w", err)
+	}
+	return h.send(&outbound[T]{
+		topic:    envelope.Topic,
+		envelope: envelope,
+		match:    match,
+	}), nil
+}
+
+// UseTopicNormalizer passes the topics clients subscribe to, unsubscribe from
+// and resume through normalize, so that different spellings of a topic, such
+// as differently cased addresses, are one topic. It must be called before the
+// hub is used.
+func (h *Hub[T]) UseTopicNormalizer(normalize func(topic string) string) {
+	h.normalize = normalize
+}
+
+func (h *Hub[T]) normalizeTopics(topics []string) []string {
+	if h.normalize == nil {
+		return topics
+	}
+	normalized := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		topic = h.normalize(topic)
+		if !slices.Contains(normalized, topic) {
+			normalized = append(normalized, topic)
+		}
+	}
+	return normalized
+}
+
+// Subscribe limits client to the given topics (in addition to any it already
+// has) in the given mode, SubscribeModeAll if empty. For each topic in resume,
+// buffered messages with a greater sequence number are replayed once every
+// topic is subscribed.
+func (h *Hub[T]) Subscribe(client *UserClient[T], topics []string, mode string, resume map[string]uint64) Replay {
+	if mode == "" {
+		mode = SubscribeModeAll
+	}
+	if h.normalize != nil && len(resume) > 0 {
+		normalized := make(map[string]uint64, len(resume))
+		for topic, seq := range resume {
+			normalized[h.normalize(topic)] = seq
+		}
+		resume = normalized
+	}
+	change := &subscriptionChange[T]{
+		client:   client,
+		topics:   h.normalizeTopics(topics),
+		mode:     mode,
+		resume:   resume,
+		replayed: make(chan Replay, 1),
+	}
+	h.subscriptions <- change
+	return <-change.replayed
+}
+
+func (h *Hub[T]) Unsubscribe(client *UserClient[T], topics []string) {
+	change := &subscriptionChange[T]{
+		client:      client,
+		topics:      h.normalizeTopics(topics),
+		unsubscribe: true,
+		replayed:    make(chan Replay, 1),
+	}
+	h.subscriptions <- change
+	<-change.replayed
+}
+
+// record numbers envelope on its topic and each of also, and returns the
+// topics with the envelope encoded for each. match is kept for replays. It
+// must only be called from Run.
+func (h *Hub[T]) record(envelope *Envelope, also []string, match func(client *UserClient[T]) bool) ([]string, [][]byte, error) {
+	topics := []string{envelope.Topic}
+	for _, topic := range also {
+		if !slices.Contains(topics, topic) {
+			topics = append(topics, topic)
+		}
+	}
+	h.publications++
+	published := &published[T]{order: h.publications, seqs: make(map[string]uint64, len(topics)), match: match}
+
+	messages := make([][]byte, len(topics))
+	for i, topic := range topics {
+		history, ok := h.history[topic]
+		if !ok {
+			history = &topicHistory[T]{}
+			h.history[topic] = history
+		}
+		onTopic := *envelope
+		onTopic.Topic = topic
+		message, err := history.append(&onTopic, published)
+		if err != nil {
+			return nil, nil, err
+		}
+		messages[i] = message
+	}
+	return topics, messages, nil
+}
+
+// applySubscription must only be called from Run.
+func (h *Hub[T]) applySubscription(change *subscriptionChange[T]) Replay {
+	client := change.client
+	if !h.clients.Contains(client) {
+		return Replay{}
+	}
+	if change.unsubscribe {
+		for _, topic := range change.topics {
+			if _, ok := client.topics[topic]; ok {
+				delete(client.topics, topic)
+				h.removeSubscriber(topic)
+			}
+		}
+		return Replay{}
+	}
+
+	if client.topics == nil {
+		client.topics = make(map[string]string)
+	}
+	client.filtered = true
+
+	for _, topic := range change.topics {
+		if _, ok := client.topics[topic]; !ok {
+			h.addSubscriber(topic)
+		}
+		client.topics[topic] = change.mode
+	}
+
+	// Envelopes published on several of the topics are replayed once, in the
+	// order they were published, unless the client got them on another.
+	type replayEntry struct {
+		topic string
+		historyEntry[T]
+	}
+	var pending []replayEntry
+	for _, topic := range change.topics {
+		seq, ok := change.resume[topic]
+		history, hasHistory := h.history[topic]
+		if !ok || !hasHistory {
+			continue
+		}
+		for _, entry := range history.after(seq) {
+			if entry.published.match != nil && !entry.published.match(client) {
+				continue
+			}
+			if !entry.published.seenBy(change.resume) {
+				pending = append(pending, replayEntry{topic: topic, historyEntry: entry})
+			}
+		}
+	}
+	slices.SortStableFunc(pending, func(a, b replayEntry) int {
+		return cmp.Compare(a.published.order, b.published.order)
+	})
+
+	replay := Replay{}
+	sent := make(map[*published[T]]bool, len(pending))
+	for i, entry := range pending {
+		if sent[entry.published] {
+			continue
+		}
+		// The last slot of the send buffer is left for the subscribe ack,
+		// which reports what was truncated.
+		if len(client.send) < cap(client.send)-1 {
+			select {
+			case client.send <- entry.message:
+				sent[entry.published] = true
+				replay.Replayed++
+				continue
+			default:
+			}
+		}
+		for _, rest := range pending[i:] {
+			if !sent[rest.published] && !slices.Contains(replay.Truncated, rest.topic) {
+				replay.Truncated = append(replay.Truncated, rest.topic)
+			}
+		}
+		break
+	}
+	return replay
+}
+
+type SubscribeRequest struct {
+	Topics []string `json:"topics" validate:"required,min=1,max=100,dive,required,max=256"`
+	// Mode is SubscribeModeAll (the default) or SubscribeModeLatest.
+	Mode string `json:"mode,omitempty" validate:"omitempty,oneof=all latest"`
+	// Resume maps a topic to the last seq the client received on it.
+	Resume map[string]uint64 `json:"resume,omitempty"`
+}
+
+type SubscribeResponse struct {
+	Topics   []string `json:"topics"`
+	Mode     string   `json:"mode"`
+	Replayed int      `json:"replayed"`
+	// Truncated are the resumed topics not fully replayed.
+	Truncated []string `json:"truncated,omitempty"`
+}
+
+type UnsubscribeRequest struct {
+	Topics []string `json:"topics" validate:"required,min=1,max=100"`
+}
+
+type UnsubscribeResponse struct {
+	Topics []string `json:"topics"`
+}
+
+// HandleSubscriptions registers the "subscribe" and "unsubscribe" message
+// types on r.
+func HandleSubscriptions[T any](r *Router[T]) {
+	Handle(r, "subscribe", func(client *UserClient[T], req SubscribeRequest) (SubscribeResponse, error) {
+		if client.hub == nil {
+			return SubscribeResponse{}, fmt.Errorf("client %s is not attached to a hub", client.id)
+		}
+		mode := req.Mode
+		if mode == "" {
+			mode = SubscribeModeAll
+		}
+		replay := client.hub.Subscribe(client, req.Topics, mode, req.Resume)
+		return SubscribeResponse{
+			Topics:    client.hub.normalizeTopics(req.Topics),
+			Mode:      mode,
+			Replayed:  replay.Replayed,
+			Truncated: replay.Truncated,
+		}, nil
+	})
+	Handle(r, "unsubscribe", func(client *UserClient[T], req UnsubscribeRequest) (UnsubscribeResponse, error) {
+		if client.hub == nil {
+			return UnsubscribeResponse{}, fmt.Errorf("client %s is not attached to a hub", client.id)
+		}
+		client.hub.Unsubscribe(client, req.Topics)
+		return UnsubscribeResponse{Topics: client.hub.normalizeTopics(req.Topics)}, nil
+	})
+}
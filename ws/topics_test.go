@@ -0,0 +1,134 @@
+package ws
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSubscribeReplayTruncated(t *testing.T) {
+	hub := NewHub[any]()
+	for _, topic := range []string{"a", "b"} {
+		for i := 0; i < 3; i++ {
+			hub.deliver(&outbound[any]{topic: topic, envelope: NewEnvelope(EnvelopeEvent, topic, i)})
+		}
+	}
+
+	client := NewUserClient[any](nil, nil)
+	client.send = make(chan []byte, 5)
+	hub.clients.Add(client)
+
+	replay := hub.applySubscription(&subscriptionChange[any]{
+		client: client,
+		topics: []string{"a", "b", "c"},
+		mode:   SubscribeModeAll,
+		resume: map[string]uint64{"a": 0, "b": 0},
+	})
+	if replay.Replayed != 4 {
+		t.Fatalf("replayed %d, want 4", replay.Replayed)
+	}
+	if !slices.Equal(replay.Truncated, []string{"b"}) {
+		t.Fatalf("truncated %v, want [b]", replay.Truncated)
+	}
+	for _, topic := range []string{"a", "b", "c"} {
+		if _, ok := client.topics[topic]; !ok {
+			t.Fatalf("topic %s was not subscribed", topic)
+		}
+	}
+}
+
+func TestPublishOncePerClient(t *testing.T) {
+	hub := NewHub[any]()
+	unfiltered := NewUserClient[any](nil, nil)
+	both := NewUserClient[any](nil, nil)
+	hub.clients.Add(unfiltered)
+	hub.clients.Add(both)
+	hub.applySubscription(&subscriptionChange[any]{client: both, topics: []string{"token:0xt:1", "pair:0xp:1"}, mode: SubscribeModeAll})
+
+	envelope := NewEnvelope(EnvelopeEvent, "TOKEN_PAIR_EVENT", "data")
+	reached := hub.deliver(&outbound[any]{topic: envelope.Topic, also: []string{"pair:0xp:1", "token:0xt:1"}, envelope: envelope})
+	if reached != 2 {
+		t.Fatalf("reached %d, want 2", reached)
+	}
+	if len(unfiltered.send) != 1 || len(both.send) != 1 {
+		t.Fatalf("got %d and %d messages, want one each", len(unfiltered.send), len(both.send))
+	}
+	for client, topic := range map[*UserClient[any]]string{unfiltered: "TOKEN_PAIR_EVENT", both: "pair:0xp:1"} {
+		got, err := decodeEnvelope(<-client.send)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Topic != topic || got.Seq != 1 {
+			t.Fatalf("got %s seq %d, want %s seq 1", got.Topic, got.Seq, topic)
+		}
+	}
+
+	// Resuming after the envelope was received on the pair topic doesn't
+	// replay it from the token topic.
+	resumed := NewUserClient[any](nil, nil)
+	hub.clients.Add(resumed)
+	replay := hub.applySubscription(&subscriptionChange[any]{
+		client: resumed,
+		topics: []string{"pair:0xp:1", "token:0xt:1"},
+		mode:   SubscribeModeAll,
+		resume: map[string]uint64{"pair:0xp:1": 1, "token:0xt:1": 0},
+	})
+	if replay.Replayed != 0 {
+		t.Fatalf("replayed %d, want 0", replay.Replayed)
+	}
+	replay = hub.applySubscription(&subscriptionChange[any]{
+		client: resumed,
+		topics: []string{"pair:0xp:1", "token:0xt:1"},
+		mode:   SubscribeModeAll,
+		resume: map[string]uint64{"pair:0xp:1": 0, "token:0xt:1": 0},
+	})
+	if replay.Replayed != 1 {
+		t.Fatalf("replayed %d, want 1", replay.Replayed)
+	}
+}
+
+func TestPublishWhereNumbersAndReplaysToMatches(t *testing.T) {
+	hub := NewHub[any]()
+	alice := NewUserClient[any](nil, "alice")
+	bob := NewUserClient[any](nil, "bob")
+	hub.clients.Add(alice)
+	hub.clients.Add(bob)
+	to := func(user string) func(client *UserClient[any]) bool {
+		return func(client *UserClient[any]) bool { return client.data == user }
+	}
+
+	for _, user := range []string{"alice", "bob", "alice"} {
+		envelope := NewEnvelope(EnvelopeEvent, "alerts", user)
+		hub.deliver(&outbound[any]{topic: envelope.Topic, envelope: envelope, match: to(user)})
+	}
+	var seqs []uint64
+	for len(alice.send) > 0 {
+		got, err := decodeEnvelope(<-alice.send)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, got.Seq)
+	}
+	if !slices.Equal(seqs, []uint64{1, 3}) || len(bob.send) != 1 {
+		t.Fatalf("alice got seqs %v and bob %d messages, want seqs [1 3] and 1", seqs, len(bob.send))
+	}
+
+	// Resuming only replays the envelopes sent to the client.
+	resumed := NewUserClient[any](nil, "alice")
+	hub.clients.Add(resumed)
+	replay := hub.applySubscription(&subscriptionChange[any]{
+		client: resumed,
+		topics: []string{"alerts"},
+		mode:   SubscribeModeAll,
+		resume: map[string]uint64{"alerts": 1},
+	})
+	if replay.Replayed != 1 {
+		t.Fatalf("replayed %d, want 1", replay.Replayed)
+	}
+	got, err := decodeEnvelope(<-resumed.send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Seq != 3 {
+		t.Fatalf("replayed seq %d, want 3", got.Seq)
+	}
+}